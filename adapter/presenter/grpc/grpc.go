@@ -31,6 +31,10 @@ func (p *userPresenter) ConvertError(err error) *status.Status {
 		return status.New(codes.DeadlineExceeded, err.Error())
 	case context.Canceled:
 		return status.New(codes.Canceled, err.Error())
+	case customErrors.NotSupported:
+		return status.New(codes.Unimplemented, err.Error())
+	case customErrors.ChecksumMismatch:
+		return status.New(codes.DataLoss, err.Error())
 	}
 
 	// Check if err has defined type
@@ -38,10 +42,41 @@ func (p *userPresenter) ConvertError(err error) *status.Status {
 
 	switch errObj := err.(type) {
 	case validation.Errors:
-		grpcErr = status.New(codes.InvalidArgument, errObj.Error())
+		grpcErr = status.New(convertErrorsCode(errObj), errObj.Error())
 		grpcErr, _ = grpcErr.WithDetails(customErrors.BadRequestDetails(&errObj))
+	case validation.Error:
+		grpcErr = status.New(convertErrorCode(errObj.Code()), errObj.Error())
 	default:
 		grpcErr = status.New(codes.Internal, err.Error())
 	}
 	return grpcErr
 }
+
+// convertErrorCode converts code of validation error to grpc code
+func convertErrorCode(code string) codes.Code {
+	switch code {
+	case "404":
+		return codes.NotFound
+	case "409":
+		return codes.AlreadyExists
+	case "413":
+		return codes.ResourceExhausted
+	case "416":
+		return codes.OutOfRange
+	default:
+		return codes.InvalidArgument
+	}
+}
+
+// convertErrorsCode returns grpc code of the first field error with specific code,
+// e.g. too large file is reported as ResourceExhausted with field violations
+func convertErrorsCode(errs validation.Errors) codes.Code {
+	for _, err := range errs {
+		if errObj, ok := err.(validation.Error); ok {
+			if code := convertErrorCode(errObj.Code()); code != codes.InvalidArgument {
+				return code
+			}
+		}
+	}
+	return codes.InvalidArgument
+}
@@ -0,0 +1,56 @@
+package boltRepo
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+)
+
+type (
+	txContextKey struct{}
+
+	// transactor opens read-write transactions of the database, repositories of the database
+	// called with the context of the transaction join it, so their changes are committed together
+	transactor struct {
+		db *bbolt.DB
+	}
+)
+
+func NewTransactor(db *bbolt.DB) *transactor {
+	return &transactor{db: db}
+}
+
+// Transact commits changes made by fn unless it fails, fn joins the transaction of ctx if it's opened
+func (t *transactor) Transact(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx, t.db) != nil {
+		return fn(ctx)
+	}
+	return t.db.Update(func(tx *bbolt.Tx) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	})
+}
+
+// Update runs fn in the transaction of ctx or in a new read-write transaction.
+// A write transaction of the database is exclusive, so repositories must not open their own inside it
+func Update(ctx context.Context, db *bbolt.DB, fn func(tx *bbolt.Tx) error) error {
+	if tx := txFromContext(ctx, db); tx != nil {
+		return fn(tx)
+	}
+	return db.Update(fn)
+}
+
+// View runs fn in the transaction of ctx or in a new read-only transaction
+func View(ctx context.Context, db *bbolt.DB, fn func(tx *bbolt.Tx) error) error {
+	if tx := txFromContext(ctx, db); tx != nil {
+		return fn(tx)
+	}
+	return db.View(fn)
+}
+
+// txFromContext returns transaction opened by Transact if it belongs to the database
+func txFromContext(ctx context.Context, db *bbolt.DB) *bbolt.Tx {
+	if tx, ok := ctx.Value(txContextKey{}).(*bbolt.Tx); ok && tx.DB() == db {
+		return tx
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package boltRepo_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	boltRepo "github.com/freemen-app/file_storage/adapter/repository/bolt"
+	catalogRepo "github.com/freemen-app/file_storage/adapter/repository/catalog"
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+)
+
+func testDB(t *testing.T, name string) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), name), 0600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db
+}
+
+func TestTransactor_Transact(t *testing.T) {
+	db := testDB(t, "test.db")
+	transactor := boltRepo.NewTransactor(db)
+	catalog, dedup := catalogRepo.New(db), dedupRepo.New(db)
+	entry := dto.NewCatalogEntry("test.jpg", "http://localhost/test.jpg")
+	ref := &dto.BlobRef{Key: entry.Key, Url: entry.Url, BlobUrl: "http://localhost/_blobs/1"}
+	testErr := errors.New("test")
+
+	// Changes of repositories are discarded together
+	err := transactor.Transact(helpers.DefaultCtx, func(ctx context.Context) error {
+		if _, err := dedup.Link(ctx, ref); err != nil {
+			return err
+		} else if err := catalog.Save(ctx, entry); err != nil {
+			return err
+		}
+		got, err := catalog.Get(ctx, entry.Url)
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+		return testErr
+	})
+	assert.EqualValues(t, testErr, err)
+	got, err := catalog.Get(helpers.DefaultCtx, entry.Url)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	refs, err := dedup.Refs(helpers.DefaultCtx, ref.BlobUrl)
+	assert.NoError(t, err)
+	assert.Zero(t, refs)
+
+	// Nested transaction joins the outer one, repositories of another database don't join it
+	other := catalogRepo.New(testDB(t, "other.db"))
+	err = transactor.Transact(helpers.DefaultCtx, func(ctx context.Context) error {
+		if _, err := dedup.Link(ctx, ref); err != nil {
+			return err
+		} else if err := other.Save(ctx, entry); err != nil {
+			return err
+		}
+		return transactor.Transact(ctx, func(ctx context.Context) error {
+			return catalog.Save(ctx, entry)
+		})
+	})
+	assert.NoError(t, err)
+	for _, repo := range []interface {
+		Get(context.Context, string) (*dto.CatalogEntry, error)
+	}{catalog, other} {
+		got, err := repo.Get(helpers.DefaultCtx, entry.Url)
+		assert.NoError(t, err)
+		assert.NotNil(t, got)
+	}
+	refs, err = dedup.Refs(helpers.DefaultCtx, ref.BlobUrl)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, refs)
+}
@@ -0,0 +1,150 @@
+package catalogRepo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	boltRepo "github.com/freemen-app/file_storage/adapter/repository/bolt"
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+var (
+	// entriesBucket maps file key to its CatalogEntry, so entries are listed sorted by keys
+	entriesBucket = []byte("catalog_entries")
+	// urlsBucket maps file url to its key
+	urlsBucket = []byte("catalog_urls")
+)
+
+type (
+	repo struct {
+		db *bbolt.DB
+	}
+)
+
+func New(db *bbolt.DB) *repo {
+	return &repo{db: db}
+}
+
+// Save records the file replacing previous entry of its key
+func (r *repo) Save(ctx context.Context, entry *dto.CatalogEntry) error {
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		entries, urls, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+		if err := putEntry(entries, entry); err != nil {
+			return err
+		}
+		return urls.Put([]byte(entry.Url), []byte(entry.Key))
+	})
+}
+
+// MarkDeleted sets deletion time of recorded files, unknown and already deleted files are skipped
+func (r *repo) MarkDeleted(ctx context.Context, urls dto.BatchDeleteInput, at time.Time) error {
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		entries, keys, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+		for _, url := range urls {
+			entry, err := getEntry(entries, keys.Get([]byte(url)))
+			if err != nil {
+				return err
+			} else if entry == nil || entry.IsDeleted() {
+				continue
+			}
+			entry.DeletedAt = at
+			if err := putEntry(entries, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns entry of the file, nil is returned if the file isn't recorded
+func (r *repo) Get(ctx context.Context, url string) (entry *dto.CatalogEntry, err error) {
+	err = boltRepo.View(ctx, r.db, func(tx *bbolt.Tx) error {
+		entries, keys := tx.Bucket(entriesBucket), tx.Bucket(urlsBucket)
+		if entries != nil && keys != nil {
+			entry, err = getEntry(entries, keys.Get([]byte(url)))
+		}
+		return err
+	})
+	return entry, err
+}
+
+// List returns page of entries sorted by keys, page token is the last key of the previous page
+func (r *repo) List(ctx context.Context, input *dto.CatalogListInput) (*dto.CatalogListOutput, error) {
+	output := &dto.CatalogListOutput{Entries: make([]*dto.CatalogEntry, 0)}
+	err := boltRepo.View(ctx, r.db, func(tx *bbolt.Tx) error {
+		entries := tx.Bucket(entriesBucket)
+		if entries == nil {
+			return nil
+		}
+		prefix := []byte(input.Prefix)
+		cursor := entries.Cursor()
+		key, data := cursor.Seek(prefix)
+		if input.PageToken != "" {
+			key, data = cursor.Seek([]byte(input.PageToken))
+			if key != nil && string(key) == input.PageToken {
+				key, data = cursor.Next()
+			}
+		}
+		for ; key != nil && bytes.HasPrefix(key, prefix); key, data = cursor.Next() {
+			entry := new(dto.CatalogEntry)
+			if err := json.Unmarshal(data, entry); err != nil {
+				return err
+			} else if !input.Match(entry) {
+				continue
+			}
+			if int64(len(output.Entries)) == input.Limit() {
+				output.NextPageToken = output.Entries[len(output.Entries)-1].Key
+				break
+			}
+			output.Entries = append(output.Entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func buckets(tx *bbolt.Tx) (entries, urls *bbolt.Bucket, err error) {
+	if entries, err = tx.CreateBucketIfNotExists(entriesBucket); err != nil {
+		return nil, nil, err
+	}
+	if urls, err = tx.CreateBucketIfNotExists(urlsBucket); err != nil {
+		return nil, nil, err
+	}
+	return entries, urls, nil
+}
+
+func getEntry(entries *bbolt.Bucket, key []byte) (*dto.CatalogEntry, error) {
+	if key == nil {
+		return nil, nil
+	}
+	data := entries.Get(key)
+	if data == nil {
+		return nil, nil
+	}
+	entry := new(dto.CatalogEntry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func putEntry(entries *bbolt.Bucket, entry *dto.CatalogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return entries.Put([]byte(entry.Key), data)
+}
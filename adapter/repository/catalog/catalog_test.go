@@ -0,0 +1,140 @@
+package catalogRepo_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	catalogRepo "github.com/freemen-app/file_storage/adapter/repository/catalog"
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+)
+
+func testDB(t *testing.T) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db
+}
+
+func testEntry(key, owner string) *dto.CatalogEntry {
+	entry := dto.NewCatalogEntry(key, "http://localhost/files/"+key)
+	entry.Owner, entry.Size, entry.ContentType = owner, 4, "image/jpeg"
+	entry.CreatedAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry.UpdatedAt = entry.CreatedAt
+	return entry
+}
+
+func TestRepo_Save_Get(t *testing.T) {
+	repo := catalogRepo.New(testDB(t))
+
+	got, err := repo.Get(helpers.DefaultCtx, "http://localhost/files/test.jpg")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	entry := testEntry("test/test.jpg", "user")
+	assert.NoError(t, repo.Save(helpers.DefaultCtx, entry))
+	got, err = repo.Get(helpers.DefaultCtx, entry.Url)
+	assert.NoError(t, err)
+	assert.EqualValues(t, entry, got)
+
+	// Entry of the key is replaced
+	entry.Size = 8
+	assert.NoError(t, repo.Save(helpers.DefaultCtx, entry))
+	got, err = repo.Get(helpers.DefaultCtx, entry.Url)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 8, got.Size)
+}
+
+func TestRepo_MarkDeleted(t *testing.T) {
+	repo := catalogRepo.New(testDB(t))
+	entry := testEntry("test.jpg", "user")
+	assert.NoError(t, repo.Save(helpers.DefaultCtx, entry))
+
+	deletedAt := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	urls := dto.BatchDeleteInput{dto.DeleteInput(entry.Url), "http://localhost/files/missing.jpg"}
+	assert.NoError(t, repo.MarkDeleted(helpers.DefaultCtx, urls, deletedAt))
+	got, err := repo.Get(helpers.DefaultCtx, entry.Url)
+	assert.NoError(t, err)
+	assert.True(t, got.DeletedAt.Equal(deletedAt))
+
+	// Deletion time of already deleted file isn't changed
+	assert.NoError(t, repo.MarkDeleted(helpers.DefaultCtx, urls, deletedAt.Add(time.Hour)))
+	got, err = repo.Get(helpers.DefaultCtx, entry.Url)
+	assert.NoError(t, err)
+	assert.True(t, got.DeletedAt.Equal(deletedAt))
+}
+
+func TestRepo_List(t *testing.T) {
+	repo := catalogRepo.New(testDB(t))
+	for _, entry := range []*dto.CatalogEntry{
+		testEntry("a/1.jpg", "user"),
+		testEntry("a/2.jpg", "other"),
+		testEntry("a/3.jpg", "user"),
+		testEntry("a/4.jpg", "user"),
+		testEntry("b/1.jpg", "user"),
+	} {
+		assert.NoError(t, repo.Save(helpers.DefaultCtx, entry))
+	}
+	assert.NoError(t, repo.MarkDeleted(helpers.DefaultCtx, dto.BatchDeleteInput{"http://localhost/files/a/4.jpg"}, time.Now()))
+
+	keys := func(output *dto.CatalogListOutput) []string {
+		keys := make([]string, 0, len(output.Entries))
+		for _, entry := range output.Entries {
+			keys = append(keys, entry.Key)
+		}
+		return keys
+	}
+	tests := []struct {
+		name          string
+		input         *dto.CatalogListInput
+		wantKeys      []string
+		wantPageToken string
+	}{
+		{
+			name:     "All",
+			input:    &dto.CatalogListInput{},
+			wantKeys: []string{"a/1.jpg", "a/2.jpg", "a/3.jpg", "b/1.jpg"},
+		},
+		{
+			name:     "Prefix",
+			input:    &dto.CatalogListInput{Prefix: "a/"},
+			wantKeys: []string{"a/1.jpg", "a/2.jpg", "a/3.jpg"},
+		},
+		{
+			name:     "Owner including deleted",
+			input:    &dto.CatalogListInput{Prefix: "a/", Owner: "user", Deleted: true},
+			wantKeys: []string{"a/1.jpg", "a/3.jpg", "a/4.jpg"},
+		},
+		{
+			name:          "First page",
+			input:         &dto.CatalogListInput{Owner: "user", PageSize: 2},
+			wantKeys:      []string{"a/1.jpg", "a/3.jpg"},
+			wantPageToken: "a/3.jpg",
+		},
+		{
+			name:     "Last page",
+			input:    &dto.CatalogListInput{Owner: "user", PageSize: 2, PageToken: "a/3.jpg"},
+			wantKeys: []string{"b/1.jpg"},
+		},
+		{
+			name:     "Nothing found",
+			input:    &dto.CatalogListInput{Prefix: "c/"},
+			wantKeys: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.List(helpers.DefaultCtx, tt.input)
+			assert.NoError(t, err)
+			assert.EqualValues(t, tt.wantKeys, keys(got))
+			assert.EqualValues(t, tt.wantPageToken, got.NextPageToken)
+		})
+	}
+}
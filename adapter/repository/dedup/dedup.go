@@ -0,0 +1,187 @@
+package dedupRepo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+
+	"go.etcd.io/bbolt"
+
+	boltRepo "github.com/freemen-app/file_storage/adapter/repository/bolt"
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+var (
+	// refsBucket maps file url to its BlobRef
+	refsBucket = []byte("dedup_refs")
+	// blobsBucket maps blob url to number of files referring it
+	blobsBucket = []byte("dedup_blobs")
+	// metaBucket keeps id of the database under ownerKey
+	metaBucket = []byte("dedup_meta")
+	ownerKey   = []byte("owner")
+)
+
+type (
+	repo struct {
+		db *bbolt.DB
+	}
+)
+
+func New(db *bbolt.DB) *repo {
+	return &repo{db: db}
+}
+
+// Link refers the file to the blob and returns url of the blob previously referred by the file
+// if it lost its last reference
+func (r *repo) Link(ctx context.Context, ref *dto.BlobRef) (released string, err error) {
+	err = boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		refs, blobs, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+		old, err := getRef(refs, ref.Url)
+		if err != nil {
+			return err
+		}
+		if old == nil || old.BlobUrl != ref.BlobUrl {
+			if old != nil {
+				if isReleased, err := decrement(blobs, old.BlobUrl); err != nil {
+					return err
+				} else if isReleased {
+					released = old.BlobUrl
+				}
+			}
+			if err := putCount(blobs, ref.BlobUrl, getCount(blobs, ref.BlobUrl)+1); err != nil {
+				return err
+			}
+		}
+		return putRef(refs, ref)
+	})
+	if err != nil {
+		return "", err
+	}
+	return released, nil
+}
+
+// Unlink removes reference of the file, nil is returned if the file isn't deduplicated.
+// Released is true if the blob lost its last reference
+func (r *repo) Unlink(ctx context.Context, url string) (ref *dto.BlobRef, released bool, err error) {
+	err = boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		refs, blobs, err := buckets(tx)
+		if err != nil {
+			return err
+		}
+		if ref, err = getRef(refs, url); err != nil || ref == nil {
+			return err
+		}
+		if released, err = decrement(blobs, ref.BlobUrl); err != nil {
+			return err
+		}
+		return refs.Delete([]byte(url))
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return ref, released, nil
+}
+
+// Get returns reference of the file, nil is returned if the file isn't deduplicated
+func (r *repo) Get(ctx context.Context, url string) (ref *dto.BlobRef, err error) {
+	err = boltRepo.View(ctx, r.db, func(tx *bbolt.Tx) error {
+		if refs := tx.Bucket(refsBucket); refs != nil {
+			ref, err = getRef(refs, url)
+		}
+		return err
+	})
+	return ref, err
+}
+
+// Refs returns number of files referring the blob
+func (r *repo) Refs(ctx context.Context, blobUrl string) (refs int, err error) {
+	err = boltRepo.View(ctx, r.db, func(tx *bbolt.Tx) error {
+		if blobs := tx.Bucket(blobsBucket); blobs != nil {
+			refs = int(getCount(blobs, blobUrl))
+		}
+		return nil
+	})
+	return refs, err
+}
+
+// Owner returns id of the database, it's generated once, so it identifies the instance counting references
+func (r *repo) Owner(ctx context.Context) (owner string, err error) {
+	err = boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		if data := meta.Get(ownerKey); data != nil {
+			owner = string(data)
+			return nil
+		}
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		owner = hex.EncodeToString(id)
+		return meta.Put(ownerKey, []byte(owner))
+	})
+	return owner, err
+}
+
+func buckets(tx *bbolt.Tx) (refs, blobs *bbolt.Bucket, err error) {
+	if refs, err = tx.CreateBucketIfNotExists(refsBucket); err != nil {
+		return nil, nil, err
+	}
+	if blobs, err = tx.CreateBucketIfNotExists(blobsBucket); err != nil {
+		return nil, nil, err
+	}
+	return refs, blobs, nil
+}
+
+func getRef(refs *bbolt.Bucket, url string) (*dto.BlobRef, error) {
+	data := refs.Get([]byte(url))
+	if data == nil {
+		return nil, nil
+	}
+	ref := new(dto.BlobRef)
+	if err := json.Unmarshal(data, ref); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+func putRef(refs *bbolt.Bucket, ref *dto.BlobRef) error {
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+	return refs.Put([]byte(ref.Url), data)
+}
+
+func getCount(blobs *bbolt.Bucket, blobUrl string) uint64 {
+	data := blobs.Get([]byte(blobUrl))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+func putCount(blobs *bbolt.Bucket, blobUrl string, count uint64) error {
+	if count == 0 {
+		return blobs.Delete([]byte(blobUrl))
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, count)
+	return blobs.Put([]byte(blobUrl), data)
+}
+
+// decrement removes one reference of the blob and reports whether it was the last one
+func decrement(blobs *bbolt.Bucket, blobUrl string) (bool, error) {
+	count := getCount(blobs, blobUrl)
+	if count > 0 {
+		count--
+	}
+	return count == 0, putCount(blobs, blobUrl, count)
+}
@@ -0,0 +1,120 @@
+package dedupRepo_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+)
+
+func testDB(t *testing.T) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db
+}
+
+func TestRepo_Link(t *testing.T) {
+	repo := dedupRepo.New(testDB(t))
+	first := &dto.BlobRef{Key: "test/first.jpg", Url: "http://localhost/test/first.jpg", BlobUrl: "http://localhost/_blobs/1"}
+	second := &dto.BlobRef{Key: "test/second.jpg", Url: "http://localhost/test/second.jpg", BlobUrl: "http://localhost/_blobs/1"}
+
+	released, err := repo.Link(helpers.DefaultCtx, first)
+	assert.NoError(t, err)
+	assert.Empty(t, released)
+	released, err = repo.Link(helpers.DefaultCtx, second)
+	assert.NoError(t, err)
+	assert.Empty(t, released)
+	refs, err := repo.Refs(helpers.DefaultCtx, first.BlobUrl)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, refs)
+
+	// Linking the file to the same blob again doesn't add a reference
+	released, err = repo.Link(helpers.DefaultCtx, first)
+	assert.NoError(t, err)
+	assert.Empty(t, released)
+	refs, err = repo.Refs(helpers.DefaultCtx, first.BlobUrl)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, refs)
+
+	// Blob is released when its last file is linked to another blob
+	changed := &dto.BlobRef{Key: first.Key, Url: first.Url, BlobUrl: "http://localhost/_blobs/2"}
+	released, err = repo.Link(helpers.DefaultCtx, changed)
+	assert.NoError(t, err)
+	assert.Empty(t, released)
+	second.BlobUrl = changed.BlobUrl
+	released, err = repo.Link(helpers.DefaultCtx, second)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/_blobs/1", released)
+
+	refs, err = repo.Refs(helpers.DefaultCtx, "http://localhost/_blobs/1")
+	assert.NoError(t, err)
+	assert.Zero(t, refs)
+	ref, err := repo.Get(helpers.DefaultCtx, first.Url)
+	assert.NoError(t, err)
+	assert.EqualValues(t, changed, ref)
+}
+
+func TestRepo_Unlink(t *testing.T) {
+	repo := dedupRepo.New(testDB(t))
+	first := &dto.BlobRef{Key: "test/first.jpg", Url: "http://localhost/test/first.jpg", BlobUrl: "http://localhost/_blobs/1"}
+	second := &dto.BlobRef{Key: "test/second.jpg", Url: "http://localhost/test/second.jpg", BlobUrl: "http://localhost/_blobs/1"}
+
+	ref, released, err := repo.Unlink(helpers.DefaultCtx, first.Url)
+	assert.NoError(t, err)
+	assert.Nil(t, ref)
+	assert.False(t, released)
+
+	for _, ref := range []*dto.BlobRef{first, second} {
+		_, err := repo.Link(helpers.DefaultCtx, ref)
+		assert.NoError(t, err)
+	}
+	ref, released, err = repo.Unlink(helpers.DefaultCtx, first.Url)
+	assert.NoError(t, err)
+	assert.EqualValues(t, first, ref)
+	assert.False(t, released)
+	ref, released, err = repo.Unlink(helpers.DefaultCtx, second.Url)
+	assert.NoError(t, err)
+	assert.EqualValues(t, second, ref)
+	assert.True(t, released)
+
+	ref, err = repo.Get(helpers.DefaultCtx, second.Url)
+	assert.NoError(t, err)
+	assert.Nil(t, ref)
+	refs, err := repo.Refs(helpers.DefaultCtx, second.BlobUrl)
+	assert.NoError(t, err)
+	assert.Zero(t, refs)
+}
+
+func TestRepo_Get(t *testing.T) {
+	repo := dedupRepo.New(testDB(t))
+	ref, err := repo.Get(helpers.DefaultCtx, "http://localhost/test.jpg")
+	assert.NoError(t, err)
+	assert.Nil(t, ref)
+	refs, err := repo.Refs(helpers.DefaultCtx, "http://localhost/_blobs/1")
+	assert.NoError(t, err)
+	assert.Zero(t, refs)
+}
+
+func TestRepo_Owner(t *testing.T) {
+	repo := dedupRepo.New(testDB(t))
+
+	owner, err := repo.Owner(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{32}$`, owner)
+	again, err := repo.Owner(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.EqualValues(t, owner, again)
+
+	other, err := dedupRepo.New(testDB(t)).Owner(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.NotEqual(t, owner, other)
+}
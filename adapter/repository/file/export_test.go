@@ -1,11 +1,16 @@
 package fileRepo
 
 import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
 )
 
 type Repo = repo
 
+var IfNoneMatch = ifNoneMatch
+
 func (r *repo) BucketName() string {
 	return r.bucketName
 }
@@ -14,6 +19,14 @@ func (r *repo) SetBucketName(bucketName string) {
 	r.bucketName = bucketName
 }
 
+func (r *repo) Client() s3iface.S3API {
+	return r.client
+}
+
+func (r *repo) SetClient(client s3iface.S3API) {
+	r.client = client
+}
+
 func (r *repo) Uploader() s3manageriface.UploaderAPI {
 	return r.uploader
 }
@@ -37,3 +50,11 @@ func (r *repo) BatchDeleter() s3manageriface.BatchDelete {
 func (r *repo) SetBatchDeleter(batchDeleter s3manageriface.BatchDelete) {
 	r.batchDeleter = batchDeleter
 }
+
+func (r *repo) MaxPresignExpiry() time.Duration {
+	return r.maxPresignExpiry
+}
+
+func (r *repo) SetMaxPresignExpiry(maxPresignExpiry time.Duration) {
+	r.maxPresignExpiry = maxPresignExpiry
+}
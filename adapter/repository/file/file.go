@@ -2,17 +2,32 @@ package fileRepo
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/rs/zerolog/log"
 
 	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+const (
+	// maxCopyObjectSize is the max size of object which can be copied by a single request
+	maxCopyObjectSize int64 = 5 << 30
+	copyPartSize      int64 = 512 << 20
+	maxCopyParts      int64 = 10000
 )
 
 type (
@@ -21,41 +36,191 @@ type (
 	}
 
 	repo struct {
+		client       s3iface.S3API
 		deleter      Deleter
 		uploader     s3manageriface.UploaderAPI
 		batchDeleter s3manageriface.BatchDelete
 		bucketName   string
+		// maxPresignExpiry limits lifetime of presigned urls
+		maxPresignExpiry time.Duration
 	}
 )
 
-func New(session *session.Session, bucketName string) *repo {
+func New(session *session.Session, bucketName string, maxPresignExpiry time.Duration) *repo {
 	service := s3.New(session)
 	uploader := s3manager.NewUploaderWithClient(service)
 	batchDeleter := s3manager.NewBatchDeleteWithClient(service)
 	return &repo{
+		client:       service,
 		deleter:      service,
 		uploader:     uploader,
 		batchDeleter: batchDeleter,
 		bucketName:   bucketName,
+
+		maxPresignExpiry: maxPresignExpiry,
 	}
 }
 
-func (r *repo) Upload(ctx context.Context, input *dto.UploadInput) (string, error) {
+func (r *repo) Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error) {
 	log.Info().Msgf("%s", time.Now())
 	s3Input := input.ToS3Input(r.bucketName)
 	log.Info().Msgf("%s", time.Now())
 
-	resp, err := r.uploader.UploadWithContext(ctx, s3Input)
+	var opts []func(*s3manager.Uploader)
+	if input.Exclusive {
+		opts = append(opts, func(u *s3manager.Uploader) {
+			// Full slice expression keeps options of the shared uploader untouched
+			u.RequestOptions = append(u.RequestOptions[:len(u.RequestOptions):len(u.RequestOptions)], ifNoneMatch)
+		})
+	}
+	resp, err := r.uploader.UploadWithContext(ctx, s3Input, opts...)
 
 	log.Info().Msgf("%s", time.Now())
+	if err != nil {
+		return nil, convertError(err)
+	}
+	// Location differs for multipart uploads, so the url is built as urls of listed files
+	url, err := r.url(aws.StringValue(s3Input.Key))
+	if err != nil {
+		return nil, err
+	}
+	return &dto.UploadOutput{Url: url, VersionId: aws.StringValue(resp.VersionID)}, nil
+}
+
+func (r *repo) Copy(ctx context.Context, input *dto.CopyInput) (string, error) {
+	bucket, err := r.bucket()
 	if err != nil {
 		return "", err
 	}
-	return resp.Location, nil
+	s3Input, err := input.ToS3Input(bucket)
+	if err != nil {
+		return "", err
+	}
+	sourceKey, _ := bucket.Key(input.SourceUrl)
+	if sourceKey == aws.StringValue(s3Input.Key) {
+		return "", customErrors.SameFile
+	}
+	head, err := r.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(sourceKey),
+	})
+	if err != nil {
+		return "", convertError(err)
+	}
+	if input.Metadata != nil {
+		// Replaced metadata resets headers of the source, so they are copied explicitly
+		s3Input.CacheControl, s3Input.ContentDisposition = head.CacheControl, head.ContentDisposition
+		s3Input.ContentEncoding, s3Input.ContentLanguage = head.ContentEncoding, head.ContentLanguage
+		s3Input.ContentType, head.Metadata = head.ContentType, s3Input.Metadata
+	}
+
+	if _, err := r.copy(ctx, s3Input, head); err != nil {
+		return "", convertError(err)
+	}
+	return r.url(aws.StringValue(s3Input.Key))
+}
+
+// copy copies object described by its head and returns version id of the copy
+func (r *repo) copy(ctx context.Context, input *s3.CopyObjectInput, source *s3.HeadObjectOutput) (string, error) {
+	if aws.Int64Value(source.ContentLength) > maxCopyObjectSize {
+		return r.multipartCopy(ctx, input, source)
+	}
+	resp, err := r.client.CopyObjectWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(resp.VersionId), nil
+}
+
+// ifNoneMatch makes S3 write the object only if the key is free. Single part uploads are
+// checked by PutObject and multipart uploads are checked when they are completed
+func ifNoneMatch(req *request.Request) {
+	switch req.Operation.Name {
+	case "PutObject", "CompleteMultipartUpload":
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	}
+}
+
+// multipartCopy copies objects bigger than maxCopyObjectSize by parts
+func (r *repo) multipartCopy(ctx context.Context, input *s3.CopyObjectInput, source *s3.HeadObjectOutput) (string, error) {
+	// Unlike CopyObject, multipart upload doesn't take headers and metadata of the source
+	upload, err := r.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:             input.Bucket,
+		Key:                input.Key,
+		ACL:                input.ACL,
+		CacheControl:       source.CacheControl,
+		ContentDisposition: source.ContentDisposition,
+		ContentEncoding:    source.ContentEncoding,
+		ContentLanguage:    source.ContentLanguage,
+		ContentType:        source.ContentType,
+		Metadata:           source.Metadata,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	size := aws.Int64Value(source.ContentLength)
+	partSize := copyPartSize
+	// S3 allows at most maxCopyParts parts
+	if minPartSize := (size + maxCopyParts - 1) / maxCopyParts; minPartSize > partSize {
+		partSize = minPartSize
+	}
+	var parts []*s3.CompletedPart
+	for offset, number := int64(0), int64(1); offset < size; offset, number = offset+partSize, number+1 {
+		end := offset + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		resp, err := r.client.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+			Bucket:          input.Bucket,
+			Key:             input.Key,
+			CopySource:      input.CopySource,
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+			PartNumber:      aws.Int64(number),
+			UploadId:        upload.UploadId,
+		})
+		if err != nil {
+			r.abortMultipartUpload(input, upload.UploadId)
+			return "", err
+		}
+		parts = append(parts, &s3.CompletedPart{
+			ETag:       resp.CopyPartResult.ETag,
+			PartNumber: aws.Int64(number),
+		})
+	}
+
+	resp, err := r.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          input.Bucket,
+		Key:             input.Key,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		r.abortMultipartUpload(input, upload.UploadId)
+		return "", err
+	}
+	return aws.StringValue(resp.VersionId), nil
+}
+
+// abortMultipartUpload removes uploaded parts, it doesn't use request context,
+// because parts have to be removed even if request was canceled
+func (r *repo) abortMultipartUpload(input *s3.CopyObjectInput, uploadId *string) {
+	_, err := r.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   input.Bucket,
+		Key:      input.Key,
+		UploadId: uploadId,
+	})
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to abort multipart upload %s", aws.StringValue(uploadId))
+	}
 }
 
 func (r *repo) Delete(ctx context.Context, input dto.DeleteInput) error {
-	if s3Input, err := input.ToS3Input(r.bucketName); err != nil {
+	bucket, err := r.bucket()
+	if err != nil {
+		return err
+	}
+	if s3Input, err := input.ToS3Input(bucket); err != nil {
 		return err
 	} else if _, err := r.deleter.DeleteObjectWithContext(ctx, s3Input); err != nil {
 		return err
@@ -64,10 +229,335 @@ func (r *repo) Delete(ctx context.Context, input dto.DeleteInput) error {
 }
 
 func (r *repo) BatchDelete(ctx context.Context, input dto.BatchDeleteInput) error {
-	if s3Input, err := input.ToS3Input(r.bucketName); err != nil {
+	bucket, err := r.bucket()
+	if err != nil {
+		return err
+	}
+	if s3Input, err := input.ToS3Input(bucket); err != nil {
 		return err
 	} else if err := r.batchDeleter.Delete(ctx, s3Input); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (r *repo) Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	s3Input, err := input.ToS3Input(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return r.download(ctx, s3Input)
+}
+
+func (r *repo) download(ctx context.Context, s3Input *s3.GetObjectInput) (*dto.DownloadOutput, error) {
+	resp, err := r.client.GetObjectWithContext(ctx, s3Input)
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	output := &dto.DownloadOutput{
+		File:        resp.Body,
+		ContentType: aws.StringValue(resp.ContentType),
+		Size:        aws.Int64Value(resp.ContentLength),
+		Length:      aws.Int64Value(resp.ContentLength),
+	}
+	if resp.ContentRange != nil {
+		// Content-Range header has format "bytes <start>-<end>/<size>"
+		var end int64
+		if _, err := fmt.Sscanf(*resp.ContentRange, "bytes %d-%d/%d", &output.Offset, &end, &output.Size); err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+func (r *repo) Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	s3Input, err := input.ToS3Input(bucket)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.HeadObjectWithContext(ctx, s3Input)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return &dto.FileInfo{
+		Key:          aws.StringValue(s3Input.Key),
+		Url:          input.Url,
+		Size:         aws.Int64Value(resp.ContentLength),
+		ContentType:  aws.StringValue(resp.ContentType),
+		ETag:         aws.StringValue(resp.ETag),
+		LastModified: aws.TimeValue(resp.LastModified),
+		Metadata:     aws.StringValueMap(resp.Metadata),
+	}, nil
+}
+
+// ACL returns canned ACL of the object, empty string is returned if its grants don't match any canned ACL
+func (r *repo) ACL(ctx context.Context, url string) (string, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return "", err
+	}
+	key, err := bucket.Key(url)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.GetObjectAclWithContext(ctx, &s3.GetObjectAclInput{
+		Bucket: aws.String(bucket.Name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", convertError(err)
+	}
+	return dto.CannedACL(resp), nil
+}
+
+func (r *repo) List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error) {
+	resp, err := r.client.ListObjectsV2WithContext(ctx, input.ToS3Input(r.bucketName))
+	if err != nil {
+		return nil, err
+	}
+	output := &dto.ListOutput{
+		Files:         make([]*dto.FileInfo, len(resp.Contents)),
+		Prefixes:      make([]string, len(resp.CommonPrefixes)),
+		NextPageToken: aws.StringValue(resp.NextContinuationToken),
+	}
+	for i, obj := range resp.Contents {
+		url, err := r.url(aws.StringValue(obj.Key))
+		if err != nil {
+			return nil, err
+		}
+		output.Files[i] = &dto.FileInfo{
+			Key:          aws.StringValue(obj.Key),
+			Url:          url,
+			Size:         aws.Int64Value(obj.Size),
+			ETag:         aws.StringValue(obj.ETag),
+			LastModified: aws.TimeValue(obj.LastModified),
+		}
+	}
+	for i, prefix := range resp.CommonPrefixes {
+		output.Prefixes[i] = aws.StringValue(prefix.Prefix)
+	}
+	return output, nil
+}
+
+func (r *repo) ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	s3Input, err := input.ToS3Input(bucket)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.ListObjectVersionsWithContext(ctx, s3Input)
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	key := aws.StringValue(s3Input.Prefix)
+	output := &dto.ListVersionsOutput{}
+	for _, v := range resp.Versions {
+		if aws.StringValue(v.Key) == key {
+			output.Versions = append(output.Versions, &dto.FileVersion{
+				VersionId:    aws.StringValue(v.VersionId),
+				Size:         aws.Int64Value(v.Size),
+				ETag:         aws.StringValue(v.ETag),
+				LastModified: aws.TimeValue(v.LastModified),
+				IsLatest:     aws.BoolValue(v.IsLatest),
+			})
+		}
+	}
+	for _, m := range resp.DeleteMarkers {
+		if aws.StringValue(m.Key) == key {
+			output.Versions = append(output.Versions, &dto.FileVersion{
+				VersionId:      aws.StringValue(m.VersionId),
+				LastModified:   aws.TimeValue(m.LastModified),
+				IsLatest:       aws.BoolValue(m.IsLatest),
+				IsDeleteMarker: true,
+			})
+		}
+	}
+	// S3 returns versions and delete markers separately
+	sort.SliceStable(output.Versions, func(i, j int) bool {
+		return output.Versions[i].LastModified.After(output.Versions[j].LastModified)
+	})
+	// Versions of the key are listed before versions of longer keys with the same prefix,
+	// so the next page contains versions of the key only if it's the next key marker
+	if aws.BoolValue(resp.IsTruncated) && aws.StringValue(resp.NextKeyMarker) == key {
+		output.NextPageToken = aws.StringValue(resp.NextVersionIdMarker)
+	}
+	return output, nil
+}
+
+func (r *repo) GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	s3Input, err := input.ToS3Input(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return r.download(ctx, s3Input)
+}
+
+// RestoreVersion copies the version over the file, so that it becomes the latest version
+// and the history of the file is kept
+func (r *repo) RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	headInput, err := input.HeadInput(bucket)
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.client.HeadObjectWithContext(ctx, headInput)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	s3Input, err := input.ToS3Input(bucket)
+	if err != nil {
+		return nil, err
+	}
+	versionId, err := r.copy(ctx, s3Input, head)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	url, err := r.url(aws.StringValue(s3Input.Key))
+	if err != nil {
+		return nil, err
+	}
+	return &dto.UploadOutput{Url: url, VersionId: versionId}, nil
+}
+
+func (r *repo) PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error) {
+	expires, err := r.presignExpiry(input.Expires)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := r.bucket()
+	if err != nil {
+		return nil, err
+	}
+	s3Input, err := input.ToS3Input(bucket)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := r.client.GetObjectRequest(s3Input)
+	req.SetContext(ctx)
+	return presign(req, expires)
+}
+
+func (r *repo) PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error) {
+	expires, err := r.presignExpiry(input.Expires)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := r.client.PutObjectRequest(input.ToS3Input(r.bucketName))
+	req.SetContext(ctx)
+	return presign(req, expires)
+}
+
+// presignExpiry returns max expiry if requested expiry is not set
+// and validates that requested expiry doesn't exceed max expiry
+func (r *repo) presignExpiry(expires time.Duration) (time.Duration, error) {
+	if expires == 0 {
+		return r.maxPresignExpiry, nil
+	}
+	if err := validation.Validate(expires, validation.Max(r.maxPresignExpiry)); err != nil {
+		return 0, validation.Errors{"Expires": err}
+	}
+	return expires, nil
+}
+
+func presign(req *request.Request, expires time.Duration) (*dto.PresignOutput, error) {
+	url, signedHeaders, err := req.PresignRequest(expires)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(signedHeaders))
+	for name, values := range signedHeaders {
+		headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ",")
+	}
+	return &dto.PresignOutput{
+		Url:       url,
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+// URL returns url of the key without checking whether the object exists
+func (r *repo) URL(key string) (string, error) {
+	return r.url(key)
+}
+
+// Key returns key of the url without checking whether the object exists
+func (r *repo) Key(url string) (string, error) {
+	bucket, err := r.bucket()
+	if err != nil {
+		return "", err
+	}
+	return bucket.Key(url)
+}
+
+// bucket returns the bucket with its url built by the client, so that urls of objects are parsed
+// by the configured endpoint and addressing style the same way as they are built
+func (r *repo) bucket() (dto.S3Bucket, error) {
+	req, _ := r.client.HeadBucketRequest(&s3.HeadBucketInput{Bucket: aws.String(r.bucketName)})
+	if err := req.Build(); err != nil {
+		return dto.S3Bucket{}, err
+	}
+	return dto.S3Bucket{Name: r.bucketName, BaseURL: req.HTTPRequest.URL.String()}, nil
+}
+
+// url builds object url the same way as uploader does
+func (r *repo) url(key string) (string, error) {
+	req, _ := r.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(r.bucketName),
+		Key:    aws.String(key),
+	})
+	if err := req.Build(); err != nil {
+		return "", err
+	}
+	return req.HTTPRequest.URL.String(), nil
+}
+
+// convertError converts S3 errors to domain errors
+func convertError(err error) error {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+	switch awsErr.Code() {
+	// HEAD responses have no body, so missing object is reported as NotFound
+	case s3.ErrCodeNoSuchKey, "NotFound":
+		return customErrors.NotFound
+	case "InvalidRange":
+		return customErrors.InvalidRange
+	// Content-MD5 declared by client doesn't match the uploaded file
+	case "BadDigest":
+		return customErrors.ChecksumMismatch
+	// Delete markers can't be read, HEAD and GET of them fail with 405
+	case "NoSuchVersion", "MethodNotAllowed":
+		return customErrors.VersionNotFound
+	// Conditional write failed or lost the race to a concurrent conditional write
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return customErrors.AlreadyExists
+	case s3.ErrCodeNoSuchUpload:
+		return customErrors.UploadNotFound
+	case "EntityTooSmall":
+		return customErrors.PartTooSmall
+	default:
+		return err
+	}
+}
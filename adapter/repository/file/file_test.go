@@ -3,10 +3,17 @@ package fileRepo_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
 	"github.com/stretchr/testify/assert"
@@ -22,6 +29,7 @@ import (
 )
 
 type fields struct {
+	Client       s3iface.S3API
 	Uploader     s3manageriface.UploaderAPI
 	Deleter      fileRepo.Deleter
 	BatchDeleter s3manageriface.BatchDelete
@@ -29,8 +37,13 @@ type fields struct {
 }
 
 func testRepo(f *fields) *fileRepo.Repo {
+	if f.Client == nil {
+		// Client builds urls of the bucket even if it isn't called
+		f.Client = new(mocks.S3Client)
+	}
 	repo := &fileRepo.Repo{}
 	repo.SetBucketName(f.bucketName)
+	repo.SetClient(f.Client)
 	repo.SetUploader(f.Uploader)
 	repo.SetDeleter(f.Deleter)
 	repo.SetBatchDeleter(f.BatchDeleter)
@@ -40,15 +53,16 @@ func testRepo(f *fields) *fileRepo.Repo {
 func TestNew(t *testing.T) {
 	t.Run("Nil session panic", func(t *testing.T) {
 		assert.Panics(t, func() {
-			fileRepo.New(nil, "test.bucket")
+			fileRepo.New(nil, "test.bucket", time.Hour)
 		})
 	})
 	t.Run("Succeed", func(t *testing.T) {
 		session := awsSession.New(config.S3Config{})
 		bucket := "test.bucket"
 
-		repo := fileRepo.New(session, bucket)
+		repo := fileRepo.New(session, bucket, time.Hour)
 		assert.EqualValues(t, bucket, repo.BucketName())
+		assert.NotNil(t, repo.Client())
 		assert.NotNil(t, repo.Uploader())
 		assert.NotNil(t, repo.Deleter())
 		assert.NotNil(t, repo.BatchDeleter())
@@ -82,12 +96,16 @@ func TestRepo_Upload(t *testing.T) {
 		ctx   context.Context
 		input *dto.UploadInput
 	}
+	tracked := &dto.UploadInput{File: strings.NewReader("test"), Filename: "test.jpg", ACL: "public-read", ContentType: "image/jpeg"}
+	tracked.TrackChecksum()
+	// Uploader mock doesn't read the file
+	_, _ = ioutil.ReadAll(tracked.File)
 	tests := []struct {
 		name    string
 		fields  fields
 		args    args
 		mocks   map[string]mocks.Calls
-		want    string
+		want    *dto.UploadOutput
 		wantErr error
 	}{
 		{
@@ -110,11 +128,11 @@ func TestRepo_Upload(t *testing.T) {
 					{
 						Method:     "UploadWithContext",
 						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
-						ReturnArgs: []interface{}{&s3manager.UploadOutput{Location: "https://aws.s3/test/test.jpg"}, nil},
+						ReturnArgs: []interface{}{&s3manager.UploadOutput{Location: "https://test.bucket.aws.s3/test/test.jpg", VersionID: aws.String("v1")}, nil},
 					},
 				},
 			},
-			want: "https://aws.s3/test/test.jpg",
+			want: &dto.UploadOutput{Url: "https://aws.s3/test.bucket/test/test.jpg", VersionId: "v1"},
 		},
 		{
 			name: "error returned",
@@ -142,6 +160,76 @@ func TestRepo_Upload(t *testing.T) {
 			},
 			wantErr: context.DeadlineExceeded,
 		},
+		{
+			name: "bad digest",
+			fields: fields{
+				Uploader:   new(mocks.Uploader),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					Filename: "test.jpg",
+					MD5:      "098f6bcd4621d373cade4e832627b4f6",
+				},
+			},
+			mocks: map[string]mocks.Calls{
+				"Uploader": {
+					{
+						Method: "UploadWithContext",
+						Args: []interface{}{helpers.DefaultCtx, mock.MatchedBy(func(input *s3manager.UploadInput) bool {
+							return aws.StringValue(input.ContentMD5) == "CY9rzUYh03PK3k6DJie09g=="
+						})},
+						ReturnArgs: []interface{}{nil, awserr.New("BadDigest", "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.ChecksumMismatch,
+		},
+		{
+			name: "file exists",
+			fields: fields{
+				Uploader:   new(mocks.Uploader),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.UploadInput{Filename: "test.jpg", Exclusive: true},
+			},
+			mocks: map[string]mocks.Calls{
+				"Uploader": {
+					{
+						Method:     "UploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil, awserr.New("PreconditionFailed", "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.AlreadyExists,
+		},
+		{
+			name: "computed checksum isn't stored",
+			fields: fields{
+				Uploader:   new(mocks.Uploader),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: tracked,
+			},
+			mocks: map[string]mocks.Calls{
+				"Uploader": {
+					{
+						Method: "UploadWithContext",
+						Args: []interface{}{helpers.DefaultCtx, mock.MatchedBy(func(input *s3manager.UploadInput) bool {
+							return input.Metadata == nil
+						})},
+						ReturnArgs: []interface{}{&s3manager.UploadOutput{Location: "https://aws.s3/test.bucket/test.jpg", VersionID: aws.String("v1")}, nil},
+					},
+				},
+			},
+			want: &dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg", VersionId: "v1"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -155,66 +243,171 @@ func TestRepo_Upload(t *testing.T) {
 	}
 }
 
-func TestRepo_Delete(t *testing.T) {
+func TestIfNoneMatch(t *testing.T) {
+	client := s3.New(awsSession.New(config.S3Config{}))
+	put, _ := client.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String("test.bucket"), Key: aws.String("test.jpg")})
+	fileRepo.IfNoneMatch(put)
+	assert.EqualValues(t, "*", put.HTTPRequest.Header.Get("If-None-Match"))
+
+	complete, _ := client.CompleteMultipartUploadRequest(&s3.CompleteMultipartUploadInput{})
+	fileRepo.IfNoneMatch(complete)
+	assert.EqualValues(t, "*", complete.HTTPRequest.Header.Get("If-None-Match"))
+
+	part, _ := client.UploadPartRequest(&s3.UploadPartInput{})
+	fileRepo.IfNoneMatch(part)
+	assert.Empty(t, part.HTTPRequest.Header.Get("If-None-Match"))
+}
+
+func TestRepo_Download(t *testing.T) {
 	type args struct {
 		ctx   context.Context
-		input dto.DeleteInput
+		input *dto.DownloadInput
 	}
 	tests := []struct {
 		name    string
 		fields  fields
 		args    args
 		mocks   map[string]mocks.Calls
-		want    string
+		want    *dto.DownloadOutput
 		wantErr error
 	}{
 		{
 			name: "succeed",
 			fields: fields{
-				Deleter:    new(mocks.Deleter),
+				Client:     new(mocks.S3Client),
 				bucketName: "test.bucket",
 			},
 			args: args{
 				ctx:   helpers.DefaultCtx,
-				input: "https://aws.s3/test.bucket/test.jpg",
+				input: &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
 			},
 			mocks: map[string]mocks.Calls{
-				"Deleter": {
+				"Client": {
 					{
-						Method:     "DeleteObjectWithContext",
+						Method: "GetObjectWithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.GetObjectInput{
+							Bucket: aws.String("test.bucket"),
+							Key:    aws.String("test.jpg"),
+						}},
+						ReturnArgs: []interface{}{&s3.GetObjectOutput{
+							Body:          ioutil.NopCloser(strings.NewReader("test")),
+							ContentType:   aws.String("image/jpeg"),
+							ContentLength: aws.Int64(4),
+						}, nil},
+					},
+				},
+			},
+			want: &dto.DownloadOutput{
+				File:        ioutil.NopCloser(strings.NewReader("test")),
+				ContentType: "image/jpeg",
+				Size:        4,
+				Length:      4,
+			},
+		},
+		{
+			name: "succeed range",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg", Offset: 10, Length: 4},
+			},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "GetObjectWithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.GetObjectInput{
+							Bucket: aws.String("test.bucket"),
+							Key:    aws.String("test.jpg"),
+							Range:  aws.String("bytes=10-13"),
+						}},
+						ReturnArgs: []interface{}{&s3.GetObjectOutput{
+							Body:          ioutil.NopCloser(strings.NewReader("test")),
+							ContentType:   aws.String("image/jpeg"),
+							ContentLength: aws.Int64(4),
+							ContentRange:  aws.String("bytes 10-13/100"),
+						}, nil},
+					},
+				},
+			},
+			want: &dto.DownloadOutput{
+				File:        ioutil.NopCloser(strings.NewReader("test")),
+				ContentType: "image/jpeg",
+				Size:        100,
+				Offset:      10,
+				Length:      4,
+			},
+		},
+		{
+			name: "invalid range",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg", Offset: 1000},
+			},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "GetObjectWithContext",
 						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
-						ReturnArgs: []interface{}{new(s3.DeleteObjectOutput), nil},
+						ReturnArgs: []interface{}{nil, awserr.New("InvalidRange", "test error", nil)},
 					},
 				},
 			},
-			want: "https://aws.s3/test/test.jpg",
+			wantErr: customErrors.InvalidRange,
+		},
+		{
+			name: "not found",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+			},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "GetObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil, awserr.New(s3.ErrCodeNoSuchKey, "test error", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.NotFound,
 		},
 		{
 			name: "invalid input",
 			fields: fields{
-				Deleter:    new(mocks.Deleter),
+				Client:     new(mocks.S3Client),
 				bucketName: "test.bucket",
 			},
 			args: args{
 				ctx:   helpers.DefaultCtx,
-				input: "https://aws.s3/invalid.bucket/test.jpg",
+				input: &dto.DownloadInput{Url: "https://aws.s3/invalid.bucket/test.jpg"},
 			},
 			wantErr: customErrors.InvalidURL,
 		},
 		{
 			name: "error returned",
 			fields: fields{
-				Deleter:    new(mocks.Deleter),
+				Client:     new(mocks.S3Client),
 				bucketName: "test.bucket",
 			},
 			args: args{
 				ctx:   helpers.DefaultCtx,
-				input: "https://aws.s3/test.bucket/test.jpg",
+				input: &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
 			},
 			mocks: map[string]mocks.Calls{
-				"Deleter": {
+				"Client": {
 					{
-						Method:     "DeleteObjectWithContext",
+						Method:     "GetObjectWithContext",
 						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
 						ReturnArgs: []interface{}{nil, errors.New("test error")},
 					},
@@ -228,72 +421,237 @@ func TestRepo_Delete(t *testing.T) {
 			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
 			defer assertMocks()
 			repo := testRepo(&tt.fields)
-			err := repo.Delete(tt.args.ctx, tt.args.input)
+			got, err := repo.Download(tt.args.ctx, tt.args.input)
 			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
 		})
 	}
 }
 
-func TestRepo_BatchDelete(t *testing.T) {
-	type args struct {
-		ctx   context.Context
-		input dto.BatchDeleteInput
-	}
+func TestRepo_Stat(t *testing.T) {
+	lastModified := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
 	tests := []struct {
 		name    string
 		fields  fields
-		args    args
+		input   *dto.StatInput
 		mocks   map[string]mocks.Calls
+		want    *dto.FileInfo
 		wantErr error
 	}{
 		{
 			name: "succeed",
 			fields: fields{
-				BatchDeleter: new(mocks.BatchDeleter),
-				bucketName:   "test.bucket",
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
 			},
-			args: args{
-				ctx:   helpers.DefaultCtx,
-				input: dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg", "https://aws.s3/test.bucket/test2.jpg"},
+			input: &dto.StatInput{Url: "https://aws.s3/test.bucket/test/test.jpg"},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "HeadObjectWithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.HeadObjectInput{
+							Bucket: aws.String("test.bucket"),
+							Key:    aws.String("test/test.jpg"),
+						}},
+						ReturnArgs: []interface{}{&s3.HeadObjectOutput{
+							ContentLength: aws.Int64(4),
+							ContentType:   aws.String("image/jpeg"),
+							ETag:          aws.String(`"etag"`),
+							LastModified:  aws.Time(lastModified),
+							Metadata:      map[string]*string{"Owner": aws.String("test")},
+						}, nil},
+					},
+				},
+			},
+			want: &dto.FileInfo{
+				Key:          "test/test.jpg",
+				Url:          "https://aws.s3/test.bucket/test/test.jpg",
+				Size:         4,
+				ContentType:  "image/jpeg",
+				ETag:         `"etag"`,
+				LastModified: lastModified,
+				Metadata:     map[string]string{"Owner": "test"},
+			},
+		},
+		{
+			name: "not found",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
 			},
+			input: &dto.StatInput{Url: "https://aws.s3/test.bucket/test/test.jpg"},
 			mocks: map[string]mocks.Calls{
-				"BatchDeleter": {
+				"Client": {
 					{
-						Method:     "Delete",
+						Method:     "HeadObjectWithContext",
 						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
-						ReturnArgs: []interface{}{nil},
+						ReturnArgs: []interface{}{nil, awserr.New("NotFound", "test error", nil)},
 					},
 				},
 			},
+			wantErr: customErrors.NotFound,
 		},
 		{
 			name: "invalid input",
 			fields: fields{
-				BatchDeleter: new(mocks.BatchDeleter),
-				bucketName:   "test.bucket",
-			},
-			args: args{
-				ctx:   helpers.DefaultCtx,
-				input: dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg", "https://aws.s3/invalid.bucket/test2.jpg"},
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
 			},
+			input:   &dto.StatInput{Url: "https://aws.s3/invalid.bucket/test.jpg"},
 			wantErr: customErrors.InvalidURL,
 		},
 		{
 			name: "error returned",
 			fields: fields{
-				BatchDeleter: new(mocks.BatchDeleter),
-				bucketName:   "test.bucket",
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
 			},
-			args: args{
-				ctx:   helpers.DefaultCtx,
-				input: dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg", "https://aws.s3/test.bucket/test2.jpg"},
+			input: &dto.StatInput{Url: "https://aws.s3/test.bucket/test/test.jpg"},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "HeadObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil, errors.New("test error")},
+					},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			repo := testRepo(&tt.fields)
+			got, err := repo.Stat(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_ACL(t *testing.T) {
+	aclInput := &s3.GetObjectAclInput{Bucket: aws.String("test.bucket"), Key: aws.String("test/test.jpg")}
+	tests := []struct {
+		name    string
+		mocks   map[string]mocks.Calls
+		want    string
+		wantErr error
+	}{
+		{
+			name: "succeed",
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "GetObjectAclWithContext",
+						Args:   []interface{}{helpers.DefaultCtx, aclInput},
+						ReturnArgs: []interface{}{&s3.GetObjectAclOutput{
+							Owner: &s3.Owner{ID: aws.String("owner")},
+							Grants: []*s3.Grant{
+								{Grantee: &s3.Grantee{ID: aws.String("owner")}, Permission: aws.String(s3.PermissionFullControl)},
+								{
+									Grantee:    &s3.Grantee{URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers")},
+									Permission: aws.String(s3.PermissionRead),
+								},
+							},
+						}, nil},
+					},
+				},
 			},
+			want: "public-read",
+		},
+		{
+			name: "not found",
 			mocks: map[string]mocks.Calls{
-				"BatchDeleter": {
+				"Client": {
 					{
-						Method:     "Delete",
+						Method:     "GetObjectAclWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, aclInput},
+						ReturnArgs: []interface{}{nil, awserr.New(s3.ErrCodeNoSuchKey, "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fields{Client: new(mocks.S3Client), bucketName: "test.bucket"}
+			assertMocks := setupMocks(t, &f, tt.mocks)
+			defer assertMocks()
+			got, err := testRepo(&f).ACL(helpers.DefaultCtx, "https://aws.s3/test.bucket/test/test.jpg")
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_List(t *testing.T) {
+	lastModified := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		fields  fields
+		input   *dto.ListInput
+		mocks   map[string]mocks.Calls
+		want    *dto.ListOutput
+		wantErr error
+	}{
+		{
+			name: "succeed",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input: &dto.ListInput{Prefix: "test/", Delimiter: "/", PageSize: 1},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "ListObjectsV2WithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.ListObjectsV2Input{
+							Bucket:    aws.String("test.bucket"),
+							Prefix:    aws.String("test/"),
+							Delimiter: aws.String("/"),
+							MaxKeys:   aws.Int64(1),
+						}},
+						ReturnArgs: []interface{}{&s3.ListObjectsV2Output{
+							Contents: []*s3.Object{{
+								Key:          aws.String("test/my file.jpg"),
+								Size:         aws.Int64(4),
+								ETag:         aws.String(`"etag"`),
+								LastModified: aws.Time(lastModified),
+							}},
+							CommonPrefixes:        []*s3.CommonPrefix{{Prefix: aws.String("test/sub/")}},
+							NextContinuationToken: aws.String("token"),
+						}, nil},
+					},
+				},
+			},
+			want: &dto.ListOutput{
+				Files: []*dto.FileInfo{{
+					Key:          "test/my file.jpg",
+					Url:          "https://aws.s3/test.bucket/test/my%20file.jpg",
+					Size:         4,
+					ETag:         `"etag"`,
+					LastModified: lastModified,
+				}},
+				Prefixes:      []string{"test/sub/"},
+				NextPageToken: "token",
+			},
+		},
+		{
+			name: "error returned",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input: &dto.ListInput{},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "ListObjectsV2WithContext",
 						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
-						ReturnArgs: []interface{}{errors.New("test error")},
+						ReturnArgs: []interface{}{nil, errors.New("test error")},
 					},
 				},
 			},
@@ -305,8 +663,798 @@ func TestRepo_BatchDelete(t *testing.T) {
 			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
 			defer assertMocks()
 			repo := testRepo(&tt.fields)
-			err := repo.BatchDelete(tt.args.ctx, tt.args.input)
+			got, err := repo.List(helpers.DefaultCtx, tt.input)
 			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_Copy(t *testing.T) {
+	headInput := &s3.HeadObjectInput{
+		Bucket: aws.String("test.bucket"),
+		Key:    aws.String("tmp/test.jpg"),
+	}
+	copyInput := &s3.CopyObjectInput{
+		Bucket:     aws.String("test.bucket"),
+		Key:        aws.String("test/test.jpg"),
+		CopySource: aws.String("test.bucket/tmp/test.jpg"),
+		ACL:        aws.String("public-read"),
+	}
+	input := &dto.CopyInput{
+		SourceUrl: "https://aws.s3/test.bucket/tmp/test.jpg",
+		Directory: "test",
+		Filename:  "test.jpg",
+		ACL:       "public-read",
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		input   *dto.CopyInput
+		mocks   map[string]mocks.Calls
+		want    string
+		wantErr error
+	}{
+		{
+			name: "succeed",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input: input,
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "HeadObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{&s3.HeadObjectOutput{ContentLength: aws.Int64(4)}, nil},
+					},
+					{
+						Method:     "CopyObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, copyInput},
+						ReturnArgs: []interface{}{&s3.CopyObjectOutput{}, nil},
+					},
+				},
+			},
+			want: "https://aws.s3/test.bucket/test/test.jpg",
+		},
+		{
+			name: "multipart copy",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input: input,
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "HeadObjectWithContext",
+						Args:   []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{&s3.HeadObjectOutput{
+							ContentLength:      aws.Int64(6 << 30),
+							ContentType:        aws.String("image/jpeg"),
+							ContentDisposition: aws.String("attachment"),
+							Metadata:           map[string]*string{"Sha256": aws.String("checksum")},
+						}, nil},
+					},
+					{
+						Method: "CreateMultipartUploadWithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.CreateMultipartUploadInput{
+							Bucket:             aws.String("test.bucket"),
+							Key:                aws.String("test/test.jpg"),
+							ACL:                aws.String("public-read"),
+							ContentType:        aws.String("image/jpeg"),
+							ContentDisposition: aws.String("attachment"),
+							Metadata:           map[string]*string{"Sha256": aws.String("checksum")},
+						}},
+						ReturnArgs: []interface{}{&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload")}, nil},
+					},
+					{
+						Method: "UploadPartCopyWithContext",
+						Args: []interface{}{helpers.DefaultCtx, mock.MatchedBy(func(input *s3.UploadPartCopyInput) bool {
+							return aws.Int64Value(input.PartNumber) == 12 &&
+								aws.StringValue(input.CopySourceRange) == fmt.Sprintf("bytes=%d-%d", 11<<29, 6<<30-1)
+						})},
+						ReturnArgs: []interface{}{&s3.UploadPartCopyOutput{CopyPartResult: &s3.CopyPartResult{ETag: aws.String("etag")}}, nil},
+					},
+					{
+						Method:     "UploadPartCopyWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{&s3.UploadPartCopyOutput{CopyPartResult: &s3.CopyPartResult{ETag: aws.String("etag")}}, nil},
+					},
+					{
+						Method: "CompleteMultipartUploadWithContext",
+						Args: []interface{}{helpers.DefaultCtx, mock.MatchedBy(func(input *s3.CompleteMultipartUploadInput) bool {
+							return len(input.MultipartUpload.Parts) == 12
+						})},
+						ReturnArgs: []interface{}{&s3.CompleteMultipartUploadOutput{}, nil},
+					},
+				},
+			},
+			want: "https://aws.s3/test.bucket/test/test.jpg",
+		},
+		{
+			name: "multipart copy error",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input: input,
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "HeadObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{&s3.HeadObjectOutput{ContentLength: aws.Int64(6 << 30)}, nil},
+					},
+					{
+						Method:     "CreateMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload")}, nil},
+					},
+					{
+						Method:     "UploadPartCopyWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil, errors.New("test error")},
+					},
+					{
+						Method: "AbortMultipartUpload",
+						Args: []interface{}{&s3.AbortMultipartUploadInput{
+							Bucket:   aws.String("test.bucket"),
+							Key:      aws.String("test/test.jpg"),
+							UploadId: aws.String("upload"),
+						}},
+						ReturnArgs: []interface{}{&s3.AbortMultipartUploadOutput{}, nil},
+					},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+		{
+			name: "source not found",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input: input,
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "HeadObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{nil, awserr.New("NotFound", "test error", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.NotFound,
+		},
+		{
+			name: "replaced metadata",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input: &dto.CopyInput{
+				SourceUrl: input.SourceUrl,
+				Directory: input.Directory,
+				Filename:  input.Filename,
+				ACL:       input.ACL,
+				Metadata:  map[string]string{"Trash-Acl": "public-read"},
+			},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "HeadObjectWithContext",
+						Args:   []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{&s3.HeadObjectOutput{
+							ContentLength:      aws.Int64(4),
+							ContentType:        aws.String("image/jpeg"),
+							ContentDisposition: aws.String("attachment"),
+							Metadata:           map[string]*string{"Sha256": aws.String("checksum")},
+						}, nil},
+					},
+					{
+						Method: "CopyObjectWithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.CopyObjectInput{
+							Bucket:             aws.String("test.bucket"),
+							Key:                aws.String("test/test.jpg"),
+							CopySource:         aws.String("test.bucket/tmp/test.jpg"),
+							ACL:                aws.String("public-read"),
+							ContentType:        aws.String("image/jpeg"),
+							ContentDisposition: aws.String("attachment"),
+							Metadata:           map[string]*string{"Trash-Acl": aws.String("public-read")},
+							MetadataDirective:  aws.String("REPLACE"),
+						}},
+						ReturnArgs: []interface{}{&s3.CopyObjectOutput{}, nil},
+					},
+				},
+			},
+			want: "https://aws.s3/test.bucket/test/test.jpg",
+		},
+		{
+			name: "same file",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input:   &dto.CopyInput{SourceUrl: "https://aws.s3/test.bucket/tmp/test.jpg", Directory: "tmp", Filename: "test.jpg"},
+			wantErr: customErrors.SameFile,
+		},
+		{
+			name: "invalid source url",
+			fields: fields{
+				Client:     new(mocks.S3Client),
+				bucketName: "test.bucket",
+			},
+			input:   &dto.CopyInput{SourceUrl: "https://aws.s3/other.bucket/tmp/test.jpg", Filename: "test.jpg"},
+			wantErr: customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			repo := testRepo(&tt.fields)
+			got, err := repo.Copy(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_Delete(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		input dto.DeleteInput
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mocks   map[string]mocks.Calls
+		want    string
+		wantErr error
+	}{
+		{
+			name: "succeed",
+			fields: fields{
+				Deleter:    new(mocks.Deleter),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: "https://aws.s3/test.bucket/test.jpg",
+			},
+			mocks: map[string]mocks.Calls{
+				"Deleter": {
+					{
+						Method:     "DeleteObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{new(s3.DeleteObjectOutput), nil},
+					},
+				},
+			},
+			want: "https://aws.s3/test/test.jpg",
+		},
+		{
+			name: "invalid input",
+			fields: fields{
+				Deleter:    new(mocks.Deleter),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: "https://aws.s3/invalid.bucket/test.jpg",
+			},
+			wantErr: customErrors.InvalidURL,
+		},
+		{
+			name: "error returned",
+			fields: fields{
+				Deleter:    new(mocks.Deleter),
+				bucketName: "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: "https://aws.s3/test.bucket/test.jpg",
+			},
+			mocks: map[string]mocks.Calls{
+				"Deleter": {
+					{
+						Method:     "DeleteObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil, errors.New("test error")},
+					},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			repo := testRepo(&tt.fields)
+			err := repo.Delete(tt.args.ctx, tt.args.input)
+			assert.EqualValues(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestRepo_BatchDelete(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		input dto.BatchDeleteInput
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		mocks   map[string]mocks.Calls
+		wantErr error
+	}{
+		{
+			name: "succeed",
+			fields: fields{
+				BatchDeleter: new(mocks.BatchDeleter),
+				bucketName:   "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg", "https://aws.s3/test.bucket/test2.jpg"},
+			},
+			mocks: map[string]mocks.Calls{
+				"BatchDeleter": {
+					{
+						Method:     "Delete",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid input",
+			fields: fields{
+				BatchDeleter: new(mocks.BatchDeleter),
+				bucketName:   "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg", "https://aws.s3/invalid.bucket/test2.jpg"},
+			},
+			wantErr: customErrors.InvalidURL,
+		},
+		{
+			name: "error returned",
+			fields: fields{
+				BatchDeleter: new(mocks.BatchDeleter),
+				bucketName:   "test.bucket",
+			},
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg", "https://aws.s3/test.bucket/test2.jpg"},
+			},
+			mocks: map[string]mocks.Calls{
+				"BatchDeleter": {
+					{
+						Method:     "Delete",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{errors.New("test error")},
+					},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			repo := testRepo(&tt.fields)
+			err := repo.BatchDelete(tt.args.ctx, tt.args.input)
+			assert.EqualValues(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestRepo_PresignDownload(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       *dto.PresignDownloadInput
+		wantURL     string
+		wantExpires string
+		wantErr     bool
+	}{
+		{
+			name: "succeed",
+			input: &dto.PresignDownloadInput{
+				Url:     "https://s3.eu-central-1.amazonaws.com/test.bucket/test/test.jpg",
+				Expires: time.Minute,
+			},
+			wantURL:     "https://s3.eu-central-1.amazonaws.com/test.bucket/test/test.jpg?",
+			wantExpires: "X-Amz-Expires=60",
+		},
+		{
+			name:        "default expiry",
+			input:       &dto.PresignDownloadInput{Url: "https://s3.eu-central-1.amazonaws.com/test.bucket/test/test.jpg"},
+			wantURL:     "https://s3.eu-central-1.amazonaws.com/test.bucket/test/test.jpg?",
+			wantExpires: "X-Amz-Expires=3600",
+		},
+		{
+			name: "expiry exceeds max",
+			input: &dto.PresignDownloadInput{
+				Url:     "https://s3.eu-central-1.amazonaws.com/test.bucket/test/test.jpg",
+				Expires: 2 * time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			input:   &dto.PresignDownloadInput{Url: "https://s3.eu-central-1.amazonaws.com/other.bucket/test/test.jpg"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := awsSession.New(config.S3Config{Region: "eu-central-1", AccessKeyID: "test", SecretAccessKey: "test"})
+			repo := fileRepo.New(session, "test.bucket", time.Hour)
+			got, err := repo.PresignDownload(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			assert.True(t, strings.HasPrefix(got.Url, tt.wantURL), got.Url)
+			assert.Contains(t, got.Url, tt.wantExpires)
+			assert.Contains(t, got.Url, "X-Amz-Signature=")
+			assert.True(t, got.ExpiresAt.After(time.Now()))
+		})
+	}
+}
+
+func TestRepo_PresignUpload(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       *dto.PresignUploadInput
+		wantURL     string
+		wantHeaders map[string]string
+		wantErr     bool
+	}{
+		{
+			name: "succeed",
+			input: &dto.PresignUploadInput{
+				Directory:   "test",
+				Filename:    "test.jpg",
+				ACL:         "public-read",
+				ContentType: "image/jpeg",
+				Size:        1024,
+				Expires:     time.Minute,
+			},
+			wantURL: "https://s3.eu-central-1.amazonaws.com/test.bucket/test/test.jpg?",
+			wantHeaders: map[string]string{
+				"X-Amz-Acl":      "public-read",
+				"Content-Type":   "image/jpeg",
+				"Content-Length": "1024",
+			},
+		},
+		{
+			name:        "without headers",
+			input:       &dto.PresignUploadInput{Filename: "test.jpg"},
+			wantURL:     "https://s3.eu-central-1.amazonaws.com/test.bucket/test.jpg?",
+			wantHeaders: map[string]string{},
+		},
+		{
+			name:    "expiry exceeds max",
+			input:   &dto.PresignUploadInput{Filename: "test.jpg", Expires: 2 * time.Hour},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := awsSession.New(config.S3Config{Region: "eu-central-1", AccessKeyID: "test", SecretAccessKey: "test"})
+			repo := fileRepo.New(session, "test.bucket", time.Hour)
+			got, err := repo.PresignUpload(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			assert.True(t, strings.HasPrefix(got.Url, tt.wantURL), got.Url)
+			assert.EqualValues(t, tt.wantHeaders, got.Headers)
+		})
+	}
+}
+
+func TestRepo_URL(t *testing.T) {
+	repo := testRepo(&fields{Client: new(mocks.S3Client), bucketName: "test.bucket"})
+	got, err := repo.URL("test/my file.jpg")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "https://aws.s3/test.bucket/test/my%20file.jpg", got)
+}
+
+func TestRepo_Key(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.S3Config
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "virtual-hosted-style",
+			conf: config.S3Config{Region: "eu-central-1"},
+			url:  "https://files.s3.eu-central-1.amazonaws.com/files/a.png",
+			want: "files/a.png",
+		},
+		{
+			name: "path-style",
+			conf: config.S3Config{Region: "eu-central-1", Endpoint: "https://files.example.com", ForcePathStyle: true},
+			url:  "https://files.example.com/files/a.png",
+			want: "a.png",
+		},
+		{
+			name: "virtual-hosted-style with custom endpoint",
+			conf: config.S3Config{Region: "eu-central-1", Endpoint: "https://example.com"},
+			url:  "https://files.example.com/files/a.png",
+			want: "files/a.png",
+		},
+		{
+			name:    "other endpoint",
+			conf:    config.S3Config{Region: "eu-central-1", Endpoint: "https://example.com", ForcePathStyle: true},
+			url:     "https://files.example.com/files/a.png",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.New(awsSession.New(tt.conf), "files", time.Hour)
+			got, err := repo.Key(tt.url)
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+			assert.EqualValues(t, tt.want, got)
+			if err == nil {
+				// Keys are taken from urls the same way as urls are built
+				url, err := repo.URL(got)
+				assert.NoError(t, err)
+				assert.EqualValues(t, tt.url, url)
+			}
+		})
+	}
+}
+
+func TestRepo_ListVersions(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	listInput := &s3.ListObjectVersionsInput{
+		Bucket:  aws.String("test.bucket"),
+		Prefix:  aws.String("test.jpg"),
+		MaxKeys: aws.Int64(2),
+	}
+	input := &dto.ListVersionsInput{Url: "https://aws.s3/test.bucket/test.jpg", PageSize: 2}
+	tests := []struct {
+		name    string
+		mocks   map[string]mocks.Calls
+		want    *dto.ListVersionsOutput
+		wantErr error
+	}{
+		{
+			name: "versions and delete markers",
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "ListObjectVersionsWithContext",
+						Args:   []interface{}{helpers.DefaultCtx, listInput},
+						ReturnArgs: []interface{}{&s3.ListObjectVersionsOutput{
+							Versions: []*s3.ObjectVersion{{
+								Key:          aws.String("test.jpg"),
+								VersionId:    aws.String("v1"),
+								Size:         aws.Int64(4),
+								ETag:         aws.String("etag"),
+								LastModified: aws.Time(now.Add(-time.Hour)),
+							}},
+							DeleteMarkers: []*s3.DeleteMarkerEntry{{
+								Key:          aws.String("test.jpg"),
+								VersionId:    aws.String("v2"),
+								LastModified: aws.Time(now),
+								IsLatest:     aws.Bool(true),
+							}},
+							IsTruncated:         aws.Bool(true),
+							NextKeyMarker:       aws.String("test.jpg"),
+							NextVersionIdMarker: aws.String("v1"),
+						}, nil},
+					},
+				},
+			},
+			want: &dto.ListVersionsOutput{
+				Versions: []*dto.FileVersion{
+					{VersionId: "v2", LastModified: now, IsLatest: true, IsDeleteMarker: true},
+					{VersionId: "v1", Size: 4, ETag: "etag", LastModified: now.Add(-time.Hour)},
+				},
+				NextPageToken: "v1",
+			},
+		},
+		{
+			name: "versions of other keys",
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "ListObjectVersionsWithContext",
+						Args:   []interface{}{helpers.DefaultCtx, listInput},
+						ReturnArgs: []interface{}{&s3.ListObjectVersionsOutput{
+							Versions: []*s3.ObjectVersion{
+								{Key: aws.String("test.jpg"), VersionId: aws.String("v1"), IsLatest: aws.Bool(true)},
+								{Key: aws.String("test.jpg.bak"), VersionId: aws.String("v2")},
+							},
+							IsTruncated:         aws.Bool(true),
+							NextKeyMarker:       aws.String("test.jpg.bak"),
+							NextVersionIdMarker: aws.String("v2"),
+						}, nil},
+					},
+				},
+			},
+			want: &dto.ListVersionsOutput{
+				Versions: []*dto.FileVersion{{VersionId: "v1", IsLatest: true}},
+			},
+		},
+		{
+			name: "error",
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "ListObjectVersionsWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, listInput},
+						ReturnArgs: []interface{}{nil, errors.New("test error")},
+					},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fields{Client: new(mocks.S3Client), bucketName: "test.bucket"}
+			assertMocks := setupMocks(t, &f, tt.mocks)
+			defer assertMocks()
+			got, err := testRepo(&f).ListVersions(helpers.DefaultCtx, input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_GetVersion(t *testing.T) {
+	f := fields{Client: new(mocks.S3Client), bucketName: "test.bucket"}
+	assertMocks := setupMocks(t, &f, map[string]mocks.Calls{
+		"Client": {
+			{
+				Method: "GetObjectWithContext",
+				Args: []interface{}{helpers.DefaultCtx, &s3.GetObjectInput{
+					Bucket:    aws.String("test.bucket"),
+					Key:       aws.String("test.jpg"),
+					VersionId: aws.String("v1"),
+				}},
+				ReturnArgs: []interface{}{&s3.GetObjectOutput{
+					Body:          ioutil.NopCloser(strings.NewReader("test")),
+					ContentType:   aws.String("image/jpeg"),
+					ContentLength: aws.Int64(4),
+				}, nil},
+			},
+			{
+				Method: "GetObjectWithContext",
+				Args: []interface{}{helpers.DefaultCtx, &s3.GetObjectInput{
+					Bucket:    aws.String("test.bucket"),
+					Key:       aws.String("test.jpg"),
+					VersionId: aws.String("marker"),
+				}},
+				ReturnArgs: []interface{}{nil, awserr.New("MethodNotAllowed", "test", nil)},
+			},
+		},
+	})
+	defer assertMocks()
+	repo := testRepo(&f)
+
+	got, err := repo.GetVersion(helpers.DefaultCtx, &dto.GetVersionInput{
+		DownloadInput: dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+		VersionId:     "v1",
+	})
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(got.File)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test", string(content))
+	assert.EqualValues(t, 4, got.Size)
+
+	_, err = repo.GetVersion(helpers.DefaultCtx, &dto.GetVersionInput{
+		DownloadInput: dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+		VersionId:     "marker",
+	})
+	assert.EqualValues(t, customErrors.VersionNotFound, err)
+}
+
+func TestRepo_RestoreVersion(t *testing.T) {
+	headInput := &s3.HeadObjectInput{
+		Bucket:    aws.String("test.bucket"),
+		Key:       aws.String("test/test.jpg"),
+		VersionId: aws.String("v1"),
+	}
+	input := &dto.RestoreVersionInput{Url: "https://aws.s3/test.bucket/test/test.jpg", VersionId: "v1", ACL: "public-read"}
+	tests := []struct {
+		name    string
+		mocks   map[string]mocks.Calls
+		want    *dto.UploadOutput
+		wantErr error
+	}{
+		{
+			name: "succeed",
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "HeadObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{&s3.HeadObjectOutput{ContentLength: aws.Int64(4)}, nil},
+					},
+					{
+						Method: "CopyObjectWithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.CopyObjectInput{
+							Bucket:     aws.String("test.bucket"),
+							Key:        aws.String("test/test.jpg"),
+							CopySource: aws.String("test.bucket/test/test.jpg?versionId=v1"),
+							ACL:        aws.String("public-read"),
+						}},
+						ReturnArgs: []interface{}{&s3.CopyObjectOutput{VersionId: aws.String("v3")}, nil},
+					},
+				},
+			},
+			want: &dto.UploadOutput{Url: "https://aws.s3/test.bucket/test/test.jpg", VersionId: "v3"},
+		},
+		{
+			name: "multipart copy",
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "HeadObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{&s3.HeadObjectOutput{ContentLength: aws.Int64(6 << 30)}, nil},
+					},
+					{
+						Method:     "CreateMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload")}, nil},
+					},
+					{
+						Method: "UploadPartCopyWithContext",
+						Args: []interface{}{helpers.DefaultCtx, mock.MatchedBy(func(input *s3.UploadPartCopyInput) bool {
+							return aws.StringValue(input.CopySource) == "test.bucket/test/test.jpg?versionId=v1"
+						})},
+						ReturnArgs: []interface{}{&s3.UploadPartCopyOutput{CopyPartResult: &s3.CopyPartResult{ETag: aws.String("etag")}}, nil},
+					},
+					{
+						Method:     "CompleteMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{&s3.CompleteMultipartUploadOutput{VersionId: aws.String("v3")}, nil},
+					},
+				},
+			},
+			want: &dto.UploadOutput{Url: "https://aws.s3/test.bucket/test/test.jpg", VersionId: "v3"},
+		},
+		{
+			name: "delete marker",
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "HeadObjectWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, headInput},
+						ReturnArgs: []interface{}{nil, awserr.New("MethodNotAllowed", "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.VersionNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fields{Client: new(mocks.S3Client), bucketName: "test.bucket"}
+			assertMocks := setupMocks(t, &f, tt.mocks)
+			defer assertMocks()
+			got, err := testRepo(&f).RestoreVersion(helpers.DefaultCtx, input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
 		})
 	}
 }
@@ -0,0 +1,499 @@
+package fileRepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+const (
+	localTmpDir = ".tmp"
+	// localMetaDir keeps metadata of files, which can't be stored in file system
+	localMetaDir = ".meta"
+)
+
+type (
+	// localRepo stores files under root directory and builds urls by joining baseURL and file key
+	localRepo struct {
+		root    string
+		baseURL string
+	}
+
+	ctxReader struct {
+		ctx    context.Context
+		reader io.Reader
+	}
+
+	readCloser struct {
+		io.Reader
+		io.Closer
+	}
+
+	localMetadata struct {
+		ContentType string            `json:"content_type"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+	}
+
+	// listPage is a page of keys and common prefixes built by paginate
+	listPage struct {
+		keys      []string
+		prefixes  []string
+		nextToken string
+	}
+)
+
+func NewLocal(root, baseURL string) *localRepo {
+	return &localRepo{
+		root:    root,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (r *localRepo) Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error) {
+	key := cleanKey(input.Key())
+	if key == "" || isReservedKey(key) {
+		return nil, customErrors.InvalidKey
+	}
+	if err := r.write(key, &ctxReader{ctx: ctx, reader: input.File}, input.Exclusive); err != nil {
+		return nil, err
+	} else if err := r.writeMetadata(key, &localMetadata{
+		ContentType: input.ContentType,
+		Metadata:    input.Metadata(),
+	}); err != nil {
+		return nil, err
+	}
+	return &dto.UploadOutput{Url: r.url(key)}, nil
+}
+
+func (r *localRepo) Copy(ctx context.Context, input *dto.CopyInput) (string, error) {
+	sourceKey, err := r.key(input.SourceUrl)
+	if err != nil {
+		return "", err
+	}
+	key := cleanKey(input.Key())
+	if key == "" || isReservedKey(key) {
+		return "", customErrors.InvalidKey
+	} else if key == sourceKey {
+		return "", customErrors.SameFile
+	}
+
+	source, err := os.Open(r.path(sourceKey))
+	if os.IsNotExist(err) {
+		return "", customErrors.NotFound
+	} else if err != nil {
+		return "", err
+	}
+	defer source.Close()
+	metadata, err := r.readMetadata(sourceKey)
+	if err != nil {
+		return "", err
+	} else if input.Metadata != nil {
+		metadata.Metadata = input.Metadata
+	}
+	if err := r.write(key, &ctxReader{ctx: ctx, reader: source}, false); err != nil {
+		return "", err
+	} else if err := r.writeMetadata(key, metadata); err != nil {
+		return "", err
+	}
+	return r.url(key), nil
+}
+
+func (r *localRepo) Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error) {
+	key, err := r.key(input.Url)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(r.path(key))
+	if os.IsNotExist(err) {
+		return nil, customErrors.NotFound
+	} else if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	length, err := input.RangeLength(info.Size())
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	metadata, err := r.readMetadata(key)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &dto.DownloadOutput{
+		File: &readCloser{
+			Reader: io.NewSectionReader(file, input.Offset, length),
+			Closer: file,
+		},
+		ContentType: metadata.ContentType,
+		Size:        info.Size(),
+		Offset:      input.Offset,
+		Length:      length,
+	}, nil
+}
+
+func (r *localRepo) Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error) {
+	key, err := r.key(input.Url)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(r.path(key))
+	if os.IsNotExist(err) || err == nil && info.IsDir() {
+		return nil, customErrors.NotFound
+	} else if err != nil {
+		return nil, err
+	}
+	metadata, err := r.readMetadata(key)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo := r.fileInfo(key, info)
+	fileInfo.ContentType = metadata.ContentType
+	fileInfo.Metadata = metadata.Metadata
+	return fileInfo, nil
+}
+
+// ACL returns empty string, because local storage has no ACLs
+func (r *localRepo) ACL(ctx context.Context, url string) (string, error) {
+	if _, err := r.Stat(ctx, &dto.StatInput{Url: url}); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func (r *localRepo) List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error) {
+	// Walk only the deepest directory containing all keys with the prefix
+	dir := r.path(cleanKey(path.Dir(input.Prefix)))
+	infos := make(map[string]os.FileInfo)
+	err := filepath.Walk(dir, func(filename string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(r.root, filename)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if info.IsDir() {
+			if isReservedKey(key) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(key, input.Prefix) {
+			infos[key] = info
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(infos))
+	for key := range infos {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	page := paginate(keys, input)
+	files := make([]*dto.FileInfo, len(page.keys))
+	for i, key := range page.keys {
+		files[i] = r.fileInfo(key, infos[key])
+	}
+	return &dto.ListOutput{
+		Files:         files,
+		Prefixes:      page.prefixes,
+		NextPageToken: page.nextToken,
+	}, nil
+}
+
+func (r *localRepo) Delete(ctx context.Context, input dto.DeleteInput) error {
+	key, err := r.key(input.String())
+	if err != nil {
+		return err
+	}
+	return r.remove(key)
+}
+
+func (r *localRepo) BatchDelete(ctx context.Context, input dto.BatchDeleteInput) error {
+	keys := make([]string, len(input))
+	for i, obj := range input {
+		key, err := r.key(obj.String())
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		} else if err := r.remove(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PresignDownload is not supported, files can't be accessed without the service
+func (r *localRepo) PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+// PresignUpload is not supported, files can't be accessed without the service
+func (r *localRepo) PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+// Versioning is not supported, files are overwritten in place
+
+func (r *localRepo) ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *localRepo) GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *localRepo) RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+// Upload sessions are not supported, they are built on S3 multipart uploads
+
+func (r *localRepo) InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *localRepo) UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *localRepo) GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *localRepo) CompleteUpload(ctx context.Context, session *dto.UploadSession) (string, error) {
+	return "", customErrors.NotSupported
+}
+
+func (r *localRepo) AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error {
+	return customErrors.NotSupported
+}
+
+// URL returns url of the key without checking whether the file exists
+func (r *localRepo) URL(key string) (string, error) {
+	if key = cleanKey(key); key == "" || isReservedKey(key) {
+		return "", customErrors.InvalidKey
+	}
+	return r.url(key), nil
+}
+
+// Key returns key of the url without checking whether the file exists
+func (r *localRepo) Key(url string) (string, error) {
+	return r.key(url)
+}
+
+func (r *localRepo) key(rawURL string) (string, error) {
+	key, err := parseKey(rawURL, r.baseURL)
+	if err != nil {
+		return "", err
+	} else if isReservedKey(key) {
+		return "", customErrors.InvalidURL
+	}
+	return key, nil
+}
+
+func (r *localRepo) remove(key string) error {
+	// Deleting missing file is not an error, the same way as in S3
+	for _, filename := range []string{r.path(key), r.metadataPath(key)} {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *localRepo) metadataPath(key string) string {
+	return r.path(path.Join(localMetaDir, key+".json"))
+}
+
+// readMetadata returns stored metadata of the file, content type is taken
+// from the extension for files without metadata, e.g. copied to the root manually
+func (r *localRepo) readMetadata(key string) (*localMetadata, error) {
+	metadata := new(localMetadata)
+	data, err := ioutil.ReadFile(r.metadataPath(key))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	} else if err == nil {
+		if err := json.Unmarshal(data, metadata); err != nil {
+			return nil, err
+		}
+	}
+	if metadata.ContentType == "" {
+		metadata.ContentType = dto.ContentTypeByExtension(key)
+	}
+	return metadata, nil
+}
+
+func (r *localRepo) writeMetadata(key string, metadata *localMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	filename := r.metadataPath(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// write writes file through temporary one, so readers never see partially written file.
+// Exclusive write links the file instead of renaming it, because link fails if the file exists
+func (r *localRepo) write(key string, reader io.Reader, exclusive bool) error {
+	filename := r.path(key)
+	tmpDir := filepath.Join(r.root, localTmpDir)
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(tmpDir, "upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, reader); err != nil {
+		_ = tmp.Close()
+		return err
+	} else if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !exclusive {
+		return os.Rename(tmp.Name(), filename)
+	} else if err := os.Link(tmp.Name(), filename); os.IsExist(err) {
+		return customErrors.AlreadyExists
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *localRepo) fileInfo(key string, info os.FileInfo) *dto.FileInfo {
+	return &dto.FileInfo{
+		Key:  key,
+		Url:  r.url(key),
+		Size: info.Size(),
+		// Weak ETag built from modification time and size, the same way as nginx does
+		ETag:         fmt.Sprintf(`"%x-%x"`, info.ModTime().Unix(), info.Size()),
+		LastModified: info.ModTime(),
+	}
+}
+
+func (r *localRepo) path(key string) string {
+	return filepath.Join(r.root, filepath.FromSlash(key))
+}
+
+func (r *localRepo) url(key string) string {
+	return joinURL(r.baseURL, key)
+}
+
+func joinURL(baseURL, key string) string {
+	return baseURL + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+// isReservedKey reports whether key points to directories used by localRepo itself
+func isReservedKey(key string) bool {
+	dir := strings.SplitN(key, "/", 2)[0]
+	return dir == localTmpDir || dir == localMetaDir
+}
+
+// parseKey extracts normalized key from url built by joinURL
+func parseKey(rawURL, baseURL string) (string, error) {
+	key, err := dto.KeyFromURL(rawURL, baseURL)
+	if err != nil {
+		return "", err
+	} else if key = cleanKey(key); key == "" {
+		return "", customErrors.InvalidURL
+	}
+	return key, nil
+}
+
+// paginate groups sorted keys by delimiter the same way as S3 does and returns requested page,
+// page token is the last key or common prefix of the previous page
+func paginate(keys []string, input *dto.ListInput) *listPage {
+	page := new(listPage)
+	limit := int(input.Limit())
+	count := 0
+	for _, key := range keys {
+		if !strings.HasPrefix(key, input.Prefix) {
+			continue
+		}
+		entry, isPrefix := key, false
+		if input.Delimiter != "" {
+			if i := strings.Index(key[len(input.Prefix):], input.Delimiter); i >= 0 {
+				entry, isPrefix = key[:len(input.Prefix)+i+len(input.Delimiter)], true
+			}
+		}
+		// Keys are sorted, so keys with the same common prefix follow each other
+		if entry <= input.PageToken || isPrefix && count > 0 && page.lastEntry() == entry {
+			continue
+		}
+		if count == limit {
+			page.nextToken = page.lastEntry()
+			break
+		}
+		if isPrefix {
+			page.prefixes = append(page.prefixes, entry)
+		} else {
+			page.keys = append(page.keys, entry)
+		}
+		count++
+	}
+	return page
+}
+
+func (p *listPage) lastEntry() string {
+	var lastKey, lastPrefix string
+	if len(p.keys) > 0 {
+		lastKey = p.keys[len(p.keys)-1]
+	}
+	if len(p.prefixes) > 0 {
+		lastPrefix = p.prefixes[len(p.prefixes)-1]
+	}
+	if lastKey > lastPrefix {
+		return lastKey
+	}
+	return lastPrefix
+}
+
+// cleanKey normalizes key and prevents it from pointing outside of storage root
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
@@ -0,0 +1,595 @@
+package fileRepo_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+)
+
+const testBaseURL = "http://localhost/files"
+
+func writeFile(t *testing.T, root, key, content string) {
+	t.Helper()
+	filename := filepath.Join(root, filepath.FromSlash(key))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+}
+
+func TestLocalRepo_Upload(t *testing.T) {
+	canceledCtx, cancel := context.WithCancel(helpers.DefaultCtx)
+	cancel()
+
+	type args struct {
+		ctx   context.Context
+		input *dto.UploadInput
+	}
+	tests := []struct {
+		name     string
+		args     args
+		want     *dto.UploadOutput
+		wantFile string
+		wantErr  error
+	}{
+		{
+			name: "succeed",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: "test",
+					Filename:  "test.jpg",
+					ACL:       "public-read",
+				},
+			},
+			want:     &dto.UploadOutput{Url: testBaseURL + "/test/test.jpg"},
+			wantFile: "test/test.jpg",
+		},
+		{
+			name: "escaped url",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: "test",
+					Filename:  "my file.jpg",
+				},
+			},
+			want:     &dto.UploadOutput{Url: testBaseURL + "/test/my%20file.jpg"},
+			wantFile: "test/my file.jpg",
+		},
+		{
+			name: "path outside of root",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: "../../test",
+					Filename:  "test.jpg",
+				},
+			},
+			want:     &dto.UploadOutput{Url: testBaseURL + "/test/test.jpg"},
+			wantFile: "test/test.jpg",
+		},
+		{
+			name: "invalid key",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:     strings.NewReader("test"),
+					Filename: "..",
+				},
+			},
+			wantErr: customErrors.InvalidKey,
+		},
+		{
+			name: "reserved directory",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: ".meta",
+					Filename:  "test.jpg",
+				},
+			},
+			wantErr: customErrors.InvalidKey,
+		},
+		{
+			name: "canceled context",
+			args: args{
+				ctx: canceledCtx,
+				input: &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: "test",
+					Filename:  "test.jpg",
+				},
+			},
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := helpers.TempDir(t)
+			repo := fileRepo.NewLocal(root, testBaseURL)
+			got, err := repo.Upload(tt.args.ctx, tt.args.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+			if tt.wantFile != "" {
+				content, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(tt.wantFile)))
+				assert.NoError(t, err)
+				assert.EqualValues(t, "test", string(content))
+			}
+		})
+	}
+}
+
+func TestLocalRepo_ContentType(t *testing.T) {
+	root := helpers.TempDir(t)
+	repo := fileRepo.NewLocal(root, testBaseURL)
+	uploaded, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:        strings.NewReader("test"),
+		Directory:   "test",
+		Filename:    "test.jpg",
+		ContentType: "text/plain",
+	})
+	assert.NoError(t, err)
+	url := uploaded.Url
+	copyURL, err := repo.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: url, Directory: "copy", Filename: "test.jpg"})
+	assert.NoError(t, err)
+
+	for _, url := range []string{url, copyURL} {
+		info, err := repo.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+		assert.NoError(t, err)
+		assert.EqualValues(t, "text/plain", info.ContentType)
+		output, err := repo.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: url})
+		assert.NoError(t, err)
+		assert.NoError(t, output.File.Close())
+		assert.EqualValues(t, "text/plain", output.ContentType)
+	}
+
+	list, err := repo.List(helpers.DefaultCtx, &dto.ListInput{})
+	assert.NoError(t, err)
+	assert.Len(t, list.Files, 2)
+
+	assert.NoError(t, repo.Delete(helpers.DefaultCtx, dto.DeleteInput(url)))
+	_, err = os.Stat(filepath.Join(root, ".meta", "test", "test.jpg.json"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = repo.Stat(helpers.DefaultCtx, &dto.StatInput{Url: testBaseURL + "/.meta/copy/test.jpg.json"})
+	assert.EqualValues(t, customErrors.InvalidURL, err)
+}
+
+func TestLocalRepo_Metadata(t *testing.T) {
+	repo := fileRepo.NewLocal(helpers.TempDir(t), testBaseURL)
+	uploaded, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:     strings.NewReader("test"),
+		Filename: "test.jpg",
+		SHA256:   "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08",
+	})
+	assert.NoError(t, err)
+	url := uploaded.Url
+	copyURL, err := repo.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: url, Directory: "copy", Filename: "test.jpg"})
+	assert.NoError(t, err)
+
+	for _, url := range []string{url, copyURL} {
+		info, err := repo.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]string{
+			dto.SHA256MetadataKey: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		}, info.Metadata)
+	}
+}
+
+func TestLocalRepo_Copy(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *dto.CopyInput
+		want     string
+		wantFile string
+		wantErr  error
+	}{
+		{
+			name:     "succeed",
+			input:    &dto.CopyInput{SourceUrl: testBaseURL + "/tmp/test.jpg", Directory: "test", Filename: "test.jpg"},
+			want:     testBaseURL + "/test/test.jpg",
+			wantFile: "test/test.jpg",
+		},
+		{
+			name:    "source not found",
+			input:   &dto.CopyInput{SourceUrl: testBaseURL + "/tmp/missing.jpg", Directory: "test", Filename: "test.jpg"},
+			wantErr: customErrors.NotFound,
+		},
+		{
+			name:    "same file",
+			input:   &dto.CopyInput{SourceUrl: testBaseURL + "/tmp/test.jpg", Directory: "tmp", Filename: "test.jpg"},
+			wantErr: customErrors.SameFile,
+		},
+		{
+			name:    "invalid source url",
+			input:   &dto.CopyInput{SourceUrl: "http://localhost/other/tmp/test.jpg", Filename: "test.jpg"},
+			wantErr: customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := helpers.TempDir(t)
+			writeFile(t, root, "tmp/test.jpg", "test")
+			repo := fileRepo.NewLocal(root, testBaseURL)
+			got, err := repo.Copy(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+			if tt.wantFile != "" {
+				content, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(tt.wantFile)))
+				assert.NoError(t, err)
+				assert.EqualValues(t, "test", string(content))
+			}
+			content, err := ioutil.ReadFile(filepath.Join(root, "tmp", "test.jpg"))
+			assert.NoError(t, err)
+			assert.EqualValues(t, "test", string(content))
+		})
+	}
+}
+
+func TestLocalRepo_Download(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           *dto.DownloadInput
+		wantContent     string
+		wantContentType string
+		wantSize        int64
+		wantErr         bool
+	}{
+		{
+			name:            "succeed",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg"},
+			wantContent:     "test",
+			wantContentType: "image/jpeg",
+		},
+		{
+			name:            "unknown extension",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.unknown"},
+			wantContent:     "test",
+			wantContentType: "application/octet-stream",
+		},
+		{
+			name:            "range",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg", Offset: 1, Length: 2},
+			wantContent:     "es",
+			wantContentType: "image/jpeg",
+			wantSize:        4,
+		},
+		{
+			name:            "range until the end",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg", Offset: 1},
+			wantContent:     "est",
+			wantContentType: "image/jpeg",
+			wantSize:        4,
+		},
+		{
+			name:    "invalid range",
+			input:   &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg", Offset: 4},
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			input:   &dto.DownloadInput{Url: testBaseURL + "/test/missing.jpg"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			input:   &dto.DownloadInput{Url: "http://localhost/other/test/test.jpg"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := helpers.TempDir(t)
+			writeFile(t, root, "test/test.jpg", "test")
+			writeFile(t, root, "test/test.unknown", "test")
+			repo := fileRepo.NewLocal(root, testBaseURL)
+			got, err := repo.Download(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			content, err := ioutil.ReadAll(got.File)
+			assert.NoError(t, err)
+			assert.NoError(t, got.File.Close())
+			assert.EqualValues(t, tt.wantContent, string(content))
+			assert.EqualValues(t, tt.wantContentType, got.ContentType)
+			assert.EqualValues(t, len(tt.wantContent), got.Length)
+			assert.EqualValues(t, tt.input.Offset, got.Offset)
+			if tt.wantSize != 0 {
+				assert.EqualValues(t, tt.wantSize, got.Size)
+			}
+		})
+	}
+}
+
+func TestLocalRepo_Stat(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *dto.StatInput
+		want    *dto.FileInfo
+		wantErr error
+	}{
+		{
+			name:  "succeed",
+			input: &dto.StatInput{Url: testBaseURL + "/test/test.jpg"},
+			want: &dto.FileInfo{
+				Key:         "test/test.jpg",
+				Url:         testBaseURL + "/test/test.jpg",
+				Size:        4,
+				ContentType: "image/jpeg",
+			},
+		},
+		{
+			name:    "missing file",
+			input:   &dto.StatInput{Url: testBaseURL + "/test/missing.jpg"},
+			wantErr: customErrors.NotFound,
+		},
+		{
+			name:    "directory",
+			input:   &dto.StatInput{Url: testBaseURL + "/test"},
+			wantErr: customErrors.NotFound,
+		},
+		{
+			name:    "invalid url",
+			input:   &dto.StatInput{Url: "http://localhost/other/test/test.jpg"},
+			wantErr: customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := helpers.TempDir(t)
+			writeFile(t, root, "test/test.jpg", "test")
+			repo := fileRepo.NewLocal(root, testBaseURL)
+			got, err := repo.Stat(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.NotEmpty(t, got.ETag)
+			assert.False(t, got.LastModified.IsZero())
+			got.ETag, got.LastModified = "", time.Time{}
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestLocalRepo_List(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         *dto.ListInput
+		wantKeys      []string
+		wantPrefixes  []string
+		wantPageToken string
+	}{
+		{
+			name:     "all files",
+			input:    &dto.ListInput{},
+			wantKeys: []string{"other.jpg", "test/a.jpg", "test/b.jpg", "test/sub/c.jpg"},
+		},
+		{
+			name:         "root directory",
+			input:        &dto.ListInput{Delimiter: "/"},
+			wantKeys:     []string{"other.jpg"},
+			wantPrefixes: []string{"test/"},
+		},
+		{
+			name:         "directory",
+			input:        &dto.ListInput{Prefix: "test/", Delimiter: "/"},
+			wantKeys:     []string{"test/a.jpg", "test/b.jpg"},
+			wantPrefixes: []string{"test/sub/"},
+		},
+		{
+			name:          "first page",
+			input:         &dto.ListInput{Prefix: "test/", Delimiter: "/", PageSize: 2},
+			wantKeys:      []string{"test/a.jpg", "test/b.jpg"},
+			wantPageToken: "test/b.jpg",
+		},
+		{
+			name:         "last page",
+			input:        &dto.ListInput{Prefix: "test/", Delimiter: "/", PageSize: 2, PageToken: "test/b.jpg"},
+			wantPrefixes: []string{"test/sub/"},
+		},
+		{
+			name:     "file name prefix",
+			input:    &dto.ListInput{Prefix: "test/a"},
+			wantKeys: []string{"test/a.jpg"},
+		},
+		{
+			name:  "missing directory",
+			input: &dto.ListInput{Prefix: "missing/", Delimiter: "/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := helpers.TempDir(t)
+			for _, file := range []string{"other.jpg", "test/a.jpg", "test/b.jpg", "test/sub/c.jpg", ".tmp/upload"} {
+				writeFile(t, root, file, "test")
+			}
+			repo := fileRepo.NewLocal(root, testBaseURL)
+			got, err := repo.List(helpers.DefaultCtx, tt.input)
+			assert.NoError(t, err)
+			var gotKeys []string
+			for _, file := range got.Files {
+				gotKeys = append(gotKeys, file.Key)
+				assert.EqualValues(t, testBaseURL+"/"+file.Key, file.Url)
+				assert.EqualValues(t, 4, file.Size)
+			}
+			assert.EqualValues(t, tt.wantKeys, gotKeys)
+			assert.EqualValues(t, tt.wantPrefixes, got.Prefixes)
+			assert.EqualValues(t, tt.wantPageToken, got.NextPageToken)
+		})
+	}
+}
+
+func TestLocalRepo_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		input   dto.DeleteInput
+		wantErr error
+	}{
+		{
+			name:  "succeed",
+			files: []string{"test/test.jpg"},
+			input: testBaseURL + "/test/test.jpg",
+		},
+		{
+			name:  "missing file",
+			input: testBaseURL + "/test/test.jpg",
+		},
+		{
+			name:    "invalid url",
+			files:   []string{"test/test.jpg"},
+			input:   "http://localhost/other/test/test.jpg",
+			wantErr: customErrors.InvalidURL,
+		},
+		{
+			name:    "root directory",
+			input:   testBaseURL + "/..",
+			wantErr: customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := helpers.TempDir(t)
+			for _, file := range tt.files {
+				writeFile(t, root, file, "test")
+			}
+			repo := fileRepo.NewLocal(root, testBaseURL)
+			err := repo.Delete(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				_, err := os.Stat(filepath.Join(root, "test", "test.jpg"))
+				assert.True(t, os.IsNotExist(err))
+			}
+			_, err = os.Stat(root)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLocalRepo_BatchDelete(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []string
+		input     dto.BatchDeleteInput
+		wantFiles []string
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			files: []string{"test/test.jpg", "test/test2.jpg", "test/test3.jpg"},
+			input: dto.BatchDeleteInput{
+				testBaseURL + "/test/test.jpg",
+				testBaseURL + "/test/test2.jpg",
+			},
+			wantFiles: []string{"test/test3.jpg"},
+		},
+		{
+			name:  "invalid url",
+			files: []string{"test/test.jpg", "test/test2.jpg"},
+			input: dto.BatchDeleteInput{
+				testBaseURL + "/test/test.jpg",
+				"http://localhost/other/test2.jpg",
+			},
+			wantFiles: []string{"test/test.jpg", "test/test2.jpg"},
+			wantErr:   customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := helpers.TempDir(t)
+			for _, file := range tt.files {
+				writeFile(t, root, file, "test")
+			}
+			repo := fileRepo.NewLocal(root, testBaseURL)
+			err := repo.BatchDelete(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			for _, file := range tt.wantFiles {
+				_, err := os.Stat(filepath.Join(root, filepath.FromSlash(file)))
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLocalRepo_Presign(t *testing.T) {
+	repo := fileRepo.NewLocal(helpers.TempDir(t), testBaseURL)
+	_, err := repo.PresignDownload(helpers.DefaultCtx, &dto.PresignDownloadInput{Url: testBaseURL + "/test/test.jpg"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.PresignUpload(helpers.DefaultCtx, &dto.PresignUploadInput{Filename: "test.jpg"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+}
+
+func TestLocalRepo_UploadSessions(t *testing.T) {
+	repo := fileRepo.NewLocal(helpers.TempDir(t), testBaseURL)
+	session := &dto.UploadSessionInput{UploadId: dto.NewUploadId("test/test.jpg", "test")}
+	_, err := repo.InitiateUpload(helpers.DefaultCtx, &dto.InitiateUploadInput{Filename: "test.jpg"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.UploadPart(helpers.DefaultCtx, &dto.UploadPartInput{UploadId: session.UploadId, PartNumber: 1})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.GetUpload(helpers.DefaultCtx, session)
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.CompleteUpload(helpers.DefaultCtx, &dto.UploadSession{UploadId: session.UploadId})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	assert.EqualValues(t, customErrors.NotSupported, repo.AbortUpload(helpers.DefaultCtx, session))
+}
+
+func TestLocalRepo_URL(t *testing.T) {
+	repo := fileRepo.NewLocal(helpers.TempDir(t), testBaseURL)
+	got, err := repo.URL("/test/../test/my file.jpg")
+	assert.NoError(t, err)
+	assert.EqualValues(t, testBaseURL+"/test/my%20file.jpg", got)
+	_, err = repo.URL("..")
+	assert.EqualValues(t, customErrors.InvalidKey, err)
+}
+
+func TestLocalRepo_Versions(t *testing.T) {
+	repo := fileRepo.NewLocal(helpers.TempDir(t), testBaseURL)
+	url := testBaseURL + "/test/test.jpg"
+	_, err := repo.ListVersions(helpers.DefaultCtx, &dto.ListVersionsInput{Url: url})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.GetVersion(helpers.DefaultCtx, &dto.GetVersionInput{DownloadInput: dto.DownloadInput{Url: url}, VersionId: "v1"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.RestoreVersion(helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	key, err := repo.Key(url)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test/test.jpg", key)
+}
+
+func TestLocalRepo_Upload_Exclusive(t *testing.T) {
+	repo := fileRepo.NewLocal(helpers.TempDir(t), testBaseURL)
+	upload := func(content string, exclusive bool) error {
+		_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      strings.NewReader(content),
+			Filename:  "test.txt",
+			Exclusive: exclusive,
+		})
+		return err
+	}
+	assert.NoError(t, upload("first", true))
+	assert.EqualValues(t, customErrors.AlreadyExists, upload("second", true))
+	assert.NoError(t, upload("third", false))
+
+	output, err := repo.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: testBaseURL + "/test.txt"})
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(output.File)
+	assert.NoError(t, err)
+	assert.NoError(t, output.File.Close())
+	assert.EqualValues(t, "third", string(content))
+}
@@ -0,0 +1,313 @@
+package fileRepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+type (
+	MemoryObject struct {
+		Key         string
+		Url         string
+		Body        []byte
+		ACL         string
+		ContentType string
+		Metadata    map[string]string
+		UploadedAt  time.Time
+	}
+
+	// memoryRepo keeps files in memory, it is safe for concurrent use
+	memoryRepo struct {
+		mu      sync.RWMutex
+		baseURL string
+		objects map[string]*MemoryObject
+	}
+)
+
+func NewMemory(baseURL string) *memoryRepo {
+	return &memoryRepo{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		objects: make(map[string]*MemoryObject),
+	}
+}
+
+func (r *memoryRepo) Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error) {
+	key := cleanKey(input.Key())
+	if key == "" {
+		return nil, customErrors.InvalidKey
+	}
+	body, err := ioutil.ReadAll(&ctxReader{ctx: ctx, reader: input.File})
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &MemoryObject{
+		Key:         key,
+		Url:         joinURL(r.baseURL, key),
+		Body:        body,
+		ACL:         input.ACL,
+		ContentType: input.ContentType,
+		Metadata:    input.Metadata(),
+		UploadedAt:  time.Now(),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.objects[key]; ok && input.Exclusive {
+		return nil, customErrors.AlreadyExists
+	}
+	r.objects[key] = obj
+	return &dto.UploadOutput{Url: obj.Url}, nil
+}
+
+func (r *memoryRepo) Copy(ctx context.Context, input *dto.CopyInput) (string, error) {
+	sourceKey, err := r.key(input.SourceUrl)
+	if err != nil {
+		return "", err
+	}
+	key := cleanKey(input.Key())
+	if key == "" {
+		return "", customErrors.InvalidKey
+	} else if key == sourceKey {
+		return "", customErrors.SameFile
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	source, ok := r.objects[sourceKey]
+	if !ok {
+		return "", customErrors.NotFound
+	}
+	obj := source.copy()
+	obj.Key = key
+	obj.Url = joinURL(r.baseURL, key)
+	obj.ACL = input.ACL
+	if input.Metadata != nil {
+		obj.Metadata = input.Metadata
+	}
+	obj.UploadedAt = time.Now()
+	r.objects[key] = obj
+	return obj.Url, nil
+}
+
+func (r *memoryRepo) Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error) {
+	obj, err := r.object(input.Url)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(obj.Body))
+	length, err := input.RangeLength(size)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.DownloadOutput{
+		File:        ioutil.NopCloser(bytes.NewReader(obj.Body[input.Offset : input.Offset+length])),
+		ContentType: obj.contentType(),
+		Size:        size,
+		Offset:      input.Offset,
+		Length:      length,
+	}, nil
+}
+
+func (r *memoryRepo) Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error) {
+	obj, err := r.object(input.Url)
+	if err != nil {
+		return nil, err
+	}
+	info := obj.fileInfo()
+	info.ContentType = obj.contentType()
+	info.Metadata = obj.Metadata
+	return info, nil
+}
+
+func (r *memoryRepo) ACL(ctx context.Context, url string) (string, error) {
+	obj, err := r.object(url)
+	if err != nil {
+		return "", err
+	}
+	return obj.ACL, nil
+}
+
+func (r *memoryRepo) List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]string, 0, len(r.objects))
+	for key := range r.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	page := paginate(keys, input)
+	files := make([]*dto.FileInfo, len(page.keys))
+	for i, key := range page.keys {
+		files[i] = r.objects[key].fileInfo()
+	}
+	return &dto.ListOutput{
+		Files:         files,
+		Prefixes:      page.prefixes,
+		NextPageToken: page.nextToken,
+	}, nil
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, input dto.DeleteInput) error {
+	key, err := r.key(input.String())
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	delete(r.objects, key)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *memoryRepo) BatchDelete(ctx context.Context, input dto.BatchDeleteInput) error {
+	keys := make([]string, len(input))
+	for i, obj := range input {
+		key, err := r.key(obj.String())
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+	r.mu.Lock()
+	for _, key := range keys {
+		delete(r.objects, key)
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// PresignDownload is not supported, files can't be accessed without the service
+func (r *memoryRepo) PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+// PresignUpload is not supported, files can't be accessed without the service
+func (r *memoryRepo) PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+// Versioning is not supported, files are overwritten in place
+
+func (r *memoryRepo) ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *memoryRepo) GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *memoryRepo) RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error) {
+	return nil, customErrors.NotSupported
+}
+
+// Upload sessions are not supported, they are built on S3 multipart uploads
+
+func (r *memoryRepo) InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *memoryRepo) UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *memoryRepo) GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error) {
+	return nil, customErrors.NotSupported
+}
+
+func (r *memoryRepo) CompleteUpload(ctx context.Context, session *dto.UploadSession) (string, error) {
+	return "", customErrors.NotSupported
+}
+
+func (r *memoryRepo) AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error {
+	return customErrors.NotSupported
+}
+
+// URL returns url of the key without checking whether the object exists
+func (r *memoryRepo) URL(key string) (string, error) {
+	if key = cleanKey(key); key == "" {
+		return "", customErrors.InvalidKey
+	}
+	return joinURL(r.baseURL, key), nil
+}
+
+// Key returns key of the url without checking whether the object exists
+func (r *memoryRepo) Key(url string) (string, error) {
+	return r.key(url)
+}
+
+// Object returns copy of stored object by its url
+func (r *memoryRepo) Object(url string) (*MemoryObject, bool) {
+	obj, err := r.object(url)
+	return obj, err == nil
+}
+
+// Objects returns copies of all stored objects sorted by key
+func (r *memoryRepo) Objects() []*MemoryObject {
+	r.mu.RLock()
+	objects := make([]*MemoryObject, 0, len(r.objects))
+	for _, obj := range r.objects {
+		objects = append(objects, obj.copy())
+	}
+	r.mu.RUnlock()
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return objects
+}
+
+func (r *memoryRepo) object(rawURL string) (*MemoryObject, error) {
+	key, err := r.key(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	obj, ok := r.objects[key]
+	if !ok {
+		return nil, customErrors.NotFound
+	}
+	return obj.copy(), nil
+}
+
+func (r *memoryRepo) key(rawURL string) (string, error) {
+	return parseKey(rawURL, r.baseURL)
+}
+
+func (o *MemoryObject) fileInfo() *dto.FileInfo {
+	return &dto.FileInfo{
+		Key:          o.Key,
+		Url:          o.Url,
+		Size:         int64(len(o.Body)),
+		ETag:         fmt.Sprintf(`"%x"`, md5.Sum(o.Body)),
+		LastModified: o.UploadedAt,
+	}
+}
+
+// contentType falls back to the extension for objects uploaded without content type
+func (o *MemoryObject) contentType() string {
+	if o.ContentType != "" {
+		return o.ContentType
+	}
+	return dto.ContentTypeByExtension(o.Key)
+}
+
+func (o *MemoryObject) copy() *MemoryObject {
+	obj := *o
+	obj.Body = append([]byte(nil), o.Body...)
+	if o.Metadata != nil {
+		obj.Metadata = make(map[string]string, len(o.Metadata))
+		for k, v := range o.Metadata {
+			obj.Metadata[k] = v
+		}
+	}
+	return &obj
+}
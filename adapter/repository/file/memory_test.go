@@ -0,0 +1,562 @@
+package fileRepo_test
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+)
+
+func TestMemoryRepo_Upload(t *testing.T) {
+	canceledCtx, cancel := context.WithCancel(helpers.DefaultCtx)
+	cancel()
+
+	type args struct {
+		ctx   context.Context
+		input *dto.UploadInput
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *dto.UploadOutput
+		wantKey string
+		wantErr error
+	}{
+		{
+			name: "succeed",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: "test",
+					Filename:  "test.jpg",
+					ACL:       "public-read",
+				},
+			},
+			want:    &dto.UploadOutput{Url: testBaseURL + "/test/test.jpg"},
+			wantKey: "test/test.jpg",
+		},
+		{
+			name: "invalid key",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:     strings.NewReader("test"),
+					Filename: "..",
+				},
+			},
+			wantErr: customErrors.InvalidKey,
+		},
+		{
+			name: "canceled context",
+			args: args{
+				ctx: canceledCtx,
+				input: &dto.UploadInput{
+					File:     strings.NewReader("test"),
+					Filename: "test.jpg",
+				},
+			},
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.NewMemory(testBaseURL)
+			got, err := repo.Upload(tt.args.ctx, tt.args.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+			if tt.wantKey == "" {
+				assert.Empty(t, repo.Objects())
+				return
+			}
+			obj, ok := repo.Object(got.Url)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantKey, obj.Key)
+			assert.EqualValues(t, "test", string(obj.Body))
+			assert.EqualValues(t, tt.args.input.ACL, obj.ACL)
+		})
+	}
+}
+
+func TestMemoryRepo_Copy(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *dto.CopyInput
+		want     string
+		wantKeys []string
+		wantErr  error
+	}{
+		{
+			name: "succeed",
+			input: &dto.CopyInput{
+				SourceUrl: testBaseURL + "/tmp/test.jpg",
+				Directory: "test",
+				Filename:  "test.jpg",
+				ACL:       "public-read",
+			},
+			want:     testBaseURL + "/test/test.jpg",
+			wantKeys: []string{"test/test.jpg", "tmp/test.jpg"},
+		},
+		{
+			name:     "source not found",
+			input:    &dto.CopyInput{SourceUrl: testBaseURL + "/tmp/missing.jpg", Filename: "test.jpg"},
+			wantKeys: []string{"tmp/test.jpg"},
+			wantErr:  customErrors.NotFound,
+		},
+		{
+			name:     "same file",
+			input:    &dto.CopyInput{SourceUrl: testBaseURL + "/tmp/test.jpg", Directory: "tmp", Filename: "test.jpg"},
+			wantKeys: []string{"tmp/test.jpg"},
+			wantErr:  customErrors.SameFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.NewMemory(testBaseURL)
+			_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:      strings.NewReader("test"),
+				Directory: "tmp",
+				Filename:  "test.jpg",
+			})
+			assert.NoError(t, err)
+
+			got, err := repo.Copy(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+			var gotKeys []string
+			for _, obj := range repo.Objects() {
+				gotKeys = append(gotKeys, obj.Key)
+				assert.EqualValues(t, "test", string(obj.Body))
+			}
+			assert.EqualValues(t, tt.wantKeys, gotKeys)
+			if tt.want != "" {
+				obj, _ := repo.Object(tt.want)
+				assert.EqualValues(t, tt.input.ACL, obj.ACL)
+			}
+		})
+	}
+}
+
+func TestMemoryRepo_Download(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           *dto.DownloadInput
+		wantContent     string
+		wantContentType string
+		wantSize        int64
+		wantErr         bool
+	}{
+		{
+			name:            "succeed",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg"},
+			wantContent:     "test",
+			wantContentType: "image/jpeg",
+		},
+		{
+			name:            "range",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg", Offset: 1, Length: 2},
+			wantContent:     "es",
+			wantContentType: "image/jpeg",
+			wantSize:        4,
+		},
+		{
+			name:            "range until the end",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg", Offset: 1},
+			wantContent:     "est",
+			wantContentType: "image/jpeg",
+			wantSize:        4,
+		},
+		{
+			name:            "max length",
+			input:           &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg", Offset: 1, Length: math.MaxInt64 - 1},
+			wantContent:     "est",
+			wantContentType: "image/jpeg",
+			wantSize:        4,
+		},
+		{
+			name:    "invalid range",
+			input:   &dto.DownloadInput{Url: testBaseURL + "/test/test.jpg", Offset: 4},
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			input:   &dto.DownloadInput{Url: testBaseURL + "/test/missing.jpg"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.NewMemory(testBaseURL)
+			_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:      strings.NewReader("test"),
+				Directory: "test",
+				Filename:  "test.jpg",
+			})
+			assert.NoError(t, err)
+
+			got, err := repo.Download(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			content, err := ioutil.ReadAll(got.File)
+			assert.NoError(t, err)
+			assert.EqualValues(t, tt.wantContent, string(content))
+			assert.EqualValues(t, tt.wantContentType, got.ContentType)
+			assert.EqualValues(t, len(tt.wantContent), got.Length)
+			assert.EqualValues(t, tt.input.Offset, got.Offset)
+			if tt.wantSize != 0 {
+				assert.EqualValues(t, tt.wantSize, got.Size)
+			}
+		})
+	}
+}
+
+func TestMemoryRepo_ContentType(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	uploaded, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:        strings.NewReader("test"),
+		Filename:    "test.jpg",
+		ContentType: "text/plain",
+	})
+	assert.NoError(t, err)
+	url := uploaded.Url
+	info, err := repo.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "text/plain", info.ContentType)
+	output, err := repo.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: url})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "text/plain", output.ContentType)
+}
+
+func TestMemoryRepo_Metadata(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	uploaded, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:     strings.NewReader("test"),
+		Filename: "test.jpg",
+		SHA256:   "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08",
+	})
+	assert.NoError(t, err)
+	url := uploaded.Url
+	copyURL, err := repo.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: url, Directory: "copy", Filename: "test.jpg"})
+	assert.NoError(t, err)
+
+	for _, url := range []string{url, copyURL} {
+		info, err := repo.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]string{
+			dto.SHA256MetadataKey: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		}, info.Metadata)
+	}
+}
+
+func TestMemoryRepo_Stat(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *dto.StatInput
+		want    *dto.FileInfo
+		wantErr error
+	}{
+		{
+			name:  "succeed",
+			input: &dto.StatInput{Url: testBaseURL + "/test/test.jpg"},
+			want: &dto.FileInfo{
+				Key:         "test/test.jpg",
+				Url:         testBaseURL + "/test/test.jpg",
+				Size:        4,
+				ContentType: "image/jpeg",
+				ETag:        `"098f6bcd4621d373cade4e832627b4f6"`,
+			},
+		},
+		{
+			name:    "missing file",
+			input:   &dto.StatInput{Url: testBaseURL + "/test/missing.jpg"},
+			wantErr: customErrors.NotFound,
+		},
+		{
+			name:    "invalid url",
+			input:   &dto.StatInput{Url: "http://localhost/other/test/test.jpg"},
+			wantErr: customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.NewMemory(testBaseURL)
+			_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:      strings.NewReader("test"),
+				Directory: "test",
+				Filename:  "test.jpg",
+			})
+			assert.NoError(t, err)
+
+			got, err := repo.Stat(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.False(t, got.LastModified.IsZero())
+			got.LastModified = time.Time{}
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestMemoryRepo_List(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         *dto.ListInput
+		wantKeys      []string
+		wantPrefixes  []string
+		wantPageToken string
+	}{
+		{
+			name:     "all files",
+			input:    &dto.ListInput{},
+			wantKeys: []string{"other.jpg", "test/a.jpg", "test/b.jpg", "test/sub/c.jpg"},
+		},
+		{
+			name:         "root directory",
+			input:        &dto.ListInput{Delimiter: "/"},
+			wantKeys:     []string{"other.jpg"},
+			wantPrefixes: []string{"test/"},
+		},
+		{
+			name:         "directory",
+			input:        &dto.ListInput{Prefix: "test/", Delimiter: "/"},
+			wantKeys:     []string{"test/a.jpg", "test/b.jpg"},
+			wantPrefixes: []string{"test/sub/"},
+		},
+		{
+			name:          "first page",
+			input:         &dto.ListInput{Prefix: "test/", Delimiter: "/", PageSize: 2},
+			wantKeys:      []string{"test/a.jpg", "test/b.jpg"},
+			wantPageToken: "test/b.jpg",
+		},
+		{
+			name:         "last page",
+			input:        &dto.ListInput{Prefix: "test/", Delimiter: "/", PageSize: 2, PageToken: "test/b.jpg"},
+			wantPrefixes: []string{"test/sub/"},
+		},
+		{
+			name:     "file name prefix",
+			input:    &dto.ListInput{Prefix: "test/a"},
+			wantKeys: []string{"test/a.jpg"},
+		},
+		{
+			name:  "missing directory",
+			input: &dto.ListInput{Prefix: "missing/", Delimiter: "/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.NewMemory(testBaseURL)
+			for _, file := range []string{"other.jpg", "test/a.jpg", "test/b.jpg", "test/sub/c.jpg"} {
+				_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+					File:     strings.NewReader("test"),
+					Filename: file,
+				})
+				assert.NoError(t, err)
+			}
+
+			got, err := repo.List(helpers.DefaultCtx, tt.input)
+			assert.NoError(t, err)
+			var gotKeys []string
+			for _, file := range got.Files {
+				gotKeys = append(gotKeys, file.Key)
+				assert.EqualValues(t, testBaseURL+"/"+file.Key, file.Url)
+				assert.EqualValues(t, 4, file.Size)
+			}
+			assert.EqualValues(t, tt.wantKeys, gotKeys)
+			assert.EqualValues(t, tt.wantPrefixes, got.Prefixes)
+			assert.EqualValues(t, tt.wantPageToken, got.NextPageToken)
+		})
+	}
+}
+
+func TestMemoryRepo_Delete(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    dto.DeleteInput
+		wantKeys []string
+		wantErr  error
+	}{
+		{
+			name:     "succeed",
+			input:    testBaseURL + "/test/test.jpg",
+			wantKeys: []string{"test/test2.jpg"},
+		},
+		{
+			name:     "missing file",
+			input:    testBaseURL + "/test/test3.jpg",
+			wantKeys: []string{"test/test.jpg", "test/test2.jpg"},
+		},
+		{
+			name:     "invalid url",
+			input:    "http://localhost/other/test/test.jpg",
+			wantKeys: []string{"test/test.jpg", "test/test2.jpg"},
+			wantErr:  customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.NewMemory(testBaseURL)
+			for _, filename := range []string{"test.jpg", "test2.jpg"} {
+				_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: "test",
+					Filename:  filename,
+				})
+				assert.NoError(t, err)
+			}
+
+			err := repo.Delete(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			var gotKeys []string
+			for _, obj := range repo.Objects() {
+				gotKeys = append(gotKeys, obj.Key)
+			}
+			assert.EqualValues(t, tt.wantKeys, gotKeys)
+		})
+	}
+}
+
+func TestMemoryRepo_BatchDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    dto.BatchDeleteInput
+		wantKeys []string
+		wantErr  error
+	}{
+		{
+			name:     "succeed",
+			input:    dto.BatchDeleteInput{testBaseURL + "/test/test.jpg", testBaseURL + "/test/test2.jpg"},
+			wantKeys: []string{"test/test3.jpg"},
+		},
+		{
+			name:     "invalid url",
+			input:    dto.BatchDeleteInput{testBaseURL + "/test/test.jpg", "http://localhost/other/test2.jpg"},
+			wantKeys: []string{"test/test.jpg", "test/test2.jpg", "test/test3.jpg"},
+			wantErr:  customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fileRepo.NewMemory(testBaseURL)
+			for _, filename := range []string{"test.jpg", "test2.jpg", "test3.jpg"} {
+				_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+					File:      strings.NewReader("test"),
+					Directory: "test",
+					Filename:  filename,
+				})
+				assert.NoError(t, err)
+			}
+
+			err := repo.BatchDelete(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			var gotKeys []string
+			for _, obj := range repo.Objects() {
+				gotKeys = append(gotKeys, obj.Key)
+			}
+			assert.EqualValues(t, tt.wantKeys, gotKeys)
+		})
+	}
+}
+
+func TestMemoryRepo_Concurrency(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uploaded, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:     strings.NewReader("test"),
+				Filename: "test.jpg",
+			})
+			assert.NoError(t, err)
+			url := uploaded.Url
+			assert.NoError(t, repo.Delete(helpers.DefaultCtx, dto.DeleteInput(url)))
+			repo.Objects()
+		}()
+	}
+	wg.Wait()
+	assert.Empty(t, repo.Objects())
+}
+
+func TestMemoryRepo_Presign(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	_, err := repo.PresignDownload(helpers.DefaultCtx, &dto.PresignDownloadInput{Url: testBaseURL + "/test/test.jpg"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.PresignUpload(helpers.DefaultCtx, &dto.PresignUploadInput{Filename: "test.jpg"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+}
+
+func TestMemoryRepo_UploadSessions(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	session := &dto.UploadSessionInput{UploadId: dto.NewUploadId("test/test.jpg", "test")}
+	_, err := repo.InitiateUpload(helpers.DefaultCtx, &dto.InitiateUploadInput{Filename: "test.jpg"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.UploadPart(helpers.DefaultCtx, &dto.UploadPartInput{UploadId: session.UploadId, PartNumber: 1})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.GetUpload(helpers.DefaultCtx, session)
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.CompleteUpload(helpers.DefaultCtx, &dto.UploadSession{UploadId: session.UploadId})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	assert.EqualValues(t, customErrors.NotSupported, repo.AbortUpload(helpers.DefaultCtx, session))
+}
+
+func TestMemoryRepo_URL(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	got, err := repo.URL("test/my file.jpg")
+	assert.NoError(t, err)
+	assert.EqualValues(t, testBaseURL+"/test/my%20file.jpg", got)
+	_, err = repo.URL("/")
+	assert.EqualValues(t, customErrors.InvalidKey, err)
+}
+
+func TestMemoryRepo_Versions(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	url := testBaseURL + "/test/test.jpg"
+	_, err := repo.ListVersions(helpers.DefaultCtx, &dto.ListVersionsInput{Url: url})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.GetVersion(helpers.DefaultCtx, &dto.GetVersionInput{DownloadInput: dto.DownloadInput{Url: url}, VersionId: "v1"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = repo.RestoreVersion(helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	key, err := repo.Key(url)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test/test.jpg", key)
+}
+
+func TestMemoryRepo_Upload_Exclusive(t *testing.T) {
+	repo := fileRepo.NewMemory(testBaseURL)
+	upload := func(content string, exclusive bool) error {
+		_, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      strings.NewReader(content),
+			Filename:  "test.txt",
+			Exclusive: exclusive,
+		})
+		return err
+	}
+	assert.NoError(t, upload("first", true))
+	assert.EqualValues(t, customErrors.AlreadyExists, upload("second", true))
+	assert.NoError(t, upload("third", false))
+
+	output, err := repo.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: testBaseURL + "/test.txt"})
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(output.File)
+	assert.NoError(t, err)
+	assert.NoError(t, output.File.Close())
+	assert.EqualValues(t, "third", string(content))
+}
@@ -0,0 +1,118 @@
+package fileRepo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+// Upload sessions are S3 multipart uploads, so their state is kept by S3
+
+func (r *repo) InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error) {
+	resp, err := r.client.CreateMultipartUploadWithContext(ctx, input.ToS3Input(r.bucketName))
+	if err != nil {
+		return nil, err
+	}
+	key := input.Key()
+	url, err := r.url(key)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.UploadSession{
+		UploadId: dto.NewUploadId(key, aws.StringValue(resp.UploadId)),
+		Key:      key,
+		Url:      url,
+	}, nil
+}
+
+func (r *repo) UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error) {
+	// S3 requires length of the part in advance, so the part is buffered
+	body, err := ioutil.ReadAll(io.LimitReader(input.File, dto.MaxPartSize+1))
+	if err != nil {
+		return nil, err
+	} else if len(body) > dto.MaxPartSize {
+		return nil, customErrors.PartTooLarge
+	}
+	s3Input, err := input.ToS3Input(r.bucketName, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.UploadPartWithContext(ctx, s3Input)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return &dto.UploadPart{
+		PartNumber: input.PartNumber,
+		Size:       int64(len(body)),
+		ETag:       aws.StringValue(resp.ETag),
+	}, nil
+}
+
+func (r *repo) GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error) {
+	key, multipartId, err := dto.ParseUploadId(input.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	url, err := r.url(key)
+	if err != nil {
+		return nil, err
+	}
+	session := &dto.UploadSession{
+		UploadId: input.UploadId,
+		Key:      key,
+		Url:      url,
+		Parts:    make([]*dto.UploadPart, 0),
+	}
+	s3Input := &s3.ListPartsInput{
+		Bucket:   aws.String(r.bucketName),
+		Key:      aws.String(key),
+		UploadId: aws.String(multipartId),
+	}
+	for {
+		resp, err := r.client.ListPartsWithContext(ctx, s3Input)
+		if err != nil {
+			return nil, convertError(err)
+		}
+		for _, part := range resp.Parts {
+			session.Parts = append(session.Parts, &dto.UploadPart{
+				PartNumber: aws.Int64Value(part.PartNumber),
+				Size:       aws.Int64Value(part.Size),
+				ETag:       aws.StringValue(part.ETag),
+			})
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			return session, nil
+		}
+		s3Input.PartNumberMarker = resp.NextPartNumberMarker
+	}
+}
+
+func (r *repo) CompleteUpload(ctx context.Context, session *dto.UploadSession) (string, error) {
+	s3Input, err := session.ToS3Input(r.bucketName)
+	if err != nil {
+		return "", err
+	} else if _, err := r.client.CompleteMultipartUploadWithContext(ctx, s3Input); err != nil {
+		return "", convertError(err)
+	}
+	return r.url(aws.StringValue(s3Input.Key))
+}
+
+func (r *repo) AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error {
+	key, multipartId, err := dto.ParseUploadId(input.UploadId)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(r.bucketName),
+		Key:      aws.String(key),
+		UploadId: aws.String(multipartId),
+	})
+	return convertError(err)
+}
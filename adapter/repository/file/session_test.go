@@ -0,0 +1,349 @@
+package fileRepo_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+var testUploadId = dto.NewUploadId("test/test.jpg", "multipart-id")
+
+func TestRepo_InitiateUpload(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  fields
+		input   *dto.InitiateUploadInput
+		mocks   map[string]mocks.Calls
+		want    *dto.UploadSession
+		wantErr error
+	}{
+		{
+			name:   "succeed",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.InitiateUploadInput{Directory: "test", Filename: "test.jpg", ACL: "private", ContentType: "image/jpeg"},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "CreateMultipartUploadWithContext",
+						Args: []interface{}{helpers.DefaultCtx, &s3.CreateMultipartUploadInput{
+							Bucket:      aws.String("test.bucket"),
+							Key:         aws.String("test/test.jpg"),
+							ACL:         aws.String("private"),
+							ContentType: aws.String("image/jpeg"),
+						}},
+						ReturnArgs: []interface{}{&s3.CreateMultipartUploadOutput{UploadId: aws.String("multipart-id")}, nil},
+					},
+				},
+			},
+			want: &dto.UploadSession{
+				UploadId: testUploadId,
+				Key:      "test/test.jpg",
+				Url:      "https://aws.s3/test.bucket/test/test.jpg",
+			},
+		},
+		{
+			name:   "error returned",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.InitiateUploadInput{Directory: "test", Filename: "test.jpg"},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "CreateMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil, errors.New("test error")},
+					},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			got, err := testRepo(&tt.fields).InitiateUpload(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_UploadPart(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  fields
+		input   *dto.UploadPartInput
+		mocks   map[string]mocks.Calls
+		want    *dto.UploadPart
+		wantErr error
+	}{
+		{
+			name:   "succeed",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.UploadPartInput{UploadId: testUploadId, PartNumber: 2, File: strings.NewReader("test")},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "UploadPartWithContext",
+						Args: []interface{}{helpers.DefaultCtx, mock.MatchedBy(func(input *s3.UploadPartInput) bool {
+							body, _ := ioutil.ReadAll(input.Body)
+							return aws.StringValue(input.Key) == "test/test.jpg" &&
+								aws.StringValue(input.UploadId) == "multipart-id" &&
+								aws.Int64Value(input.PartNumber) == 2 &&
+								string(body) == "test"
+						})},
+						ReturnArgs: []interface{}{&s3.UploadPartOutput{ETag: aws.String(`"etag"`)}, nil},
+					},
+				},
+			},
+			want: &dto.UploadPart{PartNumber: 2, Size: 4, ETag: `"etag"`},
+		},
+		{
+			name:    "too large part",
+			fields:  fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:   &dto.UploadPartInput{UploadId: testUploadId, PartNumber: 1, File: bytes.NewReader(make([]byte, dto.MaxPartSize+1))},
+			wantErr: customErrors.PartTooLarge,
+		},
+		{
+			name:   "missing upload",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.UploadPartInput{UploadId: testUploadId, PartNumber: 1, File: strings.NewReader("test")},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "UploadPartWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+						ReturnArgs: []interface{}{nil, awserr.New(s3.ErrCodeNoSuchUpload, "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.UploadNotFound,
+		},
+		{
+			name:    "invalid upload id",
+			fields:  fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:   &dto.UploadPartInput{UploadId: "invalid", PartNumber: 1, File: strings.NewReader("test")},
+			wantErr: customErrors.InvalidUploadId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			got, err := testRepo(&tt.fields).UploadPart(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_GetUpload(t *testing.T) {
+	listInput := func(marker *int64) *s3.ListPartsInput {
+		return &s3.ListPartsInput{
+			Bucket:           aws.String("test.bucket"),
+			Key:              aws.String("test/test.jpg"),
+			UploadId:         aws.String("multipart-id"),
+			PartNumberMarker: marker,
+		}
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		input   *dto.UploadSessionInput
+		mocks   map[string]mocks.Calls
+		want    *dto.UploadSession
+		wantErr error
+	}{
+		{
+			name:   "succeed",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.UploadSessionInput{UploadId: testUploadId},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method: "ListPartsWithContext",
+						Args:   []interface{}{helpers.DefaultCtx, listInput(nil)},
+						ReturnArgs: []interface{}{&s3.ListPartsOutput{
+							Parts: []*s3.Part{
+								{PartNumber: aws.Int64(1), Size: aws.Int64(dto.MinPartSize), ETag: aws.String(`"1"`)},
+							},
+							IsTruncated:          aws.Bool(true),
+							NextPartNumberMarker: aws.Int64(1),
+						}, nil},
+					},
+					{
+						Method: "ListPartsWithContext",
+						Args:   []interface{}{helpers.DefaultCtx, listInput(aws.Int64(1))},
+						ReturnArgs: []interface{}{&s3.ListPartsOutput{
+							Parts: []*s3.Part{
+								{PartNumber: aws.Int64(2), Size: aws.Int64(4), ETag: aws.String(`"2"`)},
+							},
+						}, nil},
+					},
+				},
+			},
+			want: &dto.UploadSession{
+				UploadId: testUploadId,
+				Key:      "test/test.jpg",
+				Url:      "https://aws.s3/test.bucket/test/test.jpg",
+				Parts: []*dto.UploadPart{
+					{PartNumber: 1, Size: dto.MinPartSize, ETag: `"1"`},
+					{PartNumber: 2, Size: 4, ETag: `"2"`},
+				},
+			},
+		},
+		{
+			name:   "missing upload",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.UploadSessionInput{UploadId: testUploadId},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "ListPartsWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, listInput(nil)},
+						ReturnArgs: []interface{}{nil, awserr.New(s3.ErrCodeNoSuchUpload, "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.UploadNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			got, err := testRepo(&tt.fields).GetUpload(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_CompleteUpload(t *testing.T) {
+	session := &dto.UploadSession{
+		UploadId: testUploadId,
+		Parts:    []*dto.UploadPart{{PartNumber: 1, Size: 4, ETag: `"1"`}},
+	}
+	completeInput := &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String("test.bucket"),
+		Key:      aws.String("test/test.jpg"),
+		UploadId: aws.String("multipart-id"),
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: []*s3.CompletedPart{{ETag: aws.String(`"1"`), PartNumber: aws.Int64(1)}},
+		},
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		session *dto.UploadSession
+		mocks   map[string]mocks.Calls
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "succeed",
+			fields:  fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			session: session,
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "CompleteMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, completeInput},
+						ReturnArgs: []interface{}{&s3.CompleteMultipartUploadOutput{}, nil},
+					},
+				},
+			},
+			want: "https://aws.s3/test.bucket/test/test.jpg",
+		},
+		{
+			name:    "too small part",
+			fields:  fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			session: session,
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "CompleteMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, completeInput},
+						ReturnArgs: []interface{}{nil, awserr.New("EntityTooSmall", "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.PartTooSmall,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			got, err := testRepo(&tt.fields).CompleteUpload(helpers.DefaultCtx, tt.session)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepo_AbortUpload(t *testing.T) {
+	abortInput := &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("test.bucket"),
+		Key:      aws.String("test/test.jpg"),
+		UploadId: aws.String("multipart-id"),
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		input   *dto.UploadSessionInput
+		mocks   map[string]mocks.Calls
+		wantErr error
+	}{
+		{
+			name:   "succeed",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.UploadSessionInput{UploadId: testUploadId},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "AbortMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, abortInput},
+						ReturnArgs: []interface{}{&s3.AbortMultipartUploadOutput{}, nil},
+					},
+				},
+			},
+		},
+		{
+			name:   "missing upload",
+			fields: fields{Client: new(mocks.S3Client), bucketName: "test.bucket"},
+			input:  &dto.UploadSessionInput{UploadId: testUploadId},
+			mocks: map[string]mocks.Calls{
+				"Client": {
+					{
+						Method:     "AbortMultipartUploadWithContext",
+						Args:       []interface{}{helpers.DefaultCtx, abortInput},
+						ReturnArgs: []interface{}{nil, awserr.New(s3.ErrCodeNoSuchUpload, "test", nil)},
+					},
+				},
+			},
+			wantErr: customErrors.UploadNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertMocks := setupMocks(t, &tt.fields, tt.mocks)
+			defer assertMocks()
+			err := testRepo(&tt.fields).AbortUpload(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package outboxRepo
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	boltRepo "github.com/freemen-app/file_storage/adapter/repository/bolt"
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+var (
+	// eventsBucket maps key of the event to its FileEvent, keys start with timestamps,
+	// so pending events are iterated in order they were saved
+	eventsBucket = []byte("outbox_events")
+	// preparedBucket keeps events of changes which aren't confirmed yet by the same keys
+	preparedBucket = []byte("outbox_prepared")
+)
+
+type (
+	repo struct {
+		db *bbolt.DB
+	}
+)
+
+func New(db *bbolt.DB) *repo {
+	return &repo{db: db}
+}
+
+// Prepare records events of the change which is about to be made, they aren't relayed until they are confirmed
+func (r *repo) Prepare(ctx context.Context, events []*dto.FileEvent) error {
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		prepared, err := tx.CreateBucketIfNotExists(preparedBucket)
+		if err != nil {
+			return err
+		}
+		return putEvents(prepared, events)
+	})
+}
+
+// Confirm records events as pending replacing their prepared records,
+// events are taken as they are, so they may be confirmed without being prepared
+func (r *repo) Confirm(ctx context.Context, events []*dto.FileEvent) error {
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		pending, err := tx.CreateBucketIfNotExists(eventsBucket)
+		if err != nil {
+			return err
+		} else if err := putEvents(pending, events); err != nil {
+			return err
+		}
+		return deleteEvents(tx.Bucket(preparedBucket), events)
+	})
+}
+
+// Discard removes prepared events of the change which wasn't made
+func (r *repo) Discard(ctx context.Context, events []*dto.FileEvent) error {
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		return deleteEvents(tx.Bucket(preparedBucket), events)
+	})
+}
+
+// Unconfirmed returns up to limit the oldest events prepared before the time
+func (r *repo) Unconfirmed(ctx context.Context, before time.Time, limit int) ([]*dto.FileEvent, error) {
+	return r.list(ctx, preparedBucket, limit, func(key []byte) bool {
+		return int64(binary.BigEndian.Uint64(key)) < before.UnixNano()
+	})
+}
+
+// Pending returns up to limit the oldest events which weren't delivered
+func (r *repo) Pending(ctx context.Context, limit int) ([]*dto.FileEvent, error) {
+	return r.list(ctx, eventsBucket, limit, func([]byte) bool {
+		return true
+	})
+}
+
+// MarkDelivered removes the event, so the outbox keeps only pending events
+func (r *repo) MarkDelivered(ctx context.Context, event *dto.FileEvent) error {
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		return deleteEvents(tx.Bucket(eventsBucket), []*dto.FileEvent{event})
+	})
+}
+
+// list returns up to limit events of the bucket in order of their keys while keys match
+func (r *repo) list(ctx context.Context, name []byte, limit int, match func(key []byte) bool) ([]*dto.FileEvent, error) {
+	events := make([]*dto.FileEvent, 0)
+	err := boltRepo.View(ctx, r.db, func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(name)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for key, data := cursor.First(); key != nil && len(events) < limit && match(key); key, data = cursor.Next() {
+			event := new(dto.FileEvent)
+			if err := json.Unmarshal(data, event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func putEvents(bucket *bbolt.Bucket, events []*dto.FileEvent) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		} else if err := bucket.Put(eventKey(event), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteEvents(bucket *bbolt.Bucket, events []*dto.FileEvent) error {
+	if bucket == nil {
+		return nil
+	}
+	for _, event := range events {
+		if err := bucket.Delete(eventKey(event)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventKey is the timestamp of the event in nanoseconds followed by its id
+func eventKey(event *dto.FileEvent) []byte {
+	key := make([]byte, 8, 8+len(event.Id))
+	binary.BigEndian.PutUint64(key, uint64(event.Timestamp.UnixNano()))
+	return append(key, event.Id...)
+}
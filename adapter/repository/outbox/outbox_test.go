@@ -0,0 +1,104 @@
+package outboxRepo_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	outboxRepo "github.com/freemen-app/file_storage/adapter/repository/outbox"
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+)
+
+func testDB(t *testing.T) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db
+}
+
+func testEvent(id string, timestamp time.Time) *dto.FileEvent {
+	return &dto.FileEvent{
+		Id:        id,
+		Type:      dto.EventFileUploaded,
+		Key:       "test.jpg",
+		Url:       "http://localhost/files/test.jpg",
+		Size:      4,
+		Timestamp: timestamp,
+	}
+}
+
+func TestRepo_Pending(t *testing.T) {
+	repo := outboxRepo.New(testDB(t))
+	pending, err := repo.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first, second, third := testEvent("b", now), testEvent("a", now.Add(time.Second)), testEvent("c", now)
+	assert.NoError(t, repo.Confirm(helpers.DefaultCtx, []*dto.FileEvent{second, first, third}))
+
+	// Events are ordered by timestamps and then by ids
+	pending, err = repo.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*dto.FileEvent{first, third, second}, pending)
+	pending, err = repo.Pending(helpers.DefaultCtx, 2)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*dto.FileEvent{first, third}, pending)
+}
+
+func TestRepo_MarkDelivered(t *testing.T) {
+	repo := outboxRepo.New(testDB(t))
+	// Delivering to empty outbox is a no-op
+	assert.NoError(t, repo.MarkDelivered(helpers.DefaultCtx, testEvent("a", time.Now())))
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first, second := testEvent("a", now), testEvent("b", now.Add(time.Second))
+	assert.NoError(t, repo.Confirm(helpers.DefaultCtx, []*dto.FileEvent{first, second}))
+
+	pending, err := repo.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.MarkDelivered(helpers.DefaultCtx, pending[0]))
+	pending, err = repo.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*dto.FileEvent{second}, pending)
+	assert.NoError(t, repo.MarkDelivered(helpers.DefaultCtx, second))
+	pending, err = repo.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestRepo_Prepare(t *testing.T) {
+	repo := outboxRepo.New(testDB(t))
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first, second, third := testEvent("a", now), testEvent("b", now.Add(time.Second)), testEvent("c", now.Add(time.Minute))
+	assert.NoError(t, repo.Prepare(helpers.DefaultCtx, []*dto.FileEvent{first, second, third}))
+
+	// Prepared events aren't relayed until they are confirmed
+	pending, err := repo.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	unconfirmed, err := repo.Unconfirmed(helpers.DefaultCtx, now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*dto.FileEvent{first, second}, unconfirmed)
+	unconfirmed, err = repo.Unconfirmed(helpers.DefaultCtx, now.Add(time.Hour), 1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*dto.FileEvent{first}, unconfirmed)
+
+	// Confirmed event replaces the prepared one
+	first.Size = 8
+	assert.NoError(t, repo.Confirm(helpers.DefaultCtx, []*dto.FileEvent{first}))
+	assert.NoError(t, repo.Discard(helpers.DefaultCtx, []*dto.FileEvent{second}))
+	pending, err = repo.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*dto.FileEvent{first}, pending)
+	unconfirmed, err = repo.Unconfirmed(helpers.DefaultCtx, now.Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*dto.FileEvent{third}, unconfirmed)
+}
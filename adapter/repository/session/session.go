@@ -0,0 +1,70 @@
+package sessionRepo
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	boltRepo "github.com/freemen-app/file_storage/adapter/repository/bolt"
+)
+
+// sessionsBucket maps upload id of the session initiated by the service to its initiation time,
+// so the sweeper aborts only sessions of the service, not multipart uploads of other clients of the bucket
+var sessionsBucket = []byte("upload_sessions")
+
+type (
+	repo struct {
+		db *bbolt.DB
+	}
+)
+
+func New(db *bbolt.DB) *repo {
+	return &repo{db: db}
+}
+
+// Save records the session initiated at the time
+func (r *repo) Save(ctx context.Context, uploadId string, initiated time.Time) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(initiated.UnixNano()))
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		sessions, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		if err != nil {
+			return err
+		}
+		return sessions.Put([]byte(uploadId), value)
+	})
+}
+
+// Delete forgets completed or aborted session, unknown sessions are skipped
+func (r *repo) Delete(ctx context.Context, uploadId string) error {
+	return boltRepo.Update(ctx, r.db, func(tx *bbolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		if sessions == nil {
+			return nil
+		}
+		return sessions.Delete([]byte(uploadId))
+	})
+}
+
+// Stale returns upload ids of sessions initiated before the time
+func (r *repo) Stale(ctx context.Context, before time.Time) ([]string, error) {
+	stale := make([]string, 0)
+	err := boltRepo.View(ctx, r.db, func(tx *bbolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		if sessions == nil {
+			return nil
+		}
+		return sessions.ForEach(func(uploadId, value []byte) error {
+			if initiated := int64(binary.BigEndian.Uint64(value)); initiated < before.UnixNano() {
+				stale = append(stale, string(uploadId))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stale, nil
+}
@@ -0,0 +1,54 @@
+package sessionRepo_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	sessionRepo "github.com/freemen-app/file_storage/adapter/repository/session"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+)
+
+func testDB(t *testing.T) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, db.Close())
+	})
+	return db
+}
+
+func TestRepo_Stale(t *testing.T) {
+	repo := sessionRepo.New(testDB(t))
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	stale, err := repo.Stale(helpers.DefaultCtx, now)
+	assert.NoError(t, err)
+	assert.Empty(t, stale)
+
+	assert.NoError(t, repo.Save(helpers.DefaultCtx, "old", now.Add(-time.Hour)))
+	assert.NoError(t, repo.Save(helpers.DefaultCtx, "new", now))
+	stale, err = repo.Stale(helpers.DefaultCtx, now)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"old"}, stale)
+	stale, err = repo.Stale(helpers.DefaultCtx, now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"old", "new"}, stale)
+}
+
+func TestRepo_Delete(t *testing.T) {
+	repo := sessionRepo.New(testDB(t))
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Deleting from empty repo is a no-op
+	assert.NoError(t, repo.Delete(helpers.DefaultCtx, "old"))
+
+	assert.NoError(t, repo.Save(helpers.DefaultCtx, "old", now.Add(-time.Hour)))
+	assert.NoError(t, repo.Delete(helpers.DefaultCtx, "old"))
+	assert.NoError(t, repo.Delete(helpers.DefaultCtx, "unknown"))
+	stale, err := repo.Stale(helpers.DefaultCtx, now)
+	assert.NoError(t, err)
+	assert.Empty(t, stale)
+}
@@ -0,0 +1,8 @@
+module github.com/freemen-app/api
+
+go 1.14
+
+require (
+	github.com/golang/protobuf v1.4.3
+	google.golang.org/protobuf v1.25.0
+)
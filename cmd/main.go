@@ -8,6 +8,10 @@ import (
 	"github.com/freemen-app/file_storage/infrastructure/app"
 	"github.com/freemen-app/file_storage/infrastructure/events"
 	grpcApi "github.com/freemen-app/file_storage/infrastructure/grpc"
+	"github.com/freemen-app/file_storage/infrastructure/purger"
+	"github.com/freemen-app/file_storage/infrastructure/reconciler"
+	"github.com/freemen-app/file_storage/infrastructure/relay"
+	"github.com/freemen-app/file_storage/infrastructure/sweeper"
 )
 
 func main() {
@@ -18,17 +22,37 @@ func main() {
 	}
 
 	api := grpcApi.New(application, &conf.Api)
-	amqp := events.New(application, &conf.AMQP)
+	amqp := events.New(application, &conf.AMQP, &conf.Retry)
 	if err := amqp.Start(); err != nil {
 		panic(err)
 	}
+	uploadSweeper := sweeper.New(application, &conf.Upload.Sessions)
+	if err := uploadSweeper.Start(); err != nil {
+		panic(err)
+	}
+	orphanReconciler := reconciler.New(application, &conf.Reconcile)
+	if err := orphanReconciler.Start(); err != nil {
+		panic(err)
+	}
+	trashPurger := purger.New(application, &conf.Trash)
+	if err := trashPurger.Start(); err != nil {
+		panic(err)
+	}
+	eventsRelay := relay.New(application, &conf.Events)
+	if err := eventsRelay.Start(); err != nil {
+		panic(err)
+	}
 	go api.Start()
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// api timeout of 10 seconds.
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 
 	api.Shutdown()
+	uploadSweeper.Shutdown()
+	orphanReconciler.Shutdown()
+	trashPurger.Shutdown()
+	eventsRelay.Shutdown()
 	application.Shutdown()
 }
@@ -1,27 +1,147 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"runtime"
+	"strings"
+	"time"
 
 	amqpStore "github.com/freemen-app/amqp-store"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 	"github.com/sherifabdlnaby/configuro"
+
+	"github.com/freemen-app/file_storage/domain/dto"
 )
 
 type (
 	Config struct {
-		Api    ApiConfig
-		Logger loggerConfig
-		AMQP   amqpStore.Config
-		S3     S3Config
+		Api       ApiConfig
+		Logger    loggerConfig
+		AMQP      amqpStore.Config
+		Retry     RetryConfig
+		S3        S3Config
+		Storage   StorageConfig
+		Upload    UploadConfig
+		Trash     TrashConfig
+		Catalog   CatalogConfig
+		Reconcile ReconcileConfig
+		Events    EventsConfig
+		Database  DatabaseConfig
 	}
 
 	S3Config struct {
 		Bucket string
 		Region string
+		// Endpoint allows to use S3 compatible storages such as MinIO or LocalStack
+		Endpoint        string
+		ForcePathStyle  bool   `config:"force_path_style"`
+		DisableSSL      bool   `config:"disable_ssl"`
+		AccessKeyID     string `config:"access_key_id"`
+		SecretAccessKey string `config:"secret_access_key"`
+		// MaxPresignExpiry limits lifetime of presigned urls
+		MaxPresignExpiry time.Duration `config:"max_presign_expiry"`
+	}
+
+	StorageConfig struct {
+		Driver string
+		Local  LocalStorageConfig
+		Memory MemoryStorageConfig
+	}
+
+	LocalStorageConfig struct {
+		Root    string
+		BaseURL string `config:"base_url"`
+	}
+
+	MemoryStorageConfig struct {
+		BaseURL string `config:"base_url"`
+	}
+
+	UploadConfig struct {
+		Policies []UploadPolicyConfig
+		Sessions UploadSessionsConfig
+		Dedup    UploadDedupConfig
+	}
+
+	// UploadDedupConfig enables storing files with the same content once as blobs inside Prefix directory,
+	// references of files to blobs are kept in the embedded database, so only a single instance
+	// may deduplicate files of the bucket, the instance claims Prefix at start.
+	// Deduplicated files are private, policies must not apply other ACLs by default
+	UploadDedupConfig struct {
+		Enabled bool
+		Prefix  string
+	}
+
+	// TrashConfig enables moving deleted files to Prefix directory, they are kept there for Retention
+	// and then permanently deleted by the job running every PurgeInterval
+	TrashConfig struct {
+		Enabled       bool
+		Prefix        string
+		Retention     time.Duration
+		PurgeInterval time.Duration `config:"purge_interval"`
+	}
+
+	// CatalogConfig enables recording metadata of stored files to the embedded database
+	CatalogConfig struct {
+		Enabled bool
+	}
+
+	// ReconcileConfig configures job deleting files under Prefix which aren't recorded to the catalog,
+	// orphans are only logged in DryRun mode. Prefix is required to delete orphans, so files stored
+	// before the catalog was enabled aren't deleted across the whole bucket
+	ReconcileConfig struct {
+		Enabled     bool
+		Interval    time.Duration
+		GracePeriod time.Duration `config:"grace_period"`
+		DryRun      bool          `config:"dry_run"`
+		Prefix      string
+	}
+
+	// EventsConfig enables publishing events of changed files by AMQP publish config FileEventsPublish,
+	// events are recorded to the outbox in the embedded database and relayed every RelayInterval
+	EventsConfig struct {
+		Enabled       bool
+		RelayInterval time.Duration `config:"relay_interval"`
+	}
+
+	// RetryConfig bounds redelivery of consumed messages failed by temporary errors. Message is delayed
+	// by Backoff doubled by every failed attempt up to MaxBackoff, after MaxAttempts it's sent to DeadLetterExchange
+	RetryConfig struct {
+		MaxAttempts        int `config:"max_attempts"`
+		Backoff            time.Duration
+		MaxBackoff         time.Duration `config:"max_backoff"`
+		DeadLetterExchange string        `config:"dead_letter_exchange"`
+	}
+
+	// DatabaseConfig configures embedded database, which is opened only by features requiring it
+	DatabaseConfig struct {
+		Path string
+	}
+
+	// UploadSessionsConfig enables tracking upload sessions initiated by the service in the embedded database,
+	// so the sweeper aborts ones which weren't completed in TTL
+	UploadSessionsConfig struct {
+		Enabled       bool
+		TTL           time.Duration `config:"ttl"`
+		SweepInterval time.Duration `config:"sweep_interval"`
+	}
+
+	// UploadPolicyConfig restricts files uploaded to directories starting with Prefix,
+	// ContentTypes may contain wildcards like "image/*", zero MaxSize means no limit.
+	// ACL is applied when client doesn't set it, ACLs restrict ACLs allowed to be set by client.
+	// Collision is a strategy applied when the key of uploaded file is taken and client doesn't set it
+	UploadPolicyConfig struct {
+		Prefix       string
+		MaxSize      int64    `config:"max_size"`
+		ContentTypes []string `config:"content_types"`
+		Extensions   []string
+		ACL          string
+		ACLs         []string
+		Collision    string
 	}
 
 	ApiConfig struct {
@@ -39,6 +159,17 @@ func (c ApiConfig) Addr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// DatabaseRequired reports whether an enabled feature keeps its data in the embedded database,
+// the service is stateless without them
+func (c *Config) DatabaseRequired() bool {
+	return c.Upload.Sessions.Enabled || c.Upload.Dedup.Enabled || c.Catalog.Enabled || c.Events.Enabled
+}
+
+// FileEventsPublishConfig returns AMQP publish config of file events or nil
+func (c *Config) FileEventsPublishConfig() *amqpStore.PublishConfig {
+	return c.AMQP.Publishes[FileEventsPublish]
+}
+
 func New(filename string) *Config {
 	_, dir, _, ok := runtime.Caller(0)
 	if !ok {
@@ -66,6 +197,171 @@ func (c *Config) Validate() error {
 		c,
 		validation.Field(&c.Api),
 		validation.Field(&c.S3),
+		validation.Field(&c.Storage),
+		validation.Field(&c.Upload),
+		validation.Field(&c.Trash, validation.When(
+			c.Trash.Enabled && c.Upload.Dedup.Enabled && nested(c.Trash.Prefix, c.Upload.Dedup.Prefix),
+			validation.By(func(interface{}) error { return errors.New("prefix must not overlap deduplication prefix") }),
+		)),
+		validation.Field(&c.Reconcile, validation.When(
+			c.Reconcile.Enabled && !c.Catalog.Enabled,
+			validation.By(func(interface{}) error { return errors.New("requires catalog") }),
+		)),
+		validation.Field(&c.Events, validation.When(
+			c.Events.Enabled && c.FileEventsPublishConfig() == nil,
+			validation.By(func(interface{}) error { return errors.New("requires amqp publish config " + FileEventsPublish) }),
+		)),
+		validation.Field(&c.Retry),
+		validation.Field(&c.Database),
 		validation.Field(&c.Logger),
 	)
 }
+
+func (c UploadConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Policies, validation.When(
+			c.Dedup.Enabled && publicDefaultACL(c.Policies),
+			validation.By(func(interface{}) error { return errors.New("default acl must be private with deduplication") }),
+		)),
+		validation.Field(&c.Sessions),
+		validation.Field(&c.Dedup),
+	)
+}
+
+// publicDefaultACL reports whether a policy applies ACL other than private by default,
+// deduplicated files are private, because their urls can't be fetched
+func publicDefaultACL(policies []UploadPolicyConfig) bool {
+	for _, p := range policies {
+		acl := p.ACL
+		if acl == "" && len(p.ACLs) > 0 {
+			acl = p.ACLs[0]
+		}
+		if acl != "" && acl != dto.BlobACL {
+			return true
+		}
+	}
+	return false
+}
+
+func (c UploadDedupConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Prefix, validation.When(c.Enabled, validation.Required, validation.Match(directoryPattern))),
+	)
+}
+
+func (c TrashConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Prefix, validation.When(c.Enabled, validation.Required, validation.Match(directoryPattern))),
+		validation.Field(&c.Retention, validation.When(c.Enabled, validation.Required, validation.Min(time.Minute))),
+		validation.Field(&c.PurgeInterval, validation.When(c.Enabled, validation.Required, validation.Min(time.Minute))),
+	)
+}
+
+// nested reports whether one of directories contains the other
+func nested(first, second string) bool {
+	return first == second || strings.HasPrefix(first, second+"/") || strings.HasPrefix(second, first+"/")
+}
+
+func (c ReconcileConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Interval, validation.When(c.Enabled, validation.Required, validation.Min(time.Minute))),
+		validation.Field(&c.GracePeriod, validation.When(c.Enabled, validation.Required, validation.Min(dto.MinReconcileGracePeriod))),
+		validation.Field(&c.Prefix, validation.When(c.Enabled && !c.DryRun, validation.Required.Error("is required to delete orphans"))),
+	)
+}
+
+func (c EventsConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.RelayInterval, validation.When(c.Enabled, validation.Required, validation.Min(MinEventsRelayInterval))),
+	)
+}
+
+func (c RetryConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.MaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&c.Backoff, validation.Required, validation.Min(MinRetryBackoff)),
+		validation.Field(&c.MaxBackoff, validation.Required, validation.Min(c.Backoff)),
+		validation.Field(&c.DeadLetterExchange, validation.Required),
+	)
+}
+
+func (c DatabaseConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Path, validation.Required),
+	)
+}
+
+func (c UploadSessionsConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.TTL, validation.When(c.Enabled, validation.Required, validation.Min(MinUploadSessionTTL))),
+		validation.Field(&c.SweepInterval, validation.When(c.Enabled, validation.Required, validation.Min(time.Minute))),
+	)
+}
+
+func (c UploadPolicyConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.MaxSize, validation.Min(0)),
+		validation.Field(&c.ContentTypes, validation.Each(validation.Match(mediaTypePattern))),
+		validation.Field(&c.Extensions, validation.Each(validation.Match(extensionPattern))),
+		validation.Field(&c.ACL, validation.In(dto.CannedACLs...), validation.When(
+			len(c.ACLs) > 0,
+			validation.In(toInterfaces(c.ACLs)...).Error("must be one of acls"),
+		)),
+		validation.Field(&c.ACLs, validation.Each(validation.In(dto.CannedACLs...))),
+		validation.Field(&c.Collision, validation.In(dto.Collisions...)),
+	)
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+func (c StorageConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Driver, validation.Required, validation.In(
+			StorageDriverS3,
+			StorageDriverLocal,
+			StorageDriverMemory,
+		)),
+		validation.Field(&c.Local, validation.Skip.When(c.Driver != StorageDriverLocal)),
+		validation.Field(&c.Memory, validation.Skip.When(c.Driver != StorageDriverMemory)),
+	)
+}
+
+func (c LocalStorageConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Root, validation.Required),
+		validation.Field(&c.BaseURL, validation.Required, is.URL),
+	)
+}
+
+func (c MemoryStorageConfig) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.BaseURL, validation.Required, is.URL),
+	)
+}
+
+func (c S3Config) Validate() error {
+	return validation.ValidateStruct(
+		&c,
+		validation.Field(&c.Endpoint, is.URL),
+		validation.Field(&c.SecretAccessKey, validation.When(c.AccessKeyID != "", validation.Required)),
+		validation.Field(&c.MaxPresignExpiry, validation.Required, validation.Max(MaxPresignExpiry)),
+	)
+}
@@ -1,3 +1,37 @@
 package config
 
+import (
+	"regexp"
+	"time"
+)
+
 const DefaultConfig = "config.yml"
+
+// FileEventsPublish is the name of AMQP publish config of file events
+const FileEventsPublish = "file_events"
+
+const (
+	StorageDriverS3     = "s3"
+	StorageDriverLocal  = "local"
+	StorageDriverMemory = "memory"
+)
+
+// MaxPresignExpiry is the longest lifetime of presigned url allowed by S3
+const MaxPresignExpiry = 7 * 24 * time.Hour
+
+// MinUploadSessionTTL keeps the sweeper from aborting sessions of clients uploading slowly
+const MinUploadSessionTTL = time.Hour
+
+// MinRetryBackoff keeps failed messages from being retried too often
+const MinRetryBackoff = 100 * time.Millisecond
+
+// MinEventsRelayInterval keeps the relay from polling the outbox too often
+const MinEventsRelayInterval = 100 * time.Millisecond
+
+var (
+	// mediaTypePattern matches media types without parameters, e.g. "image/png" or "image/*"
+	mediaTypePattern = regexp.MustCompile(`^[\w.+-]+/([\w.+-]+|\*)$`)
+	extensionPattern = regexp.MustCompile(`^\.[\w.-]+$`)
+	// directoryPattern matches directories without leading or trailing slash, e.g. "_blobs" or "files/_blobs"
+	directoryPattern = regexp.MustCompile(`^[\w.-]+(/[\w.-]+)*$`)
+)
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Groups of grantees used by canned ACLs
+const (
+	allUsersGroup           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	authenticatedUsersGroup = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// CannedACL returns canned ACL which grants the same permissions as the object ACL,
+// empty string is returned if grants can't be expressed by one of owner-only canned ACLs
+func CannedACL(output *s3.GetObjectAclOutput) string {
+	var ownerId string
+	if output.Owner != nil {
+		ownerId = aws.StringValue(output.Owner.ID)
+	}
+	var publicRead, publicWrite, authenticatedRead bool
+	for _, grant := range output.Grants {
+		if grant.Grantee == nil {
+			return ""
+		}
+		uri, permission := aws.StringValue(grant.Grantee.URI), aws.StringValue(grant.Permission)
+		switch {
+		case aws.StringValue(grant.Grantee.ID) == ownerId && permission == s3.PermissionFullControl:
+		case uri == allUsersGroup && permission == s3.PermissionRead:
+			publicRead = true
+		case uri == allUsersGroup && permission == s3.PermissionWrite:
+			publicWrite = true
+		case uri == authenticatedUsersGroup && permission == s3.PermissionRead:
+			authenticatedRead = true
+		default:
+			return ""
+		}
+	}
+	switch {
+	case authenticatedRead && !publicRead && !publicWrite:
+		return s3.ObjectCannedACLAuthenticatedRead
+	case authenticatedRead:
+		return ""
+	case publicRead && publicWrite:
+		return s3.ObjectCannedACLPublicReadWrite
+	case publicRead:
+		return s3.ObjectCannedACLPublicRead
+	case publicWrite:
+		return ""
+	default:
+		return s3.ObjectCannedACLPrivate
+	}
+}
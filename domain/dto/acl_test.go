@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCannedACL(t *testing.T) {
+	owner := &s3.Grant{
+		Grantee:    &s3.Grantee{ID: aws.String("owner"), Type: aws.String(s3.TypeCanonicalUser)},
+		Permission: aws.String(s3.PermissionFullControl),
+	}
+	group := func(uri, permission string) *s3.Grant {
+		return &s3.Grant{
+			Grantee:    &s3.Grantee{URI: aws.String(uri), Type: aws.String(s3.TypeGroup)},
+			Permission: aws.String(permission),
+		}
+	}
+	tests := []struct {
+		name   string
+		grants []*s3.Grant
+		want   string
+	}{
+		{name: "private", grants: []*s3.Grant{owner}, want: "private"},
+		{name: "public-read", grants: []*s3.Grant{owner, group(allUsersGroup, s3.PermissionRead)}, want: "public-read"},
+		{
+			name:   "public-read-write",
+			grants: []*s3.Grant{owner, group(allUsersGroup, s3.PermissionRead), group(allUsersGroup, s3.PermissionWrite)},
+			want:   "public-read-write",
+		},
+		{name: "authenticated-read", grants: []*s3.Grant{owner, group(authenticatedUsersGroup, s3.PermissionRead)}, want: "authenticated-read"},
+		{
+			name: "other account",
+			grants: []*s3.Grant{owner, {
+				Grantee:    &s3.Grantee{ID: aws.String("other"), Type: aws.String(s3.TypeCanonicalUser)},
+				Permission: aws.String(s3.PermissionRead),
+			}},
+			want: "",
+		},
+		{name: "public write only", grants: []*s3.Grant{owner, group(allUsersGroup, s3.PermissionWrite)}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &s3.GetObjectAclOutput{Owner: &s3.Owner{ID: aws.String("owner")}, Grants: tt.grants}
+			assert.EqualValues(t, tt.want, CannedACL(output))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"path"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type (
+	// CatalogEntry records stored file, DeletedAt is set when the file is deleted
+	CatalogEntry struct {
+		Key         string
+		Url         string
+		Directory   string
+		Owner       string
+		Size        int64
+		ContentType string
+		// SHA256 is hex checksum of the content, it's empty for files uploaded by sessions
+		SHA256    string
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		DeletedAt time.Time
+	}
+
+	// CatalogListInput describes page of recorded files which keys start with Prefix,
+	// empty Owner matches all owners, deleted files are skipped unless Deleted is set.
+	// Zero PageSize means MaxListPageSize
+	CatalogListInput struct {
+		Prefix    string
+		Owner     string
+		Deleted   bool
+		PageSize  int64
+		PageToken string
+	}
+
+	// CatalogListOutput contains page of recorded files sorted by key,
+	// NextPageToken is empty on the last page
+	CatalogListOutput struct {
+		Entries       []*CatalogEntry
+		NextPageToken string
+	}
+)
+
+// NewCatalogEntry returns entry of the file with directory taken from the key
+func NewCatalogEntry(key, url string) *CatalogEntry {
+	directory := path.Dir(key)
+	if directory == "." {
+		directory = ""
+	}
+	return &CatalogEntry{Key: key, Url: url, Directory: directory}
+}
+
+func (e *CatalogEntry) IsDeleted() bool {
+	return !e.DeletedAt.IsZero()
+}
+
+func (i *CatalogListInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.PageSize, validation.Min(0), validation.Max(MaxListPageSize)),
+	)
+}
+
+func (i *CatalogListInput) Limit() int64 {
+	if i.PageSize == 0 {
+		return MaxListPageSize
+	}
+	return i.PageSize
+}
+
+// Match reports whether the entry is requested by the input regardless of the page
+func (i *CatalogListInput) Match(entry *CatalogEntry) bool {
+	if i.Owner != "" && i.Owner != entry.Owner {
+		return false
+	}
+	return i.Deleted || !entry.IsDeleted()
+}
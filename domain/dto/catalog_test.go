@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCatalogEntry(t *testing.T) {
+	assert.EqualValues(t, &CatalogEntry{Key: "a/b/test.jpg", Url: "http://localhost/a/b/test.jpg", Directory: "a/b"},
+		NewCatalogEntry("a/b/test.jpg", "http://localhost/a/b/test.jpg"))
+	assert.EqualValues(t, &CatalogEntry{Key: "test.jpg", Url: "http://localhost/test.jpg"},
+		NewCatalogEntry("test.jpg", "http://localhost/test.jpg"))
+}
+
+func TestCatalogListInput_Validate(t *testing.T) {
+	assert.NoError(t, (&CatalogListInput{Prefix: "test/", PageSize: MaxListPageSize}).Validate())
+	assert.Error(t, (&CatalogListInput{PageSize: -1}).Validate())
+	assert.Error(t, (&CatalogListInput{PageSize: MaxListPageSize + 1}).Validate())
+}
+
+func TestCatalogListInput_Match(t *testing.T) {
+	live := &CatalogEntry{Key: "test.jpg", Owner: "user"}
+	deleted := &CatalogEntry{Key: "test.jpg", Owner: "user", DeletedAt: time.Now()}
+	tests := []struct {
+		name  string
+		input *CatalogListInput
+		entry *CatalogEntry
+		want  bool
+	}{
+		{name: "Any owner", input: &CatalogListInput{}, entry: live, want: true},
+		{name: "Same owner", input: &CatalogListInput{Owner: "user"}, entry: live, want: true},
+		{name: "Other owner", input: &CatalogListInput{Owner: "other"}, entry: live},
+		{name: "Deleted", input: &CatalogListInput{}, entry: deleted},
+		{name: "Including deleted", input: &CatalogListInput{Deleted: true}, entry: deleted, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, tt.input.Match(tt.entry))
+		})
+	}
+}
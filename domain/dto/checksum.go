@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strings"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+// Metadata keys of checksums, S3 returns metadata keys in canonical form
+const (
+	SHA256MetadataKey = "Sha256"
+	MD5MetadataKey    = "Md5"
+)
+
+type (
+	// ChecksumReader computes SHA-256 and MD5 checksums and size of the data read through it
+	ChecksumReader struct {
+		reader io.Reader
+		sha256 hash.Hash
+		md5    hash.Hash
+		size   int64
+	}
+)
+
+func NewChecksumReader(reader io.Reader) *ChecksumReader {
+	return &ChecksumReader{
+		reader: reader,
+		sha256: sha256.New(),
+		md5:    md5.New(),
+	}
+}
+
+func (r *ChecksumReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	// Hashes never return errors
+	_, _ = r.sha256.Write(p[:n])
+	_, _ = r.md5.Write(p[:n])
+	r.size += int64(n)
+	return n, err
+}
+
+// Size returns number of bytes read so far
+func (r *ChecksumReader) Size() int64 {
+	return r.size
+}
+
+// SHA256 returns hex checksum of the data read so far
+func (r *ChecksumReader) SHA256() string {
+	return hex.EncodeToString(r.sha256.Sum(nil))
+}
+
+// MD5 returns hex checksum of the data read so far
+func (r *ChecksumReader) MD5() string {
+	return hex.EncodeToString(r.md5.Sum(nil))
+}
+
+// Verify compares read data with checksums declared by client, empty checksums are skipped
+func (r *ChecksumReader) Verify(sha256, md5 string) error {
+	if sha256 != "" && !strings.EqualFold(sha256, r.SHA256()) {
+		return customErrors.ChecksumMismatch
+	} else if md5 != "" && !strings.EqualFold(md5, r.MD5()) {
+		return customErrors.ChecksumMismatch
+	}
+	return nil
+}
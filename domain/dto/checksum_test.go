@@ -0,0 +1,81 @@
+package dto
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+const (
+	testSHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	testMD5    = "098f6bcd4621d373cade4e832627b4f6"
+)
+
+func TestChecksumReader_Verify(t *testing.T) {
+	type args struct {
+		sha256 string
+		md5    string
+	}
+	tests := []struct {
+		name    string
+		content string
+		args    args
+		wantErr error
+	}{
+		{
+			name:    "Both match",
+			content: "test",
+			args:    args{sha256: testSHA256, md5: testMD5},
+		},
+		{
+			name:    "Upper case",
+			content: "test",
+			args:    args{sha256: strings.ToUpper(testSHA256)},
+		},
+		{
+			name:    "Nothing declared",
+			content: "test",
+		},
+		{
+			name:    "SHA256 mismatch",
+			content: "test2",
+			args:    args{sha256: testSHA256},
+			wantErr: customErrors.ChecksumMismatch,
+		},
+		{
+			name:    "MD5 mismatch",
+			content: "test2",
+			args:    args{md5: testMD5},
+			wantErr: customErrors.ChecksumMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewChecksumReader(strings.NewReader(tt.content))
+			content, err := ioutil.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.EqualValues(t, tt.content, string(content))
+			assert.EqualValues(t, tt.wantErr, reader.Verify(tt.args.sha256, tt.args.md5))
+		})
+	}
+}
+
+func TestChecksumReader_Sums(t *testing.T) {
+	reader := NewChecksumReader(strings.NewReader("test"))
+	_, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.EqualValues(t, testSHA256, reader.SHA256())
+	assert.EqualValues(t, testMD5, reader.MD5())
+}
+
+func TestChecksumReader_Size(t *testing.T) {
+	reader := NewChecksumReader(strings.NewReader("test"))
+	assert.EqualValues(t, 0, reader.Size())
+	_, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, reader.Size())
+}
@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"net/url"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type (
+	// CopyInput describes source file and destination of copy or move,
+	// ACL is applied to the destination file. Metadata replaces metadata of the source
+	// file if it isn't nil, otherwise the source metadata is copied
+	CopyInput struct {
+		SourceUrl string
+		Directory string
+		Filename  string
+		ACL       string
+		Metadata  map[string]string
+	}
+)
+
+func (i *CopyInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.SourceUrl, validation.Required, is.URL),
+		validation.Field(&i.Filename, validation.Required),
+		validation.Field(&i.ACL, validation.In(CannedACLs...)),
+	)
+}
+
+func (i *CopyInput) Key() string {
+	return ObjectKey(i.Directory, i.Filename)
+}
+
+func (i *CopyInput) ToS3Input(bucket S3Bucket) (*s3.CopyObjectInput, error) {
+	sourceKey, err := bucket.Key(i.SourceUrl)
+	if err != nil {
+		return nil, err
+	}
+	s3Input := &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket.Name),
+		Key:        aws.String(i.Key()),
+		CopySource: aws.String(CopySource(bucket.Name, sourceKey)),
+	}
+	if i.ACL != "" {
+		s3Input.ACL = aws.String(i.ACL)
+	}
+	if i.Metadata != nil {
+		s3Input.Metadata = aws.StringMap(i.Metadata)
+		s3Input.MetadataDirective = aws.String(s3.MetadataDirectiveReplace)
+	}
+	return s3Input, nil
+}
+
+// CopySource builds url encoded source of S3 copy request
+func CopySource(bucketName, key string) string {
+	return (&url.URL{Path: path.Join(bucketName, key)}).EscapedPath()
+}
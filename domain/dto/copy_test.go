@@ -0,0 +1,114 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+func TestCopyInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *CopyInput
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			input: &CopyInput{
+				SourceUrl: "https://aws.amazonaws.com/bucket/tmp/test.jpg",
+				Directory: "test",
+				Filename:  "test.jpg",
+				ACL:       "public-read",
+			},
+		},
+		{
+			name:    "Invalid source url",
+			input:   &CopyInput{SourceUrl: "test", Filename: "test.jpg"},
+			wantErr: true,
+		},
+		{
+			name:    "Empty filename",
+			input:   &CopyInput{SourceUrl: "https://aws.amazonaws.com/bucket/tmp/test.jpg"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid ACL",
+			input:   &CopyInput{SourceUrl: "https://aws.amazonaws.com/bucket/tmp/test.jpg", Filename: "test.jpg", ACL: "test"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestCopyInput_ToS3Input(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *CopyInput
+		want    *s3.CopyObjectInput
+		wantErr error
+	}{
+		{
+			name: "Valid",
+			input: &CopyInput{
+				SourceUrl: "https://aws.s3/test.bucket/tmp/my%20file.jpg",
+				Directory: "test",
+				Filename:  "test.jpg",
+				ACL:       "public-read",
+			},
+			want: &s3.CopyObjectInput{
+				Bucket:     aws.String("test.bucket"),
+				Key:        aws.String("test/test.jpg"),
+				CopySource: aws.String("test.bucket/tmp/my%20file.jpg"),
+				ACL:        aws.String("public-read"),
+			},
+		},
+		{
+			name: "Without ACL",
+			input: &CopyInput{
+				SourceUrl: "https://aws.s3/test.bucket/tmp/test.jpg",
+				Filename:  "test.jpg",
+			},
+			want: &s3.CopyObjectInput{
+				Bucket:     aws.String("test.bucket"),
+				Key:        aws.String("test.jpg"),
+				CopySource: aws.String("test.bucket/tmp/test.jpg"),
+			},
+		},
+		{
+			name: "Metadata",
+			input: &CopyInput{
+				SourceUrl: "https://aws.s3/test.bucket/tmp/test.jpg",
+				Filename:  "test.jpg",
+				Metadata:  map[string]string{},
+			},
+			want: &s3.CopyObjectInput{
+				Bucket:            aws.String("test.bucket"),
+				Key:               aws.String("test.jpg"),
+				CopySource:        aws.String("test.bucket/tmp/test.jpg"),
+				Metadata:          map[string]*string{},
+				MetadataDirective: aws.String("REPLACE"),
+			},
+		},
+		{
+			name:    "Wrong bucket",
+			input:   &CopyInput{SourceUrl: "https://aws.s3/other.bucket/tmp/test.jpg", Filename: "test.jpg"},
+			wantErr: customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.input.ToS3Input(testBucket)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
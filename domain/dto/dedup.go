@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path"
+	"strings"
+)
+
+const (
+	// dedupStagingDir keeps uploaded files until their hash is known
+	dedupStagingDir = "staging"
+	// dedupOwnerFile keeps id of the instance which counts references of blobs
+	dedupOwnerFile = ".owner"
+)
+
+type (
+	// BlobRef refers file by its key and url to content-addressed blob,
+	// which is shared by all files with the same content. ContentType and Metadata
+	// belong to the file, the blob keeps ones of the file it was uploaded by
+	BlobRef struct {
+		Key         string
+		Url         string
+		BlobUrl     string
+		ContentType string
+		Metadata    map[string]string
+	}
+)
+
+// BlobKey returns key of the blob with the SHA-256 hex checksum
+func BlobKey(prefix, sha256 string) string {
+	return path.Join(prefix, strings.ToLower(sha256))
+}
+
+// StagingKey returns unique key where file is uploaded before it's moved to its blob
+func StagingKey(prefix string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return path.Join(prefix, dedupStagingDir, hex.EncodeToString(id)), nil
+}
+
+// DedupOwnerKey returns key of the file claiming directory of blobs with the prefix by an instance
+func DedupOwnerKey(prefix string) string {
+	return path.Join(prefix, dedupOwnerFile)
+}
+
+// IsBlobKey reports whether the key is inside directory of blobs with the prefix
+func IsBlobKey(prefix, key string) bool {
+	return inDirectory(prefix, key)
+}
+
+func inDirectory(directory, key string) bool {
+	return strings.HasPrefix(path.Clean("/"+key), path.Clean("/"+directory)+"/")
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlobKey(t *testing.T) {
+	assert.EqualValues(t, "blobs/"+testSHA256, BlobKey("blobs", testSHA256))
+	assert.EqualValues(t, "blobs/"+testSHA256, BlobKey("blobs/", "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08"))
+}
+
+func TestStagingKey(t *testing.T) {
+	first, err := StagingKey("blobs")
+	assert.NoError(t, err)
+	second, err := StagingKey("blobs")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^blobs/staging/[0-9a-f]{32}$`, first)
+	assert.NotEqual(t, first, second)
+	assert.True(t, IsBlobKey("blobs", first))
+}
+
+func TestIsBlobKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want bool
+	}{
+		{name: "Blob", key: "blobs/" + testSHA256, want: true},
+		{name: "Not normalized", key: "/docs/../blobs/test", want: true},
+		{name: "Same prefix", key: "blobs2/test.jpg"},
+		{name: "Directory itself", key: "blobs"},
+		{name: "Other directory", key: "docs/blobs/test.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, IsBlobKey("blobs", tt.key))
+		})
+	}
+}
+
+func TestDedupOwnerKey(t *testing.T) {
+	assert.EqualValues(t, "blobs/.owner", DedupOwnerKey("blobs/"))
+	assert.True(t, IsBlobKey("blobs", DedupOwnerKey("blobs")))
+}
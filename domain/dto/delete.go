@@ -1,22 +1,23 @@
 package dto
 
 import (
-	"fmt"
-	"regexp"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
-
-	customErrors "github.com/freemen-app/file_storage/domain/errors"
 )
 
 type (
 	DeleteInput string
 
 	BatchDeleteInput []DeleteInput
+
+	// DeleteFilesInput deletes files by urls and keys at once
+	DeleteFilesInput struct {
+		Urls BatchDeleteInput
+		Keys []string
+	}
 )
 
 func (i DeleteInput) Validate() error {
@@ -31,25 +32,31 @@ func (i BatchDeleteInput) Validate() error {
 	return validation.Validate([]DeleteInput(i))
 }
 
-func (i DeleteInput) ToS3Input(bucketName string) (*s3.DeleteObjectInput, error) {
-	regexpString := fmt.Sprintf("https://.*/%s/(.*)$", bucketName)
-	re := regexp.MustCompile(regexpString)
-	groups := re.FindStringSubmatch(i.String())
-	if len(groups) != 2 {
-		return nil, customErrors.InvalidURL
+func (i *DeleteFilesInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Urls, validation.Required.When(len(i.Keys) == 0)),
+		validation.Field(&i.Keys, validation.Each(validation.Required)),
+	)
+}
+
+func (i DeleteInput) ToS3Input(bucket S3Bucket) (*s3.DeleteObjectInput, error) {
+	key, err := bucket.Key(i.String())
+	if err != nil {
+		return nil, err
 	}
 	return &s3.DeleteObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(groups[1]),
+		Bucket: aws.String(bucket.Name),
+		Key:    aws.String(key),
 	}, nil
 }
 
-func (i BatchDeleteInput) ToS3Input(bucketName string) (*s3manager.DeleteObjectsIterator, error) {
+func (i BatchDeleteInput) ToS3Input(bucket S3Bucket) (*s3manager.DeleteObjectsIterator, error) {
 	files := &s3manager.DeleteObjectsIterator{
 		Objects: []s3manager.BatchDeleteObject{},
 	}
 	for _, obj := range i {
-		if s3Input, err := obj.ToS3Input(bucketName); err != nil {
+		if s3Input, err := obj.ToS3Input(bucket); err != nil {
 			return nil, err
 		} else {
 			files.Objects = append(files.Objects, s3manager.BatchDeleteObject{Object: s3Input})
@@ -79,22 +79,67 @@ func TestBatchDeleteInput_Validate(t *testing.T) {
 	}
 }
 
+func TestDeleteFilesInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *DeleteFilesInput
+		wantErr bool
+	}{
+		{
+			name:  "urls",
+			input: &DeleteFilesInput{Urls: BatchDeleteInput{"https://aws.amazonaws.com/bucket/test/test.yml"}},
+		},
+		{
+			name:  "keys",
+			input: &DeleteFilesInput{Keys: []string{"test/test.yml"}},
+		},
+		{
+			name: "urls and keys",
+			input: &DeleteFilesInput{
+				Urls: BatchDeleteInput{"https://aws.amazonaws.com/bucket/test/test.yml"},
+				Keys: []string{"test/test2.yml"},
+			},
+		},
+		{
+			name:    "nothing to delete",
+			input:   &DeleteFilesInput{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			input:   &DeleteFilesInput{Urls: BatchDeleteInput{"test"}, Keys: []string{"test/test.yml"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			input:   &DeleteFilesInput{Keys: []string{"test/test.yml", ""}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
 func TestDeleteInput_ToS3Input(t *testing.T) {
 	type fields struct {
 		Url string
 	}
 	tests := []struct {
-		name       string
-		bucketName string
-		fields     fields
-		want       *s3.DeleteObjectInput
-		wantErr    bool
+		name    string
+		bucket  S3Bucket
+		fields  fields
+		want    *s3.DeleteObjectInput
+		wantErr bool
 	}{
 		{
-			name:       "Valid",
-			bucketName: "test.bucket",
+			name:   "Valid",
+			bucket: testBucket,
 			fields: fields{
-				Url: "https://aws.amazonaws.com/test.bucket/test/test.jpg",
+				Url: "https://aws.s3/test.bucket/test/test.jpg",
 			},
 			want: &s3.DeleteObjectInput{
 				Bucket: aws.String("test.bucket"),
@@ -102,10 +147,86 @@ func TestDeleteInput_ToS3Input(t *testing.T) {
 			},
 		},
 		{
-			name:       "Wrong bucket name",
-			bucketName: "test.bucket",
+			name:   "Virtual-hosted-style",
+			bucket: S3Bucket{Name: "test-bucket", BaseURL: "https://test-bucket.s3.eu-central-1.amazonaws.com/"},
+			fields: fields{
+				Url: "https://test-bucket.s3.eu-central-1.amazonaws.com/test/test.jpg",
+			},
+			want: &s3.DeleteObjectInput{
+				Bucket: aws.String("test-bucket"),
+				Key:    aws.String("test/test.jpg"),
+			},
+		},
+		{
+			name:   "Virtual-hosted-style key starting with bucket name",
+			bucket: S3Bucket{Name: "files", BaseURL: "https://files.example.com/"},
+			fields: fields{
+				Url: "https://files.example.com/files/a.png",
+			},
+			want: &s3.DeleteObjectInput{
+				Bucket: aws.String("files"),
+				Key:    aws.String("files/a.png"),
+			},
+		},
+		{
+			name:   "Path-style with custom endpoint",
+			bucket: S3Bucket{Name: "test.bucket", BaseURL: "http://localhost:9000/test.bucket"},
+			fields: fields{
+				Url: "http://localhost:9000/test.bucket/test/my%20file.jpg",
+			},
+			want: &s3.DeleteObjectInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test/my file.jpg"),
+			},
+		},
+		{
+			name:   "Path-style host starting with bucket name",
+			bucket: S3Bucket{Name: "files", BaseURL: "https://files.example.com/files"},
 			fields: fields{
-				Url: "https://aws.amazonaws.com/wrong.bucket/test/test.jpg",
+				Url: "https://files.example.com/files/a.png",
+			},
+			want: &s3.DeleteObjectInput{
+				Bucket: aws.String("files"),
+				Key:    aws.String("a.png"),
+			},
+		},
+		{
+			name:   "Wrong bucket name",
+			bucket: testBucket,
+			fields: fields{
+				Url: "https://aws.s3/wrong.bucket/test/test.jpg",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Wrong virtual-hosted bucket name",
+			bucket: S3Bucket{Name: "test-bucket", BaseURL: "https://test-bucket.s3.amazonaws.com/"},
+			fields: fields{
+				Url: "https://wrong-bucket.s3.amazonaws.com/test/test.jpg",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Wrong endpoint",
+			bucket: S3Bucket{Name: "test.bucket", BaseURL: "http://localhost:9000/test.bucket"},
+			fields: fields{
+				Url: "https://aws.amazonaws.com/test.bucket/test/test.jpg",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Empty key",
+			bucket: testBucket,
+			fields: fields{
+				Url: "https://aws.s3/test.bucket/",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Unsupported scheme",
+			bucket: testBucket,
+			fields: fields{
+				Url: "ftp://aws.s3/test.bucket/test/test.jpg",
 			},
 			wantErr: true,
 		},
@@ -113,7 +234,7 @@ func TestDeleteInput_ToS3Input(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			i := DeleteInput(tt.fields.Url)
-			got, err := i.ToS3Input(tt.bucketName)
+			got, err := i.ToS3Input(tt.bucket)
 			assert.EqualValues(t, tt.wantErr, err != nil, err)
 			assert.EqualValues(t, tt.want, got)
 		})
@@ -125,19 +246,17 @@ func TestBatchDeleteInput_ToS3Input(t *testing.T) {
 		Urls []DeleteInput
 	}
 	tests := []struct {
-		name       string
-		bucketName string
-		fields     fields
-		want       *s3manager.DeleteObjectsIterator
-		wantErr    bool
+		name    string
+		fields  fields
+		want    *s3manager.DeleteObjectsIterator
+		wantErr bool
 	}{
 		{
-			name:       "Valid",
-			bucketName: "test.bucket",
+			name: "Valid",
 			fields: fields{
 				Urls: []DeleteInput{
-					DeleteInput("https://aws.amazonaws.com/test.bucket/test/test.yml"),
-					DeleteInput("https://aws.amazonaws.com/test.bucket/test/test2.yml"),
+					DeleteInput("https://aws.s3/test.bucket/test/test.yml"),
+					DeleteInput("https://aws.s3/test.bucket/test/test2.yml"),
 				},
 			},
 			want: &s3manager.DeleteObjectsIterator{
@@ -154,12 +273,11 @@ func TestBatchDeleteInput_ToS3Input(t *testing.T) {
 			},
 		},
 		{
-			name:       "Wrong bucket name",
-			bucketName: "test.bucket",
+			name: "Wrong bucket name",
 			fields: fields{
 				Urls: []DeleteInput{
-					DeleteInput("https://aws.amazonaws.com/wrong.bucket/test/test.yml"),
-					DeleteInput("https://aws.amazonaws.com/test.bucket/test/test2.yml"),
+					DeleteInput("https://aws.s3/wrong.bucket/test/test.yml"),
+					DeleteInput("https://aws.s3/test.bucket/test/test2.yml"),
 				},
 			},
 			want:    nil,
@@ -169,7 +287,7 @@ func TestBatchDeleteInput_ToS3Input(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			i := BatchDeleteInput(tt.fields.Urls)
-			got, err := i.ToS3Input(tt.bucketName)
+			got, err := i.ToS3Input(testBucket)
 			assert.EqualValues(t, tt.wantErr, err != nil, err)
 			assert.EqualValues(t, tt.want, got)
 		})
@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+type (
+	// DownloadInput describes file to download. Offset and Length allow to read
+	// only part of the file, zero Length means reading until the end of file
+	DownloadInput struct {
+		Url    string
+		Offset int64
+		Length int64
+	}
+
+	// DownloadOutput contains file content starting from Offset.
+	// Size is the size of the whole file, Length is the size of returned content
+	DownloadOutput struct {
+		File        io.ReadCloser
+		ContentType string
+		Size        int64
+		Offset      int64
+		Length      int64
+	}
+)
+
+func (i *DownloadInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Url, validation.Required, is.URL),
+		validation.Field(&i.Offset, validation.Min(0)),
+		validation.Field(
+			&i.Length,
+			validation.Min(0),
+			// End of the range has to fit int64, otherwise it overflows
+			validation.When(i.Offset >= 0, validation.Max(math.MaxInt64-i.Offset).Error("range is too long")),
+		),
+	)
+}
+
+// IsRanged reports whether only part of the file is requested
+func (i *DownloadInput) IsRanged() bool {
+	return i.Offset > 0 || i.Length > 0
+}
+
+// Range returns value of HTTP Range header for requested part of the file
+func (i *DownloadInput) Range() string {
+	if !i.IsRanged() {
+		return ""
+	} else if i.Length == 0 {
+		return fmt.Sprintf("bytes=%d-", i.Offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", i.Offset, i.Offset+i.Length-1)
+}
+
+// RangeLength returns length of requested part of the file with given size
+func (i *DownloadInput) RangeLength(size int64) (int64, error) {
+	if i.Offset < 0 || i.Length < 0 || i.Offset > 0 && i.Offset >= size {
+		return 0, customErrors.InvalidRange
+	} else if i.Length == 0 || i.Length > size-i.Offset {
+		return size - i.Offset, nil
+	}
+	return i.Length, nil
+}
+
+func (i *DownloadInput) ToS3Input(bucket S3Bucket) (*s3.GetObjectInput, error) {
+	key, err := bucket.Key(i.Url)
+	if err != nil {
+		return nil, err
+	}
+	s3Input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket.Name),
+		Key:    aws.String(key),
+	}
+	if i.IsRanged() {
+		s3Input.Range = aws.String(i.Range())
+	}
+	return s3Input, nil
+}
@@ -0,0 +1,185 @@
+package dto
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+func TestDownloadInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		offset  int64
+		length  int64
+		wantErr bool
+	}{
+		{
+			name: "Valid url",
+			url:  "https://aws.amazonaws.com/bucket/test/test.jpg",
+		},
+		{
+			name:    "Empty url",
+			wantErr: true,
+		},
+		{
+			name:    "Negative offset",
+			url:     "https://aws.amazonaws.com/bucket/test/test.jpg",
+			offset:  -1,
+			wantErr: true,
+		},
+		{
+			name:    "Negative length",
+			url:     "https://aws.amazonaws.com/bucket/test/test.jpg",
+			length:  -1,
+			wantErr: true,
+		},
+		{
+			name:   "Range until max int64",
+			url:    "https://aws.amazonaws.com/bucket/test/test.jpg",
+			offset: 1,
+			length: math.MaxInt64 - 1,
+		},
+		{
+			name:    "Overflowing range",
+			url:     "https://aws.amazonaws.com/bucket/test/test.jpg",
+			offset:  2,
+			length:  math.MaxInt64 - 1,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid url",
+			url:     "test",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &DownloadInput{Url: tt.url, Offset: tt.offset, Length: tt.length}
+			err := i.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestDownloadInput_ToS3Input(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		offset  int64
+		length  int64
+		want    *s3.GetObjectInput
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			url:  "https://aws.s3/test.bucket/test/test.jpg",
+			want: &s3.GetObjectInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test/test.jpg"),
+			},
+		},
+		{
+			name:   "Range",
+			url:    "https://aws.s3/test.bucket/test/test.jpg",
+			offset: 10,
+			length: 10,
+			want: &s3.GetObjectInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test/test.jpg"),
+				Range:  aws.String("bytes=10-19"),
+			},
+		},
+		{
+			name:   "Range until the end",
+			url:    "https://aws.s3/test.bucket/test/test.jpg",
+			offset: 10,
+			want: &s3.GetObjectInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test/test.jpg"),
+				Range:  aws.String("bytes=10-"),
+			},
+		},
+		{
+			name:    "Wrong bucket name",
+			url:     "https://aws.s3/wrong.bucket/test/test.jpg",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &DownloadInput{Url: tt.url, Offset: tt.offset, Length: tt.length}
+			got, err := i.ToS3Input(testBucket)
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestDownloadInput_RangeLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		offset  int64
+		length  int64
+		size    int64
+		want    int64
+		wantErr error
+	}{
+		{
+			name: "Whole file",
+			size: 100,
+			want: 100,
+		},
+		{
+			name: "Empty file",
+			size: 0,
+			want: 0,
+		},
+		{
+			name:   "Range",
+			offset: 10,
+			length: 10,
+			size:   100,
+			want:   10,
+		},
+		{
+			name:   "Range until the end",
+			offset: 10,
+			size:   100,
+			want:   90,
+		},
+		{
+			name:   "Range longer than file",
+			offset: 90,
+			length: 20,
+			size:   100,
+			want:   10,
+		},
+		{
+			name:   "Max length",
+			offset: 10,
+			length: math.MaxInt64 - 10,
+			size:   100,
+			want:   90,
+		},
+		{
+			name:    "Offset out of file",
+			offset:  100,
+			size:    100,
+			wantErr: customErrors.InvalidRange,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &DownloadInput{Offset: tt.offset, Length: tt.length}
+			got, err := i.RangeLength(tt.size)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
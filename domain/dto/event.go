@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	EventFileUploaded = "file.uploaded"
+	EventFileDeleted  = "file.deleted"
+	EventFileCopied   = "file.copied"
+)
+
+// EventRelayBatchSize is the number of pending events read from the outbox at once
+const EventRelayBatchSize = 100
+
+// EventConfirmTimeout is longer than a change may take, event which isn't confirmed or discarded
+// by then belongs to the interrupted change, so the relay settles it by the storage
+const EventConfirmTimeout = time.Hour
+
+type (
+	// FileEvent describes change of the file made through the service. SourceUrl is set for copied files,
+	// Size, ContentType and SHA256 are empty for deleted files. Actor is who made the change, Reason is why
+	// and CorrelationId links the change to the request which caused it, if they are known
+	FileEvent struct {
+		Id            string
+		Type          string
+		Key           string
+		Url           string
+		SourceUrl     string
+		Size          int64
+		ContentType   string
+		SHA256        string
+		Actor         string
+		Reason        string
+		CorrelationId string
+		Timestamp     time.Time
+	}
+
+	actorContextKey         struct{}
+	reasonContextKey        struct{}
+	correlationIdContextKey struct{}
+)
+
+// Describe fills the event prepared before the change by entry of the changed file,
+// owner of the file is the actor if it's known
+func (e *FileEvent) Describe(entry *CatalogEntry) {
+	e.Key, e.Url, e.Size, e.ContentType, e.SHA256 = entry.Key, entry.Url, entry.Size, entry.ContentType, entry.SHA256
+	if entry.Owner != "" {
+		e.Actor = entry.Owner
+	}
+}
+
+// WithActor returns context of the request made by the actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns actor set by WithActor or empty string
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorContextKey{}).(string)
+	return actor
+}
+
+// WithReason returns context of the request made for the reason
+func WithReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, reasonContextKey{}, reason)
+}
+
+// ReasonFromContext returns reason set by WithReason or empty string
+func ReasonFromContext(ctx context.Context) string {
+	reason, _ := ctx.Value(reasonContextKey{}).(string)
+	return reason
+}
+
+// WithCorrelationId returns context of the request with correlation id
+func WithCorrelationId(ctx context.Context, correlationId string) context.Context {
+	return context.WithValue(ctx, correlationIdContextKey{}, correlationId)
+}
+
+// CorrelationIdFromContext returns correlation id set by WithCorrelationId or empty string
+func CorrelationIdFromContext(ctx context.Context) string {
+	correlationId, _ := ctx.Value(correlationIdContextKey{}).(string)
+	return correlationId
+}
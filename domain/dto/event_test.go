@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileEvent_Describe(t *testing.T) {
+	entry := &CatalogEntry{Key: "a/test.jpg", Url: "http://localhost/a/test.jpg", Size: 4, SHA256: "sha256"}
+	event := &FileEvent{Id: "id", Type: EventFileCopied, Key: "a/test.jpg", SourceUrl: "http://localhost/test.jpg", Actor: "admin"}
+	event.Describe(entry)
+	assert.EqualValues(t, &FileEvent{
+		Id:        "id",
+		Type:      EventFileCopied,
+		Key:       "a/test.jpg",
+		Url:       "http://localhost/a/test.jpg",
+		SourceUrl: "http://localhost/test.jpg",
+		Size:      4,
+		SHA256:    "sha256",
+		Actor:     "admin",
+	}, event)
+
+	entry.Owner = "user"
+	event.Describe(entry)
+	assert.EqualValues(t, "user", event.Actor)
+}
+
+func TestActorFromContext(t *testing.T) {
+	assert.Empty(t, ActorFromContext(context.Background()))
+	assert.EqualValues(t, "user", ActorFromContext(WithActor(context.Background(), "user")))
+}
+
+func TestReasonFromContext(t *testing.T) {
+	assert.Empty(t, ReasonFromContext(context.Background()))
+	assert.EqualValues(t, "cleanup", ReasonFromContext(WithReason(context.Background(), "cleanup")))
+}
+
+func TestCorrelationIdFromContext(t *testing.T) {
+	assert.Empty(t, CorrelationIdFromContext(context.Background()))
+	assert.EqualValues(t, "id", CorrelationIdFromContext(WithCorrelationId(context.Background(), "id")))
+}
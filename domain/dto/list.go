@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	// MaxListPageSize is the max number of keys returned by S3 in a single response
+	MaxListPageSize = 1000
+	// ListDelimiter is the only supported delimiter, so that all storages group keys the same way
+	ListDelimiter = "/"
+)
+
+type (
+	// ListInput describes page of files which keys start with Prefix.
+	// Keys containing Delimiter after the Prefix are grouped into common prefixes,
+	// zero PageSize means MaxListPageSize
+	ListInput struct {
+		Prefix    string
+		Delimiter string
+		PageSize  int64
+		PageToken string
+	}
+
+	// ListOutput contains page of files and common prefixes ("subdirectories"),
+	// NextPageToken is empty on the last page. Files contain only
+	// key, url, size, ETag and last modified time
+	ListOutput struct {
+		Files         []*FileInfo
+		Prefixes      []string
+		NextPageToken string
+	}
+)
+
+func (i *ListInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Delimiter, validation.In(ListDelimiter)),
+		validation.Field(&i.PageSize, validation.Min(0), validation.Max(MaxListPageSize)),
+	)
+}
+
+func (i *ListInput) Limit() int64 {
+	if i.PageSize == 0 {
+		return MaxListPageSize
+	}
+	return i.PageSize
+}
+
+func (i *ListInput) ToS3Input(bucketName string) *s3.ListObjectsV2Input {
+	s3Input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucketName),
+		MaxKeys: aws.Int64(i.Limit()),
+	}
+	if i.Prefix != "" {
+		s3Input.Prefix = aws.String(i.Prefix)
+	}
+	if i.Delimiter != "" {
+		s3Input.Delimiter = aws.String(i.Delimiter)
+	}
+	if i.PageToken != "" {
+		s3Input.ContinuationToken = aws.String(i.PageToken)
+	}
+	return s3Input
+}
@@ -0,0 +1,80 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *ListInput
+		wantErr bool
+	}{
+		{
+			name:  "Empty",
+			input: &ListInput{},
+		},
+		{
+			name:  "All fields",
+			input: &ListInput{Prefix: "test/", Delimiter: "/", PageSize: 10, PageToken: "token"},
+		},
+		{
+			name:    "Unsupported delimiter",
+			input:   &ListInput{Delimiter: "-"},
+			wantErr: true,
+		},
+		{
+			name:    "Negative page size",
+			input:   &ListInput{PageSize: -1},
+			wantErr: true,
+		},
+		{
+			name:    "Too big page size",
+			input:   &ListInput{PageSize: MaxListPageSize + 1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestListInput_ToS3Input(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *ListInput
+		want  *s3.ListObjectsV2Input
+	}{
+		{
+			name:  "Empty",
+			input: &ListInput{},
+			want: &s3.ListObjectsV2Input{
+				Bucket:  aws.String("test.bucket"),
+				MaxKeys: aws.Int64(MaxListPageSize),
+			},
+		},
+		{
+			name:  "All fields",
+			input: &ListInput{Prefix: "test/", Delimiter: "/", PageSize: 10, PageToken: "token"},
+			want: &s3.ListObjectsV2Input{
+				Bucket:            aws.String("test.bucket"),
+				Prefix:            aws.String("test/"),
+				Delimiter:         aws.String("/"),
+				MaxKeys:           aws.Int64(10),
+				ContinuationToken: aws.String("token"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, tt.input.ToS3Input("test.bucket"))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type (
+	// PresignDownloadInput describes presigned download url request,
+	// zero Expires means maximum expiry allowed by storage.
+	// ContentType overrides content type of the response if it's set
+	PresignDownloadInput struct {
+		Url         string
+		Expires     time.Duration
+		ContentType string
+	}
+
+	// PresignUploadInput describes presigned upload url request,
+	// zero Expires means maximum expiry allowed by storage.
+	// Size is signed as Content-Length, so storage accepts only the file of this size, zero means any size
+	PresignUploadInput struct {
+		Directory   string
+		Filename    string
+		ACL         string
+		ContentType string
+		Size        int64
+		Expires     time.Duration
+	}
+
+	// PresignOutput contains presigned url and headers
+	// that have to be sent along with request to presigned url
+	PresignOutput struct {
+		Url       string
+		Headers   map[string]string
+		ExpiresAt time.Time
+	}
+)
+
+func (i *PresignDownloadInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Url, validation.Required, is.URL),
+		validation.Field(&i.Expires, validation.Min(time.Second)),
+	)
+}
+
+func (i *PresignDownloadInput) ToS3Input(bucket S3Bucket) (*s3.GetObjectInput, error) {
+	key, err := bucket.Key(i.Url)
+	if err != nil {
+		return nil, err
+	}
+	s3Input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket.Name),
+		Key:    aws.String(key),
+	}
+	if i.ContentType != "" {
+		s3Input.ResponseContentType = aws.String(i.ContentType)
+	}
+	return s3Input, nil
+}
+
+func (i *PresignUploadInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Filename, validation.Required),
+		validation.Field(&i.ACL, validation.In(CannedACLs...)),
+		validation.Field(&i.ContentType, isMediaType),
+		validation.Field(&i.Size, validation.Min(int64(0))),
+		validation.Field(&i.Expires, validation.Min(time.Second)),
+	)
+}
+
+func (i *PresignUploadInput) Key() string {
+	return ObjectKey(i.Directory, i.Filename)
+}
+
+func (i *PresignUploadInput) ToS3Input(bucketName string) *s3.PutObjectInput {
+	s3Input := &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(i.Key()),
+	}
+	if i.ACL != "" {
+		s3Input.ACL = aws.String(i.ACL)
+	}
+	if i.ContentType != "" {
+		s3Input.ContentType = aws.String(i.ContentType)
+	}
+	if i.Size > 0 {
+		s3Input.ContentLength = aws.Int64(i.Size)
+	}
+	return s3Input
+}
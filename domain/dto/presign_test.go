@@ -0,0 +1,152 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresignDownloadInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		expires time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "Valid",
+			url:     "https://aws.amazonaws.com/bucket/test/test.jpg",
+			expires: time.Minute,
+		},
+		{
+			name: "Default expiry",
+			url:  "https://aws.amazonaws.com/bucket/test/test.jpg",
+		},
+		{
+			name:    "Negative expiry",
+			url:     "https://aws.amazonaws.com/bucket/test/test.jpg",
+			expires: -time.Minute,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid url",
+			url:     "test",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &PresignDownloadInput{Url: tt.url, Expires: tt.expires}
+			err := i.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestPresignDownloadInput_ToS3Input(t *testing.T) {
+	i := &PresignDownloadInput{Url: "https://aws.s3/test.bucket/test/test.jpg"}
+	got, err := i.ToS3Input(testBucket)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &s3.GetObjectInput{Bucket: aws.String("test.bucket"), Key: aws.String("test/test.jpg")}, got)
+
+	i.ContentType = "image/png"
+	got, err = i.ToS3Input(testBucket)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "image/png", aws.StringValue(got.ResponseContentType))
+}
+
+func TestPresignUploadInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *PresignUploadInput
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			input: &PresignUploadInput{
+				Directory:   "test",
+				Filename:    "test.jpg",
+				ACL:         "public-read",
+				ContentType: "image/jpeg",
+				Expires:     time.Minute,
+			},
+		},
+		{
+			name:  "Only filename",
+			input: &PresignUploadInput{Filename: "test.jpg"},
+		},
+		{
+			name:    "Empty filename",
+			input:   &PresignUploadInput{Directory: "test"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid ACL",
+			input:   &PresignUploadInput{Filename: "test.jpg", ACL: "test"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid content type",
+			input:   &PresignUploadInput{Filename: "test.jpg", ContentType: "image/"},
+			wantErr: true,
+		},
+		{
+			name:    "Negative expiry",
+			input:   &PresignUploadInput{Filename: "test.jpg", Expires: -time.Minute},
+			wantErr: true,
+		},
+		{
+			name:    "Negative size",
+			input:   &PresignUploadInput{Filename: "test.jpg", Size: -1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestPresignUploadInput_ToS3Input(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *PresignUploadInput
+		want  *s3.PutObjectInput
+	}{
+		{
+			name: "All fields",
+			input: &PresignUploadInput{
+				Directory:   "test",
+				Filename:    "test.jpg",
+				ACL:         "public-read",
+				ContentType: "image/jpeg",
+				Size:        1024,
+			},
+			want: &s3.PutObjectInput{
+				Bucket:        aws.String("test.bucket"),
+				Key:           aws.String("test/test.jpg"),
+				ACL:           aws.String("public-read"),
+				ContentType:   aws.String("image/jpeg"),
+				ContentLength: aws.Int64(1024),
+			},
+		},
+		{
+			name:  "Only filename",
+			input: &PresignUploadInput{Filename: "test.jpg"},
+			want: &s3.PutObjectInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test.jpg"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, tt.input.ToS3Input("test.bucket"))
+		})
+	}
+}
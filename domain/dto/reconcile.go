@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+// MinReconcileGracePeriod protects files being written, because they are stored before they are recorded
+const MinReconcileGracePeriod = time.Minute
+
+type (
+	// ReconcileInput describes search of orphans, stored files which keys start with Prefix
+	// and nothing refers to. Files are live if their urls are in LiveUrls or, when LiveUrls is empty,
+	// if they are recorded to the catalog. Files modified in GracePeriod are never orphans.
+	// Orphans are only reported unless Delete is set, deletion requires Prefix, because files
+	// stored before the catalog was enabled or by presigned urls aren't recorded
+	ReconcileInput struct {
+		Prefix      string
+		LiveUrls    []string
+		GracePeriod time.Duration
+		Delete      bool
+	}
+
+	// ReconcileReport summarizes reconciliation, Deleted may be less than number of Orphans
+	// if a file was written while reconciliation was running
+	ReconcileReport struct {
+		Scanned     int
+		Orphans     []string
+		OrphansSize int64
+		Deleted     int
+	}
+)
+
+func (i *ReconcileInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Prefix, validation.When(i.Delete, validation.Required.Error("is required to delete orphans"))),
+		validation.Field(&i.LiveUrls, validation.Each(is.URL)),
+		validation.Field(&i.GracePeriod, validation.Required, validation.Min(MinReconcileGracePeriod)),
+	)
+}
+
+// LiveSet returns set of LiveUrls, nil is returned if LiveUrls is empty
+func (i *ReconcileInput) LiveSet() map[string]bool {
+	if len(i.LiveUrls) == 0 {
+		return nil
+	}
+	live := make(map[string]bool, len(i.LiveUrls))
+	for _, url := range i.LiveUrls {
+		live[url] = true
+	}
+	return live
+}
@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *ReconcileInput
+		wantErr bool
+	}{
+		{
+			name:  "Valid",
+			input: &ReconcileInput{Prefix: "test/", LiveUrls: []string{"http://localhost/test.jpg"}, GracePeriod: time.Hour},
+		},
+		{
+			name:    "Too short grace period",
+			input:   &ReconcileInput{GracePeriod: time.Second},
+			wantErr: true,
+		},
+		{
+			name:    "Empty grace period",
+			input:   &ReconcileInput{},
+			wantErr: true,
+		},
+		{
+			name:  "Delete",
+			input: &ReconcileInput{Prefix: "test/", GracePeriod: time.Hour, Delete: true},
+		},
+		{
+			name:    "Delete without prefix",
+			input:   &ReconcileInput{GracePeriod: time.Hour, Delete: true},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid live url",
+			input:   &ReconcileInput{LiveUrls: []string{"test"}, GracePeriod: time.Hour},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestReconcileInput_LiveSet(t *testing.T) {
+	assert.Nil(t, (&ReconcileInput{}).LiveSet())
+	assert.EqualValues(t, map[string]bool{"http://localhost/test.jpg": true},
+		(&ReconcileInput{LiveUrls: []string{"http://localhost/test.jpg"}}).LiveSet())
+}
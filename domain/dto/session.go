@@ -0,0 +1,184 @@
+package dto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+const (
+	// MinPartSize is required by S3 for all parts except the last one
+	MinPartSize = 5 << 20
+	// MaxPartSize limits memory used to buffer a part, S3 requires its length in advance
+	MaxPartSize = 64 << 20
+	// MaxPartNumber is the max number of parts of S3 multipart upload
+	MaxPartNumber = 10000
+)
+
+type (
+	// InitiateUploadInput starts upload session of the file uploaded by parts
+	InitiateUploadInput struct {
+		Directory string
+		Filename  string
+		ACL       string
+		// ContentType is taken from the filename extension if it's not set
+		ContentType string
+	}
+
+	// UploadSessionInput refers to upload session by its id
+	UploadSessionInput struct {
+		UploadId string
+	}
+
+	UploadPartInput struct {
+		UploadId   string
+		PartNumber int64
+		File       io.Reader
+		// MD5 is optional hex checksum of the part verified by S3
+		MD5 string
+	}
+
+	// UploadSession is a state of the file uploaded by parts, it is kept by storage
+	UploadSession struct {
+		UploadId string
+		Key      string
+		Url      string
+		Parts    []*UploadPart
+	}
+
+	// UploadPart is a part received by storage
+	UploadPart struct {
+		PartNumber int64
+		Size       int64
+		ETag       string
+	}
+)
+
+// NewUploadId joins the key and id of S3 multipart upload,
+// so sessions don't need to be stored anywhere except S3
+func NewUploadId(key, multipartId string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(multipartId + "\n" + key))
+}
+
+// ParseUploadId returns the key and id of S3 multipart upload joined by NewUploadId
+func ParseUploadId(uploadId string) (key, multipartId string, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(uploadId)
+	if err != nil {
+		return "", "", customErrors.InvalidUploadId
+	}
+	parts := strings.SplitN(string(decoded), "\n", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", customErrors.InvalidUploadId
+	}
+	return parts[1], parts[0], nil
+}
+
+var isUploadId = validation.By(func(value interface{}) error {
+	if s, _ := value.(string); s != "" {
+		if _, _, err := ParseUploadId(s); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+func (i *InitiateUploadInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Filename, validation.Required),
+		validation.Field(&i.ACL, validation.In(CannedACLs...)),
+		validation.Field(&i.ContentType, isMediaType),
+	)
+}
+
+func (i *InitiateUploadInput) Key() string {
+	return ObjectKey(i.Directory, i.Filename)
+}
+
+func (i *InitiateUploadInput) ToS3Input(bucketName string) *s3.CreateMultipartUploadInput {
+	s3Input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(i.Key()),
+	}
+	if i.ACL != "" {
+		s3Input.ACL = aws.String(i.ACL)
+	}
+	if i.ContentType != "" {
+		s3Input.ContentType = aws.String(i.ContentType)
+	}
+	return s3Input
+}
+
+func (i *UploadSessionInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.UploadId, validation.Required, isUploadId),
+	)
+}
+
+func (i *UploadPartInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.UploadId, validation.Required, isUploadId),
+		validation.Field(&i.PartNumber, validation.Required, validation.Min(1), validation.Max(MaxPartNumber)),
+		validation.Field(&i.File, validation.Required),
+		validation.Field(&i.MD5, is.Hexadecimal, validation.Length(32, 32)),
+	)
+}
+
+// ToS3Input builds request of the part with buffered body
+func (i *UploadPartInput) ToS3Input(bucketName string, body io.ReadSeeker) (*s3.UploadPartInput, error) {
+	key, multipartId, err := ParseUploadId(i.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	s3Input := &s3.UploadPartInput{
+		Bucket:     aws.String(bucketName),
+		Key:        aws.String(key),
+		UploadId:   aws.String(multipartId),
+		PartNumber: aws.Int64(i.PartNumber),
+		Body:       body,
+	}
+	if sum, err := hex.DecodeString(i.MD5); err == nil && len(sum) > 0 {
+		s3Input.ContentMD5 = aws.String(base64.StdEncoding.EncodeToString(sum))
+	}
+	return s3Input, nil
+}
+
+// Size returns total size of received parts
+func (s *UploadSession) Size() int64 {
+	var size int64
+	for _, part := range s.Parts {
+		size += part.Size
+	}
+	return size
+}
+
+// ToS3Input builds request completing the upload with all received parts
+func (s *UploadSession) ToS3Input(bucketName string) (*s3.CompleteMultipartUploadInput, error) {
+	key, multipartId, err := ParseUploadId(s.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]*s3.CompletedPart, len(s.Parts))
+	for i, part := range s.Parts {
+		parts[i] = &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.PartNumber),
+		}
+	}
+	return &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(key),
+		UploadId:        aws.String(multipartId),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}, nil
+}
@@ -0,0 +1,276 @@
+package dto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+func TestParseUploadId(t *testing.T) {
+	tests := []struct {
+		name            string
+		uploadId        string
+		wantKey         string
+		wantMultipartId string
+		wantErr         error
+	}{
+		{
+			name:            "Valid",
+			uploadId:        NewUploadId("test/my file.jpg", "multipart-id"),
+			wantKey:         "test/my file.jpg",
+			wantMultipartId: "multipart-id",
+		},
+		{
+			name:     "Not base64",
+			uploadId: "test!",
+			wantErr:  customErrors.InvalidUploadId,
+		},
+		{
+			name:     "Without key",
+			uploadId: NewUploadId("", "multipart-id"),
+			wantErr:  customErrors.InvalidUploadId,
+		},
+		{
+			name:     "Without separator",
+			uploadId: "dGVzdA",
+			wantErr:  customErrors.InvalidUploadId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, multipartId, err := ParseUploadId(tt.uploadId)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.wantKey, key)
+			assert.EqualValues(t, tt.wantMultipartId, multipartId)
+		})
+	}
+}
+
+func TestInitiateUploadInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *InitiateUploadInput
+		wantErr bool
+	}{
+		{
+			name:  "Valid",
+			input: &InitiateUploadInput{Directory: "test", Filename: "test.jpg", ACL: "private", ContentType: "image/jpeg"},
+		},
+		{
+			name:    "Empty filename",
+			input:   &InitiateUploadInput{Directory: "test"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid ACL",
+			input:   &InitiateUploadInput{Filename: "test.jpg", ACL: "test"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid content type",
+			input:   &InitiateUploadInput{Filename: "test.jpg", ContentType: "image/"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestInitiateUploadInput_ToS3Input(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *InitiateUploadInput
+		want  *s3.CreateMultipartUploadInput
+	}{
+		{
+			name:  "Valid",
+			input: &InitiateUploadInput{Directory: "test", Filename: "test.jpg", ACL: "private", ContentType: "image/jpeg"},
+			want: &s3.CreateMultipartUploadInput{
+				Bucket:      aws.String("test.bucket"),
+				Key:         aws.String("test/test.jpg"),
+				ACL:         aws.String("private"),
+				ContentType: aws.String("image/jpeg"),
+			},
+		},
+		{
+			name:  "Without optional fields",
+			input: &InitiateUploadInput{Filename: "test.jpg"},
+			want: &s3.CreateMultipartUploadInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test.jpg"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, tt.input.ToS3Input("test.bucket"))
+		})
+	}
+}
+
+func TestUploadSessionInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   *UploadSessionInput
+		wantErr bool
+	}{
+		{
+			name:  "Valid",
+			input: &UploadSessionInput{UploadId: NewUploadId("test.jpg", "multipart-id")},
+		},
+		{
+			name:    "Empty upload id",
+			input:   &UploadSessionInput{},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid upload id",
+			input:   &UploadSessionInput{UploadId: "test"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestUploadPartInput_Validate(t *testing.T) {
+	uploadId := NewUploadId("test.jpg", "multipart-id")
+	tests := []struct {
+		name    string
+		input   *UploadPartInput
+		wantErr bool
+	}{
+		{
+			name:  "Valid",
+			input: &UploadPartInput{UploadId: uploadId, PartNumber: 1, File: strings.NewReader("test"), MD5: testMD5},
+		},
+		{
+			name:    "Invalid upload id",
+			input:   &UploadPartInput{UploadId: "test", PartNumber: 1, File: strings.NewReader("test")},
+			wantErr: true,
+		},
+		{
+			name:    "Empty part number",
+			input:   &UploadPartInput{UploadId: uploadId, File: strings.NewReader("test")},
+			wantErr: true,
+		},
+		{
+			name:    "Too big part number",
+			input:   &UploadPartInput{UploadId: uploadId, PartNumber: MaxPartNumber + 1, File: strings.NewReader("test")},
+			wantErr: true,
+		},
+		{
+			name:    "Empty file",
+			input:   &UploadPartInput{UploadId: uploadId, PartNumber: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid MD5",
+			input:   &UploadPartInput{UploadId: uploadId, PartNumber: 1, File: strings.NewReader("test"), MD5: "test"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestUploadPartInput_ToS3Input(t *testing.T) {
+	body := strings.NewReader("test")
+	tests := []struct {
+		name    string
+		input   *UploadPartInput
+		want    *s3.UploadPartInput
+		wantErr error
+	}{
+		{
+			name:  "Valid",
+			input: &UploadPartInput{UploadId: NewUploadId("test/test.jpg", "multipart-id"), PartNumber: 2, MD5: testMD5},
+			want: &s3.UploadPartInput{
+				Bucket:     aws.String("test.bucket"),
+				Key:        aws.String("test/test.jpg"),
+				UploadId:   aws.String("multipart-id"),
+				PartNumber: aws.Int64(2),
+				Body:       body,
+				ContentMD5: aws.String("CY9rzUYh03PK3k6DJie09g=="),
+			},
+		},
+		{
+			name:    "Invalid upload id",
+			input:   &UploadPartInput{UploadId: "test", PartNumber: 1},
+			wantErr: customErrors.InvalidUploadId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.input.ToS3Input("test.bucket", body)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestUploadSession_Size(t *testing.T) {
+	session := &UploadSession{Parts: []*UploadPart{{Size: MinPartSize}, {Size: 4}}}
+	assert.EqualValues(t, MinPartSize+4, session.Size())
+	assert.EqualValues(t, 0, new(UploadSession).Size())
+}
+
+func TestUploadSession_ToS3Input(t *testing.T) {
+	tests := []struct {
+		name    string
+		session *UploadSession
+		want    *s3.CompleteMultipartUploadInput
+		wantErr error
+	}{
+		{
+			name: "Valid",
+			session: &UploadSession{
+				UploadId: NewUploadId("test/test.jpg", "multipart-id"),
+				Parts: []*UploadPart{
+					{PartNumber: 1, Size: MinPartSize, ETag: `"1"`},
+					{PartNumber: 2, Size: 4, ETag: `"2"`},
+				},
+			},
+			want: &s3.CompleteMultipartUploadInput{
+				Bucket:   aws.String("test.bucket"),
+				Key:      aws.String("test/test.jpg"),
+				UploadId: aws.String("multipart-id"),
+				MultipartUpload: &s3.CompletedMultipartUpload{
+					Parts: []*s3.CompletedPart{
+						{ETag: aws.String(`"1"`), PartNumber: aws.Int64(1)},
+						{ETag: aws.String(`"2"`), PartNumber: aws.Int64(2)},
+					},
+				},
+			},
+		},
+		{
+			name:    "Invalid upload id",
+			session: &UploadSession{UploadId: "test"},
+			wantErr: customErrors.InvalidUploadId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.session.ToS3Input("test.bucket")
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
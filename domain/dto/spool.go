@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+type (
+	// SpoolReader copies the data read through it to a temporary file, so it can be read again
+	// after Rewind, e.g. when the upload is retried with another key. Close removes the file
+	SpoolReader struct {
+		reader io.Reader
+		file   *os.File
+		size   int64
+		offset int64
+	}
+)
+
+func NewSpoolReader(reader io.Reader) (*SpoolReader, error) {
+	file, err := ioutil.TempFile("", "spool-")
+	if err != nil {
+		return nil, err
+	}
+	return &SpoolReader{reader: reader, file: file}, nil
+}
+
+// Read returns spooled data first and then continues reading the source
+func (r *SpoolReader) Read(p []byte) (int, error) {
+	if r.offset < r.size {
+		if rest := r.size - r.offset; int64(len(p)) > rest {
+			p = p[:rest]
+		}
+		n, err := r.file.ReadAt(p, r.offset)
+		r.offset += int64(n)
+		if err == io.EOF && n > 0 {
+			err = nil
+		}
+		return n, err
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if _, writeErr := r.file.WriteAt(p[:n], r.size); writeErr != nil {
+			return 0, writeErr
+		}
+		r.size += int64(n)
+		r.offset = r.size
+	}
+	return n, err
+}
+
+// Rewind makes the next Read start from the beginning of the data
+func (r *SpoolReader) Rewind() {
+	r.offset = 0
+}
+
+func (r *SpoolReader) Close() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(r.file.Name())
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpoolReader(t *testing.T) {
+	spool, err := NewSpoolReader(strings.NewReader("test content"))
+	assert.NoError(t, err)
+
+	// Partially read data is returned again and the rest is read from the source
+	head := make([]byte, 4)
+	n, err := spool.Read(head)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test", string(head[:n]))
+	spool.Rewind()
+	content, err := ioutil.ReadAll(spool)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test content", string(content))
+
+	spool.Rewind()
+	content, err = ioutil.ReadAll(spool)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test content", string(content))
+
+	name := spool.file.Name()
+	assert.NoError(t, spool.Close())
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type (
+	StatInput struct {
+		Url string
+	}
+
+	// FileInfo describes stored file without its content
+	FileInfo struct {
+		Key          string
+		Url          string
+		Size         int64
+		ContentType  string
+		ETag         string
+		LastModified time.Time
+		Metadata     map[string]string
+	}
+)
+
+func (i *StatInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Url, validation.Required, is.URL),
+	)
+}
+
+func (i *StatInput) ToS3Input(bucket S3Bucket) (*s3.HeadObjectInput, error) {
+	key, err := bucket.Key(i.Url)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.HeadObjectInput{
+		Bucket: aws.String(bucket.Name),
+		Key:    aws.String(key),
+	}, nil
+}
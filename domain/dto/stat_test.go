@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+func TestStatInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name: "Valid url",
+			url:  "https://aws.amazonaws.com/bucket/test/test.jpg",
+		},
+		{
+			name:    "Empty url",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid url",
+			url:     "test",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &StatInput{Url: tt.url}
+			err := i.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestStatInput_ToS3Input(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    *s3.HeadObjectInput
+		wantErr error
+	}{
+		{
+			name: "Valid",
+			url:  "https://aws.s3/test.bucket/test/test.jpg",
+			want: &s3.HeadObjectInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test/test.jpg"),
+			},
+		},
+		{
+			name:    "Wrong bucket",
+			url:     "https://aws.s3/other.bucket/test/test.jpg",
+			wantErr: customErrors.InvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &StatInput{Url: tt.url}
+			got, err := i.ToS3Input(testBucket)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package dto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+// trashIdLength is length of hex id made of deletion time in nanoseconds and 4 random bytes
+const trashIdLength = 24
+
+// TrashACLMetadataKey keeps ACL of deleted file in metadata of the trash item, so it's restored with it
+const TrashACLMetadataKey = "Trash-Acl"
+
+type (
+	// TrashItem is deleted file kept in trash until it expires, Url refers the item in trash
+	// and Key is the original key of the file
+	TrashItem struct {
+		Url       string
+		Key       string
+		Size      int64
+		DeletedAt time.Time
+		ExpiresAt time.Time
+	}
+
+	// RestoreInput refers item in trash
+	RestoreInput struct {
+		Url string
+	}
+
+	// ListTrashInput describes page of trash items which original keys start with Prefix,
+	// zero PageSize means MaxListPageSize
+	ListTrashInput struct {
+		Prefix    string
+		PageSize  int64
+		PageToken string
+	}
+
+	// ListTrashOutput contains page of trash items sorted by original keys and deletion time,
+	// NextPageToken is empty on the last page
+	ListTrashOutput struct {
+		Items         []*TrashItem
+		NextPageToken string
+	}
+)
+
+// TrashKey returns key of the file in trash, files deleted several times are kept
+// in the directory named by the original key
+func TrashKey(prefix, key string, deletedAt time.Time) (string, error) {
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("%016x%s", deletedAt.UnixNano(), hex.EncodeToString(random))
+	return path.Join(prefix, key, id), nil
+}
+
+// ParseTrashKey returns original key and deletion time of the file in trash
+func ParseTrashKey(prefix, trashKey string) (string, time.Time, error) {
+	if !IsTrashKey(prefix, trashKey) {
+		return "", time.Time{}, customErrors.NotInTrash
+	}
+	key, id := path.Split(strings.TrimPrefix(path.Clean("/"+trashKey), path.Clean("/"+prefix)+"/"))
+	key = strings.TrimSuffix(key, "/")
+	if key == "" || len(id) != trashIdLength {
+		return "", time.Time{}, customErrors.NotInTrash
+	}
+	nanos, err := strconv.ParseUint(id[:16], 16, 64)
+	if err != nil {
+		return "", time.Time{}, customErrors.NotInTrash
+	}
+	return key, time.Unix(0, int64(nanos)), nil
+}
+
+// IsTrashKey reports whether the key is inside trash directory with the prefix
+func IsTrashKey(prefix, key string) bool {
+	return inDirectory(prefix, key)
+}
+
+func (i *RestoreInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Url, validation.Required, is.URL),
+	)
+}
+
+func (i *ListTrashInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.PageSize, validation.Min(0), validation.Max(MaxListPageSize)),
+	)
+}
+
+// ToListInput returns input listing trash directory with the prefix
+func (i *ListTrashInput) ToListInput(trashPrefix string) *ListInput {
+	return &ListInput{
+		Prefix:    strings.TrimPrefix(path.Clean("/"+trashPrefix), "/") + "/" + i.Prefix,
+		PageSize:  i.PageSize,
+		PageToken: i.PageToken,
+	}
+}
+
+// TrashMetadata returns metadata of the file with its ACL, it's stored to the trash item.
+// Empty ACL isn't stored, because it can't be restored
+func TrashMetadata(metadata map[string]string, acl string) map[string]string {
+	trashed := make(map[string]string, len(metadata)+1)
+	for key, value := range metadata {
+		trashed[key] = value
+	}
+	if acl != "" {
+		trashed[TrashACLMetadataKey] = acl
+	}
+	return trashed
+}
+
+// RestoredMetadata splits metadata of the trash item into metadata and ACL of the restored file,
+// ACL defaults to private, so the file isn't exposed if its ACL is unknown
+func RestoredMetadata(trashed map[string]string) (map[string]string, string) {
+	metadata := make(map[string]string, len(trashed))
+	acl := s3.ObjectCannedACLPrivate
+	for key, value := range trashed {
+		if key != TrashACLMetadataKey {
+			metadata[key] = value
+		} else if validation.Validate(value, validation.In(CannedACLs...)) == nil {
+			acl = value
+		}
+	}
+	return metadata, acl
+}
@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+func TestTrashKey(t *testing.T) {
+	deletedAt := time.Unix(1600000000, 123)
+	trashKey, err := TrashKey("_trash", "docs/test.txt", deletedAt)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^_trash/docs/test.txt/[0-9a-f]{24}$`, trashKey)
+
+	key, gotDeletedAt, err := ParseTrashKey("_trash", trashKey)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "docs/test.txt", key)
+	assert.True(t, deletedAt.Equal(gotDeletedAt))
+
+	other, err := TrashKey("_trash", "docs/test.txt", deletedAt)
+	assert.NoError(t, err)
+	assert.NotEqual(t, trashKey, other)
+}
+
+func TestParseTrashKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		trashKey string
+		wantKey  string
+		wantErr  error
+	}{
+		{name: "Valid", trashKey: "_trash/test.txt/00000000000000010000abcd", wantKey: "test.txt"},
+		{name: "Outside trash", trashKey: "docs/test.txt/00000000000000010000abcd", wantErr: customErrors.NotInTrash},
+		{name: "Without key", trashKey: "_trash/00000000000000010000abcd", wantErr: customErrors.NotInTrash},
+		{name: "Invalid id", trashKey: "_trash/test.txt/1", wantErr: customErrors.NotInTrash},
+		{name: "Not hex id", trashKey: "_trash/test.txt/zzzzzzzzzzzzzzzz0000abcd", wantErr: customErrors.NotInTrash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _, err := ParseTrashKey("_trash", tt.trashKey)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.wantKey, key)
+		})
+	}
+}
+
+func TestListTrashInput_ToListInput(t *testing.T) {
+	input := &ListTrashInput{Prefix: "docs/", PageSize: 10, PageToken: "token"}
+	assert.EqualValues(t, &ListInput{Prefix: "_trash/docs/", PageSize: 10, PageToken: "token"}, input.ToListInput("/_trash/"))
+	assert.NoError(t, input.Validate())
+	assert.Error(t, (&ListTrashInput{PageSize: MaxListPageSize + 1}).Validate())
+}
+
+func TestRestoreInput_Validate(t *testing.T) {
+	assert.NoError(t, (&RestoreInput{Url: "http://localhost/_trash/test.txt/00000000000000010000abcd"}).Validate())
+	assert.Error(t, (&RestoreInput{}).Validate())
+}
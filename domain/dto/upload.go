@@ -1,12 +1,24 @@
 package dto
 
 import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
+	"net/http"
 	"path"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/google/uuid"
 )
 
 type (
@@ -15,31 +27,221 @@ type (
 		Directory string
 		Filename  string
 		ACL       string
+		// ContentType is detected by DetectContentType if it's not set explicitly
+		ContentType string
+		// SHA256 and MD5 are hex checksums declared by client, they are verified
+		// after upload and stored as metadata of the file
+		SHA256 string
+		MD5    string
+		// Owner is recorded to the catalog of files
+		Owner string
+		// Collision is a strategy applied when the key is taken, defaults to the strategy
+		// of directory policy or CollisionOverwrite
+		Collision string
+		// Exclusive makes storage fail with AlreadyExists instead of overwriting existing file,
+		// it is checked by storage, so concurrent uploads can't overwrite each other
+		Exclusive bool
+		// OriginalFilename is stored as metadata when the filename is generated
+		OriginalFilename string
 	}
+
+	// UploadOutput refers the written file, VersionId is empty
+	// if versioning is disabled or isn't supported by storage
+	UploadOutput struct {
+		Url       string
+		VersionId string
+	}
+)
+
+// sniffLen is the max number of bytes used by http.DetectContentType
+const sniffLen = 512
+
+// DefaultContentType is used when content type can't be detected
+const DefaultContentType = "application/octet-stream"
+
+// MaxOwnerLength limits owner of the file recorded to the catalog
+const MaxOwnerLength = 255
+
+// Collision strategies of upload
+const (
+	CollisionOverwrite = "overwrite"
+	// CollisionFail fails upload with AlreadyExists
+	CollisionFail = "fail"
+	// CollisionRename appends the first free number to the filename, e.g. "test-1.jpg"
+	CollisionRename = "rename"
+	// CollisionUnique replaces the filename with UUID keeping its extension
+	CollisionUnique = "unique"
 )
 
+// FilenameMetadataKey keeps original filename of files uploaded with generated names
+const FilenameMetadataKey = "Filename"
+
+// MaxRenameAttempts limits numbers tried by CollisionRename
+const MaxRenameAttempts = 100
+
+var Collisions = []interface{}{CollisionOverwrite, CollisionFail, CollisionRename, CollisionUnique}
+
+// DefaultACL is applied to uploaded files when neither request nor policy sets ACL
+const DefaultACL = "public-read"
+
+// BlobACL is applied to deduplicated blobs, because a blob is shared by all files with the same content.
+// Urls of deduplicated files have no objects behind them, so the files can't be public and BlobACL is their default
+const BlobACL = "private"
+
+// CannedACLs are ACLs supported by S3
+var CannedACLs = []interface{}{
+	"private",
+	"public-read",
+	"public-read-write",
+	"aws-exec-read",
+	"authenticated-read",
+	"bucket-owner-read",
+	"bucket-owner-full-control",
+	"log-delivery-write",
+}
+
+var isMediaType = validation.By(func(value interface{}) error {
+	if s, _ := value.(string); s != "" {
+		if _, _, err := mime.ParseMediaType(s); err != nil {
+			return errors.New("must be a valid media type")
+		}
+	}
+	return nil
+})
+
 func (i *UploadInput) Validate() error {
 	return validation.ValidateStruct(
 		i,
 		validation.Field(&i.Filename, validation.Required),
 		validation.Field(&i.File, validation.Required),
-		validation.Field(&i.ACL, validation.In(
-			"public-read",
-			"public-read-write",
-			"aws-exec-read",
-			"authenticated-read",
-			"bucket-owner-read",
-			"bucket-owner-full-control",
-			"log-delivery-write",
-		)),
+		validation.Field(&i.ACL, validation.In(CannedACLs...)),
+		validation.Field(&i.ContentType, isMediaType),
+		validation.Field(&i.SHA256, is.Hexadecimal, validation.Length(sha256.Size*2, sha256.Size*2)),
+		validation.Field(&i.MD5, is.Hexadecimal, validation.Length(md5.Size*2, md5.Size*2)),
+		validation.Field(&i.Owner, validation.Length(0, MaxOwnerLength)),
+		validation.Field(&i.Collision, validation.In(Collisions...)),
 	)
 }
 
+// HasChecksum reports whether client declared any checksum of the file
+func (i *UploadInput) HasChecksum() bool {
+	return i.SHA256 != "" || i.MD5 != ""
+}
+
+// TrackChecksum makes File compute its checksums while it's uploaded. Only declared checksums are stored
+// with the file, because metadata is sent before the file is read, computed ones are kept by the service
+func (i *UploadInput) TrackChecksum() *ChecksumReader {
+	checksum := NewChecksumReader(i.File)
+	i.File = checksum
+	return checksum
+}
+
+// Metadata returns metadata declared by client or nil if there is nothing to store
+func (i *UploadInput) Metadata() map[string]string {
+	if !i.HasChecksum() && i.OriginalFilename == "" {
+		return nil
+	}
+	metadata := make(map[string]string)
+	if i.OriginalFilename != "" {
+		// Metadata is sent in headers, so non-ASCII names are encoded the way S3 does it
+		metadata[FilenameMetadataKey] = mime.QEncoding.Encode("utf-8", i.OriginalFilename)
+	}
+	if i.SHA256 != "" {
+		metadata[SHA256MetadataKey] = strings.ToLower(i.SHA256)
+	}
+	if i.MD5 != "" {
+		metadata[MD5MetadataKey] = strings.ToLower(i.MD5)
+	}
+	return metadata
+}
+
+// DetectContentType sets content type by the first bytes of the file without reading it,
+// content type is taken from the filename extension if content is not recognized
+func (i *UploadInput) DetectContentType() error {
+	if i.ContentType != "" {
+		return nil
+	}
+	reader := bufio.NewReaderSize(i.File, sniffLen)
+	i.File = reader
+	head, err := reader.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	i.ContentType = detectContentType(head, i.Filename)
+	return nil
+}
+
+// detectContentType prefers filename extension for generic types,
+// e.g. css, js and svg files are detected as plain text or xml
+func detectContentType(head []byte, filename string) string {
+	contentType := http.DetectContentType(head)
+	isGeneric := contentType == DefaultContentType ||
+		strings.HasPrefix(contentType, "text/plain") ||
+		strings.HasPrefix(contentType, "text/xml")
+	if isGeneric {
+		if extContentType := ContentTypeByExtension(filename); extContentType != DefaultContentType {
+			return extContentType
+		}
+	}
+	return contentType
+}
+
+// ContentTypeByExtension returns content type by the filename extension or DefaultContentType
+func ContentTypeByExtension(filename string) string {
+	if contentType := mime.TypeByExtension(path.Ext(filename)); contentType != "" {
+		return contentType
+	}
+	return DefaultContentType
+}
+
+func (i *UploadInput) Key() string {
+	return ObjectKey(i.Directory, i.Filename)
+}
+
+// ObjectKey joins directory and filename into clean key without leading slash,
+// so "/docs/a.pdf" and "docs/./a.pdf" are stored and matched by policies as "docs/a.pdf"
+func ObjectKey(directory, filename string) string {
+	return strings.TrimLeft(path.Join(directory, filename), "/")
+}
+
+// RenamedFilename returns filename with the number appended before its extension,
+// zero number means the filename itself
+func RenamedFilename(filename string, number int) string {
+	if number == 0 {
+		return filename
+	}
+	ext := path.Ext(filename)
+	if ext == filename {
+		// Hidden files like ".env" have no extension
+		ext = ""
+	}
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), number, ext)
+}
+
+// UniqueFilename returns random UUID with extension of the filename
+func UniqueFilename(filename string) string {
+	return uuid.New().String() + path.Ext(filename)
+}
+
 func (i *UploadInput) ToS3Input(bucketName string) *s3manager.UploadInput {
-	return &s3manager.UploadInput{
+	s3Input := &s3manager.UploadInput{
 		Body:   i.File,
-		Key:    aws.String(path.Join(i.Directory, i.Filename)),
+		Key:    aws.String(i.Key()),
 		Bucket: aws.String(bucketName),
-		ACL:    aws.String(i.ACL),
 	}
+	if i.ACL != "" {
+		s3Input.ACL = aws.String(i.ACL)
+	}
+	if i.ContentType != "" {
+		s3Input.ContentType = aws.String(i.ContentType)
+	}
+	if metadata := i.Metadata(); metadata != nil {
+		s3Input.Metadata = aws.StringMap(metadata)
+	}
+	// S3 verifies Content-MD5 of single part uploads, parts of multipart uploads
+	// are verified by the SDK, and the whole file is verified by ChecksumReader
+	if sum, err := hex.DecodeString(i.MD5); err == nil && len(sum) > 0 {
+		s3Input.ContentMD5 = aws.String(base64.StdEncoding.EncodeToString(sum))
+	}
+	return s3Input
 }
@@ -2,6 +2,7 @@ package dto
 
 import (
 	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 
@@ -12,10 +13,13 @@ import (
 
 func TestUploadInput_ToS3Input(t *testing.T) {
 	type fields struct {
-		File      io.Reader
-		Directory string
-		Filename  string
-		ACL       string
+		File        io.Reader
+		Directory   string
+		Filename    string
+		ACL         string
+		ContentType string
+		SHA256      string
+		MD5         string
 	}
 	tests := []struct {
 		name       string
@@ -55,14 +59,61 @@ func TestUploadInput_ToS3Input(t *testing.T) {
 				ACL:    aws.String("public-read"),
 			},
 		},
+		{
+			name:       "Empty ACL",
+			bucketName: "test.bucket",
+			fields: fields{
+				Filename: "test.jpg",
+			},
+			want: &s3manager.UploadInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test.jpg"),
+			},
+		},
+		{
+			name:       "Checksums",
+			bucketName: "test.bucket",
+			fields: fields{
+				Filename: "test.jpg",
+				SHA256:   testSHA256,
+				MD5:      strings.ToUpper(testMD5),
+			},
+			want: &s3manager.UploadInput{
+				Bucket: aws.String("test.bucket"),
+				Key:    aws.String("test.jpg"),
+				Metadata: aws.StringMap(map[string]string{
+					SHA256MetadataKey: testSHA256,
+					MD5MetadataKey:    testMD5,
+				}),
+				ContentMD5: aws.String("CY9rzUYh03PK3k6DJie09g=="),
+			},
+		},
+		{
+			name:       "Content type",
+			bucketName: "test.bucket",
+			fields: fields{
+				Filename:    "test.jpg",
+				ACL:         "public-read",
+				ContentType: "image/jpeg",
+			},
+			want: &s3manager.UploadInput{
+				Bucket:      aws.String("test.bucket"),
+				Key:         aws.String("test.jpg"),
+				ACL:         aws.String("public-read"),
+				ContentType: aws.String("image/jpeg"),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			i := &UploadInput{
-				File:      tt.fields.File,
-				Directory: tt.fields.Directory,
-				Filename:  tt.fields.Filename,
-				ACL:       tt.fields.ACL,
+				File:        tt.fields.File,
+				Directory:   tt.fields.Directory,
+				Filename:    tt.fields.Filename,
+				ACL:         tt.fields.ACL,
+				ContentType: tt.fields.ContentType,
+				SHA256:      tt.fields.SHA256,
+				MD5:         tt.fields.MD5,
 			}
 			got := i.ToS3Input(tt.bucketName)
 			assert.EqualValues(t, tt.want, got)
@@ -72,10 +123,13 @@ func TestUploadInput_ToS3Input(t *testing.T) {
 
 func TestUploadInput_Validate(t *testing.T) {
 	type fields struct {
-		File      io.Reader
-		Directory string
-		Filename  string
-		ACL       string
+		File        io.Reader
+		Directory   string
+		Filename    string
+		ACL         string
+		ContentType string
+		SHA256      string
+		MD5         string
 	}
 	tests := []struct {
 		name    string
@@ -101,6 +155,15 @@ func TestUploadInput_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Private",
+			fields: fields{
+				File:     strings.NewReader("test"),
+				Filename: "test.pdf",
+				ACL:      "private",
+			},
+			wantErr: false,
+		},
 		{
 			name: "Empty file",
 			fields: fields{
@@ -130,17 +193,177 @@ func TestUploadInput_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Invalid content type",
+			fields: fields{
+				File:        strings.NewReader("test"),
+				Filename:    "test.jpg",
+				ContentType: "image/",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Checksums",
+			fields: fields{
+				File:     strings.NewReader("test"),
+				Filename: "test.jpg",
+				SHA256:   testSHA256,
+				MD5:      testMD5,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid SHA256",
+			fields: fields{
+				File:     strings.NewReader("test"),
+				Filename: "test.jpg",
+				SHA256:   testMD5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid MD5",
+			fields: fields{
+				File:     strings.NewReader("test"),
+				Filename: "test.jpg",
+				MD5:      "test",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			i := &UploadInput{
-				File:      tt.fields.File,
-				Directory: tt.fields.Directory,
-				Filename:  tt.fields.Filename,
-				ACL:       tt.fields.ACL,
+				File:        tt.fields.File,
+				Directory:   tt.fields.Directory,
+				Filename:    tt.fields.Filename,
+				ACL:         tt.fields.ACL,
+				ContentType: tt.fields.ContentType,
+				SHA256:      tt.fields.SHA256,
+				MD5:         tt.fields.MD5,
 			}
 			err := i.Validate()
 			assert.EqualValues(t, tt.wantErr, err != nil, err)
 		})
 	}
 }
+
+func TestUploadInput_DetectContentType(t *testing.T) {
+	png := "\x89PNG\x0D\x0A\x1A\x0A" + strings.Repeat("0", 1000)
+	tests := []struct {
+		name        string
+		content     string
+		filename    string
+		contentType string
+		want        string
+	}{
+		{
+			name:     "Sniffed",
+			content:  png,
+			filename: "test.jpg",
+			want:     "image/png",
+		},
+		{
+			name:     "Extension of text file",
+			content:  "body { color: red; }",
+			filename: "test.css",
+			want:     "text/css; charset=utf-8",
+		},
+		{
+			name:     "Unknown extension",
+			content:  "test",
+			filename: "test",
+			want:     "text/plain; charset=utf-8",
+		},
+		{
+			name:     "Unknown binary",
+			content:  "\x00\x01\x02",
+			filename: "test",
+			want:     DefaultContentType,
+		},
+		{
+			name:     "Empty file",
+			filename: "test.jpg",
+			want:     "image/jpeg",
+		},
+		{
+			name:        "Explicit",
+			content:     png,
+			filename:    "test.png",
+			contentType: "application/x-custom",
+			want:        "application/x-custom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &UploadInput{
+				File:        strings.NewReader(tt.content),
+				Filename:    tt.filename,
+				ContentType: tt.contentType,
+			}
+			assert.NoError(t, i.DetectContentType())
+			assert.EqualValues(t, tt.want, i.ContentType)
+			content, err := ioutil.ReadAll(i.File)
+			assert.NoError(t, err)
+			assert.EqualValues(t, tt.content, string(content))
+		})
+	}
+}
+
+func TestUploadInput_Collision(t *testing.T) {
+	input := &UploadInput{File: strings.NewReader("test"), Filename: "test.jpg", Collision: CollisionRename}
+	assert.NoError(t, input.Validate())
+	input.Collision = "skip"
+	assert.Error(t, input.Validate())
+}
+
+func TestUploadInput_Metadata_OriginalFilename(t *testing.T) {
+	input := &UploadInput{Filename: "test.jpg", OriginalFilename: "photo.jpg"}
+	assert.EqualValues(t, map[string]string{FilenameMetadataKey: "photo.jpg"}, input.Metadata())
+	input.OriginalFilename = "фото.jpg"
+	assert.EqualValues(t, map[string]string{FilenameMetadataKey: "=?utf-8?q?=D1=84=D0=BE=D1=82=D0=BE.jpg?="}, input.Metadata())
+}
+
+func TestObjectKey(t *testing.T) {
+	tests := []struct {
+		directory string
+		filename  string
+		want      string
+	}{
+		{directory: "", filename: "test.jpg", want: "test.jpg"},
+		{directory: "docs", filename: "test.jpg", want: "docs/test.jpg"},
+		{directory: "/docs", filename: "test.jpg", want: "docs/test.jpg"},
+		{directory: "//docs/./", filename: "test.jpg", want: "docs/test.jpg"},
+		{directory: "tmp/../docs", filename: "test.jpg", want: "docs/test.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.directory, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, ObjectKey(tt.directory, tt.filename))
+		})
+	}
+}
+
+func TestRenamedFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		number   int
+		want     string
+	}{
+		{filename: "test.jpg", number: 0, want: "test.jpg"},
+		{filename: "test.jpg", number: 1, want: "test-1.jpg"},
+		{filename: "archive.tar.gz", number: 2, want: "archive.tar-2.gz"},
+		{filename: "test", number: 3, want: "test-3"},
+		{filename: ".env", number: 1, want: ".env-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, RenamedFilename(tt.filename, tt.number))
+		})
+	}
+}
+
+func TestUniqueFilename(t *testing.T) {
+	first, second := UniqueFilename("test.jpg"), UniqueFilename("test.jpg")
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.jpg$`, first)
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"net/url"
+	"strings"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+type (
+	// S3Bucket locates objects of the bucket by their urls. BaseURL is the url of the bucket built
+	// by the configured client, e.g. https://bucket.s3.amazonaws.com or https://localhost:9000/bucket
+	// for path-style urls, so urls are parsed the same way as they are built
+	S3Bucket struct {
+		Name    string
+		BaseURL string
+	}
+)
+
+// Key extracts object key from url of the object in the bucket
+func (b S3Bucket) Key(rawURL string) (string, error) {
+	return KeyFromURL(rawURL, b.BaseURL)
+}
+
+// KeyFromURL extracts key from url that was built by joining baseURL and escaped key
+func KeyFromURL(rawURL, baseURL string) (string, error) {
+	prefix := strings.TrimSuffix(baseURL, "/") + "/"
+	if !strings.HasPrefix(rawURL, prefix) || len(rawURL) == len(prefix) {
+		return "", customErrors.InvalidURL
+	}
+	key, err := url.PathUnescape(strings.TrimPrefix(rawURL, prefix))
+	if err != nil {
+		return "", customErrors.InvalidURL
+	}
+	return key, nil
+}
@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testBucket is path-style bucket of urls used by tests
+var testBucket = S3Bucket{Name: "test.bucket", BaseURL: "https://aws.s3/test.bucket"}
+
+func TestKeyFromURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Valid",
+			baseURL: "http://localhost/files",
+			url:     "http://localhost/files/test/test.jpg",
+			want:    "test/test.jpg",
+		},
+		{
+			name:    "Trailing slash in base url",
+			baseURL: "http://localhost/files/",
+			url:     "http://localhost/files/test/test.jpg",
+			want:    "test/test.jpg",
+		},
+		{
+			name:    "Escaped path",
+			baseURL: "http://localhost/files",
+			url:     "http://localhost/files/test/my%20file.jpg",
+			want:    "test/my file.jpg",
+		},
+		{
+			name:    "Wrong base url",
+			baseURL: "http://localhost/files",
+			url:     "http://localhost/other/test.jpg",
+			wantErr: true,
+		},
+		{
+			name:    "Empty key",
+			baseURL: "http://localhost/files",
+			url:     "http://localhost/files/",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := KeyFromURL(tt.url, tt.baseURL)
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package dto
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+type (
+	// ListVersionsInput describes page of versions of the file,
+	// zero PageSize means MaxListPageSize
+	ListVersionsInput struct {
+		Url       string
+		PageSize  int64
+		PageToken string
+	}
+
+	// FileVersion describes version of the file, delete markers are versions
+	// written by deletion of the file in versioned bucket, they have no content
+	FileVersion struct {
+		VersionId      string
+		Size           int64
+		ETag           string
+		LastModified   time.Time
+		IsLatest       bool
+		IsDeleteMarker bool
+	}
+
+	// ListVersionsOutput contains page of versions sorted from the newest one,
+	// NextPageToken is empty on the last page
+	ListVersionsOutput struct {
+		Versions      []*FileVersion
+		NextPageToken string
+	}
+
+	// GetVersionInput describes part of specific version of the file to download
+	GetVersionInput struct {
+		DownloadInput
+		VersionId string
+	}
+
+	// RestoreVersionInput refers version of the file which is copied over the latest one,
+	// ACL is applied to the restored version, because S3 doesn't copy ACL of objects
+	RestoreVersionInput struct {
+		Url       string
+		VersionId string
+		ACL       string
+	}
+)
+
+func (i *ListVersionsInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Url, validation.Required, is.URL),
+		validation.Field(&i.PageSize, validation.Min(0), validation.Max(MaxListPageSize)),
+	)
+}
+
+func (i *ListVersionsInput) Limit() int64 {
+	if i.PageSize == 0 {
+		return MaxListPageSize
+	}
+	return i.PageSize
+}
+
+// ToS3Input lists versions of all keys starting with the key of the file,
+// versions of other keys have to be skipped. Page token is the version id marker
+func (i *ListVersionsInput) ToS3Input(bucket S3Bucket) (*s3.ListObjectVersionsInput, error) {
+	key, err := bucket.Key(i.Url)
+	if err != nil {
+		return nil, err
+	}
+	s3Input := &s3.ListObjectVersionsInput{
+		Bucket:  aws.String(bucket.Name),
+		Prefix:  aws.String(key),
+		MaxKeys: aws.Int64(i.Limit()),
+	}
+	if i.PageToken != "" {
+		s3Input.KeyMarker = aws.String(key)
+		s3Input.VersionIdMarker = aws.String(i.PageToken)
+	}
+	return s3Input, nil
+}
+
+func (i *GetVersionInput) Validate() error {
+	if err := i.DownloadInput.Validate(); err != nil {
+		return err
+	}
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.VersionId, validation.Required),
+	)
+}
+
+func (i *GetVersionInput) ToS3Input(bucket S3Bucket) (*s3.GetObjectInput, error) {
+	s3Input, err := i.DownloadInput.ToS3Input(bucket)
+	if err != nil {
+		return nil, err
+	}
+	s3Input.VersionId = aws.String(i.VersionId)
+	return s3Input, nil
+}
+
+func (i *RestoreVersionInput) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Url, validation.Required, is.URL),
+		validation.Field(&i.VersionId, validation.Required),
+		validation.Field(&i.ACL, validation.In(CannedACLs...)),
+	)
+}
+
+// ToS3Input copies the version over the same key, so that it becomes the latest version
+func (i *RestoreVersionInput) ToS3Input(bucket S3Bucket) (*s3.CopyObjectInput, error) {
+	key, err := bucket.Key(i.Url)
+	if err != nil {
+		return nil, err
+	}
+	s3Input := &s3.CopyObjectInput{
+		Bucket:     aws.String(bucket.Name),
+		Key:        aws.String(key),
+		CopySource: aws.String(CopySource(bucket.Name, key) + "?versionId=" + url.QueryEscape(i.VersionId)),
+	}
+	if i.ACL != "" {
+		s3Input.ACL = aws.String(i.ACL)
+	}
+	return s3Input, nil
+}
+
+// HeadInput returns input to check that the version exists and isn't a delete marker
+func (i *RestoreVersionInput) HeadInput(bucket S3Bucket) (*s3.HeadObjectInput, error) {
+	key, err := bucket.Key(i.Url)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.HeadObjectInput{
+		Bucket:    aws.String(bucket.Name),
+		Key:       aws.String(key),
+		VersionId: aws.String(i.VersionId),
+	}, nil
+}
@@ -0,0 +1,121 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+func TestVersionInputs_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   interface{ Validate() error }
+		wantErr bool
+	}{
+		{
+			name:  "Valid list",
+			input: &ListVersionsInput{Url: "https://aws.s3/test.bucket/test.jpg", PageSize: 10},
+		},
+		{
+			name:    "List with too big page",
+			input:   &ListVersionsInput{Url: "https://aws.s3/test.bucket/test.jpg", PageSize: MaxListPageSize + 1},
+			wantErr: true,
+		},
+		{
+			name:  "Valid get",
+			input: &GetVersionInput{DownloadInput: DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"}, VersionId: "v1"},
+		},
+		{
+			name:    "Get with invalid range",
+			input:   &GetVersionInput{DownloadInput: DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg", Offset: -1}, VersionId: "v1"},
+			wantErr: true,
+		},
+		{
+			name:    "Get without version",
+			input:   &GetVersionInput{DownloadInput: DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"}},
+			wantErr: true,
+		},
+		{
+			name:  "Valid restore",
+			input: &RestoreVersionInput{Url: "https://aws.s3/test.bucket/test.jpg", VersionId: "v1", ACL: "private"},
+		},
+		{
+			name:    "Restore with invalid ACL",
+			input:   &RestoreVersionInput{Url: "https://aws.s3/test.bucket/test.jpg", VersionId: "v1", ACL: "test"},
+			wantErr: true,
+		},
+		{
+			name:    "Restore without url",
+			input:   &RestoreVersionInput{VersionId: "v1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.EqualValues(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestListVersionsInput_ToS3Input(t *testing.T) {
+	got, err := (&ListVersionsInput{Url: "https://aws.s3/test.bucket/test.jpg"}).ToS3Input(testBucket)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &s3.ListObjectVersionsInput{
+		Bucket:  aws.String("test.bucket"),
+		Prefix:  aws.String("test.jpg"),
+		MaxKeys: aws.Int64(MaxListPageSize),
+	}, got)
+
+	got, err = (&ListVersionsInput{Url: "https://aws.s3/test.bucket/test.jpg", PageSize: 10, PageToken: "v1"}).ToS3Input(testBucket)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &s3.ListObjectVersionsInput{
+		Bucket:          aws.String("test.bucket"),
+		Prefix:          aws.String("test.jpg"),
+		MaxKeys:         aws.Int64(10),
+		KeyMarker:       aws.String("test.jpg"),
+		VersionIdMarker: aws.String("v1"),
+	}, got)
+
+	_, err = (&ListVersionsInput{Url: "https://aws.s3/other/test.jpg"}).ToS3Input(testBucket)
+	assert.EqualValues(t, customErrors.InvalidURL, err)
+}
+
+func TestGetVersionInput_ToS3Input(t *testing.T) {
+	input := &GetVersionInput{
+		DownloadInput: DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg", Offset: 1, Length: 2},
+		VersionId:     "v1",
+	}
+	got, err := input.ToS3Input(testBucket)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &s3.GetObjectInput{
+		Bucket:    aws.String("test.bucket"),
+		Key:       aws.String("test.jpg"),
+		Range:     aws.String("bytes=1-2"),
+		VersionId: aws.String("v1"),
+	}, got)
+}
+
+func TestRestoreVersionInput_ToS3Input(t *testing.T) {
+	input := &RestoreVersionInput{Url: "https://aws.s3/test.bucket/my%20file.jpg", VersionId: "a+b", ACL: "private"}
+	got, err := input.ToS3Input(testBucket)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &s3.CopyObjectInput{
+		Bucket:     aws.String("test.bucket"),
+		Key:        aws.String("my file.jpg"),
+		CopySource: aws.String("test.bucket/my%20file.jpg?versionId=a%2Bb"),
+		ACL:        aws.String("private"),
+	}, got)
+
+	head, err := input.HeadInput(testBucket)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &s3.HeadObjectInput{
+		Bucket:    aws.String("test.bucket"),
+		Key:       aws.String("my file.jpg"),
+		VersionId: aws.String("a+b"),
+	}, head)
+}
@@ -1,7 +1,51 @@
 package customErrors
 
-import validation "github.com/go-ozzo/ozzo-validation/v4"
+import (
+	"errors"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
 
 var (
 	InvalidURL = validation.NewError("400", "url: invalid format")
+	InvalidKey = validation.NewError("400", "key: invalid format")
+	// ReservedKey is returned for keys inside directory of deduplicated blobs
+	ReservedKey = validation.NewError("400", "key: reserved")
+	SameFile    = validation.NewError("400", "destination: must differ from source")
+	NotInTrash  = validation.NewError("400", "url: not in trash")
+
+	InvalidUploadId = validation.NewError("400", "upload id: invalid format")
+	EmptyUpload     = validation.NewError("400", "upload: no parts received")
+	PartTooSmall    = validation.NewError("400", "part: too small")
+	// SizeRequired is returned for presigned uploads to directories limiting size of files
+	SizeRequired = validation.NewError("400", "size: required by policy")
+
+	ExtensionNotAllowed   = validation.NewError("400", "extension: not allowed")
+	ContentTypeNotAllowed = validation.NewError("400", "content type: not allowed")
+	ACLNotAllowed         = validation.NewError("400", "acl: not allowed")
+
+	// LiveUrlsRequired is returned by reconciliation when there is no catalog to find live files in
+	LiveUrlsRequired = validation.NewError("400", "live urls: required when catalog is disabled")
+
+	NotFound       = validation.NewError("404", "file: not found")
+	UploadNotFound = validation.NewError("404", "upload: not found")
+	// VersionNotFound is also returned for delete markers, because they have no content
+	VersionNotFound = validation.NewError("404", "version: not found")
+
+	AlreadyExists = validation.NewError("409", "file: already exists")
+
+	TooLarge     = validation.NewError("413", "file: too large")
+	PartTooLarge = validation.NewError("413", "part: too large")
+
+	InvalidRange = validation.NewError("416", "range: not satisfiable")
 )
+
+// NotSupported is returned when operation cannot be performed by configured storage
+var NotSupported = errors.New("operation is not supported by storage")
+
+// ChecksumMismatch is returned when stored file differs from the file declared by client
+var ChecksumMismatch = errors.New("file: checksum mismatch")
+
+// DedupClaimed is returned when directory of blobs is claimed by another instance,
+// references of blobs are counted by the embedded database of a single instance
+var DedupClaimed = errors.New("deduplication: blobs are claimed by another instance")
@@ -1,6 +1,8 @@
 package customErrors
 
 import (
+	"errors"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 )
@@ -19,3 +21,14 @@ func BadRequestDetails(errors *validation.Errors) *errdetails.BadRequest {
 	}
 	return details
 }
+
+// Is reports whether err is the target, validation errors aren't comparable,
+// so they are matched by code and message
+func Is(err, target error) bool {
+	errObj, ok := err.(validation.Error)
+	targetObj, isTargetObj := target.(validation.Error)
+	if ok && isTargetObj {
+		return errObj.Code() == targetObj.Code() && errObj.Message() == targetObj.Message()
+	}
+	return errors.Is(err, target)
+}
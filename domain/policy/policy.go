@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+type (
+	// Rule restricts files stored under Prefix, empty fields don't restrict anything.
+	// ContentTypes may contain wildcards like "image/*".
+	// ACL is applied when request doesn't set it, ACLs are allowed to be requested.
+	// Collision is a strategy applied when request doesn't set it
+	Rule struct {
+		Prefix       string
+		MaxSize      int64
+		ContentTypes []string
+		Extensions   []string
+		ACL          string
+		ACLs         []string
+		Collision    string
+	}
+
+	// Rules are matched by the longest prefix
+	Rules []Rule
+
+	// LimitedReader fails with customErrors.TooLarge as soon as more than limit bytes are read
+	LimitedReader struct {
+		reader   io.Reader
+		limit    int64
+		read     int64
+		exceeded bool
+	}
+)
+
+// Match returns rule with the longest prefix matching the key or nil
+func (r Rules) Match(key string) *Rule {
+	var match *Rule
+	for i := range r {
+		rule := &r[i]
+		if strings.HasPrefix(key, rule.Prefix) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// Check validates filename extension and content type of the file
+func (r *Rule) Check(filename, contentType string) error {
+	errs := validation.Errors{}
+	if len(r.Extensions) > 0 && !r.allowsExtension(path.Ext(filename)) {
+		errs["Filename"] = customErrors.ExtensionNotAllowed
+	}
+	if len(r.ContentTypes) > 0 && !r.allowsContentType(contentType) {
+		errs["ContentType"] = customErrors.ContentTypeNotAllowed
+	}
+	return errs.Filter()
+}
+
+// CheckSize validates size of the file
+func (r *Rule) CheckSize(size int64) error {
+	if r.MaxSize > 0 && size > r.MaxSize {
+		return validation.Errors{"File": customErrors.TooLarge}
+	}
+	return nil
+}
+
+// DefaultACL returns ACL of the rule or the first allowed one if it's not set
+func (r *Rule) DefaultACL() string {
+	if r.ACL == "" && len(r.ACLs) > 0 {
+		return r.ACLs[0]
+	}
+	return r.ACL
+}
+
+// CheckACL validates requested ACL of the file
+func (r *Rule) CheckACL(acl string) error {
+	if len(r.ACLs) == 0 {
+		return nil
+	}
+	for _, allowed := range r.ACLs {
+		if allowed == acl {
+			return nil
+		}
+	}
+	return validation.Errors{"ACL": customErrors.ACLNotAllowed}
+}
+
+// LimitReader limits reader by max size of the rule
+func (r *Rule) LimitReader(reader io.Reader) *LimitedReader {
+	return &LimitedReader{reader: reader, limit: r.MaxSize}
+}
+
+// LimitRest limits reader by the size left by max size of the rule after received bytes,
+// it fails with customErrors.TooLarge at once if nothing is left
+func (r *Rule) LimitRest(reader io.Reader, received int64) (*LimitedReader, error) {
+	if r.MaxSize > 0 && received >= r.MaxSize {
+		return nil, validation.Errors{"File": customErrors.TooLarge}
+	}
+	return &LimitedReader{reader: reader, limit: r.MaxSize - received}, nil
+}
+
+// RestrictsContentTypes reports whether content type of the file has to be checked
+func (r *Rule) RestrictsContentTypes() bool {
+	return len(r.ContentTypes) > 0
+}
+
+func (r *Rule) allowsExtension(ext string) bool {
+	for _, allowed := range r.Extensions {
+		if strings.EqualFold(allowed, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) allowsContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range r.ContentTypes {
+		if allowed == mediaType || strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, allowed[:len(allowed)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LimitedReader) Read(p []byte) (int, error) {
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	if l.limit > 0 && l.read > l.limit {
+		l.exceeded = true
+		return 0, customErrors.TooLarge
+	}
+	return n, err
+}
+
+// Exceeded reports whether reading was stopped because of the limit
+func (l *LimitedReader) Exceeded() bool {
+	return l.exceeded
+}
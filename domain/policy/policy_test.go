@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+func TestRules_Match(t *testing.T) {
+	rules := Rules{
+		{Prefix: ""},
+		{Prefix: "avatars/"},
+		{Prefix: "avatars/large/"},
+	}
+	tests := []struct {
+		name       string
+		rules      Rules
+		key        string
+		wantPrefix string
+		wantNil    bool
+	}{
+		{
+			name:       "Default rule",
+			rules:      rules,
+			key:        "test.jpg",
+			wantPrefix: "",
+		},
+		{
+			name:       "Directory",
+			rules:      rules,
+			key:        "avatars/test.jpg",
+			wantPrefix: "avatars/",
+		},
+		{
+			name:       "Longest prefix",
+			rules:      rules,
+			key:        "avatars/large/test.jpg",
+			wantPrefix: "avatars/large/",
+		},
+		{
+			name:    "No match",
+			rules:   rules[1:],
+			key:     "test.jpg",
+			wantNil: true,
+		},
+		{
+			name:    "No rules",
+			key:     "test.jpg",
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rules.Match(tt.key)
+			assert.EqualValues(t, tt.wantNil, got == nil)
+			if got != nil {
+				assert.EqualValues(t, tt.wantPrefix, got.Prefix)
+			}
+		})
+	}
+}
+
+func TestRule_Check(t *testing.T) {
+	rule := &Rule{
+		ContentTypes: []string{"image/*", "application/pdf"},
+		Extensions:   []string{".jpg", ".pdf"},
+	}
+	tests := []struct {
+		name        string
+		rule        *Rule
+		filename    string
+		contentType string
+		wantErr     error
+	}{
+		{
+			name:        "Allowed",
+			rule:        rule,
+			filename:    "test.JPG",
+			contentType: "image/jpeg",
+		},
+		{
+			name:        "Allowed with parameters",
+			rule:        rule,
+			filename:    "test.pdf",
+			contentType: "application/pdf; charset=utf-8",
+		},
+		{
+			name:        "Not allowed extension",
+			rule:        rule,
+			filename:    "test.exe",
+			contentType: "image/jpeg",
+			wantErr:     validation.Errors{"Filename": customErrors.ExtensionNotAllowed},
+		},
+		{
+			name:        "Not allowed content type",
+			rule:        rule,
+			filename:    "test.jpg",
+			contentType: "application/x-msdownload",
+			wantErr:     validation.Errors{"ContentType": customErrors.ContentTypeNotAllowed},
+		},
+		{
+			name:     "Empty content type",
+			rule:     rule,
+			filename: "test.jpg",
+			wantErr:  validation.Errors{"ContentType": customErrors.ContentTypeNotAllowed},
+		},
+		{
+			name:        "Prefix of content type",
+			rule:        &Rule{ContentTypes: []string{"image/*"}},
+			filename:    "test",
+			contentType: "imagex/test",
+			wantErr:     validation.Errors{"ContentType": customErrors.ContentTypeNotAllowed},
+		},
+		{
+			name:        "No restrictions",
+			rule:        &Rule{},
+			filename:    "test.exe",
+			contentType: "application/x-msdownload",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.wantErr, tt.rule.Check(tt.filename, tt.contentType))
+		})
+	}
+}
+
+func TestRule_CheckSize(t *testing.T) {
+	rule := &Rule{MaxSize: 4}
+	assert.NoError(t, rule.CheckSize(4))
+	assert.EqualValues(t, validation.Errors{"File": customErrors.TooLarge}, rule.CheckSize(5))
+	assert.NoError(t, (&Rule{}).CheckSize(5))
+}
+
+func TestRule_DefaultACL(t *testing.T) {
+	assert.EqualValues(t, "", (&Rule{}).DefaultACL())
+	assert.EqualValues(t, "private", (&Rule{ACL: "private"}).DefaultACL())
+	assert.EqualValues(t, "bucket-owner-read", (&Rule{ACLs: []string{"bucket-owner-read", "private"}}).DefaultACL())
+	assert.EqualValues(t, "private", (&Rule{ACL: "private", ACLs: []string{"bucket-owner-read", "private"}}).DefaultACL())
+}
+
+func TestRule_CheckACL(t *testing.T) {
+	rule := &Rule{ACLs: []string{"private"}}
+	assert.NoError(t, rule.CheckACL("private"))
+	assert.EqualValues(t, validation.Errors{"ACL": customErrors.ACLNotAllowed}, rule.CheckACL("public-read"))
+	assert.NoError(t, (&Rule{}).CheckACL("public-read"))
+}
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxSize      int64
+		content      string
+		wantErr      error
+		wantExceeded bool
+	}{
+		{
+			name:    "Within limit",
+			maxSize: 4,
+			content: "test",
+		},
+		{
+			name:         "Exceeded",
+			maxSize:      3,
+			content:      "test",
+			wantErr:      customErrors.TooLarge,
+			wantExceeded: true,
+		},
+		{
+			name:    "No limit",
+			content: "test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := (&Rule{MaxSize: tt.maxSize}).LimitReader(strings.NewReader(tt.content))
+			_, err := ioutil.ReadAll(reader)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.wantExceeded, reader.Exceeded())
+		})
+	}
+}
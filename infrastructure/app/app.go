@@ -1,15 +1,24 @@
 package app
 
 import (
+	"context"
 	"reflect"
 	"time"
 
 	amqpStore "github.com/freemen-app/amqp-store"
 
+	boltRepo "github.com/freemen-app/file_storage/adapter/repository/bolt"
+	catalogRepo "github.com/freemen-app/file_storage/adapter/repository/catalog"
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
 	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	outboxRepo "github.com/freemen-app/file_storage/adapter/repository/outbox"
+	sessionRepo "github.com/freemen-app/file_storage/adapter/repository/session"
 	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/domain/policy"
+	"github.com/freemen-app/file_storage/infrastructure/events/publisher"
 	"github.com/freemen-app/file_storage/infrastructure/log"
 	awsSession "github.com/freemen-app/file_storage/infrastructure/store/aws"
+	boltStore "github.com/freemen-app/file_storage/infrastructure/store/bolt"
 
 	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
 )
@@ -23,14 +32,24 @@ type (
 
 	stores struct {
 		AMQP amqpStore.Store
+		// Bolt is opened only when a feature requires the embedded database
+		Bolt boltStore.Store
 	}
 
 	repos struct {
-		File fileUseCase.FileRepo
+		File     fileUseCase.FileRepo
+		Dedup    fileUseCase.DedupRepo
+		Catalog  fileUseCase.CatalogRepo
+		Outbox   fileUseCase.EventOutbox
+		Sessions fileUseCase.SessionRepo
 	}
 
 	useCases struct {
 		FileUseCase fileUseCase.UseCase
+		// EventRelay is set only if events are enabled
+		EventRelay fileUseCase.EventRelay
+		// Deduplicator is set only if deduplication is enabled
+		Deduplicator fileUseCase.Deduplicator
 	}
 
 	App struct {
@@ -49,12 +68,45 @@ func New(config *config.Config) *App {
 		panic(err)
 	}
 	log.ConfigureLogger(config.Logger.Level)
-	session := awsSession.New(config.S3)
 	stores := &stores{
 		AMQP: amqpStore.New(config.AMQP.DSN(), time.Second),
 	}
-	repos := &repos{File: fileRepo.New(session, config.S3.Bucket)}
-	useCases := &useCases{FileUseCase: fileUseCase.New(repos.File)}
+	if config.DatabaseRequired() {
+		stores.Bolt = boltStore.New(config.Database.Path)
+	}
+	repos := &repos{File: newFileRepo(config)}
+	fileOptions := []fileUseCase.Option{fileUseCase.WithPolicies(newPolicies(config.Upload.Policies))}
+	if stores.Bolt != nil {
+		fileOptions = append(fileOptions, fileUseCase.WithTransactions(boltRepo.NewTransactor(stores.Bolt.DB())))
+	}
+	if config.Upload.Sessions.Enabled {
+		repos.Sessions = sessionRepo.New(stores.Bolt.DB())
+		fileOptions = append(fileOptions, fileUseCase.WithSessions(repos.Sessions))
+	}
+	if config.Upload.Dedup.Enabled {
+		repos.Dedup = dedupRepo.New(stores.Bolt.DB())
+		fileOptions = append(fileOptions, fileUseCase.WithDeduplication(repos.Dedup, config.Upload.Dedup.Prefix))
+	}
+	if config.Catalog.Enabled {
+		repos.Catalog = catalogRepo.New(stores.Bolt.DB())
+		fileOptions = append(fileOptions, fileUseCase.WithCatalog(repos.Catalog))
+	}
+	if config.Trash.Enabled {
+		fileOptions = append(fileOptions, fileUseCase.WithTrash(config.Trash.Prefix, config.Trash.Retention))
+	}
+	if config.Events.Enabled {
+		repos.Outbox = outboxRepo.New(stores.Bolt.DB())
+		eventPublisher := publisher.New(stores.AMQP, config.FileEventsPublishConfig())
+		fileOptions = append(fileOptions, fileUseCase.WithEvents(repos.Outbox, eventPublisher))
+	}
+	useCase := fileUseCase.New(repos.File, fileOptions...)
+	useCases := &useCases{FileUseCase: useCase}
+	if config.Events.Enabled {
+		useCases.EventRelay = useCase
+	}
+	if config.Upload.Dedup.Enabled {
+		useCases.Deduplicator = useCase
+	}
 
 	return &App{
 		config:   config,
@@ -64,6 +116,33 @@ func New(config *config.Config) *App {
 	}
 }
 
+func newFileRepo(conf *config.Config) fileUseCase.FileRepo {
+	switch conf.Storage.Driver {
+	case config.StorageDriverLocal:
+		return fileRepo.NewLocal(conf.Storage.Local.Root, conf.Storage.Local.BaseURL)
+	case config.StorageDriverMemory:
+		return fileRepo.NewMemory(conf.Storage.Memory.BaseURL)
+	default:
+		return fileRepo.New(awsSession.New(conf.S3), conf.S3.Bucket, conf.S3.MaxPresignExpiry)
+	}
+}
+
+func newPolicies(policies []config.UploadPolicyConfig) policy.Rules {
+	rules := make(policy.Rules, len(policies))
+	for i, p := range policies {
+		rules[i] = policy.Rule{
+			Prefix:       p.Prefix,
+			MaxSize:      p.MaxSize,
+			ContentTypes: p.ContentTypes,
+			Extensions:   p.Extensions,
+			ACL:          p.ACL,
+			ACLs:         p.ACLs,
+			Collision:    p.Collision,
+		}
+	}
+	return rules
+}
+
 func (a *App) Stores() *stores {
 	return a.stores
 }
@@ -96,6 +175,13 @@ func (a *App) Start() error {
 		}
 	}
 
+	// Blobs must not be deduplicated by several instances, so the instance doesn't start if it can't claim them
+	if a.useCases.Deduplicator != nil {
+		if err := a.useCases.Deduplicator.ClaimBlobs(context.Background()); err != nil {
+			return err
+		}
+	}
+
 	a.isRunning = true
 	return nil
 }
@@ -1,13 +1,17 @@
 package app_test
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/freemen-app/file_storage/config"
 	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
 )
 
 var (
@@ -15,8 +19,16 @@ var (
 )
 
 func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "file_storage")
+	if err != nil {
+		panic(err)
+	}
 	conf = config.New(config.DefaultConfig)
-	os.Exit(m.Run())
+	// Default config doesn't require the database, features requiring it open it at the path
+	conf.Database.Path = filepath.Join(dir, "test.db")
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
 }
 
 func TestNew(t *testing.T) {
@@ -33,6 +45,198 @@ func TestNew(t *testing.T) {
 			fields:    fields{conf: conf},
 			wantPanic: false,
 		},
+		{
+			name: "local storage",
+			fields: fields{conf: func() *config.Config {
+				localConf := *conf
+				localConf.Storage.Driver = config.StorageDriverLocal
+				localConf.Storage.Local.Root = "/tmp/file_storage"
+				localConf.Storage.Local.BaseURL = "http://localhost/files"
+				return &localConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "memory storage",
+			fields: fields{conf: func() *config.Config {
+				memoryConf := *conf
+				memoryConf.Storage.Driver = config.StorageDriverMemory
+				memoryConf.Storage.Memory.BaseURL = "http://localhost/files"
+				return &memoryConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "local storage without root",
+			fields: fields{conf: func() *config.Config {
+				localConf := *conf
+				localConf.Storage.Driver = config.StorageDriverLocal
+				localConf.Storage.Local.Root = ""
+				return &localConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "upload policies",
+			fields: fields{conf: func() *config.Config {
+				policyConf := *conf
+				policyConf.Upload.Policies = []config.UploadPolicyConfig{
+					{Prefix: "avatars/", MaxSize: 1024, ContentTypes: []string{"image/*"}, Extensions: []string{".jpg"}},
+					{Prefix: "invoices/", ACL: "private", ACLs: []string{"private", "bucket-owner-read"}},
+				}
+				return &policyConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "invalid upload policy",
+			fields: fields{conf: func() *config.Config {
+				policyConf := *conf
+				policyConf.Upload.Policies = []config.UploadPolicyConfig{
+					{Prefix: "avatars/", ContentTypes: []string{"image"}},
+				}
+				return &policyConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "default acl is not allowed",
+			fields: fields{conf: func() *config.Config {
+				policyConf := *conf
+				policyConf.Upload.Policies = []config.UploadPolicyConfig{
+					{Prefix: "invoices/", ACL: "public-read", ACLs: []string{"private"}},
+				}
+				return &policyConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "too short upload session ttl",
+			fields: fields{conf: func() *config.Config {
+				sessionConf := *conf
+				sessionConf.Upload.Sessions.Enabled = true
+				sessionConf.Upload.Sessions.TTL = time.Minute
+				return &sessionConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "upload sessions",
+			fields: fields{conf: func() *config.Config {
+				sessionConf := *conf
+				sessionConf.Upload.Sessions.Enabled = true
+				sessionConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+				return &sessionConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "deduplication",
+			fields: fields{conf: func() *config.Config {
+				dedupConf := *conf
+				dedupConf.Upload.Dedup = config.UploadDedupConfig{Enabled: true, Prefix: "_blobs"}
+				dedupConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+				return &dedupConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "deduplication with public default acl",
+			fields: fields{conf: func() *config.Config {
+				dedupConf := *conf
+				dedupConf.Upload.Dedup = config.UploadDedupConfig{Enabled: true, Prefix: "_blobs"}
+				dedupConf.Upload.Policies = []config.UploadPolicyConfig{{Prefix: "avatars/", ACLs: []string{"public-read", "private"}}}
+				return &dedupConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "catalog",
+			fields: fields{conf: func() *config.Config {
+				catalogConf := *conf
+				catalogConf.Catalog.Enabled = true
+				catalogConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+				return &catalogConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "reconciliation without catalog",
+			fields: fields{conf: func() *config.Config {
+				reconcileConf := *conf
+				reconcileConf.Reconcile.Enabled = true
+				return &reconcileConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "too short reconciliation grace period",
+			fields: fields{conf: func() *config.Config {
+				reconcileConf := *conf
+				reconcileConf.Catalog.Enabled = true
+				reconcileConf.Reconcile.Enabled = true
+				reconcileConf.Reconcile.GracePeriod = time.Second
+				return &reconcileConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "trash",
+			fields: fields{conf: func() *config.Config {
+				trashConf := *conf
+				trashConf.Trash = config.TrashConfig{Enabled: true, Prefix: "_trash", Retention: time.Hour, PurgeInterval: time.Hour}
+				return &trashConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "trash inside directory of blobs",
+			fields: fields{conf: func() *config.Config {
+				trashConf := *conf
+				trashConf.Trash = config.TrashConfig{Enabled: true, Prefix: "_blobs/_trash", Retention: time.Hour, PurgeInterval: time.Hour}
+				trashConf.Upload.Dedup = config.UploadDedupConfig{Enabled: true, Prefix: "_blobs"}
+				return &trashConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "invalid deduplication prefix",
+			fields: fields{conf: func() *config.Config {
+				dedupConf := *conf
+				dedupConf.Upload.Dedup = config.UploadDedupConfig{Enabled: true, Prefix: "/_blobs/"}
+				return &dedupConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "events",
+			fields: fields{conf: func() *config.Config {
+				eventsConf := *conf
+				eventsConf.Events.Enabled = true
+				eventsConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+				return &eventsConf
+			}()},
+			wantPanic: false,
+		},
+		{
+			name: "events without publish config",
+			fields: fields{conf: func() *config.Config {
+				eventsConf := *conf
+				eventsConf.Events.Enabled = true
+				eventsConf.AMQP.Publishes = nil
+				return &eventsConf
+			}()},
+			wantPanic: true,
+		},
+		{
+			name: "max backoff shorter than backoff",
+			fields: fields{conf: func() *config.Config {
+				retryConf := *conf
+				retryConf.Retry.MaxBackoff = retryConf.Retry.Backoff / 2
+				return &retryConf
+			}()},
+			wantPanic: true,
+		},
 		{
 			name:      "invalid config",
 			fields:    fields{conf: &config.Config{}},
@@ -43,6 +247,7 @@ func TestNew(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			test := func() {
 				application := app.New(tt.fields.conf)
+				defer application.Shutdown()
 				assert.NotNil(t, application.Repos())
 				assert.NotNil(t, application.UseCases())
 			}
@@ -1,56 +1,84 @@
 package events
 
 import (
+	"fmt"
+
 	amqpStore "github.com/freemen-app/amqp-store"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
-	"github.com/mitchellh/mapstructure"
 	"github.com/rs/zerolog/log"
-	"github.com/streadway/amqp"
 
+	"github.com/freemen-app/file_storage/config"
 	"github.com/freemen-app/file_storage/infrastructure/app"
 )
 
 type (
-	consumes struct {
-		DeleteFiles *amqpStore.ConsumeConfig `mapstructure:"delete_files"`
+	// command is a handler subscribed to the queue of the consume config with the same name
+	command struct {
+		name    string
+		conf    *amqpStore.ConsumeConfig
+		handler Handler
+		retry   *retry
 	}
 
 	consumer struct {
-		store    amqpStore.Store
-		handler  *handler
-		consumes *consumes
+		store     amqpStore.Store
+		consumes  map[string]*amqpStore.ConsumeConfig
+		retryConf *config.RetryConfig
+		commands  []*command
 	}
 )
 
-func (c *consumes) Validate() error {
-	return validation.ValidateStruct(
-		c,
-		validation.Field(&c.DeleteFiles, validation.Required),
-	)
+// requireQueueName rejects server-named queues, because retried messages are routed back by queue name
+func requireQueueName(value interface{}) error {
+	conf, _ := value.(*amqpStore.ConsumeConfig)
+	if conf == nil {
+		return nil
+	}
+	return validation.Validate(conf.Exchange.Queue.Name, validation.Required.Error("queue name is required"))
 }
 
-func New(app *app.App, conf *amqpStore.Config) *consumer {
-	consumes := new(consumes)
-	if err := mapstructure.Decode(conf.Consumes, consumes); err != nil {
-		panic(err)
-	} else if err := consumes.Validate(); err != nil {
+func New(app *app.App, conf *amqpStore.Config, retryConf *config.RetryConfig) *consumer {
+	c := &consumer{
+		store:     app.Stores().AMQP,
+		consumes:  conf.Consumes,
+		retryConf: retryConf,
+	}
+	handler := &handler{fileUseCase: app.UseCases().FileUseCase}
+	if err := c.Register(DeleteFiles, handler.DeleteFiles); err != nil {
 		panic(err)
 	}
+	return c
+}
 
-	handler := &handler{fileUseCase: app.UseCases().FileUseCase}
-	return &consumer{
-		store:    app.Stores().AMQP,
-		handler:  handler,
-		consumes: consumes,
+// Register subscribes handler to the queue of the consume config named by the command on Start,
+// so it has to be called before Start. Every command requires its own queue
+func (c *consumer) Register(name string, handler Handler) error {
+	conf := c.consumes[name]
+	err := validation.Validate(conf, validation.Required.Error("consume config is required"), validation.By(requireQueueName))
+	if err != nil {
+		return fmt.Errorf("command %s: %w", name, err)
+	}
+	for _, command := range c.commands {
+		if command.name == name {
+			return fmt.Errorf("command %s: already registered", name)
+		} else if command.conf.Exchange.Queue.Name == conf.Exchange.Queue.Name {
+			return fmt.Errorf("command %s: queue %s is consumed by %s", name, conf.Exchange.Queue.Name, command.name)
+		}
 	}
+	c.commands = append(c.commands, &command{
+		name:    name,
+		conf:    conf,
+		handler: handler,
+		retry:   newRetry(c.store, conf.Exchange.Queue.Name, c.retryConf),
+	})
+	return nil
 }
 
 func (c *consumer) Start() error {
-	confHandlerMapping := map[*amqpStore.ConsumeConfig]func(delivery amqp.Delivery){
-		c.consumes.DeleteFiles: c.handler.DeleteFiles,
-	}
-	for conf, handler := range confHandlerMapping {
-		if err := c.store.Subscribe(conf, handler); err != nil {
+	for _, command := range c.commands {
+		if err := command.retry.Declare(c.store.DSN()); err != nil {
+			return err
+		} else if err := c.store.Subscribe(command.conf, command.dispatch); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,81 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	amqpStore "github.com/freemen-app/amqp-store"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/freemen-app/file_storage/infrastructure/events"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+var consumes = map[string]*amqpStore.ConsumeConfig{
+	events.DeleteFiles: consumeConfig("delete_files"),
+	"copy_files":       consumeConfig("copy_files"),
+	"move_files":       consumeConfig("delete_files"),
+	"purge_directory":  consumeConfig(""),
+}
+
+func consumeConfig(queue string) *amqpStore.ConsumeConfig {
+	return &amqpStore.ConsumeConfig{
+		Exchange: amqpStore.ExchangeConfig{
+			Name:  queue,
+			Type:  amqp.ExchangeFanout,
+			Queue: amqpStore.QueueConfig{Name: queue, Durable: true},
+		},
+	}
+}
+
+func TestConsumer_Register(t *testing.T) {
+	handler := func(context.Context, amqp.Delivery) error { return nil }
+	consumer := events.NewConsumer(new(mocks.Store), consumes, retryConf)
+	assert.NoError(t, consumer.Register(events.DeleteFiles, handler))
+
+	tests := []struct {
+		name           string
+		command        string
+		wantErr        string
+		wantRegistered bool
+	}{
+		{
+			name:           "succeed",
+			command:        "copy_files",
+			wantRegistered: true,
+		},
+		{
+			name:           "already registered",
+			command:        events.DeleteFiles,
+			wantErr:        "command delete_files: already registered",
+			wantRegistered: true,
+		},
+		{
+			name:    "without consume config",
+			command: "upload_files",
+			wantErr: "command upload_files: consume config is required",
+		},
+		{
+			name:    "server-named queue",
+			command: "purge_directory",
+			wantErr: "command purge_directory: queue name is required",
+		},
+		{
+			name:    "queue of other command",
+			command: "move_files",
+			wantErr: "command move_files: queue delete_files is consumed by delete_files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := consumer.Register(tt.command, handler)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.EqualValues(t, tt.wantRegistered, consumer.Dispatch(tt.command) != nil)
+		})
+	}
+}
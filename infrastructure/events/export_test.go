@@ -0,0 +1,50 @@
+package events
+
+import (
+	"time"
+
+	amqpStore "github.com/freemen-app/amqp-store"
+	"github.com/streadway/amqp"
+
+	"github.com/freemen-app/file_storage/config"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+type (
+	Consumer = consumer
+	Retry    = retry
+)
+
+func NewConsumer(store amqpStore.Store, consumes map[string]*amqpStore.ConsumeConfig, retryConf *config.RetryConfig) *Consumer {
+	return &consumer{store: store, consumes: consumes, retryConf: retryConf}
+}
+
+func NewHandler(useCase fileUseCase.UseCase) Handler {
+	return (&handler{fileUseCase: useCase}).DeleteFiles
+}
+
+func NewRetry(pubSub amqpStore.PubSub, queue string, conf *config.RetryConfig) *Retry {
+	return newRetry(pubSub, queue, conf)
+}
+
+// Dispatch returns the function subscribed to the queue of the command
+func (c *consumer) Dispatch(name string) func(delivery amqp.Delivery) {
+	for _, command := range c.commands {
+		if command.name == name {
+			return command.dispatch
+		}
+	}
+	return nil
+}
+
+func (r *retry) Attempts(headers amqp.Table) int {
+	return r.attempts(headers)
+}
+
+func (r *retry) Delay(attempt int) time.Duration {
+	return r.delay(attempt)
+}
+
+func (r *retry) Delays() []time.Duration {
+	return r.delays()
+}
@@ -1,10 +1,15 @@
 package events
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"mime"
+	"strconv"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/streadway/amqp"
 
@@ -12,33 +17,138 @@ import (
 	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
 )
 
+// DeleteFiles is the command deleting files by urls of JSON array or by deleteFilesMessage of version 2
+const DeleteFiles = "delete_files"
+
+// versionHeader selects schema of the message, version parameter of content type may be set instead,
+// e.g. "application/json; version=2". Otherwise version field of JSON object is taken, other messages have the version 1
+const versionHeader = "version"
+
 type (
+	// Handler handles message of the command, the message is acked if it succeeds, dead-lettered
+	// if it fails by validation error and retried otherwise. Use invalidMessage for messages which can't be decoded
+	Handler func(ctx context.Context, delivery amqp.Delivery) error
+
+	// deleteFilesMessage is the version 2 of delete_files message. RequestedBy, Reason and CorrelationId
+	// are added to logs and events of deleted files, correlation id of the message is taken unless it's set
+	deleteFilesMessage struct {
+		Version       int                  `json:"version"`
+		Urls          dto.BatchDeleteInput `json:"urls"`
+		Keys          []string             `json:"keys"`
+		RequestedBy   string               `json:"requested_by"`
+		Reason        string               `json:"reason"`
+		CorrelationId string               `json:"correlation_id"`
+	}
+
 	handler struct {
 		fileUseCase fileUseCase.UseCase
 	}
 )
 
-func (h *handler) DeleteFiles(delivery amqp.Delivery) {
-	ctx := context.Background()
-	var input dto.BatchDeleteInput
-	if err := json.Unmarshal(delivery.Body, &input); logError(err) != nil {
-		logError(delivery.Reject(false))
-		return
+func (h *handler) DeleteFiles(ctx context.Context, delivery amqp.Delivery) error {
+	version, err := messageVersion(delivery)
+	if err != nil {
+		return invalidMessage(err)
+	}
+	message := &deleteFilesMessage{Version: version, CorrelationId: delivery.CorrelationId}
+	switch version {
+	case 1:
+		err = json.Unmarshal(delivery.Body, &message.Urls)
+	case 2:
+		if err = json.Unmarshal(delivery.Body, message); err == nil && message.Version != version {
+			err = fmt.Errorf("version %d of the message doesn't match %d", message.Version, version)
+		}
+	default:
+		err = fmt.Errorf("unsupported version %d", version)
+	}
+	if err != nil {
+		return invalidMessage(err)
 	}
 
-	err := h.fileUseCase.BatchDelete(ctx, input)
-	logError(err)
+	logger := zerolog.Ctx(ctx)
+	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Int("version", message.Version).
+			Str("requested_by", message.RequestedBy).
+			Str("reason", message.Reason).
+			Str("correlation_id", message.CorrelationId)
+	})
+	ctx = dto.WithActor(ctx, message.RequestedBy)
+	ctx = dto.WithReason(ctx, message.Reason)
+	ctx = dto.WithCorrelationId(ctx, message.CorrelationId)
+	if version == 1 {
+		err = h.fileUseCase.BatchDelete(ctx, message.Urls)
+	} else {
+		err = h.fileUseCase.DeleteFiles(ctx, &dto.DeleteFilesInput{Urls: message.Urls, Keys: message.Keys})
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info().Msgf("deleted %d files", len(message.Urls)+len(message.Keys))
+	return nil
+}
+
+// dispatch handles the message with logger of the command in the context, handler may add fields to it
+func (c *command) dispatch(delivery amqp.Delivery) {
+	logger := log.With().Str("command", c.name).Logger()
+	ctx := logger.WithContext(context.Background())
+	err := c.handler(ctx, delivery)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Msg(err.Error())
+	}
 
 	switch err.(type) {
 	case nil:
-		logError(delivery.Ack(true))
+		logError(delivery.Ack(false))
 
-	case validation.Error:
-	case validation.Errors:
-		logError(delivery.Reject(false))
+	// Invalid messages can't succeed, so they aren't retried
+	case validation.Error, validation.Errors:
+		logError(c.retry.DeadLetter(delivery))
+	default:
+		logError(c.retry.Retry(delivery))
+	}
+}
+
+// messageVersion returns version of the message set by the header, content type or version field of the body
+func messageVersion(delivery amqp.Delivery) (int, error) {
+	switch version := delivery.Headers[versionHeader].(type) {
+	case nil:
+	case int16:
+		return int(version), nil
+	case int32:
+		return int(version), nil
+	case int64:
+		return int(version), nil
+	case string:
+		return strconv.Atoi(version)
 	default:
-		logError(delivery.Reject(true))
+		return 0, fmt.Errorf("invalid %s header %v", versionHeader, version)
 	}
+	if delivery.ContentType != "" {
+		_, params, err := mime.ParseMediaType(delivery.ContentType)
+		if err != nil {
+			return 0, err
+		} else if version, ok := params[versionHeader]; ok {
+			return strconv.Atoi(version)
+		}
+	}
+	return bodyVersion(delivery.Body), nil
+}
+
+// bodyVersion returns version field of JSON object, legacy arrays and objects without version have the version 1
+func bodyVersion(body []byte) int {
+	var envelope struct {
+		Version int `json:"version"`
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' || json.Unmarshal(body, &envelope) != nil || envelope.Version == 0 {
+		return 1
+	}
+	return envelope.Version
+}
+
+// invalidMessage converts error of decoding message to validation error, so the message is dead-lettered
+func invalidMessage(err error) error {
+	return validation.NewError("invalid_message", err.Error())
 }
 
 func logError(err error) error {
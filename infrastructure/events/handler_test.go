@@ -0,0 +1,254 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqpStore "github.com/freemen-app/amqp-store"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/events"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+func TestHandler_DeleteFiles(t *testing.T) {
+	body := []byte(`["https://aws.s3/bucket/test.jpg"]`)
+	input := dto.BatchDeleteInput{"https://aws.s3/bucket/test.jpg"}
+	retryConfig := func(delay string) *amqpStore.PublishConfig {
+		return &amqpStore.PublishConfig{Exchange: amqpStore.ExchangeConfig{
+			Name:       "delete_files.retry",
+			Type:       amqp.ExchangeDirect,
+			Durable:    true,
+			RoutingKey: "delete_files.retry." + delay,
+		}}
+	}
+	deadLetterConfig := &amqpStore.PublishConfig{Exchange: amqpStore.ExchangeConfig{
+		Name:    "delete_files.dead",
+		Type:    amqp.ExchangeFanout,
+		Durable: true,
+	}}
+	exhausted := deaths(amqp.Table{"queue": "delete_files.retry.5s", "reason": "expired", "count": int64(4)})
+	tests := []struct {
+		name         string
+		body         []byte
+		headers      amqp.Table
+		useCaseCalls mocks.Calls
+		pubSubCalls  mocks.Calls
+		ackCalls     mocks.Calls
+	}{
+		{
+			name:         "succeed",
+			body:         body,
+			useCaseCalls: mocks.Calls{{Method: "BatchDelete", Args: []interface{}{mock.Anything, input}, ReturnArgs: []interface{}{nil}}},
+			ackCalls:     mocks.Calls{{Method: "Ack", Args: []interface{}{uint64(1), false}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:        "invalid message",
+			body:        []byte(`{`),
+			pubSubCalls: mocks.Calls{{Method: "Publish", Args: []interface{}{deadLetterConfig, mock.Anything}, ReturnArgs: []interface{}{nil}}},
+			ackCalls:    mocks.Calls{{Method: "Ack", Args: []interface{}{uint64(1), false}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:         "invalid urls",
+			body:         body,
+			useCaseCalls: mocks.Calls{{Method: "BatchDelete", Args: []interface{}{mock.Anything, input}, ReturnArgs: []interface{}{customErrors.NotFound}}},
+			pubSubCalls:  mocks.Calls{{Method: "Publish", Args: []interface{}{deadLetterConfig, mock.Anything}, ReturnArgs: []interface{}{nil}}},
+			ackCalls:     mocks.Calls{{Method: "Ack", Args: []interface{}{uint64(1), false}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:         "first retry",
+			body:         body,
+			useCaseCalls: mocks.Calls{{Method: "BatchDelete", Args: []interface{}{mock.Anything, input}, ReturnArgs: []interface{}{errors.New("test")}}},
+			pubSubCalls:  mocks.Calls{{Method: "Publish", Args: []interface{}{retryConfig("1s"), mock.Anything}, ReturnArgs: []interface{}{nil}}},
+			ackCalls:     mocks.Calls{{Method: "Ack", Args: []interface{}{uint64(1), false}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:         "third retry",
+			body:         body,
+			headers:      deaths(amqp.Table{"queue": "delete_files.retry.1s", "reason": "expired", "count": int64(2)}),
+			useCaseCalls: mocks.Calls{{Method: "BatchDelete", Args: []interface{}{mock.Anything, input}, ReturnArgs: []interface{}{errors.New("test")}}},
+			pubSubCalls:  mocks.Calls{{Method: "Publish", Args: []interface{}{retryConfig("4s"), mock.Anything}, ReturnArgs: []interface{}{nil}}},
+			ackCalls:     mocks.Calls{{Method: "Ack", Args: []interface{}{uint64(1), false}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:         "attempts exhausted",
+			body:         body,
+			headers:      exhausted,
+			useCaseCalls: mocks.Calls{{Method: "BatchDelete", Args: []interface{}{mock.Anything, input}, ReturnArgs: []interface{}{errors.New("test")}}},
+			pubSubCalls:  mocks.Calls{{Method: "Publish", Args: []interface{}{deadLetterConfig, mock.Anything}, ReturnArgs: []interface{}{nil}}},
+			ackCalls:     mocks.Calls{{Method: "Ack", Args: []interface{}{uint64(1), false}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:         "retry not published",
+			body:         body,
+			useCaseCalls: mocks.Calls{{Method: "BatchDelete", Args: []interface{}{mock.Anything, input}, ReturnArgs: []interface{}{errors.New("test")}}},
+			pubSubCalls:  mocks.Calls{{Method: "Publish", Args: []interface{}{retryConfig("1s"), mock.Anything}, ReturnArgs: []interface{}{amqpStore.ErrStoreIsNotRunning}}},
+			ackCalls:     mocks.Calls{{Method: "Reject", Args: []interface{}{uint64(1), true}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:        "dead letter not published",
+			body:        []byte(`{`),
+			pubSubCalls: mocks.Calls{{Method: "Publish", Args: []interface{}{deadLetterConfig, mock.Anything}, ReturnArgs: []interface{}{amqpStore.ErrStoreIsNotRunning}}},
+			ackCalls:    mocks.Calls{{Method: "Reject", Args: []interface{}{uint64(1), false}, ReturnArgs: []interface{}{nil}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase, store, acknowledger := new(mocks.FileUseCase), new(mocks.Store), new(mocks.Acknowledger)
+			for m, calls := range map[mocks.Mock]mocks.Calls{useCase: tt.useCaseCalls, store: tt.pubSubCalls, acknowledger: tt.ackCalls} {
+				for _, call := range calls {
+					m.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+				}
+			}
+			delivery := amqp.Delivery{
+				Acknowledger: acknowledger,
+				DeliveryTag:  1,
+				Headers:      tt.headers,
+				MessageId:    "id",
+				Body:         tt.body,
+			}
+
+			consumer := events.NewConsumer(store, consumes, retryConf)
+			assert.NoError(t, consumer.Register(events.DeleteFiles, events.NewHandler(useCase)))
+			consumer.Dispatch(events.DeleteFiles)(delivery)
+			for _, m := range []mocks.Mock{useCase, store, acknowledger} {
+				m.AssertExpectations(t)
+			}
+			for _, call := range store.Calls {
+				// Headers are kept, so the broker continues counting attempts
+				message := call.Arguments.Get(1).(*amqp.Publishing)
+				assert.EqualValues(t, tt.headers, message.Headers)
+				assert.EqualValues(t, tt.body, message.Body)
+				assert.EqualValues(t, "id", message.MessageId)
+				assert.EqualValues(t, amqp.Persistent, message.DeliveryMode)
+			}
+		})
+	}
+}
+
+func TestHandler_DeleteFiles_Versions(t *testing.T) {
+	urls := dto.BatchDeleteInput{"https://aws.s3/bucket/test.jpg"}
+	envelope := []byte(`{
+		"version": 2,
+		"urls": ["https://aws.s3/bucket/test.jpg"],
+		"keys": ["docs/test.txt"],
+		"requested_by": "admin",
+		"reason": "cleanup",
+		"correlation_id": "correlation"
+	}`)
+	input := &dto.DeleteFilesInput{Urls: urls, Keys: []string{"docs/test.txt"}}
+	requested := mock.MatchedBy(func(ctx context.Context) bool {
+		return dto.ActorFromContext(ctx) == "admin" &&
+			dto.ReasonFromContext(ctx) == "cleanup" &&
+			dto.CorrelationIdFromContext(ctx) == "correlation"
+	})
+	tests := []struct {
+		name      string
+		delivery  amqp.Delivery
+		mockCalls mocks.Calls
+		wantErr   bool
+	}{
+		{
+			name:      "legacy",
+			delivery:  amqp.Delivery{ContentType: "application/json", Body: []byte(`["https://aws.s3/bucket/test.jpg"]`)},
+			mockCalls: mocks.Calls{{Method: "BatchDelete", Args: []interface{}{mock.Anything, urls}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name: "legacy with correlation id",
+			delivery: amqp.Delivery{
+				CorrelationId: "correlation",
+				Body:          []byte(`["https://aws.s3/bucket/test.jpg"]`),
+			},
+			mockCalls: mocks.Calls{{
+				Method: "BatchDelete",
+				Args: []interface{}{
+					mock.MatchedBy(func(ctx context.Context) bool { return dto.CorrelationIdFromContext(ctx) == "correlation" }),
+					urls,
+				},
+				ReturnArgs: []interface{}{nil},
+			}},
+		},
+		{
+			name:      "version header",
+			delivery:  amqp.Delivery{Headers: amqp.Table{"version": int32(2)}, Body: envelope},
+			mockCalls: mocks.Calls{{Method: "DeleteFiles", Args: []interface{}{requested, input}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:      "version of content type",
+			delivery:  amqp.Delivery{ContentType: "application/json; version=2", Body: envelope},
+			mockCalls: mocks.Calls{{Method: "DeleteFiles", Args: []interface{}{requested, input}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:      "version of the body",
+			delivery:  amqp.Delivery{ContentType: "application/json", Body: envelope},
+			mockCalls: mocks.Calls{{Method: "DeleteFiles", Args: []interface{}{requested, input}, ReturnArgs: []interface{}{nil}}},
+		},
+		{
+			name:     "header over version of the body",
+			delivery: amqp.Delivery{Headers: amqp.Table{"version": int16(1)}, Body: []byte(`{"version": 2}`)},
+			wantErr:  true,
+		},
+		{
+			name: "correlation id of the message",
+			delivery: amqp.Delivery{
+				Headers:       amqp.Table{"version": "2"},
+				CorrelationId: "correlation",
+				Body:          []byte(`{"keys": ["docs/test.txt"], "requested_by": "admin", "reason": "cleanup"}`),
+			},
+			mockCalls: mocks.Calls{{
+				Method:     "DeleteFiles",
+				Args:       []interface{}{requested, &dto.DeleteFilesInput{Keys: []string{"docs/test.txt"}}},
+				ReturnArgs: []interface{}{nil},
+			}},
+		},
+		{
+			name:     "legacy array of version 2",
+			delivery: amqp.Delivery{Headers: amqp.Table{"version": int64(2)}, Body: []byte(`["https://aws.s3/bucket/test.jpg"]`)},
+			wantErr:  true,
+		},
+		{
+			name:     "version mismatch",
+			delivery: amqp.Delivery{Headers: amqp.Table{"version": int32(2)}, Body: []byte(`{"version": 3}`)},
+			wantErr:  true,
+		},
+		{
+			name:     "unsupported version",
+			delivery: amqp.Delivery{ContentType: "application/json; version=3", Body: envelope},
+			wantErr:  true,
+		},
+		{
+			name:     "unsupported version of the body",
+			delivery: amqp.Delivery{Body: []byte(`{"version": 3, "urls": ["https://aws.s3/bucket/test.jpg"]}`)},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid version header",
+			delivery: amqp.Delivery{Headers: amqp.Table{"version": true}, Body: envelope},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+
+			err := events.NewHandler(useCase)(helpers.DefaultCtx, tt.delivery)
+			if tt.wantErr {
+				// Message is dead-lettered, because it can't succeed
+				assert.Implements(t, (*validation.Error)(nil), err)
+			} else {
+				assert.NoError(t, err)
+			}
+			useCase.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	amqpStore "github.com/freemen-app/amqp-store"
+	"github.com/streadway/amqp"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+type (
+	// message is the body of published event, field names are the contract with subscribers
+	message struct {
+		Id            string    `json:"id"`
+		Type          string    `json:"type"`
+		Key           string    `json:"key"`
+		Url           string    `json:"url"`
+		SourceUrl     string    `json:"source_url,omitempty"`
+		Size          int64     `json:"size,omitempty"`
+		ContentType   string    `json:"content_type,omitempty"`
+		SHA256        string    `json:"sha256,omitempty"`
+		Actor         string    `json:"actor,omitempty"`
+		Reason        string    `json:"reason,omitempty"`
+		CorrelationId string    `json:"correlation_id,omitempty"`
+		Timestamp     time.Time `json:"timestamp"`
+	}
+
+	// publisher sends events to the configured exchange with their types as routing keys,
+	// so queues bound to topic exchange may receive some of them, e.g. "file.deleted"
+	publisher struct {
+		pubSub amqpStore.PubSub
+		config *amqpStore.PublishConfig
+	}
+)
+
+func New(pubSub amqpStore.PubSub, conf *amqpStore.PublishConfig) *publisher {
+	return &publisher{
+		pubSub: pubSub,
+		config: conf,
+	}
+}
+
+func (p *publisher) Publish(_ context.Context, event *dto.FileEvent) error {
+	body, err := json.Marshal(&message{
+		Id:            event.Id,
+		Type:          event.Type,
+		Key:           event.Key,
+		Url:           event.Url,
+		SourceUrl:     event.SourceUrl,
+		Size:          event.Size,
+		ContentType:   event.ContentType,
+		SHA256:        event.SHA256,
+		Actor:         event.Actor,
+		Reason:        event.Reason,
+		CorrelationId: event.CorrelationId,
+		Timestamp:     event.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+	conf := *p.config
+	conf.Exchange.RoutingKey = event.Type
+	return p.pubSub.Publish(&conf, &amqp.Publishing{
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: event.CorrelationId,
+		MessageId:     event.Id,
+		Type:          event.Type,
+		Timestamp:     event.Timestamp,
+		Body:          body,
+	})
+}
@@ -0,0 +1,94 @@
+package publisher_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	amqpStore "github.com/freemen-app/amqp-store"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/events/publisher"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+func TestPublisher_Publish(t *testing.T) {
+	conf := &amqpStore.PublishConfig{
+		Exchange: amqpStore.ExchangeConfig{Name: "file_events", Type: amqp.ExchangeTopic, Durable: true},
+	}
+	timestamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := &dto.FileEvent{
+		Id:            "id",
+		Type:          dto.EventFileCopied,
+		Key:           "test/test.jpg",
+		Url:           "https://aws.s3/bucket/test/test.jpg",
+		SourceUrl:     "https://aws.s3/bucket/tmp/test.jpg",
+		Size:          4,
+		ContentType:   "image/jpeg",
+		SHA256:        "sha256",
+		Actor:         "user",
+		Reason:        "cleanup",
+		CorrelationId: "correlation",
+		Timestamp:     timestamp,
+	}
+	wantConf := &amqpStore.PublishConfig{
+		Exchange: amqpStore.ExchangeConfig{Name: "file_events", Type: amqp.ExchangeTopic, Durable: true, RoutingKey: "file.copied"},
+	}
+	wantBody := map[string]interface{}{
+		"id":             "id",
+		"type":           "file.copied",
+		"key":            "test/test.jpg",
+		"url":            "https://aws.s3/bucket/test/test.jpg",
+		"source_url":     "https://aws.s3/bucket/tmp/test.jpg",
+		"size":           float64(4),
+		"content_type":   "image/jpeg",
+		"sha256":         "sha256",
+		"actor":          "user",
+		"reason":         "cleanup",
+		"correlation_id": "correlation",
+		"timestamp":      "2020-01-02T03:04:05Z",
+	}
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{
+			name: "succeed",
+		},
+		{
+			name:    "failed",
+			err:     amqpStore.ErrStoreIsNotRunning,
+			wantErr: amqpStore.ErrStoreIsNotRunning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pubSub := new(mocks.PubSub)
+			var message *amqp.Publishing
+			pubSub.On("Publish", wantConf, mock.Anything).Return(tt.err).Run(func(args mock.Arguments) {
+				message = args.Get(1).(*amqp.Publishing)
+			})
+
+			err := publisher.New(pubSub, conf).Publish(helpers.DefaultCtx, event)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+			pubSub.AssertExpectations(t)
+			assert.EqualValues(t, "application/json", message.ContentType)
+			assert.EqualValues(t, amqp.Persistent, message.DeliveryMode)
+			assert.EqualValues(t, "id", message.MessageId)
+			assert.EqualValues(t, "correlation", message.CorrelationId)
+			assert.EqualValues(t, "file.copied", message.Type)
+			assert.EqualValues(t, timestamp, message.Timestamp)
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(message.Body, &body))
+			assert.EqualValues(t, wantBody, body)
+			// Routing key isn't changed in the config
+			assert.Empty(t, conf.Exchange.RoutingKey)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	amqpStore "github.com/freemen-app/amqp-store"
+	"github.com/streadway/amqp"
+
+	"github.com/freemen-app/file_storage/config"
+)
+
+const (
+	// deathHeader is set by the broker to dead-lettered messages, it has an entry per queue and reason
+	deathHeader = "x-death"
+	// deathReasonExpired is the reason of messages dead-lettered by TTL of delay queues
+	deathReasonExpired = "expired"
+)
+
+type (
+	// retry delays failed messages in queues with TTL, which dead-letter them back to the consumed queue.
+	// Every delay has its own queue, because messages expire only at the head of the queue
+	retry struct {
+		pubSub      amqpStore.PubSub
+		queue       string
+		maxAttempts int
+		backoff     time.Duration
+		maxBackoff  time.Duration
+		deadLetter  *amqpStore.PublishConfig
+	}
+)
+
+func newRetry(pubSub amqpStore.PubSub, queue string, conf *config.RetryConfig) *retry {
+	return &retry{
+		pubSub:      pubSub,
+		queue:       queue,
+		maxAttempts: conf.MaxAttempts,
+		backoff:     conf.Backoff,
+		maxBackoff:  conf.MaxBackoff,
+		deadLetter: &amqpStore.PublishConfig{
+			Exchange: amqpStore.ExchangeConfig{Name: conf.DeadLetterExchange, Type: amqp.ExchangeFanout, Durable: true},
+		},
+	}
+}
+
+// Declare declares delay queues bound to the retry exchange and the dead-letter exchange
+// with the queue of the same name, so dead-lettered messages are kept until they are inspected.
+// The store can't declare queues without consuming them, so they are declared by own connection
+func (r *retry) Declare(dsn string) error {
+	conn, err := amqp.Dial(dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	channel, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	if err := channel.ExchangeDeclare(r.exchange(), amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return err
+	}
+	for _, delay := range r.delays() {
+		args := amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": r.queue,
+		}
+		if _, err := channel.QueueDeclare(r.delayQueue(delay), true, false, false, false, args); err != nil {
+			return err
+		} else if err := channel.QueueBind(r.delayQueue(delay), r.delayQueue(delay), r.exchange(), false, nil); err != nil {
+			return err
+		}
+	}
+
+	deadLetter := r.deadLetter.Exchange.Name
+	if err := channel.ExchangeDeclare(deadLetter, amqp.ExchangeFanout, true, false, false, false, nil); err != nil {
+		return err
+	} else if _, err := channel.QueueDeclare(deadLetter, true, false, false, false, nil); err != nil {
+		return err
+	}
+	return channel.QueueBind(deadLetter, "", deadLetter, false, nil)
+}
+
+// Retry publishes failed message to the delay queue of its attempt or dead-letters it when attempts are exhausted.
+// The message is acked after it's published, otherwise it's requeued, so it isn't lost
+func (r *retry) Retry(delivery amqp.Delivery) error {
+	attempts := r.attempts(delivery.Headers) + 1
+	if attempts >= r.maxAttempts {
+		return r.DeadLetter(delivery)
+	}
+	delay := r.delay(attempts)
+	conf := &amqpStore.PublishConfig{
+		Exchange: amqpStore.ExchangeConfig{
+			Name:       r.exchange(),
+			Type:       amqp.ExchangeDirect,
+			Durable:    true,
+			RoutingKey: r.delayQueue(delay),
+		},
+	}
+	if err := r.pubSub.Publish(conf, republishing(delivery)); err != nil {
+		logError(delivery.Reject(true))
+		return err
+	}
+	return delivery.Ack(false)
+}
+
+// DeadLetter publishes message to the dead-letter exchange keeping its headers,
+// the message is dropped if it can't be published
+func (r *retry) DeadLetter(delivery amqp.Delivery) error {
+	if err := r.pubSub.Publish(r.deadLetter, republishing(delivery)); err != nil {
+		logError(delivery.Reject(false))
+		return err
+	}
+	return delivery.Ack(false)
+}
+
+// attempts returns how many times the message was delayed, which is counted by the broker in x-death header
+func (r *retry) attempts(headers amqp.Table) int {
+	deaths, _ := headers[deathHeader].([]interface{})
+	attempts := 0
+	for _, death := range deaths {
+		table, ok := death.(amqp.Table)
+		if !ok || table["reason"] != deathReasonExpired {
+			continue
+		} else if queue, _ := table["queue"].(string); !strings.HasPrefix(queue, r.exchange()+".") {
+			continue
+		}
+		switch count := table["count"].(type) {
+		case int64:
+			attempts += int(count)
+		case int32:
+			attempts += int(count)
+		}
+	}
+	return attempts
+}
+
+// delay returns backoff after the failed attempt, it's doubled by every attempt up to max backoff
+func (r *retry) delay(attempt int) time.Duration {
+	delay := r.backoff
+	for i := 1; i < attempt && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		return r.maxBackoff
+	}
+	return delay
+}
+
+// delays returns distinct delays of all attempts
+func (r *retry) delays() []time.Duration {
+	delays := make([]time.Duration, 0)
+	for attempt := 1; attempt < r.maxAttempts; attempt++ {
+		delay := r.delay(attempt)
+		if len(delays) > 0 && delays[len(delays)-1] == delay {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	return delays
+}
+
+// exchange routes retried messages to delay queues by their names
+func (r *retry) exchange() string {
+	return r.queue + ".retry"
+}
+
+func (r *retry) delayQueue(delay time.Duration) string {
+	return fmt.Sprintf("%s.%s", r.exchange(), delay)
+}
+
+// republishing copies the delivered message, headers are kept, so the broker continues counting deaths
+func republishing(delivery amqp.Delivery) *amqp.Publishing {
+	return &amqp.Publishing{
+		Headers:         delivery.Headers,
+		ContentType:     delivery.ContentType,
+		ContentEncoding: delivery.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		CorrelationId:   delivery.CorrelationId,
+		MessageId:       delivery.MessageId,
+		Timestamp:       delivery.Timestamp,
+		Type:            delivery.Type,
+		AppId:           delivery.AppId,
+		Body:            delivery.Body,
+	}
+}
@@ -0,0 +1,73 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/infrastructure/events"
+)
+
+var retryConf = &config.RetryConfig{
+	MaxAttempts:        5,
+	Backoff:            time.Second,
+	MaxBackoff:         5 * time.Second,
+	DeadLetterExchange: "delete_files.dead",
+}
+
+func deaths(entries ...amqp.Table) amqp.Table {
+	values := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		values[i] = entry
+	}
+	return amqp.Table{"x-death": values}
+}
+
+func TestRetry_Attempts(t *testing.T) {
+	retry := events.NewRetry(nil, "delete_files", retryConf)
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    int
+	}{
+		{name: "first delivery", headers: nil, want: 0},
+		{
+			name: "delayed",
+			headers: deaths(
+				amqp.Table{"queue": "delete_files.retry.2s", "reason": "expired", "count": int64(1)},
+				amqp.Table{"queue": "delete_files.retry.1s", "reason": "expired", "count": int64(2)},
+			),
+			want: 3,
+		},
+		{
+			name: "other deaths",
+			headers: deaths(
+				amqp.Table{"queue": "delete_files.retry.1s", "reason": "expired", "count": int32(1)},
+				amqp.Table{"queue": "delete_files", "reason": "rejected", "count": int64(4)},
+				amqp.Table{"queue": "other.retry.1s", "reason": "expired", "count": int64(4)},
+			),
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, retry.Attempts(tt.headers))
+		})
+	}
+}
+
+func TestRetry_Delay(t *testing.T) {
+	retry := events.NewRetry(nil, "delete_files", retryConf)
+	assert.EqualValues(t, time.Second, retry.Delay(1))
+	assert.EqualValues(t, 2*time.Second, retry.Delay(2))
+	assert.EqualValues(t, 4*time.Second, retry.Delay(3))
+	assert.EqualValues(t, 5*time.Second, retry.Delay(4))
+	assert.EqualValues(t, 5*time.Second, retry.Delay(100))
+	assert.EqualValues(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, retry.Delays())
+
+	retry = events.NewRetry(nil, "delete_files", &config.RetryConfig{MaxAttempts: 1, Backoff: time.Second, MaxBackoff: time.Second})
+	assert.Empty(t, retry.Delays())
+}
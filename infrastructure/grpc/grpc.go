@@ -1,6 +1,7 @@
 package grpcApi
 
 import (
+	"context"
 	"fmt"
 	"net"
 
@@ -37,7 +38,10 @@ func New(app *app.App, config *config.ApiConfig) *api {
 	}
 
 	handler := NewHandler(app.UseCases().FileUseCase)
-	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(handler.ErrMiddleware))
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(chainUnaryInterceptors(handler.ErrMiddleware, handler.ActorMiddleware)),
+		grpc.StreamInterceptor(handler.ActorStreamMiddleware),
+	)
 	fileStorage.RegisterFileStorageServer(grpcServer, handler)
 
 	return &api{
@@ -58,3 +62,16 @@ func (g *api) Shutdown() {
 	g.grpc.Stop()
 	log.Info().Msg("Server stopped")
 }
+
+// chainUnaryInterceptors calls interceptors in order, the first one is the outermost
+func chainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
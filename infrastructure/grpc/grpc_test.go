@@ -2,25 +2,35 @@ package grpcApi_test
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/alecthomas/units"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	fileStorage "github.com/freemen-app/api/file_storage"
 
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
 	"github.com/freemen-app/file_storage/config"
 	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
 	"github.com/freemen-app/file_storage/infrastructure/app"
 	grpcApi "github.com/freemen-app/file_storage/infrastructure/grpc"
 	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
@@ -34,9 +44,17 @@ var (
 )
 
 func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "file_storage")
+	if err != nil {
+		panic(err)
+	}
 	conf = config.New(config.DefaultConfig)
+	// Upload sessions of S3 are tracked in the database
+	conf.Database.Path = filepath.Join(dir, "test.db")
 	application = app.New(conf)
 	code := m.Run()
+	application.Shutdown()
+	_ = os.RemoveAll(dir)
 	os.Exit(code)
 }
 
@@ -66,6 +84,82 @@ func testClient(t *testing.T, config *config.ApiConfig) fileStorage.FileStorageC
 	return client
 }
 
+func uploadFile(
+	t *testing.T,
+	client fileStorage.FileStorageClient,
+	ctx context.Context,
+	metadata *fileStorage.MetaData,
+	file io.Reader,
+) (string, error) {
+	t.Helper()
+	stream, err := client.Upload(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	uploadRequest := &fileStorage.UploadRequest{File: &fileStorage.UploadRequest_Metadata{Metadata: metadata}}
+	if err := stream.Send(uploadRequest); err != nil {
+		return "", err
+	}
+
+	// Send file by chunks
+	reader := bufio.NewReader(file)
+	chunk := make([]byte, 0, 1*units.MB)
+	for {
+		n, err := reader.Read(chunk[:cap(chunk)])
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			assert.NoError(t, stream.CloseSend())
+			return "", err
+		}
+		request := &fileStorage.UploadRequest{
+			File: &fileStorage.UploadRequest_Content{Content: chunk[:n]},
+		}
+		if err := stream.Send(request); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+	}
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		return "", err
+	}
+	return response.Url, nil
+}
+
+func downloadFile(
+	t *testing.T,
+	client fileStorage.FileStorageClient,
+	ctx context.Context,
+	request *fileStorage.DownloadRequest,
+) (*fileStorage.FileInfo, []byte, error) {
+	t.Helper()
+	stream, err := client.Download(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	response, err := stream.Recv()
+	if err != nil {
+		return nil, nil, err
+	}
+	info := response.GetInfo()
+
+	var content []byte
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		content = append(content, response.GetContent()...)
+	}
+	return info, content, nil
+}
+
 func TestNew(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -150,12 +244,31 @@ func TestHandler_Upload(t *testing.T) {
 				{
 					Method:     "Upload",
 					Args:       []interface{}{mock.Anything, mock.Anything},
-					ReturnArgs: []interface{}{"https://aws.s3/test/1mb.jpg", nil},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test/1mb.jpg"}, nil},
 				},
 			},
 			wantUrl:     "https://aws.s3/test/1mb.jpg",
 			wantErrCode: codes.OK,
 		},
+		{
+			name: "owner",
+			args: args{
+				ctx:      helpers.DefaultCtx,
+				metadata: &fileStorage.MetaData{Directory: "test", Filename: "test.txt", Owner: "user"},
+				file:     bytes.NewBufferString("test"),
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "Upload",
+					Args: []interface{}{mock.Anything, mock.MatchedBy(func(input *dto.UploadInput) bool {
+						return input.Owner == "user"
+					})},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test/test.txt"}, nil},
+				},
+			},
+			wantUrl:     "https://aws.s3/test/test.txt",
+			wantErrCode: codes.OK,
+		},
 		{
 			name: "succeed 5mb",
 			args: args{
@@ -167,7 +280,7 @@ func TestHandler_Upload(t *testing.T) {
 				{
 					Method:     "Upload",
 					Args:       []interface{}{mock.Anything, mock.Anything},
-					ReturnArgs: []interface{}{"https://aws.s3/test/5mb.png", nil},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test/5mb.png"}, nil},
 				},
 			},
 			wantUrl:     "https://aws.s3/test/5mb.png",
@@ -193,7 +306,7 @@ func TestHandler_Upload(t *testing.T) {
 				{
 					Method:     "Upload",
 					Args:       []interface{}{mock.Anything, mock.Anything},
-					ReturnArgs: []interface{}{"", validation.Errors{"test": errors.New("test")}},
+					ReturnArgs: []interface{}{nil, validation.Errors{"test": errors.New("test")}},
 				},
 			},
 			wantErrCode: codes.InvalidArgument,
@@ -209,11 +322,48 @@ func TestHandler_Upload(t *testing.T) {
 				{
 					Method:     "Upload",
 					Args:       []interface{}{mock.Anything, mock.Anything},
-					ReturnArgs: []interface{}{"", errors.New("test error")},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
 				},
 			},
 			wantErrCode: codes.Internal,
 		},
+		{
+			name: "private",
+			args: args{
+				ctx:      helpers.DefaultCtx,
+				metadata: &fileStorage.MetaData{Directory: "test", Filename: "test.jpg", Acl: "private"},
+				file:     bytes.NewBufferString("test"),
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "Upload",
+					Args: []interface{}{mock.Anything, mock.MatchedBy(func(input *dto.UploadInput) bool {
+						return input.ACL == "private"
+					})},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test/test.jpg"}, nil},
+				},
+			},
+			wantUrl:     "https://aws.s3/test/test.jpg",
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "file exists",
+			args: args{
+				ctx:      helpers.DefaultCtx,
+				metadata: &fileStorage.MetaData{Directory: "test", Filename: "test.jpg", Collision: "fail"},
+				file:     bytes.NewBufferString("test"),
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "Upload",
+					Args: []interface{}{mock.Anything, mock.MatchedBy(func(input *dto.UploadInput) bool {
+						return input.Collision == dto.CollisionFail
+					})},
+					ReturnArgs: []interface{}{nil, customErrors.AlreadyExists},
+				},
+			},
+			wantErrCode: codes.AlreadyExists,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -223,46 +373,7 @@ func TestHandler_Upload(t *testing.T) {
 			}
 			server.Handler().SetFileUseCase(useCase)
 
-			test := func() (string, error) {
-				stream, err := client.Upload(tt.args.ctx)
-				if err != nil {
-					return "", err
-				}
-
-				uploadRequest := &fileStorage.UploadRequest{File: &fileStorage.UploadRequest_Metadata{Metadata: tt.args.metadata}}
-				if err := stream.Send(uploadRequest); err != nil {
-					return "", err
-				}
-
-				// Send file by chunks
-				reader := bufio.NewReader(tt.args.file)
-				chunk := make([]byte, 0, 1*units.MB)
-				for {
-					n, err := reader.Read(chunk[:cap(chunk)])
-					if err == io.EOF {
-						break
-					} else if err != nil {
-						assert.NoError(t, stream.CloseSend())
-						return "", err
-					}
-					request := &fileStorage.UploadRequest{
-						File: &fileStorage.UploadRequest_Content{Content: chunk[:n]},
-					}
-					if err := stream.Send(request); err != nil {
-						if err == io.EOF {
-							break
-						}
-						return "", err
-					}
-				}
-				response, err := stream.CloseAndRecv()
-				if err != nil {
-					return "", err
-				}
-				return response.Url, nil
-			}
-
-			gotUrl, gotErr := test()
+			gotUrl, gotErr := uploadFile(t, client, tt.args.ctx, tt.args.metadata, tt.args.file)
 			grpcErr, ok := status.FromError(gotErr)
 			assert.True(t, ok)
 			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
@@ -273,41 +384,98 @@ func TestHandler_Upload(t *testing.T) {
 	}
 }
 
-func TestHandler_Delete(t *testing.T) {
+func TestHandler_Download(t *testing.T) {
 	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
 	server := testServer(t, conf)
 	client := testClient(t, conf)
 
+	content, err := ioutil.ReadFile("testdata/1mb.jpg")
+	assert.NoError(t, err)
+
 	type args struct {
 		ctx context.Context
-		in  *fileStorage.DeleteRequest
+		in  *fileStorage.DownloadRequest
 	}
 	tests := []struct {
 		name        string
 		args        args
 		mockCalls   helpers.MockCalls
+		wantInfo    *fileStorage.FileInfo
+		wantContent []byte
 		wantErrCode codes.Code
 	}{
 		{
 			name: "succeed",
 			args: args{
 				ctx: helpers.DefaultCtx,
-				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+				in:  &fileStorage.DownloadRequest{Url: "https://aws.s3/bucket/1mb.jpg"},
 			},
 			mockCalls: helpers.MockCalls{
 				{
-					Method:     "Delete",
-					Args:       []interface{}{mock.Anything, dto.DeleteInput("https://aws.s3/bucket/test.jpg")},
-					ReturnArgs: []interface{}{nil},
+					Method: "Download",
+					Args:   []interface{}{mock.Anything, &dto.DownloadInput{Url: "https://aws.s3/bucket/1mb.jpg"}},
+					ReturnArgs: []interface{}{&dto.DownloadOutput{
+						File:        ioutil.NopCloser(bytes.NewReader(content)),
+						ContentType: "image/jpeg",
+						Size:        int64(len(content)),
+					}, nil},
+				},
+			},
+			wantInfo:    &fileStorage.FileInfo{ContentType: "image/jpeg", Size: int64(len(content))},
+			wantContent: content,
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "succeed range",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in:  &fileStorage.DownloadRequest{Url: "https://aws.s3/bucket/1mb.jpg", Offset: 10, Length: 100},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "Download",
+					Args: []interface{}{
+						mock.Anything,
+						&dto.DownloadInput{Url: "https://aws.s3/bucket/1mb.jpg", Offset: 10, Length: 100},
+					},
+					ReturnArgs: []interface{}{&dto.DownloadOutput{
+						File:        ioutil.NopCloser(bytes.NewReader(content[10:110])),
+						ContentType: "image/jpeg",
+						Size:        int64(len(content)),
+						Offset:      10,
+						Length:      100,
+					}, nil},
 				},
 			},
+			wantInfo: &fileStorage.FileInfo{
+				ContentType: "image/jpeg",
+				Size:        int64(len(content)),
+				Offset:      10,
+				Length:      100,
+			},
+			wantContent: content[10:110],
 			wantErrCode: codes.OK,
 		},
+		{
+			name: "out of range",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in:  &fileStorage.DownloadRequest{Url: "https://aws.s3/bucket/1mb.jpg", Offset: 1 << 30},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Download",
+					Args:       []interface{}{mock.Anything, &dto.DownloadInput{Url: "https://aws.s3/bucket/1mb.jpg", Offset: 1 << 30}},
+					ReturnArgs: []interface{}{nil, customErrors.InvalidRange},
+				},
+			},
+			wantErrCode: codes.OutOfRange,
+		},
 		{
 			name: "timeout",
 			args: args{
 				ctx: helpers.TimeoutCtx(t, helpers.DefaultCtx, time.Nanosecond),
-				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+				in:  &fileStorage.DownloadRequest{Url: "https://aws.s3/bucket/1mb.jpg"},
 			},
 			wantErrCode: codes.DeadlineExceeded,
 		},
@@ -315,13 +483,13 @@ func TestHandler_Delete(t *testing.T) {
 			name: "validation error",
 			args: args{
 				ctx: helpers.DefaultCtx,
-				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+				in:  &fileStorage.DownloadRequest{Url: "https://aws.s3/bucket/1mb.jpg"},
 			},
 			mockCalls: helpers.MockCalls{
 				{
-					Method:     "Delete",
-					Args:       []interface{}{mock.Anything, dto.DeleteInput("https://aws.s3/bucket/test.jpg")},
-					ReturnArgs: []interface{}{validation.Errors{}},
+					Method:     "Download",
+					Args:       []interface{}{mock.Anything, &dto.DownloadInput{Url: "https://aws.s3/bucket/1mb.jpg"}},
+					ReturnArgs: []interface{}{nil, validation.Errors{}},
 				},
 			},
 			wantErrCode: codes.InvalidArgument,
@@ -330,13 +498,13 @@ func TestHandler_Delete(t *testing.T) {
 			name: "internal error",
 			args: args{
 				ctx: helpers.DefaultCtx,
-				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+				in:  &fileStorage.DownloadRequest{Url: "https://aws.s3/bucket/1mb.jpg"},
 			},
 			mockCalls: helpers.MockCalls{
 				{
-					Method:     "Delete",
-					Args:       []interface{}{mock.Anything, dto.DeleteInput("https://aws.s3/bucket/test.jpg")},
-					ReturnArgs: []interface{}{errors.New("test error")},
+					Method:     "Download",
+					Args:       []interface{}{mock.Anything, &dto.DownloadInput{Url: "https://aws.s3/bucket/1mb.jpg"}},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
 				},
 			},
 			wantErrCode: codes.Internal,
@@ -350,113 +518,177 @@ func TestHandler_Delete(t *testing.T) {
 			}
 			server.Handler().SetFileUseCase(useCase)
 
-			_, gotErr := client.Delete(tt.args.ctx, tt.args.in)
+			gotInfo, gotContent, gotErr := downloadFile(t, client, tt.args.ctx, tt.args.in)
 			grpcErr, ok := status.FromError(gotErr)
 			assert.True(t, ok)
 			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+			assert.EqualValues(t, tt.wantInfo.GetContentType(), gotInfo.GetContentType())
+			assert.EqualValues(t, tt.wantInfo.GetSize(), gotInfo.GetSize())
+			assert.EqualValues(t, tt.wantInfo.GetOffset(), gotInfo.GetOffset())
+			assert.EqualValues(t, tt.wantInfo.GetLength(), gotInfo.GetLength())
+			assert.EqualValues(t, tt.wantContent, gotContent)
 
 			useCase.AssertExpectations(t)
 		})
 	}
 }
 
-func TestHandler_BatchDelete(t *testing.T) {
+func TestHandler_Stat(t *testing.T) {
 	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
 	server := testServer(t, conf)
 	client := testClient(t, conf)
 
-	type args struct {
-		ctx context.Context
-		in  *fileStorage.BatchDeleteRequest
-	}
+	lastModified := time.Unix(1600000000, 0)
 	tests := []struct {
 		name        string
-		args        args
+		in          *fileStorage.StatRequest
 		mockCalls   helpers.MockCalls
+		want        *fileStorage.StatResponse
 		wantErrCode codes.Code
 	}{
 		{
 			name: "succeed",
-			args: args{
-				ctx: helpers.DefaultCtx,
-				in: &fileStorage.BatchDeleteRequest{Urls: []string{
-					"https://aws.s3/bucket/test.jpg",
-					"https://aws.s3/bucket/test2.jpg",
-				}},
-			},
+			in:   &fileStorage.StatRequest{Url: "https://aws.s3/bucket/test.jpg"},
 			mockCalls: helpers.MockCalls{
 				{
-					Method: "BatchDelete",
-					Args: []interface{}{
-						mock.Anything,
-						dto.BatchDeleteInput{
-							"https://aws.s3/bucket/test.jpg",
-							"https://aws.s3/bucket/test2.jpg",
-						},
-					},
-					ReturnArgs: []interface{}{nil},
+					Method: "Stat",
+					Args:   []interface{}{mock.Anything, &dto.StatInput{Url: "https://aws.s3/bucket/test.jpg"}},
+					ReturnArgs: []interface{}{&dto.FileInfo{
+						Key:          "test.jpg",
+						Url:          "https://aws.s3/bucket/test.jpg",
+						Size:         4,
+						ContentType:  "image/jpeg",
+						ETag:         `"etag"`,
+						LastModified: lastModified,
+						Metadata:     map[string]string{"Owner": "test"},
+					}, nil},
 				},
 			},
+			want: &fileStorage.StatResponse{
+				Key:          "test.jpg",
+				Url:          "https://aws.s3/bucket/test.jpg",
+				Size:         4,
+				ContentType:  "image/jpeg",
+				Etag:         `"etag"`,
+				LastModified: lastModified.Unix(),
+				Metadata:     map[string]string{"Owner": "test"},
+			},
 			wantErrCode: codes.OK,
 		},
 		{
-			name: "timeout",
-			args: args{
-				ctx: helpers.TimeoutCtx(t, helpers.DefaultCtx, time.Nanosecond),
-				in: &fileStorage.BatchDeleteRequest{Urls: []string{
-					"https://aws.s3/bucket/test.jpg",
-					"https://aws.s3/bucket/test2.jpg",
-				}},
+			name: "not found",
+			in:   &fileStorage.StatRequest{Url: "https://aws.s3/bucket/test.jpg"},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Stat",
+					Args:       []interface{}{mock.Anything, &dto.StatInput{Url: "https://aws.s3/bucket/test.jpg"}},
+					ReturnArgs: []interface{}{nil, customErrors.NotFound},
+				},
 			},
-			wantErrCode: codes.DeadlineExceeded,
+			wantErrCode: codes.NotFound,
 		},
 		{
-			name: "validation error",
-			args: args{
-				ctx: helpers.DefaultCtx,
-				in: &fileStorage.BatchDeleteRequest{Urls: []string{
-					"https://aws.s3/bucket/test.jpg",
-					"https://aws.s3/bucket/test2.jpg",
-				}},
-			},
+			name: "internal error",
+			in:   &fileStorage.StatRequest{Url: "https://aws.s3/bucket/test.jpg"},
 			mockCalls: helpers.MockCalls{
 				{
-					Method: "BatchDelete",
-					Args: []interface{}{
-						mock.Anything,
-						dto.BatchDeleteInput{
-							"https://aws.s3/bucket/test.jpg",
-							"https://aws.s3/bucket/test2.jpg",
-						},
-					},
-					ReturnArgs: []interface{}{validation.Errors{}},
+					Method:     "Stat",
+					Args:       []interface{}{mock.Anything, &dto.StatInput{Url: "https://aws.s3/bucket/test.jpg"}},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
 				},
 			},
-			wantErrCode: codes.InvalidArgument,
+			wantErrCode: codes.Internal,
 		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			got, gotErr := client.Stat(helpers.DefaultCtx, tt.in)
+			grpcErr, ok := status.FromError(gotErr)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+			if tt.want != nil {
+				assert.EqualValues(t, tt.want.Key, got.GetKey())
+				assert.EqualValues(t, tt.want.Url, got.GetUrl())
+				assert.EqualValues(t, tt.want.Size, got.GetSize())
+				assert.EqualValues(t, tt.want.ContentType, got.GetContentType())
+				assert.EqualValues(t, tt.want.Etag, got.GetEtag())
+				assert.EqualValues(t, tt.want.LastModified, got.GetLastModified())
+				assert.EqualValues(t, tt.want.Metadata, got.GetMetadata())
+			}
+
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_List(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	lastModified := time.Unix(1600000000, 0)
+	tests := []struct {
+		name        string
+		in          *fileStorage.ListRequest
+		mockCalls   helpers.MockCalls
+		want        *fileStorage.ListResponse
+		wantErrCode codes.Code
+	}{
 		{
-			name: "internal error",
-			args: args{
-				ctx: helpers.DefaultCtx,
-				in: &fileStorage.BatchDeleteRequest{Urls: []string{
-					"https://aws.s3/bucket/test.jpg",
-					"https://aws.s3/bucket/test2.jpg",
+			name: "succeed",
+			in:   &fileStorage.ListRequest{Prefix: "test/", Delimiter: "/", PageSize: 10, PageToken: "token"},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "List",
+					Args: []interface{}{mock.Anything, &dto.ListInput{
+						Prefix:    "test/",
+						Delimiter: "/",
+						PageSize:  10,
+						PageToken: "token",
+					}},
+					ReturnArgs: []interface{}{&dto.ListOutput{
+						Files: []*dto.FileInfo{{
+							Key:          "test/test.jpg",
+							Url:          "https://aws.s3/bucket/test/test.jpg",
+							Size:         4,
+							ETag:         `"etag"`,
+							LastModified: lastModified,
+						}},
+						Prefixes:      []string{"test/sub/"},
+						NextPageToken: "next token",
+					}, nil},
+				},
+			},
+			want: &fileStorage.ListResponse{
+				Files: []*fileStorage.ListItem{{
+					Key:          "test/test.jpg",
+					Url:          "https://aws.s3/bucket/test/test.jpg",
+					Size:         4,
+					Etag:         `"etag"`,
+					LastModified: lastModified.Unix(),
 				}},
+				Prefixes:      []string{"test/sub/"},
+				NextPageToken: "next token",
 			},
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "validation error",
+			in:   &fileStorage.ListRequest{PageSize: -1},
 			mockCalls: helpers.MockCalls{
 				{
-					Method: "BatchDelete",
-					Args: []interface{}{
-						mock.Anything,
-						dto.BatchDeleteInput{
-							"https://aws.s3/bucket/test.jpg",
-							"https://aws.s3/bucket/test2.jpg",
-						},
-					},
-					ReturnArgs: []interface{}{errors.New("test error")},
+					Method:     "List",
+					Args:       []interface{}{mock.Anything, &dto.ListInput{PageSize: -1}},
+					ReturnArgs: []interface{}{nil, validation.Errors{}},
 				},
 			},
-			wantErrCode: codes.Internal,
+			wantErrCode: codes.InvalidArgument,
 		},
 	}
 	for _, tt := range tests {
@@ -467,12 +699,1097 @@ func TestHandler_BatchDelete(t *testing.T) {
 			}
 			server.Handler().SetFileUseCase(useCase)
 
-			_, gotErr := client.BatchDelete(tt.args.ctx, tt.args.in)
+			got, gotErr := client.List(helpers.DefaultCtx, tt.in)
 			grpcErr, ok := status.FromError(gotErr)
 			assert.True(t, ok)
 			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+			if tt.want != nil {
+				assert.Len(t, got.GetFiles(), len(tt.want.Files))
+				for i, file := range got.GetFiles() {
+					assert.EqualValues(t, tt.want.Files[i].Key, file.GetKey())
+					assert.EqualValues(t, tt.want.Files[i].Url, file.GetUrl())
+					assert.EqualValues(t, tt.want.Files[i].Size, file.GetSize())
+					assert.EqualValues(t, tt.want.Files[i].Etag, file.GetEtag())
+					assert.EqualValues(t, tt.want.Files[i].LastModified, file.GetLastModified())
+				}
+				assert.EqualValues(t, tt.want.Prefixes, got.GetPrefixes())
+				assert.EqualValues(t, tt.want.NextPageToken, got.GetNextPageToken())
+			}
 
 			useCase.AssertExpectations(t)
 		})
 	}
 }
+
+func TestHandler_Copy(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	request := &fileStorage.CopyRequest{
+		SourceUrl: "https://aws.s3/bucket/tmp/test.jpg",
+		Directory: "test",
+		Filename:  "test.jpg",
+		Acl:       "public-read",
+	}
+	input := &dto.CopyInput{
+		SourceUrl: "https://aws.s3/bucket/tmp/test.jpg",
+		Directory: "test",
+		Filename:  "test.jpg",
+		ACL:       "public-read",
+	}
+	tests := []struct {
+		name        string
+		method      string
+		mockCalls   helpers.MockCalls
+		want        string
+		wantErrCode codes.Code
+	}{
+		{
+			name:   "copy",
+			method: "Copy",
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Copy",
+					Args:       []interface{}{mock.Anything, input},
+					ReturnArgs: []interface{}{"https://aws.s3/bucket/test/test.jpg", nil},
+				},
+			},
+			want:        "https://aws.s3/bucket/test/test.jpg",
+			wantErrCode: codes.OK,
+		},
+		{
+			name:   "move",
+			method: "Move",
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Move",
+					Args:       []interface{}{mock.Anything, input},
+					ReturnArgs: []interface{}{"https://aws.s3/bucket/test/test.jpg", nil},
+				},
+			},
+			want:        "https://aws.s3/bucket/test/test.jpg",
+			wantErrCode: codes.OK,
+		},
+		{
+			name:   "same file",
+			method: "Move",
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Move",
+					Args:       []interface{}{mock.Anything, input},
+					ReturnArgs: []interface{}{"", customErrors.SameFile},
+				},
+			},
+			wantErrCode: codes.InvalidArgument,
+		},
+		{
+			name:   "source not found",
+			method: "Copy",
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Copy",
+					Args:       []interface{}{mock.Anything, input},
+					ReturnArgs: []interface{}{"", customErrors.NotFound},
+				},
+			},
+			wantErrCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			var got *fileStorage.CopyResponse
+			var gotErr error
+			if tt.method == "Move" {
+				got, gotErr = client.Move(helpers.DefaultCtx, request)
+			} else {
+				got, gotErr = client.Copy(helpers.DefaultCtx, request)
+			}
+			grpcErr, ok := status.FromError(gotErr)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+			assert.EqualValues(t, tt.want, got.GetUrl())
+
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_Delete(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	type args struct {
+		ctx context.Context
+		in  *fileStorage.DeleteRequest
+	}
+	tests := []struct {
+		name        string
+		args        args
+		mockCalls   helpers.MockCalls
+		wantErrCode codes.Code
+	}{
+		{
+			name: "succeed",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Delete",
+					Args:       []interface{}{mock.Anything, dto.DeleteInput("https://aws.s3/bucket/test.jpg")},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "timeout",
+			args: args{
+				ctx: helpers.TimeoutCtx(t, helpers.DefaultCtx, time.Nanosecond),
+				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+			},
+			wantErrCode: codes.DeadlineExceeded,
+		},
+		{
+			name: "validation error",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Delete",
+					Args:       []interface{}{mock.Anything, dto.DeleteInput("https://aws.s3/bucket/test.jpg")},
+					ReturnArgs: []interface{}{validation.Errors{}},
+				},
+			},
+			wantErrCode: codes.InvalidArgument,
+		},
+		{
+			name: "internal error",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in:  &fileStorage.DeleteRequest{Url: "https://aws.s3/bucket/test.jpg"},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Delete",
+					Args:       []interface{}{mock.Anything, dto.DeleteInput("https://aws.s3/bucket/test.jpg")},
+					ReturnArgs: []interface{}{errors.New("test error")},
+				},
+			},
+			wantErrCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			_, gotErr := client.Delete(tt.args.ctx, tt.args.in)
+			grpcErr, ok := status.FromError(gotErr)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_BatchDelete(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	type args struct {
+		ctx context.Context
+		in  *fileStorage.BatchDeleteRequest
+	}
+	tests := []struct {
+		name        string
+		args        args
+		mockCalls   helpers.MockCalls
+		wantErrCode codes.Code
+	}{
+		{
+			name: "succeed",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in: &fileStorage.BatchDeleteRequest{Urls: []string{
+					"https://aws.s3/bucket/test.jpg",
+					"https://aws.s3/bucket/test2.jpg",
+				}},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "BatchDelete",
+					Args: []interface{}{
+						mock.Anything,
+						dto.BatchDeleteInput{
+							"https://aws.s3/bucket/test.jpg",
+							"https://aws.s3/bucket/test2.jpg",
+						},
+					},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "timeout",
+			args: args{
+				ctx: helpers.TimeoutCtx(t, helpers.DefaultCtx, time.Nanosecond),
+				in: &fileStorage.BatchDeleteRequest{Urls: []string{
+					"https://aws.s3/bucket/test.jpg",
+					"https://aws.s3/bucket/test2.jpg",
+				}},
+			},
+			wantErrCode: codes.DeadlineExceeded,
+		},
+		{
+			name: "validation error",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in: &fileStorage.BatchDeleteRequest{Urls: []string{
+					"https://aws.s3/bucket/test.jpg",
+					"https://aws.s3/bucket/test2.jpg",
+				}},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "BatchDelete",
+					Args: []interface{}{
+						mock.Anything,
+						dto.BatchDeleteInput{
+							"https://aws.s3/bucket/test.jpg",
+							"https://aws.s3/bucket/test2.jpg",
+						},
+					},
+					ReturnArgs: []interface{}{validation.Errors{}},
+				},
+			},
+			wantErrCode: codes.InvalidArgument,
+		},
+		{
+			name: "internal error",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				in: &fileStorage.BatchDeleteRequest{Urls: []string{
+					"https://aws.s3/bucket/test.jpg",
+					"https://aws.s3/bucket/test2.jpg",
+				}},
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "BatchDelete",
+					Args: []interface{}{
+						mock.Anything,
+						dto.BatchDeleteInput{
+							"https://aws.s3/bucket/test.jpg",
+							"https://aws.s3/bucket/test2.jpg",
+						},
+					},
+					ReturnArgs: []interface{}{errors.New("test error")},
+				},
+			},
+			wantErrCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			_, gotErr := client.BatchDelete(tt.args.ctx, tt.args.in)
+			grpcErr, ok := status.FromError(gotErr)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_PresignDownload(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	expiresAt := time.Unix(1600000000, 0)
+	tests := []struct {
+		name        string
+		in          *fileStorage.PresignDownloadRequest
+		mockCalls   helpers.MockCalls
+		want        *fileStorage.PresignResponse
+		wantErrCode codes.Code
+	}{
+		{
+			name: "succeed",
+			in:   &fileStorage.PresignDownloadRequest{Url: "https://aws.s3/bucket/test.jpg", ExpiresIn: 60},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "PresignDownload",
+					Args: []interface{}{mock.Anything, &dto.PresignDownloadInput{
+						Url:     "https://aws.s3/bucket/test.jpg",
+						Expires: time.Minute,
+					}},
+					ReturnArgs: []interface{}{&dto.PresignOutput{
+						Url:       "https://aws.s3/bucket/test.jpg?signature",
+						ExpiresAt: expiresAt,
+					}, nil},
+				},
+			},
+			want: &fileStorage.PresignResponse{
+				Url:       "https://aws.s3/bucket/test.jpg?signature",
+				ExpiresAt: expiresAt.Unix(),
+			},
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "not supported",
+			in:   &fileStorage.PresignDownloadRequest{Url: "https://aws.s3/bucket/test.jpg"},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "PresignDownload",
+					Args:       []interface{}{mock.Anything, &dto.PresignDownloadInput{Url: "https://aws.s3/bucket/test.jpg"}},
+					ReturnArgs: []interface{}{nil, customErrors.NotSupported},
+				},
+			},
+			wantErrCode: codes.Unimplemented,
+		},
+		{
+			name: "validation error",
+			in:   &fileStorage.PresignDownloadRequest{Url: "https://aws.s3/bucket/test.jpg"},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "PresignDownload",
+					Args:       []interface{}{mock.Anything, &dto.PresignDownloadInput{Url: "https://aws.s3/bucket/test.jpg"}},
+					ReturnArgs: []interface{}{nil, validation.Errors{}},
+				},
+			},
+			wantErrCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			got, gotErr := client.PresignDownload(helpers.DefaultCtx, tt.in)
+			grpcErr, ok := status.FromError(gotErr)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+			if tt.want != nil {
+				assert.EqualValues(t, tt.want.Url, got.GetUrl())
+				assert.EqualValues(t, tt.want.ExpiresAt, got.GetExpiresAt())
+			}
+
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_PresignUpload(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	tests := []struct {
+		name        string
+		in          *fileStorage.PresignUploadRequest
+		mockCalls   helpers.MockCalls
+		want        *fileStorage.PresignResponse
+		wantErrCode codes.Code
+	}{
+		{
+			name: "succeed",
+			in: &fileStorage.PresignUploadRequest{
+				Directory:   "test",
+				Filename:    "test.jpg",
+				Acl:         "public-read",
+				ContentType: "image/jpeg",
+				ExpiresIn:   60,
+				Size:        1024,
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "PresignUpload",
+					Args: []interface{}{mock.Anything, &dto.PresignUploadInput{
+						Directory:   "test",
+						Filename:    "test.jpg",
+						ACL:         "public-read",
+						ContentType: "image/jpeg",
+						Size:        1024,
+						Expires:     time.Minute,
+					}},
+					ReturnArgs: []interface{}{&dto.PresignOutput{
+						Url:     "https://aws.s3/bucket/test/test.jpg?signature",
+						Headers: map[string]string{"X-Amz-Acl": "public-read", "Content-Type": "image/jpeg"},
+					}, nil},
+				},
+			},
+			want: &fileStorage.PresignResponse{
+				Url:     "https://aws.s3/bucket/test/test.jpg?signature",
+				Headers: map[string]string{"X-Amz-Acl": "public-read", "Content-Type": "image/jpeg"},
+			},
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "internal error",
+			in:   &fileStorage.PresignUploadRequest{Filename: "test.jpg"},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "PresignUpload",
+					Args:       []interface{}{mock.Anything, &dto.PresignUploadInput{Filename: "test.jpg"}},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
+				},
+			},
+			wantErrCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			got, gotErr := client.PresignUpload(helpers.DefaultCtx, tt.in)
+			grpcErr, ok := status.FromError(gotErr)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+			if tt.want != nil {
+				assert.EqualValues(t, tt.want.Url, got.GetUrl())
+				assert.EqualValues(t, tt.want.Headers, got.GetHeaders())
+			}
+
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_MemoryRepo(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	repo := fileRepo.NewMemory("http://localhost/files")
+	server.Handler().SetFileUseCase(fileUseCase.New(repo))
+
+	var urls []string
+	for _, filename := range []string{"1mb.jpg", "1mb_2.jpg", "1mb_3.jpg"} {
+		url, err := uploadFile(
+			t,
+			client,
+			helpers.DefaultCtx,
+			&fileStorage.MetaData{Directory: "test", Filename: filename},
+			helpers.OpenFile(t, "testdata/1mb.jpg"),
+		)
+		assert.NoError(t, err)
+		assert.EqualValues(t, "http://localhost/files/test/"+filename, url)
+		urls = append(urls, url)
+	}
+	content, err := ioutil.ReadFile("testdata/1mb.jpg")
+	assert.NoError(t, err)
+	obj, ok := repo.Object(urls[0])
+	assert.True(t, ok)
+	assert.EqualValues(t, content, obj.Body)
+
+	info, gotContent, err := downloadFile(t, client, helpers.DefaultCtx, &fileStorage.DownloadRequest{Url: urls[0]})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "image/jpeg", info.GetContentType())
+	assert.EqualValues(t, len(content), info.GetSize())
+	assert.EqualValues(t, content, gotContent)
+
+	info, gotContent, err = downloadFile(
+		t,
+		client,
+		helpers.DefaultCtx,
+		&fileStorage.DownloadRequest{Url: urls[0], Offset: int64(len(content)) - 10},
+	)
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(content), info.GetSize())
+	assert.EqualValues(t, 10, info.GetLength())
+	assert.EqualValues(t, content[len(content)-10:], gotContent)
+
+	stat, err := client.Stat(helpers.DefaultCtx, &fileStorage.StatRequest{Url: urls[0]})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test/1mb.jpg", stat.GetKey())
+	assert.EqualValues(t, len(content), stat.GetSize())
+	assert.EqualValues(t, "image/jpeg", stat.GetContentType())
+
+	list, err := client.List(helpers.DefaultCtx, &fileStorage.ListRequest{Prefix: "test/", PageSize: 2})
+	assert.NoError(t, err)
+	assert.Len(t, list.GetFiles(), 2)
+	assert.EqualValues(t, urls[0], list.GetFiles()[0].GetUrl())
+	list, err = client.List(helpers.DefaultCtx, &fileStorage.ListRequest{Prefix: "test/", PageToken: list.GetNextPageToken()})
+	assert.NoError(t, err)
+	assert.Len(t, list.GetFiles(), 1)
+	assert.Empty(t, list.GetNextPageToken())
+
+	moved, err := client.Move(helpers.DefaultCtx, &fileStorage.CopyRequest{
+		SourceUrl: urls[0],
+		Directory: "moved",
+		Filename:  "1mb.jpg",
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/moved/1mb.jpg", moved.GetUrl())
+	_, ok = repo.Object(urls[0])
+	assert.False(t, ok)
+	_, err = client.Stat(helpers.DefaultCtx, &fileStorage.StatRequest{Url: urls[0]})
+	assert.EqualValues(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Delete(helpers.DefaultCtx, &fileStorage.DeleteRequest{Url: moved.GetUrl()})
+	assert.NoError(t, err)
+	_, ok = repo.Object(moved.GetUrl())
+	assert.False(t, ok)
+
+	_, err = client.BatchDelete(helpers.DefaultCtx, &fileStorage.BatchDeleteRequest{Urls: urls[1:]})
+	assert.NoError(t, err)
+	assert.Empty(t, repo.Objects())
+
+	sum := sha256.Sum256(content)
+	url, err := uploadFile(
+		t,
+		client,
+		helpers.DefaultCtx,
+		&fileStorage.MetaData{Directory: "test", Filename: "1mb.jpg", Sha256: hex.EncodeToString(sum[:])},
+		bytes.NewReader(content),
+	)
+	assert.NoError(t, err)
+	stat, err = client.Stat(helpers.DefaultCtx, &fileStorage.StatRequest{Url: url})
+	assert.NoError(t, err)
+	assert.EqualValues(t, hex.EncodeToString(sum[:]), stat.GetMetadata()[dto.SHA256MetadataKey])
+
+	_, err = uploadFile(
+		t,
+		client,
+		helpers.DefaultCtx,
+		&fileStorage.MetaData{Directory: "test", Filename: "corrupted.jpg", Sha256: hex.EncodeToString(sum[:])},
+		bytes.NewReader(content[1:]),
+	)
+	assert.EqualValues(t, codes.DataLoss, status.Code(err))
+	assert.Len(t, repo.Objects(), 1)
+}
+
+func TestHandler_Policies(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	repo := fileRepo.NewMemory("http://localhost/files")
+	server.Handler().SetFileUseCase(fileUseCase.New(repo, fileUseCase.WithPolicies(policy.Rules{
+		{Prefix: "test/", MaxSize: int64(512 * units.KB), ContentTypes: []string{"image/*"}},
+	})))
+
+	_, err := uploadFile(
+		t,
+		client,
+		helpers.DefaultCtx,
+		&fileStorage.MetaData{Directory: "test", Filename: "1mb.jpg"},
+		helpers.OpenFile(t, "testdata/1mb.jpg"),
+	)
+	assert.EqualValues(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = uploadFile(
+		t,
+		client,
+		helpers.DefaultCtx,
+		&fileStorage.MetaData{Directory: "test", Filename: "test.txt"},
+		bytes.NewBufferString("test"),
+	)
+	assert.EqualValues(t, codes.InvalidArgument, status.Code(err))
+	assert.Empty(t, repo.Objects())
+}
+
+func uploadPart(
+	t *testing.T,
+	client fileStorage.FileStorageClient,
+	info *fileStorage.UploadPartInfo,
+	content []byte,
+) (*fileStorage.UploadedPart, error) {
+	t.Helper()
+	stream, err := client.UploadPart(helpers.DefaultCtx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&fileStorage.UploadPartRequest{Part: &fileStorage.UploadPartRequest_Info{Info: info}}); err != nil {
+		return nil, err
+	}
+	request := &fileStorage.UploadPartRequest{Part: &fileStorage.UploadPartRequest_Content{Content: content}}
+	if err := stream.Send(request); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return stream.CloseAndRecv()
+}
+
+func TestHandler_UploadSession(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	uploadId := dto.NewUploadId("test/test.jpg", "multipart-id")
+	sessionInput := &dto.UploadSessionInput{UploadId: uploadId}
+	session := &dto.UploadSession{
+		UploadId: uploadId,
+		Key:      "test/test.jpg",
+		Url:      "https://aws.s3/bucket/test/test.jpg",
+		Parts:    []*dto.UploadPart{{PartNumber: 1, Size: 4, ETag: `"1"`}},
+	}
+	sessionResponse := &fileStorage.UploadSession{
+		UploadId: uploadId,
+		Key:      "test/test.jpg",
+		Url:      "https://aws.s3/bucket/test/test.jpg",
+		Parts:    []*fileStorage.UploadedPart{{PartNumber: 1, Size: 4, Etag: `"1"`}},
+	}
+	tests := []struct {
+		name        string
+		call        func() (interface{}, error)
+		mockCalls   helpers.MockCalls
+		want        interface{}
+		wantErrCode codes.Code
+	}{
+		{
+			name: "initiate",
+			call: func() (interface{}, error) {
+				return client.InitiateUpload(helpers.DefaultCtx, &fileStorage.InitiateUploadRequest{
+					Directory: "test",
+					Filename:  "test.jpg",
+					Acl:       "private",
+				})
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "InitiateUpload",
+					Args:       []interface{}{mock.Anything, &dto.InitiateUploadInput{Directory: "test", Filename: "test.jpg", ACL: "private"}},
+					ReturnArgs: []interface{}{session, nil},
+				},
+			},
+			want:        sessionResponse,
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "upload part",
+			call: func() (interface{}, error) {
+				return uploadPart(t, client, &fileStorage.UploadPartInfo{UploadId: uploadId, PartNumber: 1}, []byte("test"))
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "UploadPart",
+					Args: []interface{}{mock.Anything, mock.MatchedBy(func(input *dto.UploadPartInput) bool {
+						content, err := ioutil.ReadAll(input.File)
+						return err == nil && string(content) == "test" && input.UploadId == uploadId && input.PartNumber == 1
+					})},
+					ReturnArgs: []interface{}{session.Parts[0], nil},
+				},
+			},
+			want:        sessionResponse.Parts[0],
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "too large part",
+			call: func() (interface{}, error) {
+				return uploadPart(t, client, &fileStorage.UploadPartInfo{UploadId: uploadId, PartNumber: 1}, []byte("test"))
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "UploadPart",
+					Args:       []interface{}{mock.Anything, mock.Anything},
+					ReturnArgs: []interface{}{nil, customErrors.PartTooLarge},
+				},
+			},
+			wantErrCode: codes.ResourceExhausted,
+		},
+		{
+			name: "get",
+			call: func() (interface{}, error) {
+				return client.GetUpload(helpers.DefaultCtx, &fileStorage.UploadSessionRequest{UploadId: uploadId})
+			},
+			mockCalls: helpers.MockCalls{
+				{Method: "GetUpload", Args: []interface{}{mock.Anything, sessionInput}, ReturnArgs: []interface{}{session, nil}},
+			},
+			want:        sessionResponse,
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "get missing",
+			call: func() (interface{}, error) {
+				return client.GetUpload(helpers.DefaultCtx, &fileStorage.UploadSessionRequest{UploadId: uploadId})
+			},
+			mockCalls: helpers.MockCalls{
+				{Method: "GetUpload", Args: []interface{}{mock.Anything, sessionInput}, ReturnArgs: []interface{}{nil, customErrors.UploadNotFound}},
+			},
+			wantErrCode: codes.NotFound,
+		},
+		{
+			name: "complete",
+			call: func() (interface{}, error) {
+				return client.CompleteUpload(helpers.DefaultCtx, &fileStorage.UploadSessionRequest{UploadId: uploadId})
+			},
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "CompleteUpload",
+					Args:       []interface{}{mock.Anything, sessionInput},
+					ReturnArgs: []interface{}{"https://aws.s3/bucket/test/test.jpg", nil},
+				},
+			},
+			want:        &fileStorage.UploadResponse{Url: "https://aws.s3/bucket/test/test.jpg"},
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "complete empty",
+			call: func() (interface{}, error) {
+				return client.CompleteUpload(helpers.DefaultCtx, &fileStorage.UploadSessionRequest{UploadId: uploadId})
+			},
+			mockCalls: helpers.MockCalls{
+				{Method: "CompleteUpload", Args: []interface{}{mock.Anything, sessionInput}, ReturnArgs: []interface{}{"", customErrors.EmptyUpload}},
+			},
+			wantErrCode: codes.InvalidArgument,
+		},
+		{
+			name: "abort",
+			call: func() (interface{}, error) {
+				_, err := client.AbortUpload(helpers.DefaultCtx, &fileStorage.UploadSessionRequest{UploadId: uploadId})
+				return nil, err
+			},
+			mockCalls: helpers.MockCalls{
+				{Method: "AbortUpload", Args: []interface{}{mock.Anything, sessionInput}, ReturnArgs: []interface{}{nil}},
+			},
+			wantErrCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			got, gotErr := tt.call()
+			grpcErr, ok := status.FromError(gotErr)
+			assert.True(t, ok)
+			assert.EqualValues(t, tt.wantErrCode, grpcErr.Code(), grpcErr.Message())
+			if tt.want != nil {
+				assert.True(t, proto.Equal(tt.want.(proto.Message), got.(proto.Message)), got)
+			}
+
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_Reconcile(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	input := &dto.ReconcileInput{
+		Prefix:      "test/",
+		LiveUrls:    []string{"https://aws.s3/bucket/test/live.jpg"},
+		GracePeriod: time.Hour,
+		Delete:      true,
+	}
+	request := &fileStorage.ReconcileRequest{
+		Prefix:      "test/",
+		LiveUrls:    []string{"https://aws.s3/bucket/test/live.jpg"},
+		GracePeriod: 3600,
+		Delete:      true,
+	}
+	tests := []struct {
+		name        string
+		in          *fileStorage.ReconcileRequest
+		mockCalls   helpers.MockCalls
+		want        *fileStorage.ReconcileReport
+		wantErrCode codes.Code
+	}{
+		{
+			name: "succeed",
+			in:   request,
+			mockCalls: helpers.MockCalls{
+				{
+					Method: "Reconcile",
+					Args:   []interface{}{mock.Anything, input},
+					ReturnArgs: []interface{}{&dto.ReconcileReport{
+						Scanned:     2,
+						Orphans:     []string{"https://aws.s3/bucket/test/leaked.jpg"},
+						OrphansSize: 4,
+					}, nil},
+				},
+			},
+			want: &fileStorage.ReconcileReport{
+				Scanned:     2,
+				Orphans:     []string{"https://aws.s3/bucket/test/leaked.jpg"},
+				OrphansSize: 4,
+			},
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "without catalog",
+			in:   request,
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Reconcile",
+					Args:       []interface{}{mock.Anything, input},
+					ReturnArgs: []interface{}{nil, validation.Errors{"LiveUrls": customErrors.LiveUrlsRequired}},
+				},
+			},
+			wantErrCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			got, gotErr := client.Reconcile(helpers.DefaultCtx, tt.in)
+			assert.EqualValues(t, tt.wantErrCode, status.Code(gotErr), gotErr)
+			if tt.want != nil {
+				assert.True(t, proto.Equal(tt.want, got), got)
+			}
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_Restore(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	trashUrl := "https://aws.s3/bucket/_trash/test.jpg/00000000000000010000abcd"
+	tests := []struct {
+		name        string
+		mockCalls   helpers.MockCalls
+		wantUrl     string
+		wantErrCode codes.Code
+	}{
+		{
+			name: "succeed",
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Restore",
+					Args:       []interface{}{mock.Anything, &dto.RestoreInput{Url: trashUrl}},
+					ReturnArgs: []interface{}{"https://aws.s3/bucket/test.jpg", nil},
+				},
+			},
+			wantUrl:     "https://aws.s3/bucket/test.jpg",
+			wantErrCode: codes.OK,
+		},
+		{
+			name: "already exists",
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Restore",
+					Args:       []interface{}{mock.Anything, &dto.RestoreInput{Url: trashUrl}},
+					ReturnArgs: []interface{}{"", customErrors.AlreadyExists},
+				},
+			},
+			wantErrCode: codes.AlreadyExists,
+		},
+		{
+			name: "trash disabled",
+			mockCalls: helpers.MockCalls{
+				{
+					Method:     "Restore",
+					Args:       []interface{}{mock.Anything, &dto.RestoreInput{Url: trashUrl}},
+					ReturnArgs: []interface{}{"", customErrors.NotSupported},
+				},
+			},
+			wantErrCode: codes.Unimplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			for _, call := range tt.mockCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			server.Handler().SetFileUseCase(useCase)
+
+			got, gotErr := client.Restore(helpers.DefaultCtx, &fileStorage.RestoreRequest{Url: trashUrl})
+			assert.EqualValues(t, tt.wantErrCode, status.Code(gotErr), gotErr)
+			assert.EqualValues(t, tt.wantUrl, got.GetUrl())
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ListTrash(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	deletedAt := time.Unix(1600000000, 0)
+	useCase := new(mocks.FileUseCase)
+	useCase.On("ListTrash", mock.Anything, &dto.ListTrashInput{Prefix: "test/", PageSize: 1}).Return(&dto.ListTrashOutput{
+		Items: []*dto.TrashItem{{
+			Url:       "https://aws.s3/bucket/_trash/test/test.jpg/00000000000000010000abcd",
+			Key:       "test/test.jpg",
+			Size:      4,
+			DeletedAt: deletedAt,
+			ExpiresAt: deletedAt.Add(time.Hour),
+		}},
+		NextPageToken: "token",
+	}, nil)
+	useCase.On("ListTrash", mock.Anything, &dto.ListTrashInput{PageSize: -1}).Return(nil, validation.Errors{"PageSize": validation.ErrMinGreaterEqualThanRequired})
+	server.Handler().SetFileUseCase(useCase)
+
+	got, err := client.ListTrash(helpers.DefaultCtx, &fileStorage.ListTrashRequest{Prefix: "test/", PageSize: 1})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(&fileStorage.ListTrashResponse{
+		Items: []*fileStorage.TrashItem{{
+			Url:       "https://aws.s3/bucket/_trash/test/test.jpg/00000000000000010000abcd",
+			Key:       "test/test.jpg",
+			Size:      4,
+			DeletedAt: deletedAt.Unix(),
+			ExpiresAt: deletedAt.Add(time.Hour).Unix(),
+		}},
+		NextPageToken: "token",
+	}, got), got)
+
+	_, err = client.ListTrash(helpers.DefaultCtx, &fileStorage.ListTrashRequest{PageSize: -1})
+	assert.EqualValues(t, codes.InvalidArgument, status.Code(err))
+	useCase.AssertExpectations(t)
+}
+
+func TestHandler_ListVersions(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	modifiedAt := time.Unix(1600000000, 0)
+	url := "https://aws.s3/bucket/test/test.jpg"
+	useCase := new(mocks.FileUseCase)
+	useCase.On("ListVersions", mock.Anything, &dto.ListVersionsInput{Url: url, PageSize: 2}).Return(&dto.ListVersionsOutput{
+		Versions: []*dto.FileVersion{
+			{VersionId: "v2", LastModified: modifiedAt, IsLatest: true, IsDeleteMarker: true},
+			{VersionId: "v1", Size: 4, ETag: "etag", LastModified: modifiedAt.Add(-time.Hour)},
+		},
+		NextPageToken: "v1",
+	}, nil)
+	useCase.On("ListVersions", mock.Anything, &dto.ListVersionsInput{Url: "https://aws.s3/bucket/local.jpg"}).Return(nil, customErrors.NotSupported)
+	server.Handler().SetFileUseCase(useCase)
+
+	got, err := client.ListVersions(helpers.DefaultCtx, &fileStorage.ListVersionsRequest{Url: url, PageSize: 2})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(&fileStorage.ListVersionsResponse{
+		Versions: []*fileStorage.FileVersion{
+			{VersionId: "v2", LastModified: modifiedAt.Unix(), IsLatest: true, IsDeleteMarker: true},
+			{VersionId: "v1", Size: 4, Etag: "etag", LastModified: modifiedAt.Add(-time.Hour).Unix()},
+		},
+		NextPageToken: "v1",
+	}, got), got)
+
+	_, err = client.ListVersions(helpers.DefaultCtx, &fileStorage.ListVersionsRequest{Url: "https://aws.s3/bucket/local.jpg"})
+	assert.EqualValues(t, codes.Unimplemented, status.Code(err))
+	useCase.AssertExpectations(t)
+}
+
+func TestHandler_GetVersion(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	url := "https://aws.s3/bucket/test/test.txt"
+	useCase := new(mocks.FileUseCase)
+	useCase.On("GetVersion", mock.Anything, &dto.GetVersionInput{
+		DownloadInput: dto.DownloadInput{Url: url, Offset: 1, Length: 2},
+		VersionId:     "v1",
+	}).Return(&dto.DownloadOutput{
+		File:        ioutil.NopCloser(bytes.NewReader([]byte("es"))),
+		ContentType: "text/plain",
+		Size:        4,
+		Offset:      1,
+		Length:      2,
+	}, nil)
+	useCase.On("GetVersion", mock.Anything, &dto.GetVersionInput{
+		DownloadInput: dto.DownloadInput{Url: url},
+		VersionId:     "marker",
+	}).Return(nil, customErrors.VersionNotFound)
+	server.Handler().SetFileUseCase(useCase)
+
+	stream, err := client.GetVersion(helpers.DefaultCtx, &fileStorage.GetVersionRequest{Url: url, VersionId: "v1", Offset: 1, Length: 2})
+	assert.NoError(t, err)
+	resp, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(&fileStorage.FileInfo{ContentType: "text/plain", Size: 4, Offset: 1, Length: 2}, resp.GetInfo()), resp)
+	resp, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "es", string(resp.GetContent()))
+	_, err = stream.Recv()
+	assert.EqualValues(t, io.EOF, err)
+
+	stream, err = client.GetVersion(helpers.DefaultCtx, &fileStorage.GetVersionRequest{Url: url, VersionId: "marker"})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.EqualValues(t, codes.NotFound, status.Code(err))
+	useCase.AssertExpectations(t)
+}
+
+func TestHandler_RestoreVersion(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	url := "https://aws.s3/bucket/test/test.jpg"
+	useCase := new(mocks.FileUseCase)
+	useCase.On("RestoreVersion", mock.Anything, &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "private"}).
+		Return(&dto.UploadOutput{Url: url, VersionId: "v3"}, nil)
+	useCase.On("RestoreVersion", mock.Anything, &dto.RestoreVersionInput{Url: url, VersionId: "missing"}).
+		Return(nil, customErrors.VersionNotFound)
+	server.Handler().SetFileUseCase(useCase)
+
+	got, err := client.RestoreVersion(helpers.DefaultCtx, &fileStorage.RestoreVersionRequest{Url: url, VersionId: "v1", Acl: "private"})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(&fileStorage.UploadResponse{Url: url, VersionId: "v3"}, got), got)
+
+	_, err = client.RestoreVersion(helpers.DefaultCtx, &fileStorage.RestoreVersionRequest{Url: url, VersionId: "missing"})
+	assert.EqualValues(t, codes.NotFound, status.Code(err))
+	useCase.AssertExpectations(t)
+}
+
+func TestHandler_Actor(t *testing.T) {
+	conf := &config.ApiConfig{Host: "localhost", Port: 9998}
+	server := testServer(t, conf)
+	client := testClient(t, conf)
+
+	withActor := mock.MatchedBy(func(ctx context.Context) bool {
+		return dto.ActorFromContext(ctx) == "admin"
+	})
+	url := "https://aws.s3/bucket/test/test.jpg"
+	useCase := new(mocks.FileUseCase)
+	useCase.On("Delete", withActor, dto.DeleteInput(url)).Return(nil)
+	useCase.On("Upload", withActor, mock.Anything).Return(&dto.UploadOutput{Url: url}, nil)
+	server.Handler().SetFileUseCase(useCase)
+
+	ctx := metadata.AppendToOutgoingContext(helpers.DefaultCtx, "actor", "admin")
+	_, err := client.Delete(ctx, &fileStorage.DeleteRequest{Url: url})
+	assert.NoError(t, err)
+	got, err := uploadFile(t, client, ctx, &fileStorage.MetaData{Filename: "test.jpg", Directory: "test"}, bytes.NewBufferString("test"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, url, got)
+	useCase.AssertExpectations(t)
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"io"
 	"log"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	fileStorage "github.com/freemen-app/api/file_storage"
@@ -17,6 +19,12 @@ import (
 	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
 )
 
+// downloadChunkSize is a size of file chunk sent to client in a single message
+const downloadChunkSize = 1 << 20
+
+// actorMetadataKey is the key of request metadata naming who makes the request, it is published with file events
+const actorMetadataKey = "actor"
+
 type handler struct {
 	fileUseCase   fileUseCase.UseCase
 	grpcPresenter grpcPresenter.Presenter
@@ -51,41 +59,179 @@ func (h *handler) Upload(stream fileStorage.FileStorage_UploadServer) error {
 	)
 
 	pr, pw := io.Pipe()
-	go func(w *io.PipeWriter) {
-		for {
-			req, err := stream.Recv()
-			if err != nil {
-				log.Printf("Got error in pipewriter: %v", err)
-				_ = w.CloseWithError(err)
-				break
-			}
-			chunk := req.GetContent()
-			log.Printf("received a chunk with size: %d", len(chunk))
-			n, err := w.Write(chunk)
-			if err != nil {
-				_ = w.CloseWithError(err)
-				break
-			}
-			log.Printf("wrote %d bytes to a pipe writer", n)
-		}
-	}(pw)
+	// Closing reader unblocks writer if upload was stopped before the end of stream
+	defer pr.Close()
+	go pipeContent(pw, func() ([]byte, error) {
+		req, err := stream.Recv()
+		return req.GetContent(), err
+	})
 
 	uploadInput := &dto.UploadInput{
-		File:      pr,
-		Directory: metadata.GetDirectory(),
-		Filename:  metadata.GetFilename(),
-		ACL:       "public-read",
+		File:        pr,
+		Directory:   metadata.GetDirectory(),
+		Filename:    metadata.GetFilename(),
+		ACL:         metadata.GetAcl(),
+		ContentType: metadata.GetContentType(),
+		SHA256:      metadata.GetSha256(),
+		MD5:         metadata.GetMd5(),
+		Owner:       metadata.GetOwner(),
+		Collision:   metadata.GetCollision(),
 	}
-	if url, err := h.fileUseCase.Upload(stream.Context(), uploadInput); err != nil {
+	if output, err := h.fileUseCase.Upload(stream.Context(), uploadInput); err != nil {
 		log.Printf("Got error from upload: %s", err.Error())
 		return h.grpcPresenter.ConvertError(err).Err()
-	} else if err := stream.SendAndClose(&fileStorage.UploadResponse{Url: url}); err != nil {
+	} else if err := stream.SendAndClose(&fileStorage.UploadResponse{Url: output.Url, VersionId: output.VersionId}); err != nil {
 		log.Printf("Got error from send and close: %s", err.Error())
 		return status.Errorf(codes.Unknown, "cannot send response: %v", err)
 	}
 	return nil
 }
 
+// pipeContent writes chunks received from client stream to the pipe until the end of stream
+func pipeContent(w *io.PipeWriter, recv func() ([]byte, error)) {
+	for {
+		chunk, err := recv()
+		if err != nil {
+			log.Printf("Got error in pipewriter: %v", err)
+			_ = w.CloseWithError(err)
+			break
+		}
+		log.Printf("received a chunk with size: %d", len(chunk))
+		n, err := w.Write(chunk)
+		if err != nil {
+			_ = w.CloseWithError(err)
+			break
+		}
+		log.Printf("wrote %d bytes to a pipe writer", n)
+	}
+}
+
+func (h *handler) Download(request *fileStorage.DownloadRequest, stream fileStorage.FileStorage_DownloadServer) error {
+	log.Printf(
+		"receive a download request for url [%s] with offset %d and length %d",
+		request.GetUrl(),
+		request.GetOffset(),
+		request.GetLength(),
+	)
+	downloadInput := &dto.DownloadInput{
+		Url:    request.GetUrl(),
+		Offset: request.GetOffset(),
+		Length: request.GetLength(),
+	}
+	output, err := h.fileUseCase.Download(stream.Context(), downloadInput)
+	if err != nil {
+		log.Printf("Got error from download: %s", err.Error())
+		return h.grpcPresenter.ConvertError(err).Err()
+	}
+	return h.sendFile(output, stream)
+}
+
+// sendFile sends file info followed by file content split into chunks
+func (h *handler) sendFile(output *dto.DownloadOutput, stream grpc.ServerStream) error {
+	defer output.File.Close()
+
+	info := &fileStorage.DownloadResponse{
+		File: &fileStorage.DownloadResponse_Info{Info: &fileStorage.FileInfo{
+			ContentType: output.ContentType,
+			Size:        output.Size,
+			Offset:      output.Offset,
+			Length:      output.Length,
+		}},
+	}
+	if err := stream.SendMsg(info); err != nil {
+		return status.Errorf(codes.Unknown, "cannot send file info: %v", err)
+	}
+
+	// Send file by chunks
+	chunk := make([]byte, downloadChunkSize)
+	for {
+		n, err := io.ReadFull(output.File, chunk)
+		if n > 0 {
+			response := &fileStorage.DownloadResponse{
+				File: &fileStorage.DownloadResponse_Content{Content: chunk[:n]},
+			}
+			if err := stream.SendMsg(response); err != nil {
+				return status.Errorf(codes.Unknown, "cannot send chunk: %v", err)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			log.Printf("Got error while reading file: %s", err.Error())
+			return h.grpcPresenter.ConvertError(err).Err()
+		}
+	}
+	return nil
+}
+
+func (h *handler) Stat(ctx context.Context, request *fileStorage.StatRequest) (*fileStorage.StatResponse, error) {
+	info, err := h.fileUseCase.Stat(ctx, &dto.StatInput{Url: request.GetUrl()})
+	if err != nil {
+		return nil, err
+	}
+	return &fileStorage.StatResponse{
+		Url:          info.Url,
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		Etag:         info.ETag,
+		LastModified: info.LastModified.Unix(),
+		Metadata:     info.Metadata,
+	}, nil
+}
+
+func (h *handler) List(ctx context.Context, request *fileStorage.ListRequest) (*fileStorage.ListResponse, error) {
+	output, err := h.fileUseCase.List(ctx, &dto.ListInput{
+		Prefix:    request.GetPrefix(),
+		Delimiter: request.GetDelimiter(),
+		PageSize:  request.GetPageSize(),
+		PageToken: request.GetPageToken(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*fileStorage.ListItem, len(output.Files))
+	for i, file := range output.Files {
+		files[i] = &fileStorage.ListItem{
+			Url:          file.Url,
+			Key:          file.Key,
+			Size:         file.Size,
+			Etag:         file.ETag,
+			LastModified: file.LastModified.Unix(),
+		}
+	}
+	return &fileStorage.ListResponse{
+		Files:         files,
+		Prefixes:      output.Prefixes,
+		NextPageToken: output.NextPageToken,
+	}, nil
+}
+
+func (h *handler) Copy(ctx context.Context, request *fileStorage.CopyRequest) (*fileStorage.CopyResponse, error) {
+	url, err := h.fileUseCase.Copy(ctx, copyInput(request))
+	if err != nil {
+		return nil, err
+	}
+	return &fileStorage.CopyResponse{Url: url}, nil
+}
+
+func (h *handler) Move(ctx context.Context, request *fileStorage.CopyRequest) (*fileStorage.CopyResponse, error) {
+	url, err := h.fileUseCase.Move(ctx, copyInput(request))
+	if err != nil {
+		return nil, err
+	}
+	return &fileStorage.CopyResponse{Url: url}, nil
+}
+
+func copyInput(request *fileStorage.CopyRequest) *dto.CopyInput {
+	return &dto.CopyInput{
+		SourceUrl: request.GetSourceUrl(),
+		Directory: request.GetDirectory(),
+		Filename:  request.GetFilename(),
+		ACL:       request.GetAcl(),
+	}
+}
+
 func (h *handler) Delete(ctx context.Context, request *fileStorage.DeleteRequest) (*empty.Empty, error) {
 	err := h.fileUseCase.Delete(ctx, dto.DeleteInput(request.Url))
 	return new(empty.Empty), err
@@ -100,6 +246,232 @@ func (h *handler) BatchDelete(ctx context.Context, request *fileStorage.BatchDel
 	return new(empty.Empty), err
 }
 
+func (h *handler) PresignDownload(ctx context.Context, request *fileStorage.PresignDownloadRequest) (*fileStorage.PresignResponse, error) {
+	output, err := h.fileUseCase.PresignDownload(ctx, &dto.PresignDownloadInput{
+		Url:     request.GetUrl(),
+		Expires: time.Duration(request.GetExpiresIn()) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return presignResponse(output), nil
+}
+
+func (h *handler) PresignUpload(ctx context.Context, request *fileStorage.PresignUploadRequest) (*fileStorage.PresignResponse, error) {
+	output, err := h.fileUseCase.PresignUpload(ctx, &dto.PresignUploadInput{
+		Directory:   request.GetDirectory(),
+		Filename:    request.GetFilename(),
+		ACL:         request.GetAcl(),
+		ContentType: request.GetContentType(),
+		Size:        request.GetSize(),
+		Expires:     time.Duration(request.GetExpiresIn()) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return presignResponse(output), nil
+}
+
+func presignResponse(output *dto.PresignOutput) *fileStorage.PresignResponse {
+	return &fileStorage.PresignResponse{
+		Url:       output.Url,
+		Headers:   output.Headers,
+		ExpiresAt: output.ExpiresAt.Unix(),
+	}
+}
+
+func (h *handler) InitiateUpload(ctx context.Context, request *fileStorage.InitiateUploadRequest) (*fileStorage.UploadSession, error) {
+	session, err := h.fileUseCase.InitiateUpload(ctx, &dto.InitiateUploadInput{
+		Directory:   request.GetDirectory(),
+		Filename:    request.GetFilename(),
+		ACL:         request.GetAcl(),
+		ContentType: request.GetContentType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uploadSessionResponse(session), nil
+}
+
+func (h *handler) UploadPart(stream fileStorage.FileStorage_UploadPartServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return status.Errorf(codes.Unknown, "cannot receive part info")
+	}
+	info := req.GetInfo()
+	log.Printf("receive part %d of upload [%s]", info.GetPartNumber(), info.GetUploadId())
+
+	pr, pw := io.Pipe()
+	// Closing reader unblocks writer if upload was stopped before the end of stream
+	defer pr.Close()
+	go pipeContent(pw, func() ([]byte, error) {
+		req, err := stream.Recv()
+		return req.GetContent(), err
+	})
+
+	part, err := h.fileUseCase.UploadPart(stream.Context(), &dto.UploadPartInput{
+		UploadId:   info.GetUploadId(),
+		PartNumber: info.GetPartNumber(),
+		File:       pr,
+		MD5:        info.GetMd5(),
+	})
+	if err != nil {
+		log.Printf("Got error from upload part: %s", err.Error())
+		return h.grpcPresenter.ConvertError(err).Err()
+	} else if err := stream.SendAndClose(uploadedPartResponse(part)); err != nil {
+		log.Printf("Got error from send and close: %s", err.Error())
+		return status.Errorf(codes.Unknown, "cannot send response: %v", err)
+	}
+	return nil
+}
+
+func (h *handler) GetUpload(ctx context.Context, request *fileStorage.UploadSessionRequest) (*fileStorage.UploadSession, error) {
+	session, err := h.fileUseCase.GetUpload(ctx, &dto.UploadSessionInput{UploadId: request.GetUploadId()})
+	if err != nil {
+		return nil, err
+	}
+	return uploadSessionResponse(session), nil
+}
+
+func (h *handler) CompleteUpload(ctx context.Context, request *fileStorage.UploadSessionRequest) (*fileStorage.UploadResponse, error) {
+	url, err := h.fileUseCase.CompleteUpload(ctx, &dto.UploadSessionInput{UploadId: request.GetUploadId()})
+	if err != nil {
+		return nil, err
+	}
+	return &fileStorage.UploadResponse{Url: url}, nil
+}
+
+func (h *handler) AbortUpload(ctx context.Context, request *fileStorage.UploadSessionRequest) (*empty.Empty, error) {
+	err := h.fileUseCase.AbortUpload(ctx, &dto.UploadSessionInput{UploadId: request.GetUploadId()})
+	return new(empty.Empty), err
+}
+
+func (h *handler) Reconcile(ctx context.Context, request *fileStorage.ReconcileRequest) (*fileStorage.ReconcileReport, error) {
+	report, err := h.fileUseCase.Reconcile(ctx, &dto.ReconcileInput{
+		Prefix:      request.GetPrefix(),
+		LiveUrls:    request.GetLiveUrls(),
+		GracePeriod: time.Duration(request.GetGracePeriod()) * time.Second,
+		Delete:      request.GetDelete(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fileStorage.ReconcileReport{
+		Scanned:     int64(report.Scanned),
+		Orphans:     report.Orphans,
+		OrphansSize: report.OrphansSize,
+		Deleted:     int64(report.Deleted),
+	}, nil
+}
+
+func (h *handler) Restore(ctx context.Context, request *fileStorage.RestoreRequest) (*fileStorage.RestoreResponse, error) {
+	url, err := h.fileUseCase.Restore(ctx, &dto.RestoreInput{Url: request.GetUrl()})
+	if err != nil {
+		return nil, err
+	}
+	return &fileStorage.RestoreResponse{Url: url}, nil
+}
+
+func (h *handler) ListTrash(ctx context.Context, request *fileStorage.ListTrashRequest) (*fileStorage.ListTrashResponse, error) {
+	output, err := h.fileUseCase.ListTrash(ctx, &dto.ListTrashInput{
+		Prefix:    request.GetPrefix(),
+		PageSize:  request.GetPageSize(),
+		PageToken: request.GetPageToken(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*fileStorage.TrashItem, len(output.Items))
+	for i, item := range output.Items {
+		items[i] = &fileStorage.TrashItem{
+			Url:       item.Url,
+			Key:       item.Key,
+			Size:      item.Size,
+			DeletedAt: item.DeletedAt.Unix(),
+			ExpiresAt: item.ExpiresAt.Unix(),
+		}
+	}
+	return &fileStorage.ListTrashResponse{
+		Items:         items,
+		NextPageToken: output.NextPageToken,
+	}, nil
+}
+
+func (h *handler) ListVersions(ctx context.Context, request *fileStorage.ListVersionsRequest) (*fileStorage.ListVersionsResponse, error) {
+	output, err := h.fileUseCase.ListVersions(ctx, &dto.ListVersionsInput{
+		Url:       request.GetUrl(),
+		PageSize:  request.GetPageSize(),
+		PageToken: request.GetPageToken(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]*fileStorage.FileVersion, len(output.Versions))
+	for i, version := range output.Versions {
+		versions[i] = &fileStorage.FileVersion{
+			VersionId:      version.VersionId,
+			Size:           version.Size,
+			Etag:           version.ETag,
+			LastModified:   version.LastModified.Unix(),
+			IsLatest:       version.IsLatest,
+			IsDeleteMarker: version.IsDeleteMarker,
+		}
+	}
+	return &fileStorage.ListVersionsResponse{
+		Versions:      versions,
+		NextPageToken: output.NextPageToken,
+	}, nil
+}
+
+func (h *handler) GetVersion(request *fileStorage.GetVersionRequest, stream fileStorage.FileStorage_GetVersionServer) error {
+	output, err := h.fileUseCase.GetVersion(stream.Context(), &dto.GetVersionInput{
+		DownloadInput: dto.DownloadInput{
+			Url:    request.GetUrl(),
+			Offset: request.GetOffset(),
+			Length: request.GetLength(),
+		},
+		VersionId: request.GetVersionId(),
+	})
+	if err != nil {
+		log.Printf("Got error from get version: %s", err.Error())
+		return h.grpcPresenter.ConvertError(err).Err()
+	}
+	return h.sendFile(output, stream)
+}
+
+func (h *handler) RestoreVersion(ctx context.Context, request *fileStorage.RestoreVersionRequest) (*fileStorage.UploadResponse, error) {
+	output, err := h.fileUseCase.RestoreVersion(ctx, &dto.RestoreVersionInput{
+		Url:       request.GetUrl(),
+		VersionId: request.GetVersionId(),
+		ACL:       request.GetAcl(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &fileStorage.UploadResponse{Url: output.Url, VersionId: output.VersionId}, nil
+}
+
+func uploadSessionResponse(session *dto.UploadSession) *fileStorage.UploadSession {
+	parts := make([]*fileStorage.UploadedPart, len(session.Parts))
+	for i, part := range session.Parts {
+		parts[i] = uploadedPartResponse(part)
+	}
+	return &fileStorage.UploadSession{
+		UploadId: session.UploadId,
+		Key:      session.Key,
+		Url:      session.Url,
+		Parts:    parts,
+	}
+}
+
+func uploadedPartResponse(part *dto.UploadPart) *fileStorage.UploadedPart {
+	return &fileStorage.UploadedPart{
+		PartNumber: part.PartNumber,
+		Size:       part.Size,
+		Etag:       part.ETag,
+	}
+}
+
 func (h *handler) ErrMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	resp, err := handler(ctx, req)
 	if err != nil {
@@ -109,3 +481,31 @@ func (h *handler) ErrMiddleware(ctx context.Context, req interface{}, info *grpc
 	return resp, err
 
 }
+
+// ActorMiddleware passes actor of the request from actorMetadataKey metadata to use cases
+func (h *handler) ActorMiddleware(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(actorContext(ctx), req)
+}
+
+// ActorStreamMiddleware is ActorMiddleware of streaming methods
+func (h *handler) ActorStreamMiddleware(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &actorStream{ServerStream: stream, ctx: actorContext(stream.Context())})
+}
+
+// actorStream replaces context of the stream by context with actor
+type actorStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *actorStream) Context() context.Context {
+	return s.ctx
+}
+
+func actorContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(actorMetadataKey); len(values) > 0 {
+		return dto.WithActor(ctx, values[0])
+	}
+	return ctx
+}
@@ -0,0 +1,77 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	// Task is a run of the job, context of the run is limited by the interval of the job
+	Task func(ctx context.Context)
+
+	// Runner runs the task periodically, it isn't started unless the job is enabled
+	Runner struct {
+		name     string
+		interval time.Duration
+		enabled  bool
+		task     Task
+
+		stop      chan struct{}
+		done      chan struct{}
+		isRunning bool
+	}
+)
+
+func New(name string, interval time.Duration, enabled bool, task Task) *Runner {
+	return &Runner{name: name, interval: interval, enabled: enabled, task: task}
+}
+
+func (r *Runner) Start() error {
+	if r.isRunning || !r.enabled {
+		return nil
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(r.stop, r.done)
+	r.isRunning = true
+	log.Info().Msgf("Started %s with interval %s", r.name, r.interval)
+	return nil
+}
+
+func (r *Runner) IsRunning() bool {
+	return r.isRunning
+}
+
+// Shutdown waits until the current run is finished
+func (r *Runner) Shutdown() {
+	if !r.isRunning {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.isRunning = false
+	log.Info().Msgf("Stopped %s", r.name)
+}
+
+// Run runs the task once, the run is limited by the interval, so runs don't overlap
+func (r *Runner) Run() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+	r.task(ctx)
+}
+
+func (r *Runner) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.Run()
+		}
+	}
+}
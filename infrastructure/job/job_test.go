@@ -0,0 +1,146 @@
+package job_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/job"
+	"github.com/freemen-app/file_storage/infrastructure/purger"
+	"github.com/freemen-app/file_storage/infrastructure/reconciler"
+	"github.com/freemen-app/file_storage/infrastructure/relay"
+	"github.com/freemen-app/file_storage/infrastructure/sweeper"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+func TestRunner_Run(t *testing.T) {
+	var deadline time.Time
+	r := job.New("test job", time.Minute, true, func(ctx context.Context) {
+		deadline, _ = ctx.Deadline()
+	})
+	r.Run()
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func TestRunner_Start_Shutdown(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	r := job.New("test job", 10*time.Millisecond, true, func(context.Context) {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+	})
+
+	assert.NoError(t, r.Start())
+	assert.True(t, r.IsRunning())
+	assert.NoError(t, r.Start())
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("job wasn't started")
+	}
+	r.Shutdown()
+	assert.False(t, r.IsRunning())
+	assert.NotPanics(t, r.Shutdown)
+}
+
+func TestRunner_Disabled(t *testing.T) {
+	r := job.New("test job", time.Hour, false, func(context.Context) {
+		t.Fatal("disabled job was run")
+	})
+	assert.NoError(t, r.Start())
+	assert.False(t, r.IsRunning())
+	assert.NotPanics(t, r.Shutdown)
+}
+
+func TestTasks(t *testing.T) {
+	report := &dto.ReconcileReport{Scanned: 2, Orphans: []string{"http://localhost/test/1.jpg"}, OrphansSize: 4}
+	tests := []struct {
+		name         string
+		task         func(useCase fileUseCase.UseCase, eventRelay fileUseCase.EventRelay) job.Task
+		useCaseCalls mocks.Calls
+		relayCalls   mocks.Calls
+	}{
+		{
+			name: "sweep succeed",
+			task: func(useCase fileUseCase.UseCase, _ fileUseCase.EventRelay) job.Task {
+				return sweeper.Sweep(useCase, time.Hour)
+			},
+			useCaseCalls: mocks.Calls{{Method: "AbortStaleUploads", Args: []interface{}{helpers.DefaultCtx, mock.Anything}, ReturnArgs: []interface{}{2, nil}}},
+		},
+		{
+			name: "sweep error returned",
+			task: func(useCase fileUseCase.UseCase, _ fileUseCase.EventRelay) job.Task {
+				return sweeper.Sweep(useCase, time.Hour)
+			},
+			useCaseCalls: mocks.Calls{{Method: "AbortStaleUploads", Args: []interface{}{helpers.DefaultCtx, mock.Anything}, ReturnArgs: []interface{}{0, errors.New("test error")}}},
+		},
+		{
+			name: "purge succeed",
+			task: func(useCase fileUseCase.UseCase, _ fileUseCase.EventRelay) job.Task {
+				return purger.Purge(useCase)
+			},
+			useCaseCalls: mocks.Calls{{Method: "PurgeTrash", Args: []interface{}{helpers.DefaultCtx}, ReturnArgs: []interface{}{2, nil}}},
+		},
+		{
+			name: "purge error returned",
+			task: func(useCase fileUseCase.UseCase, _ fileUseCase.EventRelay) job.Task {
+				return purger.Purge(useCase)
+			},
+			useCaseCalls: mocks.Calls{{Method: "PurgeTrash", Args: []interface{}{helpers.DefaultCtx}, ReturnArgs: []interface{}{0, errors.New("test error")}}},
+		},
+		{
+			name: "reconcile dry run succeed",
+			task: func(useCase fileUseCase.UseCase, _ fileUseCase.EventRelay) job.Task {
+				return reconciler.Reconcile(useCase, config.ReconcileConfig{DryRun: true})
+			},
+			useCaseCalls: mocks.Calls{{Method: "Reconcile", Args: []interface{}{helpers.DefaultCtx, mock.Anything}, ReturnArgs: []interface{}{report, nil}}},
+		},
+		{
+			name: "reconcile error returned",
+			task: func(useCase fileUseCase.UseCase, _ fileUseCase.EventRelay) job.Task {
+				return reconciler.Reconcile(useCase, config.ReconcileConfig{})
+			},
+			useCaseCalls: mocks.Calls{{Method: "Reconcile", Args: []interface{}{helpers.DefaultCtx, mock.Anything}, ReturnArgs: []interface{}{nil, errors.New("test error")}}},
+		},
+		{
+			name: "relay succeed",
+			task: func(_ fileUseCase.UseCase, eventRelay fileUseCase.EventRelay) job.Task {
+				return relay.Relay(eventRelay)
+			},
+			relayCalls: mocks.Calls{{Method: "RelayEvents", Args: []interface{}{helpers.DefaultCtx}, ReturnArgs: []interface{}{2, nil}}},
+		},
+		{
+			name: "relay error returned",
+			task: func(_ fileUseCase.UseCase, eventRelay fileUseCase.EventRelay) job.Task {
+				return relay.Relay(eventRelay)
+			},
+			relayCalls: mocks.Calls{{Method: "RelayEvents", Args: []interface{}{helpers.DefaultCtx}, ReturnArgs: []interface{}{1, errors.New("test error")}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase, eventRelay := new(mocks.FileUseCase), new(mocks.EventRelay)
+			for _, call := range tt.useCaseCalls {
+				useCase.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			for _, call := range tt.relayCalls {
+				eventRelay.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+
+			assert.NotPanics(t, func() {
+				tt.task(useCase, eventRelay)(helpers.DefaultCtx)
+			})
+			useCase.AssertExpectations(t)
+			eventRelay.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package purger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/job"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+// New returns job which periodically deletes files which were kept in trash longer than retention period,
+// it isn't started unless trash is enabled by config
+func New(app *app.App, conf *config.TrashConfig) *job.Runner {
+	return job.New("trash purger", conf.PurgeInterval, conf.Enabled, Purge(app.UseCases().FileUseCase))
+}
+
+// Purge permanently deletes expired files in trash
+func Purge(useCase fileUseCase.UseCase) job.Task {
+	return func(ctx context.Context) {
+		purged, err := useCase.PurgeTrash(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to purge trash")
+		}
+		if purged > 0 {
+			log.Info().Msgf("purged %d files from trash", purged)
+		}
+	}
+}
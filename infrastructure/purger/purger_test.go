@@ -0,0 +1,57 @@
+package purger_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/purger"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		conf *config.TrashConfig
+	}{
+		{name: "enabled", conf: &config.TrashConfig{Enabled: true, Prefix: ".trash/", Retention: time.Hour, PurgeInterval: time.Hour}},
+		{name: "disabled", conf: &config.TrashConfig{PurgeInterval: 30 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appConf := config.New(config.DefaultConfig)
+			appConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+			application := app.New(appConf)
+			t.Cleanup(application.Shutdown)
+			useCase := new(mocks.FileUseCase)
+			application.UseCases().FileUseCase = useCase
+			// Run is limited by the purge interval
+			limited := mock.MatchedBy(func(ctx context.Context) bool {
+				deadline, ok := ctx.Deadline()
+				return ok && time.Until(deadline) <= tt.conf.PurgeInterval && time.Until(deadline) > tt.conf.PurgeInterval-time.Minute
+			})
+			useCase.On("PurgeTrash", limited).Return(0, nil)
+
+			r := purger.New(application, tt.conf)
+			r.Run()
+			assert.NoError(t, r.Start())
+			assert.Equal(t, tt.conf.Enabled, r.IsRunning())
+			r.Shutdown()
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	useCase := new(mocks.FileUseCase)
+	useCase.On("PurgeTrash", helpers.DefaultCtx).Return(1, nil)
+	purger.Purge(useCase)(helpers.DefaultCtx)
+	useCase.AssertExpectations(t)
+}
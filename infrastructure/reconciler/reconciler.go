@@ -0,0 +1,46 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/job"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+// New returns job which periodically deletes files which aren't recorded to the catalog,
+// it isn't started unless it's enabled by config
+func New(app *app.App, conf *config.ReconcileConfig) *job.Runner {
+	return job.New("reconciler", conf.Interval, conf.Enabled, Reconcile(app.UseCases().FileUseCase, *conf))
+}
+
+// Reconcile deletes or only reports orphans in dry run mode and logs the summary
+func Reconcile(useCase fileUseCase.UseCase, conf config.ReconcileConfig) job.Task {
+	return func(ctx context.Context) {
+		report, err := useCase.Reconcile(ctx, &dto.ReconcileInput{
+			Prefix:      conf.Prefix,
+			GracePeriod: conf.GracePeriod,
+			Delete:      !conf.DryRun,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to reconcile files")
+			return
+		}
+		log.Info().
+			Int("scanned", report.Scanned).
+			Int("orphans", len(report.Orphans)).
+			Int64("orphans_size", report.OrphansSize).
+			Int("deleted", report.Deleted).
+			Bool("dry_run", conf.DryRun).
+			Msg("reconciled files")
+		if conf.DryRun {
+			for _, url := range report.Orphans {
+				log.Info().Msgf("orphan %s", url)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package reconciler_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/domain/dto"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/reconciler"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  *config.ReconcileConfig
+		input *dto.ReconcileInput
+	}{
+		{
+			name:  "enabled",
+			conf:  &config.ReconcileConfig{Enabled: true, Interval: time.Hour, GracePeriod: 2 * time.Hour, Prefix: "test/"},
+			input: &dto.ReconcileInput{Prefix: "test/", GracePeriod: 2 * time.Hour, Delete: true},
+		},
+		{
+			name:  "disabled",
+			conf:  &config.ReconcileConfig{Interval: 30 * time.Minute, GracePeriod: time.Hour, DryRun: true},
+			input: &dto.ReconcileInput{GracePeriod: time.Hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appConf := config.New(config.DefaultConfig)
+			appConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+			application := app.New(appConf)
+			t.Cleanup(application.Shutdown)
+			useCase := new(mocks.FileUseCase)
+			application.UseCases().FileUseCase = useCase
+			// Run is limited by the interval
+			limited := mock.MatchedBy(func(ctx context.Context) bool {
+				deadline, ok := ctx.Deadline()
+				return ok && time.Until(deadline) <= tt.conf.Interval && time.Until(deadline) > tt.conf.Interval-time.Minute
+			})
+			useCase.On("Reconcile", limited, tt.input).Return(&dto.ReconcileReport{}, nil)
+
+			r := reconciler.New(application, tt.conf)
+			r.Run()
+			assert.NoError(t, r.Start())
+			assert.Equal(t, tt.conf.Enabled, r.IsRunning())
+			r.Shutdown()
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  config.ReconcileConfig
+		input *dto.ReconcileInput
+	}{
+		{
+			name:  "dry run",
+			conf:  config.ReconcileConfig{GracePeriod: time.Hour, Prefix: "test/", DryRun: true},
+			input: &dto.ReconcileInput{Prefix: "test/", GracePeriod: time.Hour},
+		},
+		{
+			name:  "orphans deleted",
+			conf:  config.ReconcileConfig{GracePeriod: time.Hour, Prefix: "test/"},
+			input: &dto.ReconcileInput{Prefix: "test/", GracePeriod: time.Hour, Delete: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			useCase.On("Reconcile", helpers.DefaultCtx, tt.input).Return(&dto.ReconcileReport{Scanned: 1}, nil)
+			reconciler.Reconcile(useCase, tt.conf)(helpers.DefaultCtx)
+			useCase.AssertExpectations(t)
+		})
+	}
+}
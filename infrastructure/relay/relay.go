@@ -0,0 +1,30 @@
+package relay
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/job"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+// New returns job which periodically publishes events recorded to the outbox, events failed to be published
+// are retried by the next run. It isn't started unless events are enabled by config
+func New(app *app.App, conf *config.EventsConfig) *job.Runner {
+	return job.New("events relay", conf.RelayInterval, conf.Enabled, Relay(app.UseCases().EventRelay))
+}
+
+// Relay publishes pending events, events recorded after the run is timed out wait for the next run
+func Relay(eventRelay fileUseCase.EventRelay) job.Task {
+	return func(ctx context.Context) {
+		relayed, err := eventRelay.RelayEvents(ctx)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to relay events, %d events relayed", relayed)
+		} else if relayed > 0 {
+			log.Debug().Msgf("relayed %d events", relayed)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package relay_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/relay"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		conf *config.EventsConfig
+	}{
+		{name: "enabled", conf: &config.EventsConfig{Enabled: true, RelayInterval: time.Hour}},
+		{name: "disabled", conf: &config.EventsConfig{RelayInterval: 30 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appConf := config.New(config.DefaultConfig)
+			appConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+			application := app.New(appConf)
+			t.Cleanup(application.Shutdown)
+			eventRelay := new(mocks.EventRelay)
+			application.UseCases().EventRelay = eventRelay
+			// Run is limited by the relay interval
+			limited := mock.MatchedBy(func(ctx context.Context) bool {
+				deadline, ok := ctx.Deadline()
+				return ok && time.Until(deadline) <= tt.conf.RelayInterval && time.Until(deadline) > tt.conf.RelayInterval-time.Minute
+			})
+			eventRelay.On("RelayEvents", limited).Return(0, nil)
+
+			r := relay.New(application, tt.conf)
+			r.Run()
+			assert.NoError(t, r.Start())
+			assert.Equal(t, tt.conf.Enabled, r.IsRunning())
+			r.Shutdown()
+			eventRelay.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRelay(t *testing.T) {
+	eventRelay := new(mocks.EventRelay)
+	eventRelay.On("RelayEvents", helpers.DefaultCtx).Return(1, nil)
+	relay.Relay(eventRelay)(helpers.DefaultCtx)
+	eventRelay.AssertExpectations(t)
+}
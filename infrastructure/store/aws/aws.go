@@ -2,13 +2,24 @@ package awsSession
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 
 	"github.com/freemen-app/file_storage/config"
 )
 
 func New(config config.S3Config) *session.Session {
-	return session.Must(session.NewSession(&aws.Config{
-		Region: aws.String(config.Region),
-	}))
+	awsConfig := &aws.Config{
+		Region:           aws.String(config.Region),
+		S3ForcePathStyle: aws.Bool(config.ForcePathStyle),
+		DisableSSL:       aws.Bool(config.DisableSSL),
+	}
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+	}
+	// Static credentials take precedence over the default credential chain
+	if config.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, "")
+	}
+	return session.Must(session.NewSession(awsConfig))
 }
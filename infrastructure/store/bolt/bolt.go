@@ -0,0 +1,63 @@
+package boltStore
+
+import (
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// openTimeout limits waiting for the lock of the file held by another process
+const openTimeout = time.Second
+
+type (
+	Store interface {
+		Start() error
+		IsRunning() bool
+		Shutdown()
+		DB() *bbolt.DB
+	}
+
+	// store is embedded key-value database kept in a single file,
+	// the file is locked, so it can't be shared by several instances of the service
+	store struct {
+		path string
+		db   *bbolt.DB
+	}
+)
+
+// New opens the database, so repositories can use it before the application is started
+func New(path string) Store {
+	s := &store{path: path}
+	if err := s.Start(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *store) Start() error {
+	if s.db != nil {
+		return nil
+	}
+	db, err := bbolt.Open(s.path, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *store) IsRunning() bool {
+	return s.db != nil
+}
+
+func (s *store) Shutdown() {
+	if s.db == nil {
+		return
+	}
+	_ = s.db.Close()
+	s.db = nil
+}
+
+func (s *store) DB() *bbolt.DB {
+	return s.db
+}
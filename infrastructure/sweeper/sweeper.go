@@ -0,0 +1,32 @@
+package sweeper
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/job"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+// New returns job which periodically aborts tracked upload sessions which weren't completed in TTL,
+// so storage isn't charged for their parts. The job doesn't run unless sessions are tracked
+func New(app *app.App, conf *config.UploadSessionsConfig) *job.Runner {
+	return job.New("upload sessions sweeper", conf.SweepInterval, conf.Enabled, Sweep(app.UseCases().FileUseCase, conf.TTL))
+}
+
+// Sweep aborts sessions initiated more than TTL ago
+func Sweep(useCase fileUseCase.UseCase, ttl time.Duration) job.Task {
+	return func(ctx context.Context) {
+		aborted, err := useCase.AbortStaleUploads(ctx, time.Now().Add(-ttl))
+		if err != nil {
+			log.Error().Err(err).Msg("failed to abort stale upload sessions")
+		}
+		if aborted > 0 {
+			log.Info().Msgf("aborted %d stale upload sessions", aborted)
+		}
+	}
+}
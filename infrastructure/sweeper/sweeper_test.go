@@ -0,0 +1,68 @@
+package sweeper_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/config"
+	"github.com/freemen-app/file_storage/infrastructure/app"
+	"github.com/freemen-app/file_storage/infrastructure/sweeper"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+)
+
+// stale matches time before which sessions are aborted by the TTL
+func stale(ttl time.Duration) interface{} {
+	return mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= ttl && time.Since(before) < ttl+time.Minute
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		conf *config.UploadSessionsConfig
+	}{
+		{name: "enabled", conf: &config.UploadSessionsConfig{Enabled: true, TTL: 2 * time.Hour, SweepInterval: time.Hour}},
+		{name: "disabled", conf: &config.UploadSessionsConfig{TTL: time.Hour, SweepInterval: 30 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appConf := config.New(config.DefaultConfig)
+			appConf.Database.Path = filepath.Join(helpers.TempDir(t), "test.db")
+			application := app.New(appConf)
+			t.Cleanup(application.Shutdown)
+			useCase := new(mocks.FileUseCase)
+			application.UseCases().FileUseCase = useCase
+			// Run is limited by the sweep interval
+			limited := mock.MatchedBy(func(ctx context.Context) bool {
+				deadline, ok := ctx.Deadline()
+				return ok && time.Until(deadline) <= tt.conf.SweepInterval && time.Until(deadline) > tt.conf.SweepInterval-time.Minute
+			})
+			useCase.On("AbortStaleUploads", limited, stale(tt.conf.TTL)).Return(0, nil)
+
+			r := sweeper.New(application, tt.conf)
+			r.Run()
+			assert.NoError(t, r.Start())
+			assert.Equal(t, tt.conf.Enabled, r.IsRunning())
+			r.Shutdown()
+			useCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	for _, ttl := range []time.Duration{time.Hour, 24 * time.Hour} {
+		t.Run(ttl.String(), func(t *testing.T) {
+			useCase := new(mocks.FileUseCase)
+			useCase.On("AbortStaleUploads", helpers.DefaultCtx, stale(ttl)).Return(1, nil)
+			sweeper.Sweep(useCase, ttl)(helpers.DefaultCtx)
+			useCase.AssertExpectations(t)
+		})
+	}
+}
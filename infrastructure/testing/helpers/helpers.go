@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"context"
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
@@ -35,3 +36,13 @@ func OpenFile(t *testing.T, filename string) *os.File {
 	})
 	return file
 }
+
+func TempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "file_storage")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, os.RemoveAll(dir))
+	})
+	return dir
+}
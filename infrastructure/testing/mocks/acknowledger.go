@@ -0,0 +1,22 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+type Acknowledger struct {
+	mock.Mock
+}
+
+func (a *Acknowledger) Ack(tag uint64, multiple bool) error {
+	args := a.Called(tag, multiple)
+	return args.Error(0)
+}
+
+func (a *Acknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	args := a.Called(tag, multiple, requeue)
+	return args.Error(0)
+}
+
+func (a *Acknowledger) Reject(tag uint64, requeue bool) error {
+	args := a.Called(tag, requeue)
+	return args.Error(0)
+}
@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+type CatalogRepo struct {
+	mock.Mock
+}
+
+func (c *CatalogRepo) Save(ctx context.Context, entry *dto.CatalogEntry) error {
+	args := c.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (c *CatalogRepo) MarkDeleted(ctx context.Context, urls dto.BatchDeleteInput, at time.Time) error {
+	args := c.Called(ctx, urls, at)
+	return args.Error(0)
+}
+
+func (c *CatalogRepo) Get(ctx context.Context, url string) (*dto.CatalogEntry, error) {
+	args := c.Called(ctx, url)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	entry, _ := args.Get(0).(*dto.CatalogEntry)
+	return entry, nil
+}
+
+func (c *CatalogRepo) List(ctx context.Context, input *dto.CatalogListInput) (*dto.CatalogListOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.CatalogListOutput), nil
+}
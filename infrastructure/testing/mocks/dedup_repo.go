@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+type DedupRepo struct {
+	mock.Mock
+}
+
+func (d *DedupRepo) Link(ctx context.Context, ref *dto.BlobRef) (string, error) {
+	args := d.Called(ctx, ref)
+	return args.String(0), args.Error(1)
+}
+
+func (d *DedupRepo) Unlink(ctx context.Context, url string) (*dto.BlobRef, bool, error) {
+	args := d.Called(ctx, url)
+	if args.Error(2) != nil {
+		return nil, false, args.Error(2)
+	}
+	ref, _ := args.Get(0).(*dto.BlobRef)
+	return ref, args.Bool(1), nil
+}
+
+func (d *DedupRepo) Get(ctx context.Context, url string) (*dto.BlobRef, error) {
+	args := d.Called(ctx, url)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	ref, _ := args.Get(0).(*dto.BlobRef)
+	return ref, nil
+}
+
+func (d *DedupRepo) Refs(ctx context.Context, blobUrl string) (int, error) {
+	args := d.Called(ctx, blobUrl)
+	return args.Int(0), args.Error(1)
+}
+
+func (d *DedupRepo) Owner(ctx context.Context) (string, error) {
+	args := d.Called(ctx)
+	return args.String(0), args.Error(1)
+}
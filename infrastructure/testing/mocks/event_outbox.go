@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+type EventOutbox struct {
+	mock.Mock
+}
+
+func (e *EventOutbox) Prepare(ctx context.Context, events []*dto.FileEvent) error {
+	args := e.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (e *EventOutbox) Confirm(ctx context.Context, events []*dto.FileEvent) error {
+	args := e.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (e *EventOutbox) Discard(ctx context.Context, events []*dto.FileEvent) error {
+	args := e.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (e *EventOutbox) Unconfirmed(ctx context.Context, before time.Time, limit int) ([]*dto.FileEvent, error) {
+	args := e.Called(ctx, before, limit)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*dto.FileEvent), nil
+}
+
+func (e *EventOutbox) Pending(ctx context.Context, limit int) ([]*dto.FileEvent, error) {
+	args := e.Called(ctx, limit)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*dto.FileEvent), nil
+}
+
+func (e *EventOutbox) MarkDelivered(ctx context.Context, event *dto.FileEvent) error {
+	args := e.Called(ctx, event)
+	return args.Error(0)
+}
@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+type EventPublisher struct {
+	mock.Mock
+}
+
+func (e *EventPublisher) Publish(ctx context.Context, event *dto.FileEvent) error {
+	args := e.Called(ctx, event)
+	return args.Error(0)
+}
@@ -0,0 +1,16 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type EventRelay struct {
+	mock.Mock
+}
+
+func (r *EventRelay) RelayEvents(ctx context.Context) (int, error) {
+	args := r.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
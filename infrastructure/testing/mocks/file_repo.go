@@ -12,9 +12,20 @@ type FileRepo struct {
 	mock.Mock
 }
 
-func (f *FileRepo) Upload(ctx context.Context, input *dto.UploadInput) (string, error) {
+func (f *FileRepo) Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error) {
 	args := f.Called(ctx, input)
-	return args.String(0), args.Error(1)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadOutput), nil
+}
+
+func (f *FileRepo) Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.DownloadOutput), nil
 }
 
 func (f *FileRepo) Delete(ctx context.Context, input dto.DeleteInput) error {
@@ -26,3 +37,113 @@ func (f *FileRepo) BatchDelete(ctx context.Context, input dto.BatchDeleteInput)
 	args := f.Called(ctx, input)
 	return args.Error(0)
 }
+
+func (f *FileRepo) PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.PresignOutput), nil
+}
+
+func (f *FileRepo) PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.PresignOutput), nil
+}
+
+func (f *FileRepo) Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.FileInfo), nil
+}
+
+func (f *FileRepo) List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListOutput), nil
+}
+
+func (f *FileRepo) Copy(ctx context.Context, input *dto.CopyInput) (string, error) {
+	args := f.Called(ctx, input)
+	return args.String(0), args.Error(1)
+}
+
+func (f *FileRepo) InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadSession), nil
+}
+
+func (f *FileRepo) ACL(ctx context.Context, url string) (string, error) {
+	args := f.Called(ctx, url)
+	return args.String(0), args.Error(1)
+}
+
+func (f *FileRepo) URL(key string) (string, error) {
+	args := f.Called(key)
+	return args.String(0), args.Error(1)
+}
+
+func (f *FileRepo) Key(url string) (string, error) {
+	args := f.Called(url)
+	return args.String(0), args.Error(1)
+}
+
+func (f *FileRepo) UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadPart), nil
+}
+
+func (f *FileRepo) GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadSession), nil
+}
+
+func (f *FileRepo) CompleteUpload(ctx context.Context, session *dto.UploadSession) (string, error) {
+	args := f.Called(ctx, session)
+	return args.String(0), args.Error(1)
+}
+
+func (f *FileRepo) AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error {
+	args := f.Called(ctx, input)
+	return args.Error(0)
+}
+
+func (f *FileRepo) ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListVersionsOutput), nil
+}
+
+func (f *FileRepo) GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.DownloadOutput), nil
+}
+
+func (f *FileRepo) RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error) {
+	args := f.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadOutput), nil
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -12,9 +13,20 @@ type FileUseCase struct {
 	mock.Mock
 }
 
-func (u *FileUseCase) Upload(ctx context.Context, input *dto.UploadInput) (string, error) {
+func (u *FileUseCase) Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error) {
 	args := u.Called(ctx, input)
-	return args.String(0), args.Error(1)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadOutput), nil
+}
+
+func (u *FileUseCase) Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.DownloadOutput), nil
 }
 
 func (u *FileUseCase) Delete(ctx context.Context, input dto.DeleteInput) error {
@@ -26,3 +38,139 @@ func (u *FileUseCase) BatchDelete(ctx context.Context, input dto.BatchDeleteInpu
 	args := u.Called(ctx, input)
 	return args.Error(0)
 }
+
+func (u *FileUseCase) DeleteFiles(ctx context.Context, input *dto.DeleteFilesInput) error {
+	args := u.Called(ctx, input)
+	return args.Error(0)
+}
+
+func (u *FileUseCase) PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.PresignOutput), nil
+}
+
+func (u *FileUseCase) PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.PresignOutput), nil
+}
+
+func (u *FileUseCase) Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.FileInfo), nil
+}
+
+func (u *FileUseCase) List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListOutput), nil
+}
+
+func (u *FileUseCase) Copy(ctx context.Context, input *dto.CopyInput) (string, error) {
+	args := u.Called(ctx, input)
+	return args.String(0), args.Error(1)
+}
+
+func (u *FileUseCase) Move(ctx context.Context, input *dto.CopyInput) (string, error) {
+	args := u.Called(ctx, input)
+	return args.String(0), args.Error(1)
+}
+
+func (u *FileUseCase) InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadSession), nil
+}
+
+func (u *FileUseCase) UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadPart), nil
+}
+
+func (u *FileUseCase) GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadSession), nil
+}
+
+func (u *FileUseCase) CompleteUpload(ctx context.Context, input *dto.UploadSessionInput) (string, error) {
+	args := u.Called(ctx, input)
+	return args.String(0), args.Error(1)
+}
+
+func (u *FileUseCase) AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error {
+	args := u.Called(ctx, input)
+	return args.Error(0)
+}
+
+func (u *FileUseCase) AbortStaleUploads(ctx context.Context, before time.Time) (int, error) {
+	args := u.Called(ctx, before)
+	return args.Int(0), args.Error(1)
+}
+
+func (u *FileUseCase) Reconcile(ctx context.Context, input *dto.ReconcileInput) (*dto.ReconcileReport, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ReconcileReport), nil
+}
+
+func (u *FileUseCase) Restore(ctx context.Context, input *dto.RestoreInput) (string, error) {
+	args := u.Called(ctx, input)
+	return args.String(0), args.Error(1)
+}
+
+func (u *FileUseCase) ListTrash(ctx context.Context, input *dto.ListTrashInput) (*dto.ListTrashOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListTrashOutput), nil
+}
+
+func (u *FileUseCase) PurgeTrash(ctx context.Context) (int, error) {
+	args := u.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (u *FileUseCase) ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.ListVersionsOutput), nil
+}
+
+func (u *FileUseCase) GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.DownloadOutput), nil
+}
+
+func (u *FileUseCase) RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error) {
+	args := u.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.UploadOutput), nil
+}
@@ -0,0 +1,21 @@
+package mocks
+
+import (
+	amqpStore "github.com/freemen-app/amqp-store"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/mock"
+)
+
+type PubSub struct {
+	mock.Mock
+}
+
+func (p *PubSub) Publish(publishConfig *amqpStore.PublishConfig, message *amqp.Publishing) error {
+	args := p.Called(publishConfig, message)
+	return args.Error(0)
+}
+
+func (p *PubSub) Subscribe(consumeConfig *amqpStore.ConsumeConfig, handler func(amqp.Delivery)) error {
+	args := p.Called(consumeConfig, handler)
+	return args.Error(0)
+}
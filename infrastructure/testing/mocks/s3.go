@@ -2,8 +2,11 @@ package mocks
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/stretchr/testify/mock"
 )
@@ -20,8 +23,23 @@ type (
 	BatchDeleter struct {
 		mock.Mock
 	}
+
+	// S3Client mocks only methods that are used by repository,
+	// calling any other method of s3iface.S3API panics
+	S3Client struct {
+		mock.Mock
+		s3iface.S3API
+	}
 )
 
+// s3Service builds requests to fake "https://aws.s3" endpoint without sending them
+var s3Service = s3.New(session.Must(session.NewSession(&aws.Config{
+	Region:           aws.String("us-east-1"),
+	Endpoint:         aws.String("https://aws.s3"),
+	S3ForcePathStyle: aws.Bool(true),
+	Credentials:      credentials.AnonymousCredentials,
+})))
+
 func (u *Uploader) Upload(input *s3manager.UploadInput, f ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
 	args := u.Called(input)
 	if args.Error(1) != nil {
@@ -50,3 +68,125 @@ func (b *BatchDeleter) Delete(ctx aws.Context, input s3manager.BatchDeleteIterat
 	args := b.Called(ctx, input)
 	return args.Error(0)
 }
+
+func (c *S3Client) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.GetObjectOutput), nil
+}
+
+func (c *S3Client) HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.HeadObjectOutput), nil
+}
+
+func (c *S3Client) ListObjectsV2WithContext(ctx aws.Context, input *s3.ListObjectsV2Input, opts ...request.Option) (*s3.ListObjectsV2Output, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.ListObjectsV2Output), nil
+}
+
+func (c *S3Client) ListObjectVersionsWithContext(ctx aws.Context, input *s3.ListObjectVersionsInput, opts ...request.Option) (*s3.ListObjectVersionsOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.ListObjectVersionsOutput), nil
+}
+
+// GetObjectRequest isn't mocked, it returns request to "https://aws.s3" endpoint,
+// so that repository is able to build object urls
+func (c *S3Client) GetObjectRequest(input *s3.GetObjectInput) (*request.Request, *s3.GetObjectOutput) {
+	return s3Service.GetObjectRequest(input)
+}
+
+func (c *S3Client) HeadBucketRequest(input *s3.HeadBucketInput) (*request.Request, *s3.HeadBucketOutput) {
+	return s3Service.HeadBucketRequest(input)
+}
+
+func (c *S3Client) CopyObjectWithContext(ctx aws.Context, input *s3.CopyObjectInput, opts ...request.Option) (*s3.CopyObjectOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.CopyObjectOutput), nil
+}
+
+func (c *S3Client) GetObjectAclWithContext(ctx aws.Context, input *s3.GetObjectAclInput, opts ...request.Option) (*s3.GetObjectAclOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.GetObjectAclOutput), nil
+}
+
+func (c *S3Client) CreateMultipartUploadWithContext(ctx aws.Context, input *s3.CreateMultipartUploadInput, opts ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.CreateMultipartUploadOutput), nil
+}
+
+func (c *S3Client) UploadPartCopyWithContext(ctx aws.Context, input *s3.UploadPartCopyInput, opts ...request.Option) (*s3.UploadPartCopyOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.UploadPartCopyOutput), nil
+}
+
+func (c *S3Client) CompleteMultipartUploadWithContext(ctx aws.Context, input *s3.CompleteMultipartUploadInput, opts ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.CompleteMultipartUploadOutput), nil
+}
+
+func (c *S3Client) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	args := c.Called(input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.AbortMultipartUploadOutput), nil
+}
+
+func (c *S3Client) UploadPartWithContext(ctx aws.Context, input *s3.UploadPartInput, opts ...request.Option) (*s3.UploadPartOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.UploadPartOutput), nil
+}
+
+func (c *S3Client) ListPartsWithContext(ctx aws.Context, input *s3.ListPartsInput, opts ...request.Option) (*s3.ListPartsOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.ListPartsOutput), nil
+}
+
+func (c *S3Client) ListMultipartUploadsWithContext(ctx aws.Context, input *s3.ListMultipartUploadsInput, opts ...request.Option) (*s3.ListMultipartUploadsOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.ListMultipartUploadsOutput), nil
+}
+
+func (c *S3Client) AbortMultipartUploadWithContext(ctx aws.Context, input *s3.AbortMultipartUploadInput, opts ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	args := c.Called(ctx, input)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*s3.AbortMultipartUploadOutput), nil
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionRepo struct {
+	mock.Mock
+}
+
+func (s *SessionRepo) Save(ctx context.Context, uploadId string, initiated time.Time) error {
+	args := s.Called(ctx, uploadId, initiated)
+	return args.Error(0)
+}
+
+func (s *SessionRepo) Delete(ctx context.Context, uploadId string) error {
+	args := s.Called(ctx, uploadId)
+	return args.Error(0)
+}
+
+func (s *SessionRepo) Stale(ctx context.Context, before time.Time) ([]string, error) {
+	args := s.Called(ctx, before)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), nil
+}
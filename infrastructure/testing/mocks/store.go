@@ -0,0 +1,24 @@
+package mocks
+
+type Store struct {
+	PubSub
+}
+
+func (s *Store) DSN() string {
+	args := s.Called()
+	return args.String(0)
+}
+
+func (s *Store) IsRunning() bool {
+	args := s.Called()
+	return args.Bool(0)
+}
+
+func (s *Store) Start() error {
+	args := s.Called()
+	return args.Error(0)
+}
+
+func (s *Store) Shutdown() {
+	s.Called()
+}
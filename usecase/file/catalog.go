@@ -0,0 +1,127 @@
+package fileUseCase
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+)
+
+// WithCatalog records metadata of written and deleted files, so they can be queried without the storage.
+// Files written by presigned urls aren't recorded, because the service doesn't see them
+func WithCatalog(repo CatalogRepo) Option {
+	return func(u *useCase) {
+		u.catalog = repo
+	}
+}
+
+// record saves entry of the written file keeping creation time of the overwritten one
+func (u *useCase) record(ctx context.Context, entry *dto.CatalogEntry) error {
+	if u.catalog == nil {
+		return nil
+	}
+	now := time.Now()
+	entry.CreatedAt, entry.UpdatedAt = now, now
+	previous, err := u.catalog.Get(ctx, entry.Url)
+	if err != nil {
+		return err
+	} else if previous != nil && !previous.IsDeleted() {
+		entry.CreatedAt = previous.CreatedAt
+	}
+	return u.catalog.Save(ctx, entry)
+}
+
+// recordWritten records entry of the written file and confirms its prepared event in one transaction
+func (u *useCase) recordWritten(ctx context.Context, entry *dto.CatalogEntry, event *dto.FileEvent) error {
+	if u.catalog == nil && event == nil {
+		return nil
+	}
+	return u.transact(ctx, func(ctx context.Context) error {
+		if err := u.record(ctx, entry); err != nil {
+			return err
+		} else if event == nil {
+			return nil
+		}
+		event.Describe(entry)
+		return u.confirmEvents(ctx, event)
+	})
+}
+
+// confirmWritten is recordWritten of the change already made to the storage, failure is only logged,
+// because the change can't be undone, and the event left unconfirmed is settled by the relay
+func (u *useCase) confirmWritten(ctx context.Context, entry *dto.CatalogEntry, event *dto.FileEvent) {
+	if err := u.recordWritten(ctx, entry, event); err != nil {
+		log.Error().Err(err).Msgf("failed to record written file %s", entry.Url)
+	}
+}
+
+// copiedEntry returns entry of the copy taking metadata from entry of recordedUrl, the source is inspected
+// if it isn't recorded. Deleted entry is taken only if it has url of the copy, which means the file is restored.
+// Entry is built before the file is copied, so the applied copy doesn't fail on reading metadata
+func (u *useCase) copiedEntry(ctx context.Context, input *dto.CopyInput, recordedUrl string) (*dto.CatalogEntry, error) {
+	entry := dto.NewCatalogEntry(input.Key(), "")
+	if u.catalog == nil && u.events == nil {
+		return entry, nil
+	}
+	if u.catalog != nil {
+		url, err := u.fileRepo.URL(input.Key())
+		if err != nil {
+			return nil, err
+		}
+		source, err := u.catalog.Get(ctx, recordedUrl)
+		if err != nil {
+			return nil, err
+		} else if source != nil && (!source.IsDeleted() || source.Url == url) {
+			entry.Owner, entry.Size, entry.ContentType, entry.SHA256 = source.Owner, source.Size, source.ContentType, source.SHA256
+			return entry, nil
+		}
+	}
+	return entry, u.inspect(ctx, entry, input.SourceUrl)
+}
+
+// versionEntry returns entry of the file which version is restored keeping its owner,
+// the file is inspected, because metadata of versions isn't recorded. The version is already restored,
+// so failures are only logged and the entry is recorded with what is known
+func (u *useCase) versionEntry(ctx context.Context, key, url string) *dto.CatalogEntry {
+	entry := dto.NewCatalogEntry(key, url)
+	if u.catalog == nil && u.events == nil {
+		return entry
+	}
+	u.inspectWritten(ctx, entry)
+	if u.catalog != nil {
+		if previous, err := u.catalog.Get(ctx, url); err != nil {
+			log.Error().Err(err).Msgf("failed to get entry of restored file %s", url)
+		} else if previous != nil {
+			entry.Owner = previous.Owner
+		}
+	}
+	return entry
+}
+
+// inspectWritten fills entry by the file which is already written, so failed inspection
+// is only logged and the entry is recorded without metadata instead of failing the applied change
+func (u *useCase) inspectWritten(ctx context.Context, entry *dto.CatalogEntry) {
+	if err := u.inspect(ctx, entry, entry.Url); err != nil {
+		log.Error().Err(err).Msgf("failed to inspect written file %s", entry.Url)
+	}
+}
+
+// inspect fills entry by the stored file with the url
+func (u *useCase) inspect(ctx context.Context, entry *dto.CatalogEntry, url string) error {
+	info, err := u.Stat(ctx, &dto.StatInput{Url: url})
+	if err != nil {
+		return err
+	}
+	entry.Size, entry.ContentType, entry.SHA256 = info.Size, info.ContentType, info.Metadata[dto.SHA256MetadataKey]
+	return nil
+}
+
+// recordDeleted marks entries of deleted files
+func (u *useCase) recordDeleted(ctx context.Context, urls dto.BatchDeleteInput) error {
+	if u.catalog == nil {
+		return nil
+	}
+	return u.catalog.MarkDeleted(ctx, urls, time.Now())
+}
@@ -0,0 +1,142 @@
+package fileUseCase_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.etcd.io/bbolt"
+
+	catalogRepo "github.com/freemen-app/file_storage/adapter/repository/catalog"
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+const testSHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestUseCase_Catalog(t *testing.T) {
+	for _, deduplicated := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "deduplicated"}[deduplicated], func(t *testing.T) {
+			db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+			assert.NoError(t, err)
+			defer db.Close()
+			catalog := catalogRepo.New(db)
+			options := []fileUseCase.Option{fileUseCase.WithCatalog(catalog)}
+			if deduplicated {
+				options = append(options, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+			}
+			useCase := fileUseCase.New(fileRepo.NewMemory("http://localhost/files"), options...)
+			get := func(url string) *dto.CatalogEntry {
+				entry, err := catalog.Get(helpers.DefaultCtx, url)
+				assert.NoError(t, err)
+				return entry
+			}
+
+			output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:      bytes.NewBufferString("test"),
+				Directory: "docs",
+				Filename:  "test.txt",
+				Owner:     "user",
+			})
+			assert.NoError(t, err)
+			url := output.Url
+			assert.EqualValues(t, "http://localhost/files/docs/test.txt", url)
+			uploaded := get(url)
+			assert.EqualValues(t, "docs/test.txt", uploaded.Key)
+			assert.EqualValues(t, "docs", uploaded.Directory)
+			assert.EqualValues(t, "user", uploaded.Owner)
+			assert.EqualValues(t, 4, uploaded.Size)
+			assert.EqualValues(t, "text/plain; charset=utf-8", uploaded.ContentType)
+			assert.EqualValues(t, testSHA256, uploaded.SHA256)
+			assert.False(t, uploaded.CreatedAt.IsZero())
+			assert.False(t, uploaded.IsDeleted())
+
+			// Overwritten file keeps creation time
+			_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:      bytes.NewBufferString("test2"),
+				Directory: "docs",
+				Filename:  "test.txt",
+				Owner:     "user",
+			})
+			assert.NoError(t, err)
+			overwritten := get(url)
+			assert.EqualValues(t, 5, overwritten.Size)
+			assert.True(t, overwritten.CreatedAt.Equal(uploaded.CreatedAt))
+			assert.False(t, overwritten.UpdatedAt.Before(uploaded.UpdatedAt))
+
+			movedUrl, err := useCase.Move(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: url, Directory: "moved", Filename: "test.txt"})
+			assert.NoError(t, err)
+			moved := get(movedUrl)
+			assert.EqualValues(t, "moved/test.txt", moved.Key)
+			assert.EqualValues(t, "moved", moved.Directory)
+			assert.EqualValues(t, "user", moved.Owner)
+			assert.EqualValues(t, 5, moved.Size)
+			assert.True(t, get(url).IsDeleted())
+
+			assert.NoError(t, useCase.Delete(helpers.DefaultCtx, dto.DeleteInput(movedUrl)))
+			assert.True(t, get(movedUrl).IsDeleted())
+
+			list, err := catalog.List(helpers.DefaultCtx, &dto.CatalogListInput{Owner: "user", Deleted: true})
+			assert.NoError(t, err)
+			assert.Len(t, list.Entries, 2)
+		})
+	}
+}
+
+func TestUseCase_Catalog_Errors(t *testing.T) {
+	testErr := errors.New("test error")
+	url := "http://localhost/files/test.txt"
+
+	catalog := new(mocks.CatalogRepo)
+	catalog.On("Get", helpers.DefaultCtx, url).Return(nil, nil)
+	catalog.On("Save", helpers.DefaultCtx, mock.AnythingOfType("*dto.CatalogEntry")).Return(testErr)
+	catalog.On("MarkDeleted", helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(url)}, mock.AnythingOfType("time.Time")).Return(testErr)
+	useCase := fileUseCase.New(fileRepo.NewMemory("http://localhost/files"), fileUseCase.WithCatalog(catalog))
+
+	// Failed recording doesn't fail the change already made to the storage
+	output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: "test.txt"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, url, output.Url)
+	assert.NoError(t, useCase.Delete(helpers.DefaultCtx, dto.DeleteInput(url)))
+	_, err = useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+	assert.True(t, customErrors.Is(err, customErrors.NotFound))
+	catalog.AssertExpectations(t)
+}
+
+func TestUseCase_CompleteUpload_Catalog(t *testing.T) {
+	input := &dto.UploadSessionInput{UploadId: testUploadId}
+	url := "https://aws.s3/test.bucket/avatars/test.jpg"
+	session := &dto.UploadSession{
+		UploadId: testUploadId,
+		Key:      "avatars/test.jpg",
+		Url:      url,
+		Parts:    []*dto.UploadPart{{PartNumber: 1, Size: 4}},
+	}
+	fileRepo := new(mocks.FileRepo)
+	fileRepo.On("GetUpload", helpers.DefaultCtx, input).Return(session, nil)
+	fileRepo.On("CompleteUpload", helpers.DefaultCtx, session).Return(url, nil)
+	fileRepo.On("Stat", helpers.DefaultCtx, &dto.StatInput{Url: url}).
+		Return(&dto.FileInfo{Key: session.Key, Url: url, Size: 4, ContentType: "image/jpeg"}, nil)
+	catalog := new(mocks.CatalogRepo)
+	catalog.On("Get", helpers.DefaultCtx, url).Return(nil, nil)
+	catalog.On("Save", helpers.DefaultCtx, mock.MatchedBy(func(entry *dto.CatalogEntry) bool {
+		return entry.Key == session.Key && entry.Url == url && entry.Directory == "avatars" &&
+			entry.Size == 4 && entry.ContentType == "image/jpeg" && entry.CreatedAt.After(time.Time{})
+	})).Return(nil)
+
+	useCase := fileUseCase.New(fileRepo, fileUseCase.WithCatalog(catalog))
+	got, err := useCase.CompleteUpload(helpers.DefaultCtx, input)
+	assert.NoError(t, err)
+	assert.EqualValues(t, url, got)
+	fileRepo.AssertExpectations(t)
+	catalog.AssertExpectations(t)
+}
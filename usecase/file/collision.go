@@ -0,0 +1,65 @@
+package fileUseCase
+
+import (
+	"context"
+	"path"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
+)
+
+// resolveCollision applies collision strategy of the request or the rule to the upload and returns it.
+// Strategies other than overwrite make the upload exclusive, so the key taken
+// after it was checked isn't overwritten and upload fails with AlreadyExists
+func (u *useCase) resolveCollision(ctx context.Context, input *dto.UploadInput, rule *policy.Rule) (string, error) {
+	strategy := input.Collision
+	if strategy == "" && rule != nil {
+		strategy = rule.Collision
+	}
+	switch strategy {
+	case dto.CollisionFail:
+		input.Exclusive = true
+	case dto.CollisionRename:
+		filename, err := u.freeFilename(ctx, input.Directory, input.Filename)
+		if err != nil {
+			return "", err
+		}
+		input.Filename, input.Exclusive = filename, true
+	case dto.CollisionUnique:
+		input.OriginalFilename = input.Filename
+		input.Filename, input.Exclusive = dto.UniqueFilename(input.Filename), true
+	}
+	return strategy, nil
+}
+
+// writeRenamed writes the file with the picked filename, rename strategy picks the filename before
+// the file is written, so the write is repeated with the next free filename while a concurrent upload takes it
+func (u *useCase) writeRenamed(ctx context.Context, strategy, directory, filename, picked string, write func(filename string) error) error {
+	err := write(picked)
+	for attempt := 0; strategy == dto.CollisionRename && customErrors.Is(err, customErrors.AlreadyExists) &&
+		attempt < dto.MaxRenameAttempts; attempt++ {
+		if picked, err = u.freeFilename(ctx, directory, filename); err != nil {
+			return err
+		}
+		err = write(picked)
+	}
+	return err
+}
+
+// freeFilename returns the filename with the first number which makes the key free
+func (u *useCase) freeFilename(ctx context.Context, directory, filename string) (string, error) {
+	for number := 0; number <= dto.MaxRenameAttempts; number++ {
+		renamed := dto.RenamedFilename(filename, number)
+		url, err := u.fileRepo.URL(path.Join(directory, renamed))
+		if err != nil {
+			return "", err
+		}
+		if _, err := u.Stat(ctx, &dto.StatInput{Url: url}); customErrors.Is(err, customErrors.NotFound) {
+			return renamed, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", customErrors.AlreadyExists
+}
@@ -0,0 +1,168 @@
+package fileUseCase_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+func TestUseCase_Collision(t *testing.T) {
+	repo := fileRepo.NewMemory("http://localhost/files")
+	rules := policy.Rules{{Prefix: "docs/", Collision: dto.CollisionRename}}
+	useCase := fileUseCase.New(repo, fileUseCase.WithPolicies(rules))
+	upload := func(directory, collision, content string) (string, error) {
+		output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      bytes.NewBufferString(content),
+			Directory: directory,
+			Filename:  "test.jpg",
+			Collision: collision,
+		})
+		if err != nil {
+			return "", err
+		}
+		return output.Url, nil
+	}
+	content := func(url string) string {
+		output, err := useCase.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: url})
+		assert.NoError(t, err)
+		defer output.File.Close()
+		body, _ := ioutil.ReadAll(output.File)
+		return string(body)
+	}
+
+	url, err := upload("", dto.CollisionFail, "first")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/test.jpg", url)
+	_, err = upload("", dto.CollisionFail, "second")
+	assert.EqualValues(t, customErrors.AlreadyExists, err)
+	assert.EqualValues(t, "first", content(url))
+
+	// Overwrite is the default strategy without the rule
+	_, err = upload("", "", "third")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "third", content(url))
+
+	url, err = upload("", dto.CollisionRename, "renamed")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/test-1.jpg", url)
+	url, err = upload("", dto.CollisionRename, "renamed again")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/test-2.jpg", url)
+	assert.EqualValues(t, "renamed again", content(url))
+
+	url, err = upload("", dto.CollisionUnique, "unique")
+	assert.NoError(t, err)
+	assert.NotEqualValues(t, "http://localhost/files/test.jpg", url)
+	assert.EqualValues(t, ".jpg", path.Ext(url))
+	obj, _ := repo.Object(url)
+	filename, _ := new(mime.WordDecoder).DecodeHeader(obj.Metadata[dto.FilenameMetadataKey])
+	assert.EqualValues(t, "test.jpg", filename)
+
+	// Strategy of the rule is applied when request doesn't set it
+	url, err = upload("docs", "", "doc")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/docs/test.jpg", url)
+	url, err = upload("docs", "", "doc")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/docs/test-1.jpg", url)
+	_, err = upload("docs", dto.CollisionOverwrite, "new doc")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "new doc", content("http://localhost/files/docs/test.jpg"))
+}
+
+func TestUseCase_Collision_Deduplication(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+	upload := func(collision, content string) (string, error) {
+		output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      bytes.NewBufferString(content),
+			Filename:  "test.jpg",
+			Collision: collision,
+		})
+		if err != nil {
+			return "", err
+		}
+		return output.Url, nil
+	}
+
+	_, err = upload(dto.CollisionFail, "first")
+	assert.NoError(t, err)
+	_, err = upload(dto.CollisionFail, "second")
+	assert.EqualValues(t, customErrors.AlreadyExists, err)
+	url, err := upload(dto.CollisionRename, "first")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/test-1.jpg", url)
+	// Both files refer the same blob, the blob of the failed upload is released
+	assert.EqualValues(t, []string{blobKey("first")}, objectKeys(repo))
+}
+
+// racingRepo writes a file to the key of the first upload, as if a concurrent upload took it
+// after the collision was resolved
+type racingRepo struct {
+	fileUseCase.FileRepo
+	key   string
+	raced bool
+}
+
+func (r *racingRepo) Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error) {
+	if !r.raced {
+		r.raced = true
+		directory, filename := path.Split(r.key)
+		concurrent := &dto.UploadInput{File: bytes.NewBufferString("concurrent"), Directory: directory, Filename: filename}
+		if _, err := r.FileRepo.Upload(ctx, concurrent); err != nil {
+			return nil, err
+		}
+	}
+	return r.FileRepo.Upload(ctx, input)
+}
+
+func TestUseCase_Collision_RenameRace(t *testing.T) {
+	for _, deduplicated := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "deduplicated"}[deduplicated], func(t *testing.T) {
+			repo := &racingRepo{FileRepo: fileRepo.NewMemory("http://localhost/files"), key: "docs/test.jpg"}
+			var options []fileUseCase.Option
+			if deduplicated {
+				db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+				assert.NoError(t, err)
+				defer db.Close()
+				options = append(options, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+			}
+			useCase := fileUseCase.New(repo, options...)
+			output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:      bytes.NewBufferString("renamed"),
+				Directory: "docs",
+				Filename:  "test.jpg",
+				Collision: dto.CollisionRename,
+			})
+			assert.NoError(t, err)
+			assert.EqualValues(t, "http://localhost/files/docs/test-1.jpg", output.Url)
+			for url, want := range map[string]string{
+				"http://localhost/files/docs/test.jpg":   "concurrent",
+				"http://localhost/files/docs/test-1.jpg": "renamed",
+			} {
+				download, err := useCase.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: url})
+				assert.NoError(t, err)
+				body, _ := ioutil.ReadAll(download.File)
+				assert.EqualValues(t, want, string(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package fileUseCase
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+type (
+	dedup struct {
+		repo   DedupRepo
+		prefix string
+		// mu serializes linking and releasing of blobs, so a blob can't be deleted
+		// between checking its references and linking a file to it
+		mu sync.Mutex
+	}
+)
+
+// WithDeduplication stores uploaded files as blobs inside the prefix directory named by SHA-256
+// of their content, files with the same content share a blob, which is deleted with the last file.
+// Urls of deduplicated files are logical, they have no objects behind them, so files are available
+// by Download, Stat and PresignDownload only, and List returns blobs instead of them.
+// Content type and metadata of every file are kept by its reference, because the blob is shared.
+// Deduplicated files are private, because their urls can't be fetched, requests for other ACLs are rejected.
+// References are counted by a single instance, which claims the prefix by ClaimBlobs
+func WithDeduplication(repo DedupRepo, prefix string) Option {
+	return func(u *useCase) {
+		u.dedup = &dedup{repo: repo, prefix: prefix}
+	}
+}
+
+// ClaimBlobs claims directory of blobs for the instance by writing its owner id to the directory,
+// it fails with DedupClaimed if another instance claimed it, because references of blobs
+// counted by several instances would be inconsistent and blobs would be deleted while referred
+func (u *useCase) ClaimBlobs(ctx context.Context) error {
+	if u.dedup == nil {
+		return nil
+	}
+	owner, err := u.dedup.repo.Owner(ctx)
+	if err != nil {
+		return err
+	}
+	key := dto.DedupOwnerKey(u.dedup.prefix)
+	directory, filename := path.Split(key)
+	_, err = u.fileRepo.Upload(ctx, &dto.UploadInput{
+		File:        strings.NewReader(owner),
+		Directory:   directory,
+		Filename:    filename,
+		ContentType: "text/plain",
+		ACL:         dto.BlobACL,
+		Exclusive:   true,
+	})
+	if !customErrors.Is(err, customErrors.AlreadyExists) {
+		return err
+	}
+	url, err := u.fileRepo.URL(key)
+	if err != nil {
+		return err
+	}
+	output, err := u.fileRepo.Download(ctx, &dto.DownloadInput{Url: url})
+	if err != nil {
+		return err
+	}
+	defer output.File.Close()
+	claimed, err := ioutil.ReadAll(output.File)
+	if err != nil {
+		return err
+	} else if string(claimed) != owner {
+		return customErrors.DedupClaimed
+	}
+	return nil
+}
+
+// checkBlobACL rejects ACL other than one of blobs for a deduplicated file
+func checkBlobACL(acl string) error {
+	if acl != dto.BlobACL {
+		return validation.Errors{"ACL": customErrors.ACLNotAllowed}
+	}
+	return nil
+}
+
+// blobRef returns reference of the file to its blob, nil is returned if the file isn't deduplicated
+func (u *useCase) blobRef(ctx context.Context, url string) (*dto.BlobRef, error) {
+	if u.dedup == nil {
+		return nil, nil
+	}
+	return u.dedup.repo.Get(ctx, url)
+}
+
+// stage replaces key of the upload with a temporary one and returns reference of the original key
+// with content type and metadata of the file, because the key of the blob is unknown until the whole file is read
+func (u *useCase) stage(input *dto.UploadInput) (*dto.BlobRef, error) {
+	ref := &dto.BlobRef{Key: input.Key(), ContentType: input.ContentType, Metadata: input.Metadata()}
+	stagingKey, err := dto.StagingKey(u.dedup.prefix)
+	if err != nil {
+		return nil, err
+	}
+	input.Directory, input.Filename = path.Split(stagingKey)
+	input.ACL = dto.BlobACL
+	// Blob is shared by files with different names, staging key is unique,
+	// and collision of the key is checked by link
+	input.OriginalFilename, input.Exclusive = "", false
+	return ref, nil
+}
+
+// link copies staged file to the blob with its checksum unless the blob exists
+// and refers the key to the blob, the file is recorded by recorded in the same transaction.
+// Exclusive link fails with AlreadyExists if the key is taken.
+// Staged file is kept, so the link can be repeated with another key
+func (u *useCase) link(ctx context.Context, ref *dto.BlobRef, stagingUrl, sha256 string, exclusive bool,
+	recorded func(ctx context.Context, url string) error) (string, error) {
+	garbage := make(dto.BatchDeleteInput, 0)
+	blobKey := dto.BlobKey(u.dedup.prefix, sha256)
+	url, err := u.fileRepo.URL(ref.Key)
+	if err != nil {
+		return "", err
+	}
+	blobUrl, err := u.fileRepo.URL(blobKey)
+	if err != nil {
+		return "", err
+	}
+	if ref.Metadata == nil {
+		ref.Metadata = make(map[string]string)
+	}
+	ref.Url, ref.BlobUrl, ref.Metadata[dto.SHA256MetadataKey] = url, blobUrl, sha256
+
+	u.dedup.mu.Lock()
+	defer u.dedup.mu.Unlock()
+	// Released blob is deleted under the lock, so it can't be linked again meanwhile
+	defer func() {
+		if len(garbage) > 0 {
+			u.discard(ctx, garbage)
+		}
+	}()
+	if exclusive {
+		if err := u.checkFree(ctx, url); err != nil {
+			return "", err
+		}
+	}
+	refs, err := u.dedup.repo.Refs(ctx, blobUrl)
+	if err != nil {
+		return "", err
+	} else if refs == 0 {
+		directory, filename := path.Split(blobKey)
+		copyInput := &dto.CopyInput{SourceUrl: stagingUrl, Directory: directory, Filename: filename, ACL: dto.BlobACL}
+		if _, err := u.fileRepo.Copy(ctx, copyInput); err != nil {
+			return "", err
+		}
+	}
+	released, err := u.linkRecorded(ctx, ref, recorded)
+	if err != nil {
+		if refs == 0 {
+			garbage = append(garbage, dto.DeleteInput(blobUrl))
+		}
+		return "", err
+	} else if released != "" {
+		garbage = append(garbage, dto.DeleteInput(released))
+	}
+	// Plain file written to the key before is replaced by the reference
+	garbage = append(garbage, dto.DeleteInput(url))
+	return url, nil
+}
+
+// checkFree fails with AlreadyExists if the url refers a blob or a plain file
+func (u *useCase) checkFree(ctx context.Context, url string) error {
+	if ref, err := u.dedup.repo.Get(ctx, url); err != nil {
+		return err
+	} else if ref != nil {
+		return customErrors.AlreadyExists
+	}
+	if _, err := u.fileRepo.Stat(ctx, &dto.StatInput{Url: url}); err == nil {
+		return customErrors.AlreadyExists
+	} else if !customErrors.Is(err, customErrors.NotFound) {
+		return err
+	}
+	return nil
+}
+
+// share refers the key to the blob of deduplicated source file instead of copying it,
+// the file is recorded by recorded in the same transaction
+func (u *useCase) share(ctx context.Context, key string, source *dto.BlobRef,
+	recorded func(ctx context.Context, url string) error) (string, error) {
+	url, err := u.fileRepo.URL(key)
+	if err != nil {
+		return "", err
+	} else if url == source.Url {
+		return "", customErrors.SameFile
+	}
+
+	u.dedup.mu.Lock()
+	defer u.dedup.mu.Unlock()
+	// Source may be deleted after it was resolved
+	if refs, err := u.dedup.repo.Refs(ctx, source.BlobUrl); err != nil {
+		return "", err
+	} else if refs == 0 {
+		return "", customErrors.NotFound
+	}
+	released, err := u.linkRecorded(ctx, &dto.BlobRef{
+		Key:         key,
+		Url:         url,
+		BlobUrl:     source.BlobUrl,
+		ContentType: source.ContentType,
+		Metadata:    source.Metadata,
+	}, recorded)
+	if err != nil {
+		return "", err
+	}
+	// Plain file written to the key before is replaced by the reference
+	garbage := dto.BatchDeleteInput{dto.DeleteInput(url)}
+	if released != "" {
+		garbage = append(garbage, dto.DeleteInput(released))
+	}
+	u.discard(ctx, garbage)
+	return url, nil
+}
+
+// linkRecorded refers the file to the blob and records it in one transaction, url of the released blob is returned.
+// Without transactions the reference stays linked if recording fails, so the failure is only logged
+func (u *useCase) linkRecorded(ctx context.Context, ref *dto.BlobRef,
+	recorded func(ctx context.Context, url string) error) (released string, err error) {
+	isLinked := false
+	err = u.transact(ctx, func(ctx context.Context) (err error) {
+		if released, err = u.dedup.repo.Link(ctx, ref); err != nil {
+			return err
+		}
+		isLinked = true
+		return recorded(ctx, ref.Url)
+	})
+	if err != nil && isLinked && u.transactor == nil {
+		log.Error().Err(err).Msgf("failed to record linked file %s", ref.Url)
+		return released, nil
+	}
+	return released, err
+}
+
+// applyRef replaces content type and metadata of the blob with ones of the file referring it,
+// references linked before they were kept have none
+func applyRef(info *dto.FileInfo, ref *dto.BlobRef) {
+	info.Key, info.Url = ref.Key, ref.Url
+	if ref.ContentType != "" {
+		info.ContentType = ref.ContentType
+	}
+	if ref.Metadata != nil {
+		info.Metadata = ref.Metadata
+	}
+}
+
+// unlink removes references of deduplicated files, deletes blobs which lost their last reference
+// and returns urls of the rest files. Changes made by unlinked are applied in the transaction of unlinking
+func (u *useCase) unlink(ctx context.Context, urls dto.BatchDeleteInput,
+	unlinked func(ctx context.Context, rest dto.BatchDeleteInput) error) (dto.BatchDeleteInput, error) {
+	if unlinked == nil {
+		unlinked = func(context.Context, dto.BatchDeleteInput) error { return nil }
+	}
+	if u.dedup == nil {
+		return urls, u.transact(ctx, func(ctx context.Context) error {
+			return unlinked(ctx, urls)
+		})
+	}
+	u.dedup.mu.Lock()
+	defer u.dedup.mu.Unlock()
+	rest := make(dto.BatchDeleteInput, 0, len(urls))
+	released := make(dto.BatchDeleteInput, 0)
+	err := u.transact(ctx, func(ctx context.Context) error {
+		for _, url := range urls {
+			if ref, isReleased, err := u.dedup.repo.Unlink(ctx, url.String()); err != nil {
+				return err
+			} else if ref == nil {
+				rest = append(rest, url)
+			} else if isReleased {
+				released = append(released, dto.DeleteInput(ref.BlobUrl))
+			}
+		}
+		return unlinked(ctx, rest)
+	})
+	// Without transactions blobs released before the error are deleted too, because nothing refers them anymore
+	if len(released) > 0 && (err == nil || u.transactor == nil) {
+		if deleteErr := u.fileRepo.BatchDelete(ctx, released); err == nil {
+			err = deleteErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rest, nil
+}
+
+// forget removes reference of the key when the file is written to the key by storage,
+// so the file isn't shadowed by the blob
+func (u *useCase) forget(ctx context.Context, key string) error {
+	if u.dedup == nil {
+		return nil
+	}
+	url, err := u.fileRepo.URL(key)
+	if err != nil {
+		return err
+	}
+	_, err = u.unlink(ctx, dto.BatchDeleteInput{dto.DeleteInput(url)}, nil)
+	return err
+}
+
+// discard deletes files nothing refers to, failure is only logged,
+// because result of the operation doesn't depend on it
+func (u *useCase) discard(ctx context.Context, urls dto.BatchDeleteInput) {
+	if err := u.fileRepo.BatchDelete(ctx, urls); err != nil {
+		log.Error().Err(err).Msgf("failed to delete %d unreferenced files", len(urls))
+	}
+}
@@ -0,0 +1,301 @@
+package fileUseCase_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+func blobKey(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "_blobs/" + hex.EncodeToString(sum[:])
+}
+
+func objectKeys(repo interface {
+	Objects() []*fileRepo.MemoryObject
+}) []string {
+	keys := make([]string, 0)
+	for _, obj := range repo.Objects() {
+		keys = append(keys, obj.Key)
+	}
+	return keys
+}
+
+func TestUseCase_Deduplication(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+	upload := func(directory, filename, content string) (string, error) {
+		output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      bytes.NewBufferString(content),
+			Directory: directory,
+			Filename:  filename,
+		})
+		if err != nil {
+			return "", err
+		}
+		return output.Url, nil
+	}
+
+	firstUrl, err := upload("logos", "first.png", "logo")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "http://localhost/files/logos/first.png", firstUrl)
+	secondUrl, err := upload("docs", "second.png", "logo")
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{blobKey("logo")}, objectKeys(repo))
+	blob, _ := repo.Object("http://localhost/files/" + blobKey("logo"))
+	assert.EqualValues(t, "private", blob.ACL)
+
+	output, err := useCase.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: firstUrl})
+	assert.NoError(t, err)
+	content, _ := ioutil.ReadAll(output.File)
+	assert.EqualValues(t, "logo", string(content))
+	info, err := useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: secondUrl})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "docs/second.png", info.Key)
+	assert.EqualValues(t, secondUrl, info.Url)
+	assert.EqualValues(t, 4, info.Size)
+
+	// Re-uploaded file refers new blob, the old one is still referred by the second file
+	_, err = upload("logos", "first.png", "new logo")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{blobKey("logo"), blobKey("new logo")}, objectKeys(repo))
+	assert.NoError(t, useCase.Delete(helpers.DefaultCtx, dto.DeleteInput(secondUrl)))
+	assert.EqualValues(t, []string{blobKey("new logo")}, objectKeys(repo))
+
+	// Copy and move share the blob
+	copyUrl, err := useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: firstUrl, Directory: "copies", Filename: "copy.png"})
+	assert.NoError(t, err)
+	movedUrl, err := useCase.Move(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: copyUrl, Directory: "moved", Filename: "moved.png"})
+	assert.NoError(t, err)
+	_, err = useCase.Move(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: movedUrl, Directory: "moved", Filename: "moved.png"})
+	assert.EqualValues(t, customErrors.SameFile, err)
+	assert.EqualValues(t, []string{blobKey("new logo")}, objectKeys(repo))
+	_, err = useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: copyUrl})
+	assert.EqualValues(t, customErrors.NotFound, err)
+
+	_, err = upload("_blobs", "test.png", "test")
+	assert.EqualValues(t, validation.Errors{"Directory": customErrors.ReservedKey}, err)
+	_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:     bytes.NewBufferString("corrupted"),
+		Filename: "test.txt",
+		MD5:      "098f6bcd4621d373cade4e832627b4f6",
+	})
+	assert.EqualValues(t, customErrors.ChecksumMismatch, err)
+
+	// Copy of not deduplicated file replaces deduplicated one
+	uploaded, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("plain"), Filename: "plain.txt"})
+	assert.NoError(t, err)
+	plainUrl := uploaded.Url
+	_, err = useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: plainUrl, Directory: "logos", Filename: "first.png"})
+	assert.NoError(t, err)
+	info, err = useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: firstUrl})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, info.Size)
+
+	err = useCase.BatchDelete(helpers.DefaultCtx, dto.BatchDeleteInput{
+		dto.DeleteInput(firstUrl),
+		dto.DeleteInput(movedUrl),
+		dto.DeleteInput(plainUrl),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, repo.Objects())
+}
+
+func TestUseCase_Deduplication_References(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+	// Plain file uploaded before deduplication was enabled
+	_, err = repo.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("plain"), Filename: "b.txt"})
+	assert.NoError(t, err)
+
+	first, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:             bytes.NewBufferString("test"),
+		Filename:         "a.png",
+		ContentType:      "image/png",
+		OriginalFilename: "photo.png",
+	})
+	assert.NoError(t, err)
+	second, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:        bytes.NewBufferString("test"),
+		Filename:    "b.txt",
+		ContentType: "text/plain",
+	})
+	assert.NoError(t, err)
+	// The plain file is replaced by the reference
+	assert.EqualValues(t, []string{blobKey("test")}, objectKeys(repo))
+
+	info, err := useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: first.Url})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "image/png", info.ContentType)
+	assert.EqualValues(t, "photo.png", info.Metadata[dto.FilenameMetadataKey])
+	assert.EqualValues(t, testSHA256, info.Metadata[dto.SHA256MetadataKey])
+	info, err = useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: second.Url})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "text/plain", info.ContentType)
+	assert.NotContains(t, info.Metadata, dto.FilenameMetadataKey)
+	output, err := useCase.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: second.Url})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "text/plain", output.ContentType)
+
+	// Copy keeps content type and metadata of the source
+	copyUrl, err := useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: first.Url, Filename: "c.png"})
+	assert.NoError(t, err)
+	info, err = useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: copyUrl})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "image/png", info.ContentType)
+	assert.EqualValues(t, "photo.png", info.Metadata[dto.FilenameMetadataKey])
+}
+
+func TestUseCase_Deduplication_ACL(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo,
+		fileUseCase.WithPolicies(policy.Rules{{Prefix: "public", ACL: "public-read"}}),
+		fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"),
+	)
+	upload := func(directory, acl string) (*dto.UploadOutput, error) {
+		return useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      bytes.NewBufferString("test"),
+			Directory: directory,
+			Filename:  "test.png",
+			ACL:       acl,
+		})
+	}
+	aclErr := validation.Errors{"ACL": customErrors.ACLNotAllowed}
+
+	// Url of deduplicated file can't be fetched, so it can't be public
+	_, err = upload("docs", "public-read")
+	assert.EqualValues(t, aclErr, err)
+	_, err = upload("public", "")
+	assert.EqualValues(t, aclErr, err)
+	output, err := upload("docs", "")
+	assert.NoError(t, err)
+	_, err = useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: output.Url, Filename: "copy.png", ACL: "public-read"})
+	assert.EqualValues(t, aclErr, err)
+	assert.EqualValues(t, []string{blobKey("test")}, objectKeys(repo))
+
+	// Copy of plain file is written by storage, so it may be public
+	plain, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("plain"), Filename: "plain.txt"})
+	assert.NoError(t, err)
+	copyUrl, err := useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: plain.Url, Filename: "copy.txt", ACL: "public-read"})
+	assert.NoError(t, err)
+	obj, _ := repo.Object(copyUrl)
+	assert.EqualValues(t, "public-read", obj.ACL)
+}
+
+func TestUseCase_ClaimBlobs(t *testing.T) {
+	openDB := func(name string) *bbolt.DB {
+		db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), name), 0600, nil)
+		assert.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, db.Close())
+		})
+		return db
+	}
+	repo := fileRepo.NewMemory("http://localhost/files")
+	db := openDB("first.db")
+	first := fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+
+	assert.NoError(t, first.ClaimBlobs(helpers.DefaultCtx))
+	// Restarted instance keeps its claim
+	assert.NoError(t, fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs")).ClaimBlobs(helpers.DefaultCtx))
+	obj, ok := repo.Object("http://localhost/files/_blobs/.owner")
+	assert.True(t, ok)
+	assert.EqualValues(t, "private", obj.ACL)
+
+	second := fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(openDB("second.db")), "_blobs"))
+	assert.EqualValues(t, customErrors.DedupClaimed, second.ClaimBlobs(helpers.DefaultCtx))
+	other := fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(openDB("other.db")), "_other"))
+	assert.NoError(t, other.ClaimBlobs(helpers.DefaultCtx))
+	assert.NoError(t, fileUseCase.New(repo).ClaimBlobs(helpers.DefaultCtx))
+}
+
+func TestUseCase_BatchDelete_Deduplicated(t *testing.T) {
+	input := dto.BatchDeleteInput{
+		"https://aws.s3/test.bucket/first.jpg",
+		"https://aws.s3/test.bucket/second.jpg",
+		"https://aws.s3/test.bucket/plain.jpg",
+	}
+	firstRef := &dto.BlobRef{Key: "first.jpg", Url: input[0].String(), BlobUrl: "https://aws.s3/test.bucket/_blobs/1"}
+	secondRef := &dto.BlobRef{Key: "second.jpg", Url: input[1].String(), BlobUrl: "https://aws.s3/test.bucket/_blobs/2"}
+	tests := []struct {
+		name          string
+		dedupCalls    mocks.Calls
+		fileRepoCalls mocks.Calls
+		wantErr       error
+	}{
+		{
+			name: "succeed",
+			dedupCalls: mocks.Calls{
+				{Method: "Unlink", Args: []interface{}{helpers.DefaultCtx, input[0].String()}, ReturnArgs: []interface{}{firstRef, true, nil}},
+				{Method: "Unlink", Args: []interface{}{helpers.DefaultCtx, input[1].String()}, ReturnArgs: []interface{}{secondRef, false, nil}},
+				{Method: "Unlink", Args: []interface{}{helpers.DefaultCtx, input[2].String()}, ReturnArgs: []interface{}{nil, false, nil}},
+			},
+			fileRepoCalls: mocks.Calls{
+				{
+					Method:     "BatchDelete",
+					Args:       []interface{}{helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(firstRef.BlobUrl)}},
+					ReturnArgs: []interface{}{nil},
+				},
+				{Method: "BatchDelete", Args: []interface{}{helpers.DefaultCtx, input[2:]}, ReturnArgs: []interface{}{nil}},
+			},
+		},
+		{
+			name: "error from dedup repo",
+			dedupCalls: mocks.Calls{
+				{Method: "Unlink", Args: []interface{}{helpers.DefaultCtx, input[0].String()}, ReturnArgs: []interface{}{firstRef, true, nil}},
+				{Method: "Unlink", Args: []interface{}{helpers.DefaultCtx, input[1].String()}, ReturnArgs: []interface{}{nil, false, errors.New("test error")}},
+			},
+			fileRepoCalls: mocks.Calls{
+				{
+					Method:     "BatchDelete",
+					Args:       []interface{}{helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(firstRef.BlobUrl)}},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dedup := new(mocks.DedupRepo)
+			for _, call := range tt.dedupCalls {
+				dedup.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.fileRepoCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo, fileUseCase.WithDeduplication(dedup, "_blobs"))
+			err := useCase.BatchDelete(helpers.DefaultCtx, input)
+			assert.EqualValues(t, tt.wantErr, err)
+			dedup.AssertExpectations(t)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
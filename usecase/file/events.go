@@ -0,0 +1,203 @@
+package fileUseCase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+type (
+	events struct {
+		outbox    EventOutbox
+		publisher EventPublisher
+	}
+)
+
+// WithEvents records events of files uploaded, copied and deleted through the service to the outbox,
+// they are sent by the publisher when RelayEvents is called. Event is prepared before the change is made
+// to the storage and confirmed with recording the change to the catalog, so the event of the change
+// interrupted between them is settled by the relay. Files written by presigned urls
+// aren't published, because the service doesn't see them
+func WithEvents(outbox EventOutbox, publisher EventPublisher) Option {
+	return func(u *useCase) {
+		u.events = &events{outbox: outbox, publisher: publisher}
+	}
+}
+
+// RelayEvents settles unconfirmed events and publishes pending events in order they were recorded and returns their number.
+// Event is marked delivered after it's published, so it's published again if marking fails
+// and subscribers have to skip events by their ids. Relaying stops on the first failed event
+func (u *useCase) RelayEvents(ctx context.Context) (int, error) {
+	if u.events == nil {
+		return 0, customErrors.NotSupported
+	}
+	if err := u.settleEvents(ctx); err != nil {
+		return 0, err
+	}
+	relayed := 0
+	for {
+		pending, err := u.events.outbox.Pending(ctx, dto.EventRelayBatchSize)
+		if err != nil {
+			return relayed, err
+		}
+		for _, event := range pending {
+			if err := ctx.Err(); err != nil {
+				return relayed, err
+			} else if err := u.events.publisher.Publish(ctx, event); err != nil {
+				return relayed, err
+			} else if err := u.events.outbox.MarkDelivered(ctx, event); err != nil {
+				return relayed, err
+			}
+			relayed++
+		}
+		if len(pending) < dto.EventRelayBatchSize {
+			return relayed, nil
+		}
+	}
+}
+
+// settleEvents confirms events of changes found in the storage and discards the rest, events are left
+// unconfirmed by changes interrupted after they were prepared. Only events older than
+// dto.EventConfirmTimeout are settled, so changes in progress aren't touched
+func (u *useCase) settleEvents(ctx context.Context) error {
+	for {
+		unconfirmed, err := u.events.outbox.Unconfirmed(ctx, time.Now().Add(-dto.EventConfirmTimeout), dto.EventRelayBatchSize)
+		if err != nil {
+			return err
+		}
+		confirmed, discarded := make([]*dto.FileEvent, 0), make([]*dto.FileEvent, 0)
+		for _, event := range unconfirmed {
+			info, err := u.Stat(ctx, &dto.StatInput{Url: event.Url})
+			if err != nil && !customErrors.Is(err, customErrors.NotFound) {
+				return err
+			} else if exists := err == nil; exists == (event.Type == dto.EventFileDeleted) {
+				discarded = append(discarded, event)
+				continue
+			} else if exists {
+				event.Size, event.ContentType, event.SHA256 = info.Size, info.ContentType, info.Metadata[dto.SHA256MetadataKey]
+			}
+			confirmed = append(confirmed, event)
+		}
+		if err := u.events.outbox.Confirm(ctx, confirmed); err != nil {
+			return err
+		} else if err := u.events.outbox.Discard(ctx, discarded); err != nil {
+			return err
+		}
+		if len(unconfirmed) < dto.EventRelayBatchSize {
+			return nil
+		}
+	}
+}
+
+// prepareEvent prepares event of the file which is about to be written by its key, the event is described
+// by entry of the file when it's confirmed. Nil is returned if events are disabled
+func (u *useCase) prepareEvent(ctx context.Context, event *dto.FileEvent) (*dto.FileEvent, error) {
+	if u.events == nil {
+		return nil, nil
+	}
+	url, err := u.fileRepo.URL(event.Key)
+	if err != nil {
+		return nil, err
+	}
+	event.Url = url
+	if err := u.prepareEvents(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// prepareEvents records events of the change which is about to be made to the outbox under unique ids.
+// Actor of the request is taken unless the event has one, reason and correlation id are taken from the request
+func (u *useCase) prepareEvents(ctx context.Context, events ...*dto.FileEvent) error {
+	if u.events == nil || len(events) == 0 {
+		return nil
+	}
+	for _, event := range events {
+		if event.Actor == "" {
+			event.Actor = dto.ActorFromContext(ctx)
+		}
+		event.Reason, event.CorrelationId = dto.ReasonFromContext(ctx), dto.CorrelationIdFromContext(ctx)
+		event.Id, event.Timestamp = uuid.New().String(), time.Now()
+	}
+	return u.events.outbox.Prepare(ctx, events)
+}
+
+// confirmEvents makes prepared events of the made change pending
+func (u *useCase) confirmEvents(ctx context.Context, events ...*dto.FileEvent) error {
+	if u.events == nil || len(events) == 0 {
+		return nil
+	}
+	return u.events.outbox.Confirm(ctx, events)
+}
+
+// discardEvents removes prepared events of the change which wasn't made, failure is only logged,
+// because the relay discards them when they are settled
+func (u *useCase) discardEvents(ctx context.Context, events ...*dto.FileEvent) {
+	if u.events == nil || len(events) == 0 {
+		return
+	}
+	if err := u.events.outbox.Discard(ctx, events); err != nil {
+		log.Error().Err(err).Msgf("failed to discard %d events", len(events))
+	}
+}
+
+// existing returns urls of the files which exist, it's checked before they are deleted only if events
+// are enabled, because storage doesn't fail deletion of missing files and they must not be published
+func (u *useCase) existing(ctx context.Context, urls dto.BatchDeleteInput) (dto.BatchDeleteInput, error) {
+	if u.events == nil {
+		return nil, nil
+	}
+	existing := make(dto.BatchDeleteInput, 0, len(urls))
+	for _, url := range urls {
+		if _, err := u.fileRepo.Stat(ctx, &dto.StatInput{Url: url.String()}); err == nil {
+			existing = append(existing, url)
+		} else if !customErrors.Is(err, customErrors.NotFound) {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// unlinked returns urls of deduplicated files, which are all urls except the rest returned by unlink
+func unlinked(urls, rest dto.BatchDeleteInput) dto.BatchDeleteInput {
+	isRest := make(map[dto.DeleteInput]bool, len(rest))
+	for _, url := range rest {
+		isRest[url] = true
+	}
+	unlinked := make(dto.BatchDeleteInput, 0, len(urls)-len(rest))
+	for _, url := range urls {
+		if !isRest[url] {
+			unlinked = append(unlinked, url)
+		}
+	}
+	return unlinked
+}
+
+// deletedEvents returns events of deleted files, they are unlinked deduplicated files and existing plain files.
+// Key is left empty if it can't be taken from url
+func (u *useCase) deletedEvents(unlinked, existing dto.BatchDeleteInput) []*dto.FileEvent {
+	if u.events == nil {
+		return nil
+	}
+	isDeleted := make(map[dto.DeleteInput]bool, len(unlinked)+len(existing))
+	events := make([]*dto.FileEvent, 0, len(unlinked)+len(existing))
+	for _, urls := range []dto.BatchDeleteInput{unlinked, existing} {
+		for _, url := range urls {
+			if isDeleted[url] {
+				continue
+			}
+			isDeleted[url] = true
+			event := &dto.FileEvent{Type: dto.EventFileDeleted, Url: string(url)}
+			if key, err := u.fileRepo.Key(string(url)); err == nil {
+				event.Key = key
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,305 @@
+package fileUseCase_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.etcd.io/bbolt"
+
+	boltRepo "github.com/freemen-app/file_storage/adapter/repository/bolt"
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	outboxRepo "github.com/freemen-app/file_storage/adapter/repository/outbox"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+func TestUseCase_Events(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	repo := fileRepo.NewMemory("http://localhost/files")
+	publisher := new(mocks.EventPublisher)
+	var events []*dto.FileEvent
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*dto.FileEvent))
+	})
+	useCase := fileUseCase.New(repo,
+		fileUseCase.WithTransactions(boltRepo.NewTransactor(db)),
+		fileUseCase.WithEvents(outboxRepo.New(db), publisher),
+	)
+	ctx := dto.WithActor(helpers.DefaultCtx, "admin")
+	// published relays pending events and returns them without their ids
+	published := func() []*dto.FileEvent {
+		defer func() { events = nil }()
+		relayed, err := useCase.RelayEvents(helpers.DefaultCtx)
+		assert.NoError(t, err)
+		assert.EqualValues(t, len(events), relayed)
+		for _, event := range events {
+			assert.NotEmpty(t, event.Id)
+			assert.False(t, event.Timestamp.IsZero())
+			event.Id = ""
+		}
+		return events
+	}
+
+	output, err := useCase.Upload(ctx, &dto.UploadInput{
+		File:      bytes.NewBufferString("test"),
+		Directory: "tmp",
+		Filename:  "test.txt",
+		Owner:     "user",
+	})
+	assert.NoError(t, err)
+	got := published()
+	assert.Len(t, got, 1)
+	assert.EqualValues(t, &dto.FileEvent{
+		Type:        dto.EventFileUploaded,
+		Key:         "tmp/test.txt",
+		Url:         output.Url,
+		Size:        4,
+		ContentType: "text/plain; charset=utf-8",
+		SHA256:      "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Actor:       "user",
+		Timestamp:   got[0].Timestamp,
+	}, got[0])
+
+	url, err := useCase.Move(ctx, &dto.CopyInput{SourceUrl: output.Url, Directory: "docs", Filename: "test.txt"})
+	assert.NoError(t, err)
+	got = published()
+	assert.Len(t, got, 2)
+	assert.EqualValues(t, dto.EventFileCopied, got[0].Type)
+	assert.EqualValues(t, "docs/test.txt", got[0].Key)
+	assert.EqualValues(t, url, got[0].Url)
+	assert.EqualValues(t, output.Url, got[0].SourceUrl)
+	assert.EqualValues(t, 4, got[0].Size)
+	assert.EqualValues(t, "admin", got[0].Actor)
+	assert.EqualValues(t, &dto.FileEvent{
+		Type:      dto.EventFileDeleted,
+		Key:       "tmp/test.txt",
+		Url:       output.Url,
+		Actor:     "admin",
+		Timestamp: got[1].Timestamp,
+	}, got[1])
+
+	deleteCtx := dto.WithCorrelationId(dto.WithReason(helpers.DefaultCtx, "cleanup"), "correlation")
+	assert.NoError(t, useCase.DeleteFiles(deleteCtx, &dto.DeleteFilesInput{Keys: []string{"docs/test.txt"}}))
+	got = published()
+	assert.Len(t, got, 1)
+	assert.EqualValues(t, &dto.FileEvent{
+		Type:          dto.EventFileDeleted,
+		Key:           "docs/test.txt",
+		Url:           url,
+		Reason:        "cleanup",
+		CorrelationId: "correlation",
+		Timestamp:     got[0].Timestamp,
+	}, got[0])
+
+	// Deletion of missing files isn't published
+	assert.NoError(t, useCase.Delete(ctx, dto.DeleteInput(url)))
+	assert.NoError(t, useCase.BatchDelete(ctx, dto.BatchDeleteInput{dto.DeleteInput(url)}))
+	assert.Empty(t, published())
+
+	// Failed operations aren't published
+	_, err = useCase.Copy(ctx, &dto.CopyInput{SourceUrl: url, Directory: "docs", Filename: "copy.txt"})
+	assert.Error(t, err)
+	assert.Empty(t, published())
+
+	// Events failed to be published are kept in the outbox until they are relayed
+	for _, filename := range []string{"first.txt", "second.txt"} {
+		_, err = useCase.Upload(ctx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: filename})
+		assert.NoError(t, err)
+	}
+	publishErr := errors.New("test")
+	publisher.ExpectedCalls = nil
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(publishErr).Once()
+	relayed, err := useCase.RelayEvents(helpers.DefaultCtx)
+	assert.EqualValues(t, publishErr, err)
+	assert.Zero(t, relayed)
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*dto.FileEvent))
+	})
+	got = published()
+	assert.Len(t, got, 2)
+	assert.EqualValues(t, "first.txt", got[0].Key)
+	assert.EqualValues(t, "second.txt", got[1].Key)
+	assert.Empty(t, published())
+}
+
+func TestUseCase_Events_Outbox(t *testing.T) {
+	url := "http://localhost/files/test.txt"
+	repo := fileRepo.NewMemory("http://localhost/files")
+	outbox := new(mocks.EventOutbox)
+	useCase := fileUseCase.New(repo, fileUseCase.WithEvents(outbox, new(mocks.EventPublisher)))
+	upload := func() error {
+		_, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: "test.txt"})
+		return err
+	}
+
+	// Change isn't made if its event isn't prepared, so that client retries it
+	outbox.On("Prepare", helpers.DefaultCtx, mock.Anything).Return(errors.New("test")).Once()
+	assert.EqualError(t, upload(), "test")
+	_, err := repo.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+	assert.True(t, customErrors.Is(err, customErrors.NotFound))
+
+	// Made change doesn't fail if its event isn't confirmed, the event is left to the relay
+	outbox.On("Prepare", helpers.DefaultCtx, mock.Anything).Return(nil)
+	outbox.On("Confirm", helpers.DefaultCtx, mock.Anything).Return(errors.New("test")).Once()
+	assert.NoError(t, upload())
+	outbox.AssertNotCalled(t, "Discard", mock.Anything, mock.Anything)
+
+	// Event of failed change is discarded
+	outbox.On("Discard", helpers.DefaultCtx, mock.MatchedBy(func(events []*dto.FileEvent) bool {
+		return len(events) == 1 && events[0].Type == dto.EventFileUploaded && events[0].Url == url
+	})).Return(nil).Once()
+	_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:      bytes.NewBufferString("test"),
+		Filename:  "test.txt",
+		Collision: dto.CollisionFail,
+	})
+	assert.True(t, customErrors.Is(err, customErrors.AlreadyExists))
+	outbox.AssertExpectations(t)
+
+	_, err = fileUseCase.New(repo).RelayEvents(helpers.DefaultCtx)
+	assert.EqualValues(t, customErrors.NotSupported, err)
+}
+
+func TestUseCase_Events_Transaction(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	outbox, dedup := outboxRepo.New(db), dedupRepo.New(db)
+	catalog := new(mocks.CatalogRepo)
+	catalog.On("Get", mock.Anything, mock.Anything).Return(nil, nil)
+	catalog.On("Save", mock.Anything, mock.Anything).Return(errors.New("test"))
+	useCase := fileUseCase.New(fileRepo.NewMemory("http://localhost/files"),
+		fileUseCase.WithTransactions(boltRepo.NewTransactor(db)),
+		fileUseCase.WithDeduplication(dedup, "_blobs"),
+		fileUseCase.WithCatalog(catalog),
+		fileUseCase.WithEvents(outbox, new(mocks.EventPublisher)),
+	)
+
+	// Deduplicated file is linked in the transaction of recording, so nothing is kept if recording fails
+	_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: "test.txt"})
+	assert.EqualError(t, err, "test")
+	ref, err := dedup.Get(helpers.DefaultCtx, "http://localhost/files/test.txt")
+	assert.NoError(t, err)
+	assert.Nil(t, ref)
+	pending, err := outbox.Pending(helpers.DefaultCtx, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	unconfirmed, err := outbox.Unconfirmed(helpers.DefaultCtx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, unconfirmed)
+}
+
+func TestUseCase_Events_Settle(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	repo, outbox := fileRepo.NewMemory("http://localhost/files"), outboxRepo.New(db)
+	publisher := new(mocks.EventPublisher)
+	var events []*dto.FileEvent
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*dto.FileEvent))
+	})
+	useCase := fileUseCase.New(repo, fileUseCase.WithEvents(outbox, publisher))
+	output, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: "test.txt"})
+	assert.NoError(t, err)
+	missing := "http://localhost/files/missing.txt"
+
+	// Events of changes interrupted before they were confirmed are settled by the storage
+	interrupted := time.Now().Add(-dto.EventConfirmTimeout - time.Minute)
+	inProgress := &dto.FileEvent{Id: "5", Type: dto.EventFileUploaded, Url: missing, Timestamp: time.Now()}
+	assert.NoError(t, outbox.Prepare(helpers.DefaultCtx, []*dto.FileEvent{
+		{Id: "1", Type: dto.EventFileUploaded, Key: "test.txt", Url: output.Url, Timestamp: interrupted},
+		{Id: "2", Type: dto.EventFileUploaded, Key: "missing.txt", Url: missing, Timestamp: interrupted},
+		{Id: "3", Type: dto.EventFileDeleted, Key: "missing.txt", Url: missing, Timestamp: interrupted},
+		{Id: "4", Type: dto.EventFileDeleted, Key: "test.txt", Url: output.Url, Timestamp: interrupted},
+		inProgress,
+	}))
+	relayed, err := useCase.RelayEvents(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, relayed)
+	if assert.Len(t, events, 2) {
+		assert.EqualValues(t, "1", events[0].Id)
+		assert.EqualValues(t, 4, events[0].Size)
+		assert.EqualValues(t, "3", events[1].Id)
+	}
+	unconfirmed, err := outbox.Unconfirmed(helpers.DefaultCtx, time.Now().Add(time.Minute), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, unconfirmed, 1) {
+		assert.EqualValues(t, inProgress.Id, unconfirmed[0].Id)
+	}
+}
+
+func TestUseCase_Events_Copy(t *testing.T) {
+	sourceUrl, url := "https://aws.s3/test.bucket/tmp/test.txt", "https://aws.s3/test.bucket/docs/test.txt"
+	repo := new(mocks.FileRepo)
+	// The copy isn't inspected, so the applied copy can't fail on reading its metadata
+	repo.On("Stat", helpers.DefaultCtx, &dto.StatInput{Url: sourceUrl}).
+		Return(&dto.FileInfo{Key: "tmp/test.txt", Url: sourceUrl, Size: 4, ContentType: "text/plain"}, nil).Once()
+	repo.On("URL", "docs/test.txt").Return(url, nil)
+	repo.On("Copy", helpers.DefaultCtx, mock.Anything).Return(url, nil)
+	outbox := new(mocks.EventOutbox)
+	outbox.On("Prepare", helpers.DefaultCtx, mock.MatchedBy(func(events []*dto.FileEvent) bool {
+		return len(events) == 1 && events[0].Type == dto.EventFileCopied && events[0].Url == url
+	})).Return(nil)
+	outbox.On("Confirm", helpers.DefaultCtx, mock.MatchedBy(func(events []*dto.FileEvent) bool {
+		event := events[0]
+		return len(events) == 1 && event.Type == dto.EventFileCopied && event.Key == "docs/test.txt" && event.Url == url &&
+			event.SourceUrl == sourceUrl && event.Size == 4 && event.ContentType == "text/plain"
+	})).Return(nil)
+
+	useCase := fileUseCase.New(repo, fileUseCase.WithEvents(outbox, new(mocks.EventPublisher)))
+	got, err := useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: sourceUrl, Directory: "docs", Filename: "test.txt"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, url, got)
+	repo.AssertExpectations(t)
+	outbox.AssertExpectations(t)
+}
+
+func TestUseCase_Events_Reconcile(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	root := helpers.TempDir(t)
+	repo := fileRepo.NewLocal(root, "http://localhost/files")
+	publisher := new(mocks.EventPublisher)
+	var events []*dto.FileEvent
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(*dto.FileEvent))
+	})
+	useCase := fileUseCase.New(repo,
+		fileUseCase.WithTransactions(boltRepo.NewTransactor(db)),
+		fileUseCase.WithEvents(outboxRepo.New(db), publisher),
+	)
+	// Orphan is written by storage only, so its upload isn't published
+	output, err := repo.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Directory: "tmp", Filename: "leaked.txt"})
+	assert.NoError(t, err)
+	uploadedAt := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(root, "tmp", "leaked.txt"), uploadedAt, uploadedAt))
+
+	report, err := useCase.Reconcile(helpers.DefaultCtx, &dto.ReconcileInput{
+		Prefix:      "tmp/",
+		LiveUrls:    []string{"http://localhost/files/tmp/live.txt"},
+		GracePeriod: time.Minute,
+		Delete:      true,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, report.Deleted)
+	relayed, err := useCase.RelayEvents(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, relayed)
+	assert.EqualValues(t, dto.EventFileDeleted, events[0].Type)
+	assert.EqualValues(t, "tmp/leaked.txt", events[0].Key)
+	assert.EqualValues(t, output.Url, events[0].Url)
+}
@@ -1,5 +1,11 @@
 package fileUseCase
 
+import "github.com/freemen-app/file_storage/domain/policy"
+
 func (u *useCase) FileRepo() FileRepo {
 	return u.fileRepo
 }
+
+func (u *useCase) Policies() policy.Rules {
+	return u.policies
+}
@@ -2,57 +2,578 @@ package fileUseCase
 
 import (
 	"context"
+	"path"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/rs/zerolog/log"
 
 	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
 )
 
 type (
 	useCase struct {
 		fileRepo FileRepo
+		policies policy.Rules
+		dedup    *dedup
+		catalog  CatalogRepo
+		trash    *trash
+		events   *events
+		sessions SessionRepo
+		// transactor is optional, changes of repositories are applied one by one without it
+		transactor Transactor
 	}
 
+	Option func(u *useCase)
+
 	UseCase interface {
-		Upload(ctx context.Context, input *dto.UploadInput) (string, error)
+		Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error)
+		Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error)
+		Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error)
+		List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error)
+		Copy(ctx context.Context, input *dto.CopyInput) (string, error)
+		Move(ctx context.Context, input *dto.CopyInput) (string, error)
 		Delete(ctx context.Context, input dto.DeleteInput) error
 		BatchDelete(ctx context.Context, input dto.BatchDeleteInput) error
+		DeleteFiles(ctx context.Context, input *dto.DeleteFilesInput) error
+		PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error)
+		PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error)
+		InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error)
+		UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error)
+		GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error)
+		CompleteUpload(ctx context.Context, input *dto.UploadSessionInput) (string, error)
+		AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error
+		AbortStaleUploads(ctx context.Context, before time.Time) (int, error)
+		Reconcile(ctx context.Context, input *dto.ReconcileInput) (*dto.ReconcileReport, error)
+		Restore(ctx context.Context, input *dto.RestoreInput) (string, error)
+		ListTrash(ctx context.Context, input *dto.ListTrashInput) (*dto.ListTrashOutput, error)
+		PurgeTrash(ctx context.Context) (int, error)
+		ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error)
+		GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error)
+		RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error)
+	}
+
+	// Deduplicator claims directory of blobs for the instance, it's used at start only
+	Deduplicator interface {
+		ClaimBlobs(ctx context.Context) error
+	}
+
+	// EventRelay publishes events recorded by the use case, it's used by the relay only
+	EventRelay interface {
+		RelayEvents(ctx context.Context) (int, error)
 	}
 
 	FileRepo interface {
-		Upload(ctx context.Context, input *dto.UploadInput) (string, error)
+		Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error)
+		Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error)
+		Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error)
+		// ACL returns canned ACL of the file, empty string is returned if it can't be known
+		ACL(ctx context.Context, url string) (string, error)
+		List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error)
+		Copy(ctx context.Context, input *dto.CopyInput) (string, error)
 		Delete(ctx context.Context, input dto.DeleteInput) error
 		BatchDelete(ctx context.Context, input dto.BatchDeleteInput) error
+		PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error)
+		PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error)
+		InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error)
+		UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error)
+		GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error)
+		CompleteUpload(ctx context.Context, session *dto.UploadSession) (string, error)
+		AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error
+		ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error)
+		GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error)
+		RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error)
+		URL(key string) (string, error)
+		Key(url string) (string, error)
+	}
+
+	// DedupRepo counts references of files to content-addressed blobs
+	DedupRepo interface {
+		Link(ctx context.Context, ref *dto.BlobRef) (released string, err error)
+		Unlink(ctx context.Context, url string) (ref *dto.BlobRef, released bool, err error)
+		Get(ctx context.Context, url string) (*dto.BlobRef, error)
+		Refs(ctx context.Context, blobUrl string) (int, error)
+		// Owner returns id of the database counting references
+		Owner(ctx context.Context) (string, error)
+	}
+
+	// CatalogRepo stores metadata of files by their urls, Get returns nil if the file isn't recorded
+	CatalogRepo interface {
+		Save(ctx context.Context, entry *dto.CatalogEntry) error
+		MarkDeleted(ctx context.Context, urls dto.BatchDeleteInput, at time.Time) error
+		Get(ctx context.Context, url string) (*dto.CatalogEntry, error)
+		List(ctx context.Context, input *dto.CatalogListInput) (*dto.CatalogListOutput, error)
+	}
+
+	// EventOutbox keeps events until they are delivered. Events are prepared before the change is made
+	// and confirmed or discarded after it, Pending returns the oldest confirmed events first
+	EventOutbox interface {
+		Prepare(ctx context.Context, events []*dto.FileEvent) error
+		Confirm(ctx context.Context, events []*dto.FileEvent) error
+		Discard(ctx context.Context, events []*dto.FileEvent) error
+		Unconfirmed(ctx context.Context, before time.Time, limit int) ([]*dto.FileEvent, error)
+		Pending(ctx context.Context, limit int) ([]*dto.FileEvent, error)
+		MarkDelivered(ctx context.Context, event *dto.FileEvent) error
+	}
+
+	// Transactor applies changes of repositories called with the context passed to fn in one transaction
+	Transactor interface {
+		Transact(ctx context.Context, fn func(ctx context.Context) error) error
+	}
+
+	// EventPublisher sends events of changed files to subscribers
+	EventPublisher interface {
+		Publish(ctx context.Context, event *dto.FileEvent) error
+	}
+
+	// SessionRepo keeps upload sessions initiated by the service until they are completed or aborted
+	SessionRepo interface {
+		Save(ctx context.Context, uploadId string, initiated time.Time) error
+		Delete(ctx context.Context, uploadId string) error
+		Stale(ctx context.Context, before time.Time) ([]string, error)
 	}
 )
 
-func New(fileRepo FileRepo) *useCase {
-	return &useCase{
+func New(fileRepo FileRepo, opts ...Option) *useCase {
+	u := &useCase{
 		fileRepo: fileRepo,
 	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
 }
 
-func (u *useCase) Upload(ctx context.Context, input *dto.UploadInput) (string, error) {
+// WithTransactions applies related changes of catalog, deduplication and events together
+func WithTransactions(transactor Transactor) Option {
+	return func(u *useCase) {
+		u.transactor = transactor
+	}
+}
+
+// transact applies changes made by fn in one transaction if transactions are enabled
+func (u *useCase) transact(ctx context.Context, fn func(ctx context.Context) error) error {
+	if u.transactor == nil {
+		return fn(ctx)
+	}
+	return u.transactor.Transact(ctx, fn)
+}
+
+// WithPolicies restricts uploaded files by rules matching their keys
+func WithPolicies(rules policy.Rules) Option {
+	return func(u *useCase) {
+		u.policies = rules
+	}
+}
+
+func (u *useCase) Upload(ctx context.Context, input *dto.UploadInput) (*dto.UploadOutput, error) {
 	if err := input.Validate(); err != nil {
+		return nil, err
+	} else if err := u.checkKey(input.Key()); err != nil {
+		return nil, err
+	}
+	rule := u.policies.Match(input.Key())
+	if rule != nil && rule.RestrictsContentTypes() {
+		// Declared content type isn't trusted, so the checked and stored one is detected by content
+		input.ContentType = ""
+	}
+	if err := input.DetectContentType(); err != nil {
+		return nil, err
+	}
+	acl, err := applyACL(rule, input.ACL)
+	if err != nil {
+		return nil, err
+	} else if acl == "" {
+		acl = u.defaultACL()
+	}
+	if u.dedup != nil {
+		if err := checkBlobACL(acl); err != nil {
+			return nil, err
+		}
+	}
+	input.ACL = acl
+	var limited *policy.LimitedReader
+	if rule != nil {
+		if err := rule.Check(input.Filename, input.ContentType); err != nil {
+			return nil, err
+		}
+		// Size is checked while streaming, because it is unknown in advance
+		limited = rule.LimitReader(input.File)
+		input.File = limited
+	}
+	directory, filename := input.Directory, input.Filename
+	strategy, err := u.resolveCollision(ctx, input, rule)
+	if err != nil {
+		return nil, err
+	}
+	event, err := u.prepareEvent(ctx, &dto.FileEvent{Type: dto.EventFileUploaded, Key: input.Key(), Actor: input.Owner})
+	if err != nil {
+		return nil, err
+	}
+	// Event is discarded unless the file is written
+	isWritten := false
+	defer func() {
+		if !isWritten {
+			u.discardEvents(ctx, event)
+		}
+	}()
+	checksum := input.TrackChecksum()
+	var ref *dto.BlobRef
+	var spool *dto.SpoolReader
+	exclusive := input.Exclusive
+	if u.dedup != nil {
+		if ref, err = u.stage(input); err != nil {
+			return nil, err
+		}
+	} else if strategy == dto.CollisionRename {
+		// The file is written again with another filename if the picked one is taken meanwhile
+		if spool, err = dto.NewSpoolReader(input.File); err != nil {
+			return nil, err
+		}
+		defer spool.Close()
+		input.File = spool
+	}
+
+	var output *dto.UploadOutput
+	err = u.writeRenamed(ctx, strategy, directory, filename, input.Filename, func(renamed string) (err error) {
+		if input.Filename != renamed {
+			input.Filename = renamed
+			spool.Rewind()
+		}
+		output, err = u.fileRepo.Upload(ctx, input)
+		return err
+	})
+	if limited != nil && limited.Exceeded() {
+		// Storages may wrap reading error, so it is checked explicitly
+		return nil, validation.Errors{"File": customErrors.TooLarge}
+	} else if err != nil {
+		return nil, err
+	}
+	if input.HasChecksum() {
+		if verifyErr := checksum.Verify(input.SHA256, input.MD5); verifyErr != nil {
+			// Corrupted file must not stay available
+			if err := u.fileRepo.Delete(ctx, dto.DeleteInput(output.Url)); err != nil {
+				return nil, err
+			}
+			return nil, verifyErr
+		}
+	}
+	uploaded := func(key, url string) *dto.CatalogEntry {
+		entry := dto.NewCatalogEntry(key, url)
+		entry.Owner, entry.ContentType = input.Owner, input.ContentType
+		entry.Size, entry.SHA256 = checksum.Size(), checksum.SHA256()
+		return entry
+	}
+	if u.dedup == nil {
+		isWritten = true
+		u.confirmWritten(ctx, uploaded(input.Key(), output.Url), event)
+		return output, nil
+	}
+	// Version of the staged file is meaningless, because it's deleted after linking
+	defer u.discard(ctx, dto.BatchDeleteInput{dto.DeleteInput(output.Url)})
+	var url string
+	err = u.writeRenamed(ctx, strategy, directory, filename, path.Base(ref.Key), func(renamed string) (err error) {
+		ref.Key = dto.ObjectKey(directory, renamed)
+		url, err = u.link(ctx, ref, output.Url, checksum.SHA256(), exclusive, func(ctx context.Context, url string) error {
+			return u.recordWritten(ctx, uploaded(ref.Key, url), event)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	isWritten = true
+	return &dto.UploadOutput{Url: url}, nil
+}
+
+func (u *useCase) Download(ctx context.Context, input *dto.DownloadInput) (*dto.DownloadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	ref, err := u.blobRef(ctx, input.Url)
+	if err != nil {
+		return nil, err
+	} else if ref == nil {
+		return u.fileRepo.Download(ctx, input)
+	}
+	input.Url = ref.BlobUrl
+	output, err := u.fileRepo.Download(ctx, input)
+	if err != nil {
+		return nil, err
+	} else if ref.ContentType != "" {
+		output.ContentType = ref.ContentType
+	}
+	return output, nil
+}
+
+func (u *useCase) Stat(ctx context.Context, input *dto.StatInput) (*dto.FileInfo, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	ref, err := u.blobRef(ctx, input.Url)
+	if err != nil {
+		return nil, err
+	} else if ref == nil {
+		return u.fileRepo.Stat(ctx, input)
+	}
+	info, err := u.fileRepo.Stat(ctx, &dto.StatInput{Url: ref.BlobUrl})
+	if err != nil {
+		return nil, err
+	}
+	applyRef(info, ref)
+	return info, nil
+}
+
+func (u *useCase) List(ctx context.Context, input *dto.ListInput) (*dto.ListOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	return u.fileRepo.List(ctx, input)
+}
+
+func (u *useCase) Copy(ctx context.Context, input *dto.CopyInput) (string, error) {
+	return u.copy(ctx, input, input.SourceUrl)
+}
+
+// copy copies the file and records it to the catalog by entry of recordedUrl
+func (u *useCase) copy(ctx context.Context, input *dto.CopyInput, recordedUrl string) (string, error) {
+	if err := input.Validate(); err != nil {
+		return "", err
+	} else if err := u.checkKey(input.Key()); err != nil {
+		return "", err
+	}
+	source, err := u.blobRef(ctx, input.SourceUrl)
+	if err != nil {
+		return "", err
+	}
+	rule := u.policies.Match(input.Key())
+	acl, err := applyACL(rule, input.ACL)
+	if err != nil {
+		return "", err
+	} else if acl == "" {
+		acl = u.defaultACL()
+	}
+	if source != nil {
+		// Copy of deduplicated file shares its blob
+		if err := checkBlobACL(acl); err != nil {
+			return "", err
+		}
+	}
+	input.ACL = acl
+	if rule != nil {
+		sourceUrl := input.SourceUrl
+		if source != nil {
+			sourceUrl = source.BlobUrl
+		}
+		info, err := u.fileRepo.Stat(ctx, &dto.StatInput{Url: sourceUrl})
+		if err != nil {
+			return "", err
+		} else if err := rule.Check(input.Filename, info.ContentType); err != nil {
+			return "", err
+		} else if err := rule.CheckSize(info.Size); err != nil {
+			return "", err
+		}
+	}
+	entry, err := u.copiedEntry(ctx, input, recordedUrl)
+	if err != nil {
+		return "", err
+	}
+	event, err := u.prepareEvent(ctx, &dto.FileEvent{Type: dto.EventFileCopied, Key: input.Key(), SourceUrl: input.SourceUrl})
+	if err != nil {
+		return "", err
+	}
+	if source != nil {
+		url, err := u.share(ctx, input.Key(), source, func(ctx context.Context, url string) error {
+			entry.Url = url
+			return u.recordWritten(ctx, entry, event)
+		})
+		if err != nil {
+			u.discardEvents(ctx, event)
+			return "", err
+		}
+		return url, nil
+	}
+	url, err := u.fileRepo.Copy(ctx, input)
+	if err != nil {
+		u.discardEvents(ctx, event)
+		return "", err
+	} else if err := u.forget(ctx, input.Key()); err != nil {
 		return "", err
 	}
-	url, err := u.fileRepo.Upload(ctx, input)
+	entry.Url = url
+	u.confirmWritten(ctx, entry, event)
+	return url, nil
+}
+
+// Move copies file and deletes the source, copy fails if destination is the same as source,
+// so the file can't be lost
+func (u *useCase) Move(ctx context.Context, input *dto.CopyInput) (string, error) {
+	url, err := u.Copy(ctx, input)
 	if err != nil {
 		return "", err
 	}
+	// Source isn't moved to trash, because its content is kept by the copy
+	if err := u.delete(ctx, dto.DeleteInput(input.SourceUrl)); err != nil {
+		return "", err
+	}
 	return url, nil
 }
 
+// Delete moves file to trash if it's enabled and removes deduplicated file
+// and its blob if it was the last file referring the blob
 func (u *useCase) Delete(ctx context.Context, input dto.DeleteInput) error {
 	if err := input.Validate(); err != nil {
 		return err
+	} else if err := u.moveToTrash(ctx, dto.BatchDeleteInput{input}); err != nil {
+		return err
 	}
-	err := u.fileRepo.Delete(ctx, input)
-	return err
+	return u.delete(ctx, input)
+}
+
+func (u *useCase) delete(ctx context.Context, input dto.DeleteInput) error {
+	return u.remove(ctx, dto.BatchDeleteInput{input}, func(dto.BatchDeleteInput) error {
+		return u.fileRepo.Delete(ctx, input)
+	})
 }
 
 func (u *useCase) BatchDelete(ctx context.Context, input dto.BatchDeleteInput) error {
 	if err := input.Validate(); err != nil {
 		return err
+	} else if err := u.moveToTrash(ctx, input); err != nil {
+		return err
+	}
+	return u.remove(ctx, input, func(rest dto.BatchDeleteInput) error {
+		return u.fileRepo.BatchDelete(ctx, rest)
+	})
+}
+
+// remove unlinks deduplicated files and deletes the rest files by deleteRest. Events of deleted files
+// are prepared with unlinking and confirmed with marking entries of the files deleted
+func (u *useCase) remove(ctx context.Context, urls dto.BatchDeleteInput, deleteRest func(rest dto.BatchDeleteInput) error) error {
+	existing, err := u.existing(ctx, urls)
+	if err != nil {
+		return err
+	}
+	var events []*dto.FileEvent
+	rest, err := u.unlink(ctx, urls, func(ctx context.Context, rest dto.BatchDeleteInput) error {
+		events = u.deletedEvents(unlinked(urls, rest), existing)
+		return u.prepareEvents(ctx, events...)
+	})
+	if err != nil {
+		return err
+	}
+	// Events are left unconfirmed if deletion fails, because some files may be deleted, so they are settled by the relay
+	if len(rest) > 0 {
+		if err := deleteRest(rest); err != nil {
+			return err
+		}
+	}
+	err = u.transact(ctx, func(ctx context.Context) error {
+		if err := u.recordDeleted(ctx, urls); err != nil {
+			return err
+		}
+		return u.confirmEvents(ctx, events...)
+	})
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to record %d deleted files", len(urls))
+	}
+	return nil
+}
+
+// DeleteFiles deletes files by urls and urls of keys as BatchDelete, keys of blobs and trash are rejected
+func (u *useCase) DeleteFiles(ctx context.Context, input *dto.DeleteFilesInput) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+	urls := append(make(dto.BatchDeleteInput, 0, len(input.Urls)+len(input.Keys)), input.Urls...)
+	for _, key := range input.Keys {
+		if err := u.checkKey(key); err != nil {
+			return err
+		}
+		url, err := u.fileRepo.URL(key)
+		if err != nil {
+			return err
+		}
+		urls = append(urls, dto.DeleteInput(url))
+	}
+	return u.BatchDelete(ctx, urls)
+}
+
+func (u *useCase) PresignDownload(ctx context.Context, input *dto.PresignDownloadInput) (*dto.PresignOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if ref, err := u.blobRef(ctx, input.Url); err != nil {
+		return nil, err
+	} else if ref != nil {
+		// Blob is shared, so the response is served with content type of the file
+		input.Url, input.ContentType = ref.BlobUrl, ref.ContentType
+	}
+	return u.fileRepo.PresignDownload(ctx, input)
+}
+
+// PresignUpload checks filename, declared content type and size by policies,
+// content isn't available, so it can't be checked
+func (u *useCase) PresignUpload(ctx context.Context, input *dto.PresignUploadInput) (*dto.PresignOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	} else if err := u.checkKey(input.Key()); err != nil {
+		return nil, err
+	}
+	rule := u.policies.Match(input.Key())
+	acl, err := applyACL(rule, input.ACL)
+	if err != nil {
+		return nil, err
+	}
+	input.ACL = acl
+	if rule != nil {
+		if err := rule.Check(input.Filename, input.ContentType); err != nil {
+			return nil, err
+		}
+		// Storage rejects the file of another size, because the size is signed
+		if rule.MaxSize > 0 && input.Size == 0 {
+			return nil, validation.Errors{"Size": customErrors.SizeRequired}
+		} else if err := rule.CheckSize(input.Size); err != nil {
+			return nil, err
+		}
+	}
+	// Presigned upload isn't deduplicated, so it replaces deduplicated file
+	if err := u.forget(ctx, input.Key()); err != nil {
+		return nil, err
+	}
+	return u.fileRepo.PresignUpload(ctx, input)
+}
+
+// checkKey rejects keys inside directories of blobs and trash, so they can't be overwritten by clients
+func (u *useCase) checkKey(key string) error {
+	if u.dedup != nil && dto.IsBlobKey(u.dedup.prefix, key) {
+		return validation.Errors{"Directory": customErrors.ReservedKey}
+	} else if u.trash != nil && dto.IsTrashKey(u.trash.prefix, key) {
+		return validation.Errors{"Directory": customErrors.ReservedKey}
+	}
+	return nil
+}
+
+// defaultACL returns ACL of uploaded and copied files when neither request nor policy sets it
+func (u *useCase) defaultACL() string {
+	if u.dedup != nil {
+		return dto.BlobACL
+	}
+	return dto.DefaultACL
+}
+
+// applyACL returns requested ACL or the default one of the rule and checks whether it's allowed
+func applyACL(rule *policy.Rule, acl string) (string, error) {
+	if rule == nil {
+		return acl, nil
+	}
+	if acl == "" {
+		acl = rule.DefaultACL()
+	}
+	if err := rule.CheckACL(acl); err != nil {
+		return "", err
 	}
-	err := u.fileRepo.BatchDelete(ctx, input)
-	return err
+	return acl, nil
 }
@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io/ioutil"
 	"testing"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
 	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
 	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
 	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
 	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
@@ -19,6 +25,21 @@ func TestNew(t *testing.T) {
 	fileRepo := new(mocks.FileRepo)
 	useCase := fileUseCase.New(fileRepo)
 	assert.EqualValues(t, fileRepo, useCase.FileRepo())
+	assert.Empty(t, useCase.Policies())
+
+	rules := policy.Rules{{Prefix: "avatars/", MaxSize: 4}}
+	useCase = fileUseCase.New(fileRepo, fileUseCase.WithPolicies(rules))
+	assert.EqualValues(t, rules, useCase.Policies())
+}
+
+// uploadInput matches upload input ignoring the file, which is wrapped by content type detection
+func uploadInput(want *dto.UploadInput) interface{} {
+	return mock.MatchedBy(func(input *dto.UploadInput) bool {
+		got := *input
+		_, isTracked := input.File.(*dto.ChecksumReader)
+		got.File = nil
+		return isTracked && assert.ObjectsAreEqual(*want, got)
+	})
 }
 
 func TestUseCase_Upload(t *testing.T) {
@@ -30,7 +51,7 @@ func TestUseCase_Upload(t *testing.T) {
 		name      string
 		args      args
 		mockCalls mocks.Calls
-		want      string
+		want      *dto.UploadOutput
 		wantErr   error
 	}{
 		{
@@ -44,12 +65,52 @@ func TestUseCase_Upload(t *testing.T) {
 					Method: "Upload",
 					Args: []interface{}{
 						helpers.DefaultCtx,
-						&dto.UploadInput{File: bytes.NewBufferString("test"), Directory: "test_dir", Filename: "test.jpg", ACL: "public-read"},
+						uploadInput(&dto.UploadInput{Directory: "test_dir", Filename: "test.jpg", ACL: "public-read", ContentType: "image/jpeg"}),
 					},
-					ReturnArgs: []interface{}{"https://aws.s3/test.bucket/test.jpg", nil},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg", VersionId: "v1"}, nil},
 				},
 			},
-			want: "https://aws.s3/test.bucket/test.jpg",
+			want: &dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg", VersionId: "v1"},
+		},
+		{
+			name: "explicit content type",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:        bytes.NewBufferString("test"),
+					Filename:    "test.jpg",
+					ContentType: "text/plain",
+				},
+			},
+			mockCalls: mocks.Calls{
+				{
+					Method: "Upload",
+					Args: []interface{}{
+						helpers.DefaultCtx,
+						uploadInput(&dto.UploadInput{Filename: "test.jpg", ACL: dto.DefaultACL, ContentType: "text/plain"}),
+					},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg"}, nil},
+				},
+			},
+			want: &dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg"},
+		},
+		{
+			name: "private",
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: "test.jpg", ACL: "private"},
+			},
+			mockCalls: mocks.Calls{
+				{
+					Method: "Upload",
+					Args: []interface{}{
+						helpers.DefaultCtx,
+						uploadInput(&dto.UploadInput{Filename: "test.jpg", ACL: "private", ContentType: "image/jpeg"}),
+					},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg"}, nil},
+				},
+			},
+			want: &dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg"},
 		},
 		{
 			name: "invalid input",
@@ -74,13 +135,47 @@ func TestUseCase_Upload(t *testing.T) {
 					Method: "Upload",
 					Args: []interface{}{
 						helpers.DefaultCtx,
-						&dto.UploadInput{File: bytes.NewBufferString("test"), Directory: "test_dir", Filename: "test.jpg", ACL: "public-read"},
+						uploadInput(&dto.UploadInput{Directory: "test_dir", Filename: "test.jpg", ACL: "public-read", ContentType: "image/jpeg"}),
 					},
-					ReturnArgs: []interface{}{"", errors.New("test error")},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
 				},
 			},
 			wantErr: errors.New("test error"),
 		},
+		{
+			name: "checksum mismatch",
+			args: args{
+				ctx: helpers.DefaultCtx,
+				input: &dto.UploadInput{
+					File:     bytes.NewBufferString("test"),
+					Filename: "test.jpg",
+					ACL:      "public-read",
+					MD5:      "098f6bcd4621d373cade4e832627b4f6",
+				},
+			},
+			mockCalls: mocks.Calls{
+				{
+					// Mock doesn't read the file, so the checksum of empty file is computed
+					Method: "Upload",
+					Args: []interface{}{
+						helpers.DefaultCtx,
+						uploadInput(&dto.UploadInput{
+							Filename:    "test.jpg",
+							ACL:         "public-read",
+							ContentType: "image/jpeg",
+							MD5:         "098f6bcd4621d373cade4e832627b4f6",
+						}),
+					},
+					ReturnArgs: []interface{}{&dto.UploadOutput{Url: "https://aws.s3/test.bucket/test.jpg"}, nil},
+				},
+				{
+					Method:     "Delete",
+					Args:       []interface{}{helpers.DefaultCtx, dto.DeleteInput("https://aws.s3/test.bucket/test.jpg")},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+			wantErr: customErrors.ChecksumMismatch,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -101,6 +196,447 @@ func TestUseCase_Upload(t *testing.T) {
 	}
 }
 
+func TestUseCase_Download(t *testing.T) {
+	type args struct {
+		ctx   context.Context
+		input *dto.DownloadInput
+	}
+	tests := []struct {
+		name      string
+		args      args
+		mockCalls mocks.Calls
+		want      *dto.DownloadOutput
+		wantErr   error
+	}{
+		{
+			name: "succeed",
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+			},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Download",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"}},
+					ReturnArgs: []interface{}{&dto.DownloadOutput{ContentType: "image/jpeg", Size: 4}, nil},
+				},
+			},
+			want: &dto.DownloadOutput{ContentType: "image/jpeg", Size: 4},
+		},
+		{
+			name: "invalid input",
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.DownloadInput{Url: "not url"},
+			},
+			wantErr: validation.Errors{},
+		},
+		{
+			name: "error from file repo",
+			args: args{
+				ctx:   helpers.DefaultCtx,
+				input: &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+			},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Download",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.DownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"}},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.Download(tt.args.ctx, tt.args.input)
+			if _, ok := tt.wantErr.(validation.Errors); ok {
+				assert.IsType(t, tt.wantErr, gotErr)
+			} else {
+				assert.EqualValues(t, tt.wantErr, gotErr)
+			}
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_Stat(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     *dto.StatInput
+		mockCalls mocks.Calls
+		want      *dto.FileInfo
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			input: &dto.StatInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Stat",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.StatInput{Url: "https://aws.s3/test.bucket/test.jpg"}},
+					ReturnArgs: []interface{}{&dto.FileInfo{Key: "test.jpg", Size: 4}, nil},
+				},
+			},
+			want: &dto.FileInfo{Key: "test.jpg", Size: 4},
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.StatInput{Url: "not url"},
+			wantErr: validation.Errors{},
+		},
+		{
+			name:  "error from file repo",
+			input: &dto.StatInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Stat",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.StatInput{Url: "https://aws.s3/test.bucket/test.jpg"}},
+					ReturnArgs: []interface{}{nil, customErrors.NotFound},
+				},
+			},
+			wantErr: customErrors.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.Stat(helpers.DefaultCtx, tt.input)
+			if _, ok := tt.wantErr.(validation.Errors); ok {
+				assert.IsType(t, tt.wantErr, gotErr)
+			} else {
+				assert.EqualValues(t, tt.wantErr, gotErr)
+			}
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_List(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     *dto.ListInput
+		mockCalls mocks.Calls
+		want      *dto.ListOutput
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			input: &dto.ListInput{Prefix: "test/", Delimiter: "/"},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "List",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.ListInput{Prefix: "test/", Delimiter: "/"}},
+					ReturnArgs: []interface{}{&dto.ListOutput{Prefixes: []string{"test/sub/"}}, nil},
+				},
+			},
+			want: &dto.ListOutput{Prefixes: []string{"test/sub/"}},
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.ListInput{PageSize: -1},
+			wantErr: validation.Errors{},
+		},
+		{
+			name:  "error from file repo",
+			input: &dto.ListInput{},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "List",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.ListInput{}},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.List(helpers.DefaultCtx, tt.input)
+			if _, ok := tt.wantErr.(validation.Errors); ok {
+				assert.IsType(t, tt.wantErr, gotErr)
+			} else {
+				assert.EqualValues(t, tt.wantErr, gotErr)
+			}
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_PresignDownload(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     *dto.PresignDownloadInput
+		mockCalls mocks.Calls
+		want      *dto.PresignOutput
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			input: &dto.PresignDownloadInput{Url: "https://aws.s3/test.bucket/test.jpg", Expires: time.Minute},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "PresignDownload",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.PresignDownloadInput{Url: "https://aws.s3/test.bucket/test.jpg", Expires: time.Minute}},
+					ReturnArgs: []interface{}{&dto.PresignOutput{Url: "https://aws.s3/test.bucket/test.jpg?signature"}, nil},
+				},
+			},
+			want: &dto.PresignOutput{Url: "https://aws.s3/test.bucket/test.jpg?signature"},
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.PresignDownloadInput{Url: "not url"},
+			wantErr: validation.Errors{},
+		},
+		{
+			name:  "error from file repo",
+			input: &dto.PresignDownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "PresignDownload",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.PresignDownloadInput{Url: "https://aws.s3/test.bucket/test.jpg"}},
+					ReturnArgs: []interface{}{nil, customErrors.NotSupported},
+				},
+			},
+			wantErr: customErrors.NotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.PresignDownload(helpers.DefaultCtx, tt.input)
+			if _, ok := tt.wantErr.(validation.Errors); ok {
+				assert.IsType(t, tt.wantErr, gotErr)
+			} else {
+				assert.EqualValues(t, tt.wantErr, gotErr)
+			}
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_PresignUpload(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     *dto.PresignUploadInput
+		mockCalls mocks.Calls
+		want      *dto.PresignOutput
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			input: &dto.PresignUploadInput{Directory: "test", Filename: "test.jpg", ContentType: "image/jpeg"},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "PresignUpload",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.PresignUploadInput{Directory: "test", Filename: "test.jpg", ContentType: "image/jpeg"}},
+					ReturnArgs: []interface{}{&dto.PresignOutput{Url: "https://aws.s3/test.bucket/test/test.jpg?signature"}, nil},
+				},
+			},
+			want: &dto.PresignOutput{Url: "https://aws.s3/test.bucket/test/test.jpg?signature"},
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.PresignUploadInput{Filename: "test.jpg", ACL: "test"},
+			wantErr: validation.Errors{},
+		},
+		{
+			name:  "error from file repo",
+			input: &dto.PresignUploadInput{Filename: "test.jpg"},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "PresignUpload",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.PresignUploadInput{Filename: "test.jpg"}},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.PresignUpload(helpers.DefaultCtx, tt.input)
+			if _, ok := tt.wantErr.(validation.Errors); ok {
+				assert.IsType(t, tt.wantErr, gotErr)
+			} else {
+				assert.EqualValues(t, tt.wantErr, gotErr)
+			}
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_Copy(t *testing.T) {
+	input := &dto.CopyInput{SourceUrl: "https://aws.s3/test.bucket/tmp/test.jpg", Directory: "test", Filename: "test.jpg"}
+	tests := []struct {
+		name      string
+		input     *dto.CopyInput
+		mockCalls mocks.Calls
+		want      string
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			input: input,
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Copy",
+					Args:       []interface{}{helpers.DefaultCtx, input},
+					ReturnArgs: []interface{}{"https://aws.s3/test.bucket/test/test.jpg", nil},
+				},
+			},
+			want: "https://aws.s3/test.bucket/test/test.jpg",
+		},
+		{
+			name:  "default ACL",
+			input: &dto.CopyInput{SourceUrl: "https://aws.s3/test.bucket/tmp/test.jpg", Filename: "test.jpg"},
+			mockCalls: mocks.Calls{
+				{
+					Method: "Copy",
+					Args: []interface{}{helpers.DefaultCtx, mock.MatchedBy(func(input *dto.CopyInput) bool {
+						return input.ACL == dto.DefaultACL
+					})},
+					ReturnArgs: []interface{}{"https://aws.s3/test.bucket/test.jpg", nil},
+				},
+			},
+			want: "https://aws.s3/test.bucket/test.jpg",
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.CopyInput{SourceUrl: "not url", Filename: "test.jpg"},
+			wantErr: validation.Errors{},
+		},
+		{
+			name:  "error from file repo",
+			input: input,
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Copy",
+					Args:       []interface{}{helpers.DefaultCtx, input},
+					ReturnArgs: []interface{}{"", customErrors.NotFound},
+				},
+			},
+			wantErr: customErrors.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.Copy(helpers.DefaultCtx, tt.input)
+			if _, ok := tt.wantErr.(validation.Errors); ok {
+				assert.IsType(t, tt.wantErr, gotErr)
+			} else {
+				assert.EqualValues(t, tt.wantErr, gotErr)
+			}
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_Move(t *testing.T) {
+	input := &dto.CopyInput{SourceUrl: "https://aws.s3/test.bucket/tmp/test.jpg", Directory: "test", Filename: "test.jpg"}
+	tests := []struct {
+		name      string
+		input     *dto.CopyInput
+		mockCalls mocks.Calls
+		want      string
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			input: input,
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Copy",
+					Args:       []interface{}{helpers.DefaultCtx, input},
+					ReturnArgs: []interface{}{"https://aws.s3/test.bucket/test/test.jpg", nil},
+				},
+				{
+					Method:     "Delete",
+					Args:       []interface{}{helpers.DefaultCtx, dto.DeleteInput("https://aws.s3/test.bucket/tmp/test.jpg")},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+			want: "https://aws.s3/test.bucket/test/test.jpg",
+		},
+		{
+			name:  "copy error",
+			input: input,
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Copy",
+					Args:       []interface{}{helpers.DefaultCtx, input},
+					ReturnArgs: []interface{}{"", customErrors.SameFile},
+				},
+			},
+			wantErr: customErrors.SameFile,
+		},
+		{
+			name:  "delete error",
+			input: input,
+			mockCalls: mocks.Calls{
+				{
+					Method:     "Copy",
+					Args:       []interface{}{helpers.DefaultCtx, input},
+					ReturnArgs: []interface{}{"https://aws.s3/test.bucket/test/test.jpg", nil},
+				},
+				{
+					Method:     "Delete",
+					Args:       []interface{}{helpers.DefaultCtx, dto.DeleteInput("https://aws.s3/test.bucket/tmp/test.jpg")},
+					ReturnArgs: []interface{}{errors.New("test error")},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.Move(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, gotErr)
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
 func TestUseCase_Delete(t *testing.T) {
 	type args struct {
 		ctx   context.Context
@@ -237,3 +773,229 @@ func TestUseCase_BatchDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestUseCase_DeleteFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     *dto.DeleteFilesInput
+		options   []fileUseCase.Option
+		mockCalls mocks.Calls
+		wantErr   error
+	}{
+		{
+			name: "succeed",
+			input: &dto.DeleteFilesInput{
+				Urls: dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg"},
+				Keys: []string{"test2.jpg"},
+			},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "URL",
+					Args:       []interface{}{"test2.jpg"},
+					ReturnArgs: []interface{}{"https://aws.s3/test.bucket/test2.jpg", nil},
+				},
+				{
+					Method: "BatchDelete",
+					Args: []interface{}{
+						helpers.DefaultCtx,
+						dto.BatchDeleteInput{"https://aws.s3/test.bucket/test.jpg", "https://aws.s3/test.bucket/test2.jpg"},
+					},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.DeleteFilesInput{},
+			wantErr: validation.Errors{},
+		},
+		{
+			name:    "key in trash",
+			input:   &dto.DeleteFilesInput{Keys: []string{"_trash/test.jpg"}},
+			options: []fileUseCase.Option{fileUseCase.WithTrash("_trash", time.Hour)},
+			wantErr: validation.Errors{},
+		},
+		{
+			name:  "error from file repo",
+			input: &dto.DeleteFilesInput{Keys: []string{"test.jpg"}},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "URL",
+					Args:       []interface{}{"test.jpg"},
+					ReturnArgs: []interface{}{"", errors.New("test error")},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo, tt.options...)
+			gotErr := useCase.DeleteFiles(helpers.DefaultCtx, tt.input)
+			if _, ok := tt.wantErr.(validation.Errors); ok {
+				assert.IsType(t, tt.wantErr, gotErr)
+			} else {
+				assert.EqualValues(t, tt.wantErr, gotErr)
+			}
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_MemoryRepo(t *testing.T) {
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo)
+
+	var urls dto.BatchDeleteInput
+	for _, filename := range []string{"test.jpg", "test2.jpg", "test3.jpg"} {
+		uploaded, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      bytes.NewBufferString("test"),
+			Directory: "test_dir",
+			Filename:  filename,
+			ACL:       "public-read",
+		})
+		assert.NoError(t, err)
+		url := uploaded.Url
+		urls = append(urls, dto.DeleteInput(url))
+	}
+	assert.Len(t, repo.Objects(), 3)
+	obj, ok := repo.Object(urls[0].String())
+	assert.True(t, ok)
+	assert.EqualValues(t, "test_dir/test.jpg", obj.Key)
+	assert.EqualValues(t, "test", string(obj.Body))
+	assert.EqualValues(t, "image/jpeg", obj.ContentType)
+
+	output, err := useCase.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: urls[0].String()})
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(output.File)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test", string(content))
+	assert.EqualValues(t, "image/jpeg", output.ContentType)
+
+	assert.NoError(t, useCase.Delete(helpers.DefaultCtx, urls[0]))
+	_, ok = repo.Object(urls[0].String())
+	assert.False(t, ok)
+
+	assert.NoError(t, useCase.BatchDelete(helpers.DefaultCtx, urls[1:]))
+	assert.Empty(t, repo.Objects())
+
+	uploaded, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:     bytes.NewBufferString("test"),
+		Filename: "test.jpg",
+		SHA256:   "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	})
+	assert.NoError(t, err)
+	url := uploaded.Url
+	info, err := useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", info.Metadata[dto.SHA256MetadataKey])
+
+	_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:     bytes.NewBufferString("corrupted"),
+		Filename: "corrupted.jpg",
+		SHA256:   "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	})
+	assert.EqualValues(t, customErrors.ChecksumMismatch, err)
+	assert.Len(t, repo.Objects(), 1)
+}
+
+func TestUseCase_Policies(t *testing.T) {
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo, fileUseCase.WithPolicies(policy.Rules{
+		{
+			Prefix:       "avatars/",
+			MaxSize:      4,
+			ContentTypes: []string{"image/*"},
+			Extensions:   []string{".jpg"},
+		},
+		{
+			Prefix: "invoices/",
+			ACLs:   []string{"private", "bucket-owner-read"},
+		},
+	}))
+	upload := func(directory, filename, content string) (string, error) {
+		output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+			File:      bytes.NewBufferString(content),
+			Directory: directory,
+			Filename:  filename,
+		})
+		if err != nil {
+			return "", err
+		}
+		return output.Url, nil
+	}
+
+	avatarUrl, err := upload("avatars", "test.jpg", "test")
+	assert.NoError(t, err)
+
+	// Declared content type isn't trusted by restricted directory
+	_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:        bytes.NewBufferString("<p>"),
+		Directory:   "avatars",
+		Filename:    "page.jpg",
+		ContentType: "image/jpeg",
+	})
+	assert.EqualValues(t, validation.Errors{"ContentType": customErrors.ContentTypeNotAllowed}, err)
+
+	_, err = upload("avatars", "test.exe", "test")
+	assert.EqualValues(t, validation.Errors{
+		"Filename":    customErrors.ExtensionNotAllowed,
+		"ContentType": customErrors.ContentTypeNotAllowed,
+	}, err)
+
+	_, err = upload("avatars", "large.jpg", "too large")
+	assert.EqualValues(t, validation.Errors{"File": customErrors.TooLarge}, err)
+	assert.Len(t, repo.Objects(), 1)
+
+	docUrl, err := upload("docs", "test.txt", "not restricted")
+	assert.NoError(t, err)
+
+	_, err = useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{
+		SourceUrl: docUrl,
+		Directory: "avatars",
+		Filename:  "test.txt",
+		ACL:       "public-read",
+	})
+	assert.Error(t, err)
+	_, err = useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{
+		SourceUrl: avatarUrl,
+		Directory: "avatars",
+		Filename:  "copy.jpg",
+		ACL:       "public-read",
+	})
+	assert.NoError(t, err)
+
+	_, err = useCase.PresignUpload(helpers.DefaultCtx, &dto.PresignUploadInput{
+		Directory:   "avatars",
+		Filename:    "test.jpg",
+		ACL:         "public-read",
+		ContentType: "text/plain",
+	})
+	assert.EqualValues(t, validation.Errors{"ContentType": customErrors.ContentTypeNotAllowed}, err)
+	// Size is signed, so it's required to limit size of the file
+	_, err = useCase.PresignUpload(helpers.DefaultCtx, &dto.PresignUploadInput{Directory: "avatars", Filename: "test.jpg", ContentType: "image/jpeg"})
+	assert.EqualValues(t, validation.Errors{"Size": customErrors.SizeRequired}, err)
+	_, err = useCase.PresignUpload(helpers.DefaultCtx, &dto.PresignUploadInput{Directory: "avatars", Filename: "test.jpg", ContentType: "image/jpeg", Size: 5})
+	assert.EqualValues(t, validation.Errors{"File": customErrors.TooLarge}, err)
+
+	_, err = upload("invoices", "invoice.pdf", "test")
+	assert.NoError(t, err)
+	_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+		File:      bytes.NewBufferString("test"),
+		Directory: "invoices",
+		Filename:  "public.pdf",
+		ACL:       "public-read",
+	})
+	assert.EqualValues(t, validation.Errors{"ACL": customErrors.ACLNotAllowed}, err)
+	_, err = useCase.Copy(helpers.DefaultCtx, &dto.CopyInput{
+		SourceUrl: docUrl,
+		Directory: "invoices",
+		Filename:  "test.txt",
+		ACL:       "public-read",
+	})
+	assert.EqualValues(t, validation.Errors{"ACL": customErrors.ACLNotAllowed}, err)
+}
@@ -0,0 +1,140 @@
+package fileUseCase
+
+import (
+	"context"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+// Reconcile finds orphans, files leaked when a client didn't save url of the uploaded file
+// or a deletion was lost, and deletes them on request. Files uploaded by presigned urls aren't recorded
+// to the catalog, so they must be passed as live urls or kept out of the prefix.
+// Blobs of deduplicated files are live while files refer them
+func (u *useCase) Reconcile(ctx context.Context, input *dto.ReconcileInput) (*dto.ReconcileReport, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	live := input.LiveSet()
+	if live == nil && u.catalog == nil {
+		return nil, validation.Errors{"LiveUrls": customErrors.LiveUrlsRequired}
+	}
+	before := time.Now().Add(-input.GracePeriod)
+	report := &dto.ReconcileReport{Orphans: make([]string, 0)}
+	orphans := make([]*dto.FileInfo, 0)
+	pageToken := ""
+	for {
+		page, err := u.fileRepo.List(ctx, &dto.ListInput{Prefix: input.Prefix, PageToken: pageToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range page.Files {
+			report.Scanned++
+			if !file.LastModified.Before(before) {
+				continue
+			}
+			if isLive, err := u.isLive(ctx, file.Key, file.Url, live); err != nil {
+				return nil, err
+			} else if !isLive {
+				orphans = append(orphans, file)
+				report.Orphans = append(report.Orphans, file.Url)
+				report.OrphansSize += file.Size
+			}
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	if !input.Delete || len(report.Orphans) == 0 {
+		return report, nil
+	}
+	deleted, err := u.purge(ctx, orphans, live)
+	if err != nil {
+		return nil, err
+	}
+	report.Deleted = deleted
+	return report, nil
+}
+
+// isLive reports whether something refers the file, trash is cleaned by its own purge
+func (u *useCase) isLive(ctx context.Context, key, url string, live map[string]bool) (bool, error) {
+	if u.trash != nil && dto.IsTrashKey(u.trash.prefix, key) {
+		return true, nil
+	}
+	if u.dedup != nil && dto.IsBlobKey(u.dedup.prefix, key) {
+		if key == dto.DedupOwnerKey(u.dedup.prefix) {
+			return true, nil
+		}
+		// Staged uploads have no references too, so they are orphans after the grace period
+		refs, err := u.dedup.repo.Refs(ctx, url)
+		return refs > 0, err
+	}
+	if live != nil {
+		return live[url], nil
+	}
+	entry, err := u.catalog.Get(ctx, url)
+	return entry != nil && !entry.IsDeleted(), err
+}
+
+// purge deletes orphans which are still not referred and returns their number.
+// Files are deleted as ones deleted by clients, so they are moved to trash if it's enabled,
+// their deletion is recorded to the catalog and published. Blobs are purged by purgeBlobs
+func (u *useCase) purge(ctx context.Context, orphans []*dto.FileInfo, live map[string]bool) (int, error) {
+	files := make(dto.BatchDeleteInput, 0, len(orphans))
+	blobs := make([]*dto.FileInfo, 0)
+	for _, file := range orphans {
+		if u.dedup != nil && dto.IsBlobKey(u.dedup.prefix, file.Key) {
+			blobs = append(blobs, file)
+		} else if isLive, err := u.isLive(ctx, file.Key, file.Url, live); err != nil {
+			return 0, err
+		} else if !isLive {
+			files = append(files, dto.DeleteInput(file.Url))
+		}
+	}
+	if len(files) > 0 {
+		if err := u.moveToTrash(ctx, files); err != nil {
+			return 0, err
+		}
+		err := u.remove(ctx, files, func(rest dto.BatchDeleteInput) error {
+			return u.fileRepo.BatchDelete(ctx, rest)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	deleted, err := u.purgeBlobs(ctx, blobs)
+	if err != nil {
+		return 0, err
+	}
+	return len(files) + deleted, nil
+}
+
+// purgeBlobs deletes blobs which are still not referred and returns their number,
+// they are checked under the lock, so a blob can't be linked before it's deleted.
+// Blobs aren't moved to trash, because nothing refers them to be restored by
+func (u *useCase) purgeBlobs(ctx context.Context, blobs []*dto.FileInfo) (int, error) {
+	if len(blobs) == 0 {
+		return 0, nil
+	}
+	u.dedup.mu.Lock()
+	defer u.dedup.mu.Unlock()
+	urls := make(dto.BatchDeleteInput, 0, len(blobs))
+	for _, blob := range blobs {
+		if isLive, err := u.isLive(ctx, blob.Key, blob.Url, nil); err != nil {
+			return 0, err
+		} else if !isLive {
+			urls = append(urls, dto.DeleteInput(blob.Url))
+		}
+	}
+	if len(urls) == 0 {
+		return 0, nil
+	}
+	if err := u.fileRepo.BatchDelete(ctx, urls); err != nil {
+		return 0, err
+	}
+	return len(urls), nil
+}
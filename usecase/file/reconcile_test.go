@@ -0,0 +1,212 @@
+package fileUseCase_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+func testFileInfo(key string, size int64, age time.Duration) *dto.FileInfo {
+	return &dto.FileInfo{
+		Key:          key,
+		Url:          "https://aws.s3/test.bucket/" + key,
+		Size:         size,
+		LastModified: time.Now().Add(-age),
+	}
+}
+
+func TestUseCase_Reconcile(t *testing.T) {
+	recorded := testFileInfo("test/recorded.jpg", 1, 2*time.Hour)
+	leaked := testFileInfo("test/leaked.jpg", 2, 2*time.Hour)
+	deleted := testFileInfo("test/deleted.jpg", 4, 2*time.Hour)
+	fresh := testFileInfo("test/fresh.jpg", 8, time.Minute)
+	pages := mocks.Calls{
+		{
+			Method:     "List",
+			Args:       []interface{}{helpers.DefaultCtx, &dto.ListInput{Prefix: "test/"}},
+			ReturnArgs: []interface{}{&dto.ListOutput{Files: []*dto.FileInfo{recorded, leaked}, NextPageToken: "1"}, nil},
+		},
+		{
+			Method:     "List",
+			Args:       []interface{}{helpers.DefaultCtx, &dto.ListInput{Prefix: "test/", PageToken: "1"}},
+			ReturnArgs: []interface{}{&dto.ListOutput{Files: []*dto.FileInfo{deleted, fresh}}, nil},
+		},
+	}
+	entries := map[string]*dto.CatalogEntry{
+		recorded.Url: {Key: recorded.Key, Url: recorded.Url},
+		deleted.Url:  {Key: deleted.Key, Url: deleted.Url, DeletedAt: time.Now()},
+	}
+	tests := []struct {
+		name        string
+		catalog     bool
+		input       *dto.ReconcileInput
+		deleteCalls mocks.Calls
+		// markedDeleted are urls recorded to the catalog as deleted
+		markedDeleted dto.BatchDeleteInput
+		want          *dto.ReconcileReport
+		wantErr       error
+	}{
+		{
+			name:    "orphans deleted",
+			catalog: true,
+			input:   &dto.ReconcileInput{Prefix: "test/", GracePeriod: time.Hour, Delete: true},
+			deleteCalls: mocks.Calls{
+				{
+					Method:     "BatchDelete",
+					Args:       []interface{}{helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(leaked.Url), dto.DeleteInput(deleted.Url)}},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+			markedDeleted: dto.BatchDeleteInput{dto.DeleteInput(leaked.Url), dto.DeleteInput(deleted.Url)},
+			want:          &dto.ReconcileReport{Scanned: 4, Orphans: []string{leaked.Url, deleted.Url}, OrphansSize: 6, Deleted: 2},
+		},
+		{
+			name:    "only reported",
+			catalog: true,
+			input:   &dto.ReconcileInput{Prefix: "test/", GracePeriod: time.Hour},
+			want:    &dto.ReconcileReport{Scanned: 4, Orphans: []string{leaked.Url, deleted.Url}, OrphansSize: 6},
+		},
+		{
+			name:  "live urls",
+			input: &dto.ReconcileInput{Prefix: "test/", LiveUrls: []string{recorded.Url, deleted.Url}, GracePeriod: time.Hour, Delete: true},
+			deleteCalls: mocks.Calls{
+				{
+					Method:     "BatchDelete",
+					Args:       []interface{}{helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(leaked.Url)}},
+					ReturnArgs: []interface{}{nil},
+				},
+			},
+			want: &dto.ReconcileReport{Scanned: 4, Orphans: []string{leaked.Url}, OrphansSize: 2, Deleted: 1},
+		},
+		{
+			name:    "without catalog and live urls",
+			input:   &dto.ReconcileInput{Prefix: "test/", GracePeriod: time.Hour},
+			wantErr: validation.Errors{"LiveUrls": customErrors.LiveUrlsRequired},
+		},
+		{
+			name:    "invalid input",
+			catalog: true,
+			input:   &dto.ReconcileInput{Prefix: "test/"},
+			wantErr: validation.Errors{"GracePeriod": validation.ErrRequired},
+		},
+		{
+			name:    "delete without prefix",
+			catalog: true,
+			input:   &dto.ReconcileInput{GracePeriod: time.Hour, Delete: true},
+			wantErr: validation.Errors{"Prefix": validation.ErrRequired.SetMessage("is required to delete orphans")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			if tt.wantErr == nil {
+				for _, call := range append(pages, tt.deleteCalls...) {
+					fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+				}
+			}
+			var options []fileUseCase.Option
+			catalog := new(mocks.CatalogRepo)
+			if tt.catalog {
+				for _, file := range []*dto.FileInfo{recorded, leaked, deleted} {
+					catalog.On("Get", helpers.DefaultCtx, file.Url).Return(entries[file.Url], nil)
+				}
+				if tt.markedDeleted != nil {
+					catalog.On("MarkDeleted", helpers.DefaultCtx, tt.markedDeleted, mock.Anything).Return(nil)
+				}
+				options = append(options, fileUseCase.WithCatalog(catalog))
+			}
+			useCase := fileUseCase.New(fileRepo, options...)
+			got, err := useCase.Reconcile(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+			if tt.markedDeleted != nil {
+				catalog.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestUseCase_Reconcile_Deduplicated(t *testing.T) {
+	shared := testFileInfo("_blobs/shared", 1, 2*time.Hour)
+	released := testFileInfo("_blobs/released", 2, 2*time.Hour)
+	staged := testFileInfo("_blobs/staging/1", 4, 2*time.Hour)
+	owner := testFileInfo("_blobs/.owner", 8, 2*time.Hour)
+	fileRepo := new(mocks.FileRepo)
+	fileRepo.On("List", helpers.DefaultCtx, &dto.ListInput{Prefix: "_blobs/"}).
+		Return(&dto.ListOutput{Files: []*dto.FileInfo{shared, released, staged, owner}}, nil)
+	fileRepo.On("BatchDelete", helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(released.Url), dto.DeleteInput(staged.Url)}).
+		Return(nil)
+	dedup := new(mocks.DedupRepo)
+	dedup.On("Refs", helpers.DefaultCtx, shared.Url).Return(2, nil)
+	dedup.On("Refs", helpers.DefaultCtx, released.Url).Return(0, nil)
+	dedup.On("Refs", helpers.DefaultCtx, staged.Url).Return(0, nil)
+
+	useCase := fileUseCase.New(fileRepo, fileUseCase.WithCatalog(new(mocks.CatalogRepo)), fileUseCase.WithDeduplication(dedup, "_blobs"))
+	got, err := useCase.Reconcile(helpers.DefaultCtx, &dto.ReconcileInput{Prefix: "_blobs/", GracePeriod: time.Hour, Delete: true})
+	assert.NoError(t, err)
+	assert.EqualValues(t, &dto.ReconcileReport{Scanned: 4, Orphans: []string{released.Url, staged.Url}, OrphansSize: 6, Deleted: 2}, got)
+	fileRepo.AssertExpectations(t)
+	dedup.AssertExpectations(t)
+}
+
+func TestUseCase_Reconcile_WrittenMeanwhile(t *testing.T) {
+	leaked := testFileInfo("test/leaked.jpg", 2, 2*time.Hour)
+	fileRepo := new(mocks.FileRepo)
+	fileRepo.On("List", helpers.DefaultCtx, &dto.ListInput{Prefix: "test/"}).Return(&dto.ListOutput{Files: []*dto.FileInfo{leaked}}, nil)
+	catalog := new(mocks.CatalogRepo)
+	// The file is recorded after it was found as orphan
+	catalog.On("Get", helpers.DefaultCtx, leaked.Url).Return(nil, nil).Once()
+	catalog.On("Get", helpers.DefaultCtx, leaked.Url).Return(&dto.CatalogEntry{Key: leaked.Key, Url: leaked.Url}, nil).Once()
+
+	useCase := fileUseCase.New(fileRepo, fileUseCase.WithCatalog(catalog))
+	got, err := useCase.Reconcile(helpers.DefaultCtx, &dto.ReconcileInput{Prefix: "test/", GracePeriod: time.Hour, Delete: true})
+	assert.NoError(t, err)
+	assert.EqualValues(t, &dto.ReconcileReport{Scanned: 1, Orphans: []string{leaked.Url}, OrphansSize: 2}, got)
+	fileRepo.AssertExpectations(t)
+	catalog.AssertExpectations(t)
+}
+
+func TestUseCase_Reconcile_Trash(t *testing.T) {
+	trashed := testFileInfo("_trash/test.jpg/00000000000000010000abcd", 2, 2*time.Hour)
+	fileRepo := new(mocks.FileRepo)
+	fileRepo.On("List", helpers.DefaultCtx, &dto.ListInput{}).Return(&dto.ListOutput{Files: []*dto.FileInfo{trashed}}, nil)
+
+	useCase := fileUseCase.New(fileRepo, fileUseCase.WithCatalog(new(mocks.CatalogRepo)), fileUseCase.WithTrash("_trash", time.Hour))
+	got, err := useCase.Reconcile(helpers.DefaultCtx, &dto.ReconcileInput{GracePeriod: time.Hour})
+	assert.NoError(t, err)
+	assert.EqualValues(t, &dto.ReconcileReport{Scanned: 1, Orphans: []string{}}, got)
+	fileRepo.AssertExpectations(t)
+}
+
+func TestUseCase_Reconcile_MovedToTrash(t *testing.T) {
+	leaked := testFileInfo("test/leaked.jpg", 2, 2*time.Hour)
+	fileRepo := new(mocks.FileRepo)
+	fileRepo.On("List", helpers.DefaultCtx, &dto.ListInput{Prefix: "test/"}).Return(&dto.ListOutput{Files: []*dto.FileInfo{leaked}}, nil)
+	fileRepo.On("Stat", helpers.DefaultCtx, &dto.StatInput{Url: leaked.Url}).Return(leaked, nil)
+	fileRepo.On("ACL", helpers.DefaultCtx, leaked.Url).Return("public-read", nil)
+	fileRepo.On("Copy", helpers.DefaultCtx, mock.MatchedBy(func(input *dto.CopyInput) bool {
+		return input.SourceUrl == leaked.Url && strings.HasPrefix(input.Directory, "_trash/test/leaked.jpg/")
+	})).Return("", nil)
+	fileRepo.On("BatchDelete", helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(leaked.Url)}).Return(nil)
+	catalog := new(mocks.CatalogRepo)
+	catalog.On("Get", helpers.DefaultCtx, leaked.Url).Return(nil, nil)
+	catalog.On("MarkDeleted", helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(leaked.Url)}, mock.Anything).Return(nil)
+
+	useCase := fileUseCase.New(fileRepo, fileUseCase.WithCatalog(catalog), fileUseCase.WithTrash("_trash", time.Hour))
+	got, err := useCase.Reconcile(helpers.DefaultCtx, &dto.ReconcileInput{Prefix: "test/", GracePeriod: time.Hour, Delete: true})
+	assert.NoError(t, err)
+	assert.EqualValues(t, &dto.ReconcileReport{Scanned: 1, Orphans: []string{leaked.Url}, OrphansSize: 2, Deleted: 1}, got)
+	fileRepo.AssertExpectations(t)
+	catalog.AssertExpectations(t)
+}
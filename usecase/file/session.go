@@ -0,0 +1,200 @@
+package fileUseCase
+
+import (
+	"context"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+// WithSessions tracks upload sessions initiated by the service, so AbortStaleUploads aborts only them
+// and multipart uploads of other clients of the bucket are kept. Nothing is aborted without it
+func WithSessions(repo SessionRepo) Option {
+	return func(u *useCase) {
+		u.sessions = repo
+	}
+}
+
+func (u *useCase) InitiateUpload(ctx context.Context, input *dto.InitiateUploadInput) (*dto.UploadSession, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	} else if err := u.checkKey(input.Key()); err != nil {
+		return nil, err
+	}
+	// Content isn't available yet, so only the extension is used
+	if input.ContentType == "" {
+		input.ContentType = dto.ContentTypeByExtension(input.Filename)
+	}
+	rule := u.policies.Match(input.Key())
+	acl, err := applyACL(rule, input.ACL)
+	if err != nil {
+		return nil, err
+	} else if acl == "" {
+		acl = dto.DefaultACL
+	}
+	input.ACL = acl
+	if rule != nil {
+		if err := rule.Check(input.Filename, input.ContentType); err != nil {
+			return nil, err
+		}
+	}
+	session, err := u.fileRepo.InitiateUpload(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if u.sessions != nil {
+		if err := u.sessions.Save(ctx, session.UploadId, time.Now()); err != nil {
+			// Untracked session would never be swept
+			u.abortUntracked(ctx, session.UploadId)
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// UploadPart stores the part unless the file gets larger than size policy allows with it,
+// parts uploaded concurrently aren't counted, so the size is checked by CompleteUpload again
+func (u *useCase) UploadPart(ctx context.Context, input *dto.UploadPartInput) (*dto.UploadPart, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	key, _, err := dto.ParseUploadId(input.UploadId)
+	if err != nil {
+		return nil, err
+	}
+	rule := u.policies.Match(key)
+	if rule == nil || rule.MaxSize == 0 {
+		return u.fileRepo.UploadPart(ctx, input)
+	}
+	session, err := u.fileRepo.GetUpload(ctx, &dto.UploadSessionInput{UploadId: input.UploadId})
+	if err != nil {
+		return nil, err
+	}
+	received := session.Size()
+	for _, part := range session.Parts {
+		// Uploaded again part replaces the received one
+		if part.PartNumber == input.PartNumber {
+			received -= part.Size
+		}
+	}
+	limited, err := rule.LimitRest(input.File, received)
+	if err != nil {
+		return nil, err
+	}
+	input.File = limited
+	part, err := u.fileRepo.UploadPart(ctx, input)
+	if limited.Exceeded() {
+		// Storages may wrap reading error, so it is checked explicitly
+		return nil, validation.Errors{"File": customErrors.TooLarge}
+	} else if err != nil {
+		return nil, err
+	}
+	return part, nil
+}
+
+func (u *useCase) GetUpload(ctx context.Context, input *dto.UploadSessionInput) (*dto.UploadSession, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	return u.fileRepo.GetUpload(ctx, input)
+}
+
+// CompleteUpload joins all received parts into the file, size policy is checked
+// here because parts may be uploaded in any order. Files uploaded by sessions aren't deduplicated
+func (u *useCase) CompleteUpload(ctx context.Context, input *dto.UploadSessionInput) (string, error) {
+	session, err := u.GetUpload(ctx, input)
+	if err != nil {
+		return "", err
+	} else if len(session.Parts) == 0 {
+		return "", customErrors.EmptyUpload
+	}
+	if rule := u.policies.Match(session.Key); rule != nil {
+		if sizeErr := rule.CheckSize(session.Size()); sizeErr != nil {
+			if err := u.abortUpload(ctx, input.UploadId); err != nil {
+				return "", err
+			}
+			return "", sizeErr
+		}
+	}
+	event, err := u.prepareEvent(ctx, &dto.FileEvent{Type: dto.EventFileUploaded, Key: session.Key})
+	if err != nil {
+		return "", err
+	}
+	url, err := u.fileRepo.CompleteUpload(ctx, session)
+	if err != nil {
+		u.discardEvents(ctx, event)
+		return "", err
+	}
+	u.untrack(ctx, input.UploadId)
+	if err := u.forget(ctx, session.Key); err != nil {
+		return "", err
+	}
+	if u.catalog != nil || u.events != nil {
+		entry := dto.NewCatalogEntry(session.Key, url)
+		u.inspectWritten(ctx, entry)
+		u.confirmWritten(ctx, entry, event)
+	}
+	return url, nil
+}
+
+func (u *useCase) AbortUpload(ctx context.Context, input *dto.UploadSessionInput) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+	return u.abortUpload(ctx, input.UploadId)
+}
+
+// AbortStaleUploads aborts tracked sessions initiated before the time and returns their number,
+// sessions which were already completed or aborted aren't counted
+func (u *useCase) AbortStaleUploads(ctx context.Context, before time.Time) (int, error) {
+	if u.sessions == nil {
+		return 0, nil
+	}
+	stale, err := u.sessions.Stale(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	aborted := 0
+	for _, uploadId := range stale {
+		err := u.fileRepo.AbortUpload(ctx, &dto.UploadSessionInput{UploadId: uploadId})
+		if err != nil && !customErrors.Is(err, customErrors.UploadNotFound) {
+			return aborted, err
+		} else if err == nil {
+			log.Debug().Msgf("aborted stale upload %s", uploadId)
+			aborted++
+		}
+		if err := u.sessions.Delete(ctx, uploadId); err != nil {
+			return aborted, err
+		}
+	}
+	return aborted, nil
+}
+
+func (u *useCase) abortUpload(ctx context.Context, uploadId string) error {
+	if err := u.fileRepo.AbortUpload(ctx, &dto.UploadSessionInput{UploadId: uploadId}); err != nil {
+		return err
+	}
+	u.untrack(ctx, uploadId)
+	return nil
+}
+
+// untrack forgets completed or aborted session, failure only makes the sweeper abort it again
+func (u *useCase) untrack(ctx context.Context, uploadId string) {
+	if u.sessions == nil {
+		return
+	}
+	if err := u.sessions.Delete(ctx, uploadId); err != nil {
+		log.Error().Err(err).Msgf("failed to forget upload session %s", uploadId)
+	}
+}
+
+// abortUntracked aborts session which couldn't be tracked, so its parts aren't kept forever
+func (u *useCase) abortUntracked(ctx context.Context, uploadId string) {
+	if err := u.fileRepo.AbortUpload(ctx, &dto.UploadSessionInput{UploadId: uploadId}); err != nil {
+		log.Error().Err(err).Msgf("failed to abort untracked upload session %s", uploadId)
+	}
+}
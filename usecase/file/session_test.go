@@ -0,0 +1,420 @@
+package fileUseCase_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+var testUploadId = dto.NewUploadId("avatars/test.jpg", "multipart-id")
+
+func TestUseCase_InitiateUpload(t *testing.T) {
+	session := &dto.UploadSession{
+		UploadId: testUploadId,
+		Key:      "avatars/test.jpg",
+		Url:      "https://aws.s3/test.bucket/avatars/test.jpg",
+	}
+	tests := []struct {
+		name      string
+		policies  policy.Rules
+		input     *dto.InitiateUploadInput
+		mockCalls mocks.Calls
+		want      *dto.UploadSession
+		wantErr   error
+	}{
+		{
+			name:  "succeed",
+			input: &dto.InitiateUploadInput{Directory: "avatars", Filename: "test.jpg"},
+			mockCalls: mocks.Calls{
+				{
+					Method: "InitiateUpload",
+					Args: []interface{}{helpers.DefaultCtx, &dto.InitiateUploadInput{
+						Directory:   "avatars",
+						Filename:    "test.jpg",
+						ACL:         dto.DefaultACL,
+						ContentType: "image/jpeg",
+					}},
+					ReturnArgs: []interface{}{session, nil},
+				},
+			},
+			want: session,
+		},
+		{
+			name:     "ACL of the policy",
+			policies: policy.Rules{{Prefix: "avatars/", ACL: "private"}},
+			input:    &dto.InitiateUploadInput{Directory: "avatars", Filename: "test.jpg", ContentType: "image/png"},
+			mockCalls: mocks.Calls{
+				{
+					Method: "InitiateUpload",
+					Args: []interface{}{helpers.DefaultCtx, &dto.InitiateUploadInput{
+						Directory:   "avatars",
+						Filename:    "test.jpg",
+						ACL:         "private",
+						ContentType: "image/png",
+					}},
+					ReturnArgs: []interface{}{session, nil},
+				},
+			},
+			want: session,
+		},
+		{
+			name:     "not allowed by policy",
+			policies: policy.Rules{{Prefix: "avatars/", Extensions: []string{".png"}}},
+			input:    &dto.InitiateUploadInput{Directory: "avatars", Filename: "test.jpg"},
+			wantErr:  validation.Errors{"Filename": customErrors.ExtensionNotAllowed},
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.InitiateUploadInput{Directory: "avatars"},
+			wantErr: validation.Errors{"Filename": validation.ErrRequired},
+		},
+		{
+			name:  "error from file repo",
+			input: &dto.InitiateUploadInput{Directory: "avatars", Filename: "test.jpg"},
+			mockCalls: mocks.Calls{
+				{
+					Method: "InitiateUpload",
+					Args: []interface{}{helpers.DefaultCtx, &dto.InitiateUploadInput{
+						Directory:   "avatars",
+						Filename:    "test.jpg",
+						ACL:         dto.DefaultACL,
+						ContentType: "image/jpeg",
+					}},
+					ReturnArgs: []interface{}{nil, errors.New("test error")},
+				},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo, fileUseCase.WithPolicies(tt.policies))
+			got, gotErr := useCase.InitiateUpload(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, gotErr)
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_UploadPart(t *testing.T) {
+	part := &dto.UploadPart{PartNumber: 1, Size: 4, ETag: `"1"`}
+	tests := []struct {
+		name      string
+		input     *dto.UploadPartInput
+		mockCalls mocks.Calls
+		want      *dto.UploadPart
+		wantErr   bool
+	}{
+		{
+			name:  "succeed",
+			input: &dto.UploadPartInput{UploadId: testUploadId, PartNumber: 1, File: strings.NewReader("test")},
+			mockCalls: mocks.Calls{
+				{
+					Method:     "UploadPart",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.UploadPartInput{UploadId: testUploadId, PartNumber: 1, File: strings.NewReader("test")}},
+					ReturnArgs: []interface{}{part, nil},
+				},
+			},
+			want: part,
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.UploadPartInput{UploadId: testUploadId, File: strings.NewReader("test")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo)
+			got, gotErr := useCase.UploadPart(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, gotErr != nil, gotErr)
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_UploadPart_Policy(t *testing.T) {
+	uploadId := dto.NewUploadId("videos/test.mp4", "multipart-id")
+	tests := []struct {
+		name       string
+		partNumber int64
+		content    string
+		received   []*dto.UploadPart
+		wantErr    error
+	}{
+		{
+			name:       "succeed",
+			partNumber: 2,
+			content:    "123",
+			received:   []*dto.UploadPart{{PartNumber: 1, Size: 5}},
+		},
+		{
+			name:       "uploaded again part replaces received one",
+			partNumber: 1,
+			content:    "12345678",
+			received:   []*dto.UploadPart{{PartNumber: 1, Size: 5}},
+		},
+		{
+			name:       "part exceeds rest size",
+			partNumber: 2,
+			content:    "1234",
+			received:   []*dto.UploadPart{{PartNumber: 1, Size: 5}},
+			wantErr:    validation.Errors{"File": customErrors.TooLarge},
+		},
+		{
+			name:       "nothing is left",
+			partNumber: 3,
+			content:    "1",
+			received:   []*dto.UploadPart{{PartNumber: 1, Size: 5}, {PartNumber: 2, Size: 3}},
+			wantErr:    validation.Errors{"File": customErrors.TooLarge},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &dto.UploadPartInput{UploadId: uploadId, PartNumber: tt.partNumber, File: strings.NewReader(tt.content)}
+			fileRepo := new(mocks.FileRepo)
+			fileRepo.On("GetUpload", helpers.DefaultCtx, &dto.UploadSessionInput{UploadId: uploadId}).
+				Return(&dto.UploadSession{UploadId: uploadId, Key: "videos/test.mp4", Parts: tt.received}, nil)
+			fileRepo.On("UploadPart", helpers.DefaultCtx, input).
+				Run(func(args mock.Arguments) {
+					// Storage reads the whole part before it's stored
+					_, _ = ioutil.ReadAll(args.Get(1).(*dto.UploadPartInput).File)
+				}).
+				Return(&dto.UploadPart{PartNumber: tt.partNumber}, nil).Maybe()
+			useCase := fileUseCase.New(fileRepo, fileUseCase.WithPolicies(policy.Rules{{Prefix: "videos/", MaxSize: 8}}))
+
+			got, err := useCase.UploadPart(helpers.DefaultCtx, input)
+			assert.EqualValues(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.EqualValues(t, &dto.UploadPart{PartNumber: tt.partNumber}, got)
+			}
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_CompleteUpload(t *testing.T) {
+	input := &dto.UploadSessionInput{UploadId: testUploadId}
+	newSession := func(parts ...*dto.UploadPart) *dto.UploadSession {
+		return &dto.UploadSession{
+			UploadId: testUploadId,
+			Key:      "avatars/test.jpg",
+			Url:      "https://aws.s3/test.bucket/avatars/test.jpg",
+			Parts:    parts,
+		}
+	}
+	session := newSession(&dto.UploadPart{PartNumber: 1, Size: dto.MinPartSize}, &dto.UploadPart{PartNumber: 2, Size: 4})
+	tests := []struct {
+		name      string
+		policies  policy.Rules
+		input     *dto.UploadSessionInput
+		mockCalls mocks.Calls
+		want      string
+		wantErr   error
+	}{
+		{
+			name:     "succeed",
+			policies: policy.Rules{{Prefix: "avatars/", MaxSize: dto.MinPartSize + 4}},
+			input:    input,
+			mockCalls: mocks.Calls{
+				{Method: "GetUpload", Args: []interface{}{helpers.DefaultCtx, input}, ReturnArgs: []interface{}{session, nil}},
+				{
+					Method:     "CompleteUpload",
+					Args:       []interface{}{helpers.DefaultCtx, session},
+					ReturnArgs: []interface{}{"https://aws.s3/test.bucket/avatars/test.jpg", nil},
+				},
+			},
+			want: "https://aws.s3/test.bucket/avatars/test.jpg",
+		},
+		{
+			name:     "too large",
+			policies: policy.Rules{{Prefix: "avatars/", MaxSize: dto.MinPartSize}},
+			input:    input,
+			mockCalls: mocks.Calls{
+				{Method: "GetUpload", Args: []interface{}{helpers.DefaultCtx, input}, ReturnArgs: []interface{}{session, nil}},
+				{Method: "AbortUpload", Args: []interface{}{helpers.DefaultCtx, input}, ReturnArgs: []interface{}{nil}},
+			},
+			wantErr: validation.Errors{"File": customErrors.TooLarge},
+		},
+		{
+			name:  "no parts",
+			input: input,
+			mockCalls: mocks.Calls{
+				{Method: "GetUpload", Args: []interface{}{helpers.DefaultCtx, input}, ReturnArgs: []interface{}{newSession(), nil}},
+			},
+			wantErr: customErrors.EmptyUpload,
+		},
+		{
+			name:  "missing upload",
+			input: input,
+			mockCalls: mocks.Calls{
+				{Method: "GetUpload", Args: []interface{}{helpers.DefaultCtx, input}, ReturnArgs: []interface{}{nil, customErrors.UploadNotFound}},
+			},
+			wantErr: customErrors.UploadNotFound,
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.UploadSessionInput{UploadId: "test"},
+			wantErr: validation.Errors{"UploadId": customErrors.InvalidUploadId},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(fileRepo, fileUseCase.WithPolicies(tt.policies))
+			got, gotErr := useCase.CompleteUpload(helpers.DefaultCtx, tt.input)
+			assert.EqualValues(t, tt.wantErr, gotErr)
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_AbortUpload(t *testing.T) {
+	input := &dto.UploadSessionInput{UploadId: testUploadId}
+	fileRepo := new(mocks.FileRepo)
+	fileRepo.On("AbortUpload", helpers.DefaultCtx, input).Return(nil)
+	useCase := fileUseCase.New(fileRepo)
+	assert.NoError(t, useCase.AbortUpload(helpers.DefaultCtx, input))
+	assert.Error(t, useCase.AbortUpload(helpers.DefaultCtx, &dto.UploadSessionInput{}))
+	fileRepo.AssertExpectations(t)
+}
+
+func TestUseCase_InitiateUpload_Sessions(t *testing.T) {
+	session := &dto.UploadSession{UploadId: testUploadId, Key: "avatars/test.jpg"}
+	input := &dto.InitiateUploadInput{Directory: "avatars", Filename: "test.jpg"}
+	tests := []struct {
+		name    string
+		saveErr error
+		want    *dto.UploadSession
+		wantErr error
+	}{
+		{
+			name: "tracked",
+			want: session,
+		},
+		{
+			name:    "untracked session is aborted",
+			saveErr: errors.New("test error"),
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo, sessionRepo := new(mocks.FileRepo), new(mocks.SessionRepo)
+			fileRepo.On("InitiateUpload", helpers.DefaultCtx, mock.Anything).Return(session, nil)
+			sessionRepo.On("Save", helpers.DefaultCtx, testUploadId, mock.Anything).Return(tt.saveErr)
+			if tt.saveErr != nil {
+				fileRepo.On("AbortUpload", helpers.DefaultCtx, &dto.UploadSessionInput{UploadId: testUploadId}).Return(nil)
+			}
+			useCase := fileUseCase.New(fileRepo, fileUseCase.WithSessions(sessionRepo))
+			got, err := useCase.InitiateUpload(helpers.DefaultCtx, input)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+			sessionRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_AbortUpload_Sessions(t *testing.T) {
+	input := &dto.UploadSessionInput{UploadId: testUploadId}
+	fileRepo, sessionRepo := new(mocks.FileRepo), new(mocks.SessionRepo)
+	fileRepo.On("AbortUpload", helpers.DefaultCtx, input).Return(nil)
+	sessionRepo.On("Delete", helpers.DefaultCtx, testUploadId).Return(nil)
+	useCase := fileUseCase.New(fileRepo, fileUseCase.WithSessions(sessionRepo))
+	assert.NoError(t, useCase.AbortUpload(helpers.DefaultCtx, input))
+	fileRepo.AssertExpectations(t)
+	sessionRepo.AssertExpectations(t)
+}
+
+func TestUseCase_AbortStaleUploads(t *testing.T) {
+	before := time.Now().Add(-time.Hour)
+	stale := []string{dto.NewUploadId("a.jpg", "1"), dto.NewUploadId("b.jpg", "2"), dto.NewUploadId("c.jpg", "3")}
+	tests := []struct {
+		name      string
+		sessions  bool
+		fileCalls mocks.Calls
+		repoCalls mocks.Calls
+		want      int
+		wantErr   error
+	}{
+		{
+			name: "untracked sessions are kept",
+		},
+		{
+			name:     "tracked sessions are aborted",
+			sessions: true,
+			fileCalls: mocks.Calls{
+				{Method: "AbortUpload", Args: []interface{}{helpers.DefaultCtx, &dto.UploadSessionInput{UploadId: stale[0]}}, ReturnArgs: []interface{}{nil}},
+				// Completed or aborted after it was tracked
+				{Method: "AbortUpload", Args: []interface{}{helpers.DefaultCtx, &dto.UploadSessionInput{UploadId: stale[1]}}, ReturnArgs: []interface{}{customErrors.UploadNotFound}},
+				{Method: "AbortUpload", Args: []interface{}{helpers.DefaultCtx, &dto.UploadSessionInput{UploadId: stale[2]}}, ReturnArgs: []interface{}{nil}},
+			},
+			repoCalls: mocks.Calls{
+				{Method: "Stale", Args: []interface{}{helpers.DefaultCtx, before}, ReturnArgs: []interface{}{stale, nil}},
+				{Method: "Delete", Args: []interface{}{helpers.DefaultCtx, stale[0]}, ReturnArgs: []interface{}{nil}},
+				{Method: "Delete", Args: []interface{}{helpers.DefaultCtx, stale[1]}, ReturnArgs: []interface{}{nil}},
+				{Method: "Delete", Args: []interface{}{helpers.DefaultCtx, stale[2]}, ReturnArgs: []interface{}{nil}},
+			},
+			want: 2,
+		},
+		{
+			name:     "error from file repo",
+			sessions: true,
+			fileCalls: mocks.Calls{
+				{Method: "AbortUpload", Args: []interface{}{helpers.DefaultCtx, &dto.UploadSessionInput{UploadId: stale[0]}}, ReturnArgs: []interface{}{errors.New("test error")}},
+			},
+			repoCalls: mocks.Calls{
+				{Method: "Stale", Args: []interface{}{helpers.DefaultCtx, before}, ReturnArgs: []interface{}{stale, nil}},
+			},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo, sessionRepo := new(mocks.FileRepo), new(mocks.SessionRepo)
+			for _, call := range tt.fileCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			for _, call := range tt.repoCalls {
+				sessionRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			var opts []fileUseCase.Option
+			if tt.sessions {
+				opts = append(opts, fileUseCase.WithSessions(sessionRepo))
+			}
+			got, err := fileUseCase.New(fileRepo, opts...).AbortStaleUploads(helpers.DefaultCtx, before)
+			assert.EqualValues(t, tt.wantErr, err)
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+			sessionRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package fileUseCase
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+// trashACL is applied to files in trash, they are available only after they are restored
+const trashACL = "private"
+
+type (
+	trash struct {
+		prefix    string
+		retention time.Duration
+	}
+)
+
+// WithTrash makes deletion reversible, deleted files are moved to the prefix directory
+// and can be restored until the retention period expires
+func WithTrash(prefix string, retention time.Duration) Option {
+	return func(u *useCase) {
+		u.trash = &trash{prefix: prefix, retention: retention}
+	}
+}
+
+// moveToTrash copies files to trash before they are deleted, missing files and files
+// which are already in trash are skipped, so deleting a file in trash is permanent
+func (u *useCase) moveToTrash(ctx context.Context, urls dto.BatchDeleteInput) error {
+	if u.trash == nil {
+		return nil
+	}
+	deletedAt := time.Now()
+	for _, url := range urls {
+		sourceUrl := url.String()
+		ref, err := u.blobRef(ctx, sourceUrl)
+		if err != nil {
+			return err
+		} else if ref != nil {
+			sourceUrl = ref.BlobUrl
+		}
+		info, err := u.fileRepo.Stat(ctx, &dto.StatInput{Url: sourceUrl})
+		if customErrors.Is(err, customErrors.NotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		key, metadata := info.Key, info.Metadata
+		if ref != nil {
+			key, metadata = ref.Key, ref.Metadata
+		}
+		if dto.IsTrashKey(u.trash.prefix, key) {
+			continue
+		}
+		// Blobs are private, so ACL of deduplicated file is unknown and it's restored as private
+		var acl string
+		if ref == nil {
+			if acl, err = u.fileRepo.ACL(ctx, sourceUrl); err != nil {
+				// The file is still deleted, ACL only affects how it's restored
+				log.Error().Err(err).Msgf("failed to get ACL of deleted file %s", sourceUrl)
+			}
+		}
+		trashKey, err := dto.TrashKey(u.trash.prefix, key, deletedAt)
+		if err != nil {
+			return err
+		}
+		directory, filename := path.Split(trashKey)
+		copyInput := &dto.CopyInput{
+			SourceUrl: sourceUrl,
+			Directory: directory,
+			Filename:  filename,
+			ACL:       trashACL,
+			Metadata:  dto.TrashMetadata(metadata, acl),
+		}
+		if _, err := u.fileRepo.Copy(ctx, copyInput); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore copies the file from trash to its original key and removes it from trash,
+// the original key must be free, so a newer file isn't overwritten. The file gets ACL
+// it had before deletion or private ACL if it's unknown
+func (u *useCase) Restore(ctx context.Context, input *dto.RestoreInput) (string, error) {
+	if u.trash == nil {
+		return "", customErrors.NotSupported
+	} else if err := input.Validate(); err != nil {
+		return "", err
+	}
+	info, err := u.fileRepo.Stat(ctx, &dto.StatInput{Url: input.Url})
+	if err != nil {
+		return "", err
+	}
+	key, _, err := dto.ParseTrashKey(u.trash.prefix, info.Key)
+	if err != nil {
+		return "", err
+	}
+	url, err := u.fileRepo.URL(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := u.Stat(ctx, &dto.StatInput{Url: url}); err == nil {
+		return "", customErrors.AlreadyExists
+	} else if !customErrors.Is(err, customErrors.NotFound) {
+		return "", err
+	}
+	metadata, acl := dto.RestoredMetadata(info.Metadata)
+	directory, filename := path.Split(key)
+	copyInput := &dto.CopyInput{SourceUrl: input.Url, Directory: directory, Filename: filename, ACL: acl, Metadata: metadata}
+	restored, err := u.copy(ctx, copyInput, url)
+	if err != nil {
+		return "", err
+	} else if err := u.fileRepo.Delete(ctx, dto.DeleteInput(input.Url)); err != nil {
+		return "", err
+	}
+	return restored, nil
+}
+
+func (u *useCase) ListTrash(ctx context.Context, input *dto.ListTrashInput) (*dto.ListTrashOutput, error) {
+	if u.trash == nil {
+		return nil, customErrors.NotSupported
+	} else if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	page, err := u.fileRepo.List(ctx, input.ToListInput(u.trash.prefix))
+	if err != nil {
+		return nil, err
+	}
+	output := &dto.ListTrashOutput{Items: make([]*dto.TrashItem, 0, len(page.Files)), NextPageToken: page.NextPageToken}
+	for _, file := range page.Files {
+		if item := u.trashItem(file); item != nil {
+			output.Items = append(output.Items, item)
+		}
+	}
+	return output, nil
+}
+
+// PurgeTrash permanently deletes files which were deleted more than retention period ago
+// and returns their number
+func (u *useCase) PurgeTrash(ctx context.Context) (int, error) {
+	if u.trash == nil {
+		return 0, nil
+	}
+	now := time.Now()
+	expired := make(dto.BatchDeleteInput, 0)
+	listInput := &dto.ListTrashInput{}
+	for {
+		page, err := u.fileRepo.List(ctx, listInput.ToListInput(u.trash.prefix))
+		if err != nil {
+			return 0, err
+		}
+		for _, file := range page.Files {
+			if item := u.trashItem(file); item != nil && !item.ExpiresAt.After(now) {
+				expired = append(expired, dto.DeleteInput(item.Url))
+			}
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		listInput = &dto.ListTrashInput{PageToken: page.NextPageToken}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err := u.fileRepo.BatchDelete(ctx, expired); err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+// trashItem describes the file in trash, nil is returned for files put to trash directory by others
+func (u *useCase) trashItem(file *dto.FileInfo) *dto.TrashItem {
+	key, deletedAt, err := dto.ParseTrashKey(u.trash.prefix, file.Key)
+	if err != nil {
+		log.Warn().Msgf("unexpected file %s in trash", file.Url)
+		return nil
+	}
+	return &dto.TrashItem{
+		Url:       file.Url,
+		Key:       key,
+		Size:      file.Size,
+		DeletedAt: deletedAt,
+		ExpiresAt: deletedAt.Add(u.trash.retention),
+	}
+}
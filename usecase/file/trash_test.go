@@ -0,0 +1,182 @@
+package fileUseCase_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	catalogRepo "github.com/freemen-app/file_storage/adapter/repository/catalog"
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+func TestUseCase_Trash(t *testing.T) {
+	for _, deduplicated := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "deduplicated"}[deduplicated], func(t *testing.T) {
+			db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+			assert.NoError(t, err)
+			defer db.Close()
+			catalog := catalogRepo.New(db)
+			repo := fileRepo.NewMemory("http://localhost/files")
+			options := []fileUseCase.Option{fileUseCase.WithCatalog(catalog), fileUseCase.WithTrash("_trash", time.Hour)}
+			if deduplicated {
+				options = append(options, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+			}
+			useCase := fileUseCase.New(repo, options...)
+			upload := func(content string) string {
+				uploaded, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+					File:      bytes.NewBufferString(content),
+					Directory: "docs",
+					Filename:  "test.txt",
+					Owner:     "user",
+				})
+				assert.NoError(t, err)
+				return uploaded.Url
+			}
+			listTrash := func() []*dto.TrashItem {
+				output, err := useCase.ListTrash(helpers.DefaultCtx, &dto.ListTrashInput{Prefix: "docs/"})
+				assert.NoError(t, err)
+				return output.Items
+			}
+
+			url := upload("test")
+			assert.NoError(t, useCase.Delete(helpers.DefaultCtx, dto.DeleteInput(url)))
+			_, err = useCase.Stat(helpers.DefaultCtx, &dto.StatInput{Url: url})
+			assert.EqualValues(t, customErrors.NotFound, err)
+			items := listTrash()
+			assert.Len(t, items, 1)
+			assert.EqualValues(t, "docs/test.txt", items[0].Key)
+			assert.EqualValues(t, 4, items[0].Size)
+			assert.EqualValues(t, time.Hour, items[0].ExpiresAt.Sub(items[0].DeletedAt))
+			assert.WithinDuration(t, time.Now(), items[0].DeletedAt, time.Minute)
+
+			// Restored file doesn't overwrite a newer one
+			upload("newer")
+			_, err = useCase.Restore(helpers.DefaultCtx, &dto.RestoreInput{Url: items[0].Url})
+			assert.EqualValues(t, customErrors.AlreadyExists, err)
+			assert.NoError(t, useCase.BatchDelete(helpers.DefaultCtx, dto.BatchDeleteInput{dto.DeleteInput(url)}))
+			assert.Len(t, listTrash(), 2)
+
+			restored, err := useCase.Restore(helpers.DefaultCtx, &dto.RestoreInput{Url: items[0].Url})
+			assert.NoError(t, err)
+			assert.EqualValues(t, url, restored)
+			output, err := useCase.Download(helpers.DefaultCtx, &dto.DownloadInput{Url: restored})
+			assert.NoError(t, err)
+			content, _ := ioutil.ReadAll(output.File)
+			assert.EqualValues(t, "test", string(content))
+			entry, err := catalog.Get(helpers.DefaultCtx, restored)
+			assert.NoError(t, err)
+			assert.False(t, entry.IsDeleted())
+			assert.EqualValues(t, "user", entry.Owner)
+			items = listTrash()
+			assert.Len(t, items, 1)
+
+			// Deleting a file in trash is permanent
+			assert.NoError(t, useCase.Delete(helpers.DefaultCtx, dto.DeleteInput(items[0].Url)))
+			assert.Empty(t, listTrash())
+
+			// Moved file isn't put to trash
+			_, err = useCase.Move(helpers.DefaultCtx, &dto.CopyInput{SourceUrl: restored, Directory: "moved", Filename: "test.txt"})
+			assert.NoError(t, err)
+			assert.Empty(t, listTrash())
+
+			_, err = useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Directory: "_trash", Filename: "test.txt"})
+			assert.EqualValues(t, validation.Errors{"Directory": customErrors.ReservedKey}, err)
+		})
+	}
+}
+
+func TestUseCase_Restore_ACL(t *testing.T) {
+	for _, acl := range []string{"private", "public-read", "authenticated-read", ""} {
+		t.Run(acl, func(t *testing.T) {
+			repo := fileRepo.NewMemory("http://localhost/files")
+			useCase := fileUseCase.New(repo, fileUseCase.WithTrash("_trash", time.Hour))
+			uploaded, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{
+				File:     bytes.NewBufferString("test"),
+				Filename: "test.txt",
+				ACL:      acl,
+				SHA256:   "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			})
+			assert.NoError(t, err)
+			if acl == "" {
+				// ACL set outside the service can't be known
+				obj, _ := repo.Object(uploaded.Url)
+				assert.NoError(t, repo.Delete(helpers.DefaultCtx, dto.DeleteInput(uploaded.Url)))
+				_, err = repo.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewReader(obj.Body), Filename: "test.txt", SHA256: obj.Metadata[dto.SHA256MetadataKey]})
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, useCase.Delete(helpers.DefaultCtx, dto.DeleteInput(uploaded.Url)))
+			items, err := useCase.ListTrash(helpers.DefaultCtx, &dto.ListTrashInput{})
+			assert.NoError(t, err)
+			assert.Len(t, items.Items, 1)
+
+			restored, err := useCase.Restore(helpers.DefaultCtx, &dto.RestoreInput{Url: items.Items[0].Url})
+			assert.NoError(t, err)
+			obj, ok := repo.Object(restored)
+			assert.True(t, ok)
+			want := acl
+			if want == "" {
+				want = "private"
+			}
+			assert.EqualValues(t, want, obj.ACL)
+			assert.EqualValues(t, map[string]string{dto.SHA256MetadataKey: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}, obj.Metadata)
+		})
+	}
+}
+
+func TestUseCase_Restore_Errors(t *testing.T) {
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo, fileUseCase.WithTrash("_trash", time.Hour))
+	uploaded, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: "test.txt"})
+	assert.NoError(t, err)
+	url := uploaded.Url
+
+	_, err = useCase.Restore(helpers.DefaultCtx, &dto.RestoreInput{Url: url})
+	assert.EqualValues(t, customErrors.NotInTrash, err)
+	_, err = useCase.Restore(helpers.DefaultCtx, &dto.RestoreInput{Url: "http://localhost/files/_trash/missing.txt/00000000000000010000abcd"})
+	assert.EqualValues(t, customErrors.NotFound, err)
+	_, err = useCase.Restore(helpers.DefaultCtx, &dto.RestoreInput{})
+	assert.Error(t, err)
+
+	withoutTrash := fileUseCase.New(repo)
+	_, err = withoutTrash.Restore(helpers.DefaultCtx, &dto.RestoreInput{Url: url})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = withoutTrash.ListTrash(helpers.DefaultCtx, &dto.ListTrashInput{})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	purged, err := withoutTrash.PurgeTrash(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+}
+
+func TestUseCase_PurgeTrash(t *testing.T) {
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo, fileUseCase.WithTrash("_trash", time.Hour))
+	for _, filename := range []string{"1.txt", "2.txt"} {
+		uploaded, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: filename})
+		assert.NoError(t, err)
+		url := uploaded.Url
+		assert.NoError(t, useCase.Delete(helpers.DefaultCtx, dto.DeleteInput(url)))
+	}
+
+	purged, err := useCase.PurgeTrash(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+	assert.Len(t, repo.Objects(), 2)
+
+	// The same trash with shorter retention
+	expiring := fileUseCase.New(repo, fileUseCase.WithTrash("_trash", 0))
+	purged, err = expiring.PurgeTrash(helpers.DefaultCtx)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, purged)
+	assert.Empty(t, repo.Objects())
+}
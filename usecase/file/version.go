@@ -0,0 +1,79 @@
+package fileUseCase
+
+import (
+	"context"
+
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+)
+
+// fallbackACL is applied to restored version when ACL of the latest version is unknown
+const fallbackACL = "private"
+
+func (u *useCase) ListVersions(ctx context.Context, input *dto.ListVersionsInput) (*dto.ListVersionsOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	} else if err := u.checkVersioned(ctx, input.Url); err != nil {
+		return nil, err
+	}
+	return u.fileRepo.ListVersions(ctx, input)
+}
+
+func (u *useCase) GetVersion(ctx context.Context, input *dto.GetVersionInput) (*dto.DownloadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	} else if err := u.checkVersioned(ctx, input.Url); err != nil {
+		return nil, err
+	}
+	return u.fileRepo.GetVersion(ctx, input)
+}
+
+// RestoreVersion makes the version the latest one, ACL isn't kept in versions,
+// so the file keeps ACL of the latest version unless ACL is requested.
+// The file is private if the latest version is deleted or its ACL is unknown
+func (u *useCase) RestoreVersion(ctx context.Context, input *dto.RestoreVersionInput) (*dto.UploadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	} else if err := u.checkVersioned(ctx, input.Url); err != nil {
+		return nil, err
+	}
+	key, err := u.fileRepo.Key(input.Url)
+	if err != nil {
+		return nil, err
+	} else if err := u.checkKey(key); err != nil {
+		return nil, err
+	}
+	acl := input.ACL
+	if acl == "" {
+		if acl, err = u.fileRepo.ACL(ctx, input.Url); err != nil && !customErrors.Is(err, customErrors.NotFound) {
+			return nil, err
+		} else if acl == "" {
+			acl = fallbackACL
+		}
+	}
+	if acl, err = applyACL(u.policies.Match(key), acl); err != nil {
+		return nil, err
+	}
+	event, err := u.prepareEvent(ctx, &dto.FileEvent{Type: dto.EventFileUploaded, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	output, err := u.fileRepo.RestoreVersion(ctx, &dto.RestoreVersionInput{Url: input.Url, VersionId: input.VersionId, ACL: acl})
+	if err != nil {
+		u.discardEvents(ctx, event)
+		return nil, err
+	}
+	u.confirmWritten(ctx, u.versionEntry(ctx, key, output.Url), event)
+	return output, nil
+}
+
+// checkVersioned rejects deduplicated files, they are references to blobs shared by several files,
+// so they have no versions of their own
+func (u *useCase) checkVersioned(ctx context.Context, url string) error {
+	if ref, err := u.blobRef(ctx, url); err != nil {
+		return err
+	} else if ref != nil {
+		return customErrors.NotSupported
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package fileUseCase_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.etcd.io/bbolt"
+
+	dedupRepo "github.com/freemen-app/file_storage/adapter/repository/dedup"
+	fileRepo "github.com/freemen-app/file_storage/adapter/repository/file"
+	"github.com/freemen-app/file_storage/domain/dto"
+	customErrors "github.com/freemen-app/file_storage/domain/errors"
+	"github.com/freemen-app/file_storage/domain/policy"
+	"github.com/freemen-app/file_storage/infrastructure/testing/helpers"
+	"github.com/freemen-app/file_storage/infrastructure/testing/mocks"
+	fileUseCase "github.com/freemen-app/file_storage/usecase/file"
+)
+
+func TestUseCase_RestoreVersion(t *testing.T) {
+	url := "https://aws.s3/test.bucket/invoices/test.pdf"
+	restored := &dto.UploadOutput{Url: url, VersionId: "v3"}
+	tests := []struct {
+		name      string
+		input     *dto.RestoreVersionInput
+		mockCalls mocks.Calls
+		want      *dto.UploadOutput
+		wantErr   error
+	}{
+		{
+			name:  "ACL of the latest version",
+			input: &dto.RestoreVersionInput{Url: url, VersionId: "v1"},
+			mockCalls: mocks.Calls{
+				{Method: "Key", Args: []interface{}{url}, ReturnArgs: []interface{}{"invoices/test.pdf", nil}},
+				{Method: "ACL", Args: []interface{}{helpers.DefaultCtx, url}, ReturnArgs: []interface{}{"bucket-owner-read", nil}},
+				{
+					Method:     "RestoreVersion",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "bucket-owner-read"}},
+					ReturnArgs: []interface{}{restored, nil},
+				},
+			},
+			want: restored,
+		},
+		{
+			name:  "deleted file",
+			input: &dto.RestoreVersionInput{Url: url, VersionId: "v1"},
+			mockCalls: mocks.Calls{
+				{Method: "Key", Args: []interface{}{url}, ReturnArgs: []interface{}{"invoices/test.pdf", nil}},
+				{Method: "ACL", Args: []interface{}{helpers.DefaultCtx, url}, ReturnArgs: []interface{}{"", customErrors.NotFound}},
+				{
+					Method:     "RestoreVersion",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "private"}},
+					ReturnArgs: []interface{}{restored, nil},
+				},
+			},
+			want: restored,
+		},
+		{
+			name:  "unknown ACL",
+			input: &dto.RestoreVersionInput{Url: url, VersionId: "v1"},
+			mockCalls: mocks.Calls{
+				{Method: "Key", Args: []interface{}{url}, ReturnArgs: []interface{}{"invoices/test.pdf", nil}},
+				{Method: "ACL", Args: []interface{}{helpers.DefaultCtx, url}, ReturnArgs: []interface{}{"", nil}},
+				{
+					Method:     "RestoreVersion",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "private"}},
+					ReturnArgs: []interface{}{restored, nil},
+				},
+			},
+			want: restored,
+		},
+		{
+			name:  "requested ACL",
+			input: &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "bucket-owner-read"},
+			mockCalls: mocks.Calls{
+				{Method: "Key", Args: []interface{}{url}, ReturnArgs: []interface{}{"invoices/test.pdf", nil}},
+				{
+					Method:     "RestoreVersion",
+					Args:       []interface{}{helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "bucket-owner-read"}},
+					ReturnArgs: []interface{}{restored, nil},
+				},
+			},
+			want: restored,
+		},
+		{
+			name:  "ACL not allowed",
+			input: &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "public-read"},
+			mockCalls: mocks.Calls{
+				{Method: "Key", Args: []interface{}{url}, ReturnArgs: []interface{}{"invoices/test.pdf", nil}},
+			},
+			wantErr: validation.Errors{"ACL": customErrors.ACLNotAllowed},
+		},
+		{
+			name:  "missing version",
+			input: &dto.RestoreVersionInput{Url: url, VersionId: "v1", ACL: "private"},
+			mockCalls: mocks.Calls{
+				{Method: "Key", Args: []interface{}{url}, ReturnArgs: []interface{}{"invoices/test.pdf", nil}},
+				{
+					Method:     "RestoreVersion",
+					Args:       []interface{}{helpers.DefaultCtx, mock.Anything},
+					ReturnArgs: []interface{}{nil, customErrors.VersionNotFound},
+				},
+			},
+			wantErr: customErrors.VersionNotFound,
+		},
+		{
+			name:  "trash",
+			input: &dto.RestoreVersionInput{Url: url, VersionId: "v1"},
+			mockCalls: mocks.Calls{
+				{Method: "Key", Args: []interface{}{url}, ReturnArgs: []interface{}{"_trash/test.pdf", nil}},
+			},
+			wantErr: validation.Errors{"Directory": customErrors.ReservedKey},
+		},
+		{
+			name:    "invalid input",
+			input:   &dto.RestoreVersionInput{Url: url},
+			wantErr: validation.Errors{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileRepo := new(mocks.FileRepo)
+			for _, call := range tt.mockCalls {
+				fileRepo.On(call.Method, call.Args...).Return(call.ReturnArgs...)
+			}
+			useCase := fileUseCase.New(
+				fileRepo,
+				fileUseCase.WithPolicies(policy.Rules{{Prefix: "invoices/", ACLs: []string{"private", "bucket-owner-read"}}}),
+				fileUseCase.WithTrash("_trash", time.Hour),
+			)
+			got, err := useCase.RestoreVersion(helpers.DefaultCtx, tt.input)
+			if errs, ok := tt.wantErr.(validation.Errors); ok && len(errs) == 0 {
+				assert.IsType(t, tt.wantErr, err)
+			} else {
+				assert.EqualValues(t, tt.wantErr, err)
+			}
+			assert.EqualValues(t, tt.want, got)
+			fileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUseCase_RestoreVersion_Catalog(t *testing.T) {
+	url := "https://aws.s3/test.bucket/docs/test.txt"
+	createdAt := time.Now().Add(-time.Hour)
+	fileRepo := new(mocks.FileRepo)
+	fileRepo.On("Key", url).Return("docs/test.txt", nil)
+	fileRepo.On("ACL", helpers.DefaultCtx, url).Return("public-read", nil)
+	fileRepo.On("RestoreVersion", helpers.DefaultCtx, mock.Anything).Return(&dto.UploadOutput{Url: url, VersionId: "v3"}, nil)
+	fileRepo.On("Stat", helpers.DefaultCtx, &dto.StatInput{Url: url}).
+		Return(&dto.FileInfo{Key: "docs/test.txt", Url: url, Size: 4, ContentType: "text/plain"}, nil)
+	catalog := new(mocks.CatalogRepo)
+	catalog.On("Get", helpers.DefaultCtx, url).
+		Return(&dto.CatalogEntry{Key: "docs/test.txt", Url: url, Owner: "user", Size: 8, CreatedAt: createdAt}, nil)
+	catalog.On("Save", helpers.DefaultCtx, mock.MatchedBy(func(entry *dto.CatalogEntry) bool {
+		return entry.Url == url && entry.Owner == "user" && entry.Size == 4 &&
+			entry.ContentType == "text/plain" && entry.CreatedAt.Equal(createdAt)
+	})).Return(nil)
+
+	useCase := fileUseCase.New(fileRepo, fileUseCase.WithCatalog(catalog))
+	got, err := useCase.RestoreVersion(helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, &dto.UploadOutput{Url: url, VersionId: "v3"}, got)
+	fileRepo.AssertExpectations(t)
+	catalog.AssertExpectations(t)
+}
+
+func TestUseCase_Versions_Deduplicated(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(helpers.TempDir(t), "test.db"), 0600, nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	repo := fileRepo.NewMemory("http://localhost/files")
+	useCase := fileUseCase.New(repo, fileUseCase.WithDeduplication(dedupRepo.New(db), "_blobs"))
+	output, err := useCase.Upload(helpers.DefaultCtx, &dto.UploadInput{File: bytes.NewBufferString("test"), Filename: "test.txt"})
+	assert.NoError(t, err)
+	url := output.Url
+
+	_, err = useCase.ListVersions(helpers.DefaultCtx, &dto.ListVersionsInput{Url: url})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = useCase.GetVersion(helpers.DefaultCtx, &dto.GetVersionInput{DownloadInput: dto.DownloadInput{Url: url}, VersionId: "v1"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+	_, err = useCase.RestoreVersion(helpers.DefaultCtx, &dto.RestoreVersionInput{Url: url, VersionId: "v1"})
+	assert.EqualValues(t, customErrors.NotSupported, err)
+}